@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/joho/godotenv"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/database"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/database/migration"
+)
+
+const usage = `Usage: migrate <command>
+
+Commands:
+  up             apply every pending migration
+  down [steps]   roll back the last steps migrations, 1 by default
+  to <version>   apply or roll back migrations up to version
+  status         list the migrations and when they were applied
+  create <name>  write an empty pair of up and down files to ` + migration.Dir + `
+
+0001_initial can't be rolled back: it may have adopted the books and users
+tables of a database that predates migrations, and rolling it back would
+drop them. down and to fail when they reach it.`
+
+// Applies, rolls back and creates the versioned schema migrations
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	command, args := os.Args[1], os.Args[2:]
+
+	// Creating a migration needs no database
+	if command == "create" {
+		if len(args) != 1 {
+			log.Fatal("Usage: migrate create <name>")
+		}
+		paths, err := migration.Create(migration.Dir, args[0])
+		if err != nil {
+			log.Fatalf("Failed to create migration: %v", err)
+		}
+		for _, path := range paths {
+			log.Printf("Created %s", path)
+		}
+		return
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found or error loading .env file")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	migrator, err := migration.New(database.Connect())
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
+	}
+
+	switch command {
+	case "up":
+		err = migrator.Up(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 0 {
+			if steps, err = strconv.Atoi(args[0]); err != nil || steps < 1 {
+				log.Fatalf("Invalid number of steps %q", args[0])
+			}
+		}
+		err = migrator.Down(ctx, steps)
+	case "to":
+		if len(args) != 1 {
+			log.Fatal("Usage: migrate to <version>")
+		}
+		version, parseErr := strconv.ParseInt(args[0], 10, 64)
+		if parseErr != nil || version < 0 {
+			log.Fatalf("Invalid version %q", args[0])
+		}
+		err = migrator.To(ctx, version)
+	case "status":
+		err = printStatus(ctx, migrator)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatalf("Failed to run migrate %s: %v", command, err)
+	}
+}
+
+func printStatus(ctx context.Context, migrator *migration.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05 MST")
+		}
+		if status.Missing {
+			appliedAt += " (no file in this build)"
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+	}
+	return w.Flush()
+}
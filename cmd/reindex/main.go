@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/joho/godotenv"
+	"github.com/kev1nandreas/go-rest-api-template/env"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/database"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/search"
+)
+
+// Rebuilds the search index configured by SEARCH_BACKEND from the books table.
+// The embedded index can only be open in one process, so this refuses to run
+// while the server has it open; run the reindex-search job instead.
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found or error loading .env file")
+	}
+
+	batchSize := env.GetEnvInt("SEARCH_REINDEX_BATCH_SIZE", 500)
+
+	db := database.NewDatabase()
+	searcher, err := search.NewSearcher(db)
+	if errors.Is(err, search.ErrIndexLocked) {
+		log.Fatalf("The search index is open in a running server: stop it first, or rebuild the index in place with POST /api/v1/jobs/reindex-search/run")
+	}
+	if err != nil {
+		log.Fatalf("Failed to initialize search backend: %v", err)
+	}
+
+	log.Println("Rebuilding search index...")
+	count, err := search.Reindex(context.Background(), searcher, db, batchSize)
+	if err != nil {
+		log.Fatalf("Failed to rebuild search index: %v", err)
+	}
+	log.Printf("Search index rebuilt with %d books", count)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/joho/godotenv"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/api"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/cache"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/database"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/scheduler"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/search"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/storage"
+)
+
+// Runs the background jobs on their schedules without serving the API, for
+// deployments that start the server with SCHEDULER_ENABLED=false
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found or error loading .env file")
+	}
+
+	appCache, err := cache.NewCache()
+	if err != nil {
+		log.Fatalf("Failed to initialize cache: %v", err)
+	}
+	db := database.NewDatabase()
+	searcher, err := search.NewSearcher(db)
+	if err != nil {
+		log.Fatalf("Failed to initialize search backend: %v", err)
+	}
+	blobs, err := storage.NewBlobStore()
+	if err != nil {
+		log.Fatalf("Failed to initialize blob storage: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	jobs := scheduler.New(appCache, scheduler.NewGormRunStore(db))
+	if err := api.RegisterJobs(jobs, db, appCache, searcher, blobs); err != nil {
+		log.Fatalf("Failed to register background jobs: %v", err)
+	}
+
+	log.Println("Running scheduled jobs...")
+	jobs.Start(ctx)
+	<-ctx.Done()
+	log.Println("Scheduler stopped")
+}
@@ -10,6 +10,10 @@ import (
 	"github.com/kev1nandreas/go-rest-api-template/pkg/api"
 	"github.com/kev1nandreas/go-rest-api-template/pkg/cache"
 	"github.com/kev1nandreas/go-rest-api-template/pkg/database"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/scheduler"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/search"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/storage"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/store"
 	"go.mongodb.org/mongo-driver/mongo"
 
 	"go.uber.org/zap"
@@ -57,9 +61,20 @@ func main() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	redisClient := cache.NewRedisClient()
+	appCache, err := cache.NewCache()
+	if err != nil {
+		log.Fatalf("Failed to initialize cache: %v", err)
+	}
 	db := database.NewDatabase()
 	dbWrapper := &database.GormDatabase{DB: db}
+	searcher, err := search.NewSearcher(db)
+	if err != nil {
+		log.Fatalf("Failed to initialize search backend: %v", err)
+	}
+	blobs, err := storage.NewBlobStore()
+	if err != nil {
+		log.Fatalf("Failed to initialize blob storage: %v", err)
+	}
 	var mongo *mongo.Collection
 
 	if isLogging {
@@ -67,10 +82,21 @@ func main() {
 	}
 
 	ctx := context.Background()
+
+	jobs := scheduler.New(appCache, scheduler.NewGormRunStore(db))
+	if err := api.RegisterJobs(jobs, db, appCache, searcher, blobs); err != nil {
+		log.Fatalf("Failed to register background jobs: %v", err)
+	}
+	// Every replica can run the scheduler; each scheduled run happens once.
+	// Turn it off to run the jobs from cmd/scheduler instead.
+	if env.GetEnvBool("SCHEDULER_ENABLED", true) {
+		jobs.Start(ctx)
+	}
+
 	logger, _ := zap.NewProduction()
 	defer logger.Sync()
 
-	r := api.NewRouter(logger, mongo, dbWrapper, redisClient, &ctx)
+	r := api.NewRouter(logger, mongo, dbWrapper, store.NewGormUnitOfWork(db), appCache, searcher, blobs, jobs)
 
 	if err := r.Run(":" + strconv.Itoa(appPort)); err != nil {
 		log.Fatal(err)
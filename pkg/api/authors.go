@@ -0,0 +1,300 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/database"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/pagination"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/response"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/store"
+)
+
+type AuthorRepository interface {
+	FindAuthors(c *gin.Context)
+	CreateAuthor(c *gin.Context)
+	FindAuthor(c *gin.Context)
+	UpdateAuthor(c *gin.Context)
+	DeleteAuthor(c *gin.Context)
+	FindAuthorBooks(c *gin.Context)
+}
+
+// authorSortColumns are the columns author lists can be sorted by
+var authorSortColumns = map[string]bool{"name": true, "created_at": true, "updated_at": true}
+
+// authorRepository holds shared resources for the author endpoints
+type authorRepository struct {
+	DB database.Database
+	// Stores runs the writes
+	Stores store.UnitOfWork
+}
+
+func NewAuthorRepository(db database.Database, stores store.UnitOfWork) *authorRepository {
+	return &authorRepository{
+		DB:     db,
+		Stores: stores,
+	}
+}
+
+// sendAuthorLookupError answers a request whose author couldn't be read
+func sendAuthorLookupError(c *gin.Context, err error) {
+	if errors.Is(err, store.ErrNotFound) {
+		response.NewErrorResponse(http.StatusNotFound, "Author not found", err.Error()).Send(c)
+		return
+	}
+	response.NewErrorResponse(http.StatusInternalServerError, "Failed to retrieve author", err.Error()).Send(c)
+}
+
+// db returns the database bound to the context of the request
+func (r *authorRepository) db(c *gin.Context) database.Database {
+	return r.DB.WithContext(c.Request.Context())
+}
+
+// FindAuthors godoc
+// @Summary Get all authors with pagination
+// @Description Get a paginated list of authors, optionally filtered by name
+// @Tags authors
+// @Security ApiKeyAuth
+// @Produce json
+// @Param q query string false "Only authors whose name contains this text"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Param sort query string false "Sort column" default(name)
+// @Param order query string false "Sort order (asc or desc)"
+// @Success 200 {array} models.Author "Successfully retrieved list of authors"
+// @Failure 400 {string} string "Invalid sort column"
+// @Router /authors [get]
+func (r *authorRepository) FindAuthors(c *gin.Context) {
+	var authors []models.Author
+
+	params := pagination.ParseParams(c)
+	if params.Sort == "" {
+		params.Sort = "name"
+		if c.Query("order") == "" {
+			params.Order = "asc"
+		}
+	}
+	if !authorSortColumns[params.Sort] {
+		response.NewErrorResponse(http.StatusBadRequest, "Invalid sort column", "sort must be name, created_at or updated_at").Send(c)
+		return
+	}
+
+	var query interface{}
+	var args []interface{}
+	if text := c.Query("q"); text != "" {
+		query = "name ILIKE ?"
+		args = append(args, "%"+text+"%")
+	}
+
+	if _, meta, err := params.ApplyWithQuery(r.db(c).Model(&models.Author{}), &authors, query, args...); err != nil {
+		response.NewErrorResponse(http.StatusInternalServerError, "Failed to retrieve authors", err.Error()).Send(c)
+	} else {
+		response.NewPaginatedResponse("Authors retrieved successfully", authors, meta).Send(c)
+	}
+}
+
+// CreateAuthor godoc
+// @Summary Create a new author
+// @Description Create an author. Names differing only in case, spacing or punctuation are the same author.
+// @Tags authors
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Accept  json
+// @Produce  json
+// @Param input body models.CreateAuthor true "Create author object"
+// @Success 201 {object} models.Author "Successfully created author"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 409 {string} string "Author already exists"
+// @Router /authors [post]
+func (r *authorRepository) CreateAuthor(c *gin.Context) {
+	var input models.CreateAuthor
+	var author models.Author
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.NewErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()).Send(c)
+		return
+	}
+
+	input.ApplyTo(&author)
+	if author.NameKey == "" {
+		response.NewErrorResponse(http.StatusBadRequest, "Invalid request body", "name must contain letters or digits").Send(c)
+		return
+	}
+
+	if err := r.Stores.Stores().Authors.CreateAuthor(c.Request.Context(), &author); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			response.NewErrorResponse(http.StatusConflict, "Author already exists", "an author named "+author.Name+" already exists").Send(c)
+			return
+		}
+		response.NewErrorResponse(http.StatusInternalServerError, "Failed to create author", err.Error()).Send(c)
+		return
+	}
+
+	response.Response{
+		StatusCode: http.StatusCreated,
+		Success:    true,
+		Message:    "Author created successfully",
+		Data:       author,
+	}.Send(c)
+}
+
+// FindAuthor godoc
+// @Summary Find an author by ID
+// @Description Get details of an author by its ID
+// @Tags authors
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Author ID"
+// @Success 200 {object} models.Author "Successfully retrieved author"
+// @Failure 404 {string} string "Author not found"
+// @Router /authors/{id} [get]
+func (r *authorRepository) FindAuthor(c *gin.Context) {
+	author, err := r.Stores.Stores().Authors.GetAuthor(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		sendAuthorLookupError(c, err)
+		return
+	}
+
+	response.NewSuccessResponse("Author retrieved successfully", author).Send(c)
+}
+
+// UpdateAuthor godoc
+// @Summary Replace an author by ID
+// @Description Replace the name and bio of an author. Book bylines are left as printed.
+// @Tags authors
+// @Security ApiKeyAuth
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Author ID"
+// @Param input body models.CreateAuthor true "Update author object"
+// @Success 200 {object} models.Author "Successfully updated author"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Author not found"
+// @Failure 409 {string} string "Another author already has this name"
+// @Router /authors/{id} [put]
+func (r *authorRepository) UpdateAuthor(c *gin.Context) {
+	var input models.CreateAuthor
+
+	authors := r.Stores.Stores().Authors
+	author, err := authors.GetAuthor(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		sendAuthorLookupError(c, err)
+		return
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.NewErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()).Send(c)
+		return
+	}
+
+	input.ApplyTo(&author)
+	if author.NameKey == "" {
+		response.NewErrorResponse(http.StatusBadRequest, "Invalid request body", "name must contain letters or digits").Send(c)
+		return
+	}
+
+	if err := authors.UpdateAuthor(c.Request.Context(), author); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			response.NewErrorResponse(http.StatusConflict, "Author already exists", "another author is named "+author.Name).Send(c)
+			return
+		}
+		response.NewErrorResponse(http.StatusInternalServerError, "Failed to update author", err.Error()).Send(c)
+		return
+	}
+
+	response.NewSuccessResponse("Author updated successfully", author).Send(c)
+}
+
+// DeleteAuthor godoc
+// @Summary Delete an author by ID
+// @Description Delete an author that is no longer credited on any book
+// @Tags authors
+// @Security ApiKeyAuth
+// @Produce  json
+// @Param id path string true "Author ID"
+// @Success 204 {string} string "Successfully deleted author"
+// @Failure 404 {string} string "Author not found"
+// @Failure 409 {string} string "Author is still credited on books"
+// @Router /authors/{id} [delete]
+func (r *authorRepository) DeleteAuthor(c *gin.Context) {
+	authors := r.Stores.Stores().Authors
+	author, err := authors.GetAuthor(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		sendAuthorLookupError(c, err)
+		return
+	}
+
+	// The credit check and the delete run in one transaction so a book
+	// can't credit the author in between
+	err = r.Stores.WithTx(c.Request.Context(), func(ctx context.Context, tx store.Stores) error {
+		return tx.Authors.DeleteAuthor(ctx, author)
+	})
+	if errors.Is(err, store.ErrConflict) {
+		response.NewErrorResponse(http.StatusConflict, "Author is still credited on books", "remove the author from every book first").Send(c)
+		return
+	}
+	if err != nil {
+		response.NewErrorResponse(http.StatusInternalServerError, "Failed to delete author", err.Error()).Send(c)
+		return
+	}
+
+	response.Response{
+		StatusCode: http.StatusNoContent,
+		Success:    true,
+		Message:    "Author deleted successfully",
+		Data:       true,
+	}.Send(c)
+}
+
+// FindAuthorBooks godoc
+// @Summary List the books of an author
+// @Description Get a paginated list of the author's credits, with the credited book and role
+// @Tags authors
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Author ID"
+// @Param role query string false "Only credits with this role (author, editor, translator, illustrator)"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Success 200 {array} models.BookAuthor "Successfully retrieved the author's books"
+// @Failure 404 {string} string "Author not found"
+// @Router /authors/{id}/books [get]
+func (r *authorRepository) FindAuthorBooks(c *gin.Context) {
+	var credits []models.BookAuthor
+
+	author, err := r.Stores.Stores().Authors.GetAuthor(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		sendAuthorLookupError(c, err)
+		return
+	}
+
+	params := pagination.ParseParams(c)
+
+	// Trashed books keep their credits but are hidden like every other read
+	query := r.db(c).Model(&models.BookAuthor{}).
+		Joins("JOIN books ON books.id = book_authors.book_id AND books.deleted_at IS NULL").
+		Where("book_authors.author_id = ?", author.ID)
+	if role := c.Query("role"); role != "" {
+		query = query.Where("book_authors.role = ?", role)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		response.NewErrorResponse(http.StatusInternalServerError, "Failed to retrieve books", err.Error()).Send(c)
+		return
+	}
+
+	if err := query.Preload("Book").
+		Order("books.title, book_authors.role").
+		Limit(params.PageSize).Offset(params.GetOffset()).
+		Find(&credits).Error; err != nil {
+		response.NewErrorResponse(http.StatusInternalServerError, "Failed to retrieve books", err.Error()).Send(c)
+		return
+	}
+
+	response.NewPaginatedResponse("Books retrieved successfully", credits, params.BuildMeta(total)).Send(c)
+}
@@ -0,0 +1,107 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: pkg/api/authors.go
+
+// Package api is a generated GoMock package.
+package api
+
+import (
+	reflect "reflect"
+
+	gin "github.com/gin-gonic/gin"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockAuthorRepository is a mock of AuthorRepository interface.
+type MockAuthorRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuthorRepositoryMockRecorder
+}
+
+// MockAuthorRepositoryMockRecorder is the mock recorder for MockAuthorRepository.
+type MockAuthorRepositoryMockRecorder struct {
+	mock *MockAuthorRepository
+}
+
+// NewMockAuthorRepository creates a new mock instance.
+func NewMockAuthorRepository(ctrl *gomock.Controller) *MockAuthorRepository {
+	mock := &MockAuthorRepository{ctrl: ctrl}
+	mock.recorder = &MockAuthorRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuthorRepository) EXPECT() *MockAuthorRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateAuthor mocks base method.
+func (m *MockAuthorRepository) CreateAuthor(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "CreateAuthor", c)
+}
+
+// CreateAuthor indicates an expected call of CreateAuthor.
+func (mr *MockAuthorRepositoryMockRecorder) CreateAuthor(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAuthor", reflect.TypeOf((*MockAuthorRepository)(nil).CreateAuthor), c)
+}
+
+// DeleteAuthor mocks base method.
+func (m *MockAuthorRepository) DeleteAuthor(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "DeleteAuthor", c)
+}
+
+// DeleteAuthor indicates an expected call of DeleteAuthor.
+func (mr *MockAuthorRepositoryMockRecorder) DeleteAuthor(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAuthor", reflect.TypeOf((*MockAuthorRepository)(nil).DeleteAuthor), c)
+}
+
+// FindAuthor mocks base method.
+func (m *MockAuthorRepository) FindAuthor(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "FindAuthor", c)
+}
+
+// FindAuthor indicates an expected call of FindAuthor.
+func (mr *MockAuthorRepositoryMockRecorder) FindAuthor(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAuthor", reflect.TypeOf((*MockAuthorRepository)(nil).FindAuthor), c)
+}
+
+// FindAuthorBooks mocks base method.
+func (m *MockAuthorRepository) FindAuthorBooks(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "FindAuthorBooks", c)
+}
+
+// FindAuthorBooks indicates an expected call of FindAuthorBooks.
+func (mr *MockAuthorRepositoryMockRecorder) FindAuthorBooks(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAuthorBooks", reflect.TypeOf((*MockAuthorRepository)(nil).FindAuthorBooks), c)
+}
+
+// FindAuthors mocks base method.
+func (m *MockAuthorRepository) FindAuthors(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "FindAuthors", c)
+}
+
+// FindAuthors indicates an expected call of FindAuthors.
+func (mr *MockAuthorRepositoryMockRecorder) FindAuthors(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAuthors", reflect.TypeOf((*MockAuthorRepository)(nil).FindAuthors), c)
+}
+
+// UpdateAuthor mocks base method.
+func (m *MockAuthorRepository) UpdateAuthor(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UpdateAuthor", c)
+}
+
+// UpdateAuthor indicates an expected call of UpdateAuthor.
+func (mr *MockAuthorRepositoryMockRecorder) UpdateAuthor(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAuthor", reflect.TypeOf((*MockAuthorRepository)(nil).UpdateAuthor), c)
+}
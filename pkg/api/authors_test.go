@@ -0,0 +1,114 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func newAuthorTestRouter(repo *authorRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/authors", repo.FindAuthors)
+	r.POST("/authors", repo.CreateAuthor)
+	r.GET("/authors/:id", repo.FindAuthor)
+	return r
+}
+
+func TestCreateAuthor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	authors := store.NewMockAuthorStore(ctrl)
+	r := newAuthorTestRouter(NewAuthorRepository(nil, newMockUnitOfWork(ctrl, store.Stores{Authors: authors})))
+
+	authors.EXPECT().CreateAuthor(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, author *models.Author) error {
+		assert.Equal(t, "J.K. Rowling", author.Name)
+		assert.Equal(t, "j k rowling", author.NameKey)
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/authors", bytes.NewBufferString(`{"name":" J.K.  Rowling "}`)))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NotContains(t, w.Body.String(), "name_key")
+}
+
+func TestCreateAuthorRejectsInvalidAndDuplicateNames(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	authors := store.NewMockAuthorStore(ctrl)
+	r := newAuthorTestRouter(NewAuthorRepository(nil, newMockUnitOfWork(ctrl, store.Stores{Authors: authors})))
+
+	for _, body := range []string{`{}`, `{"name":"..."}`} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/authors", bytes.NewBufferString(body)))
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+
+	authors.EXPECT().CreateAuthor(gomock.Any(), gomock.Any()).Return(store.ErrConflict)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/authors", bytes.NewBufferString(`{"name":"J. K. Rowling"}`)))
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestFindAuthorNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	authors := store.NewMockAuthorStore(ctrl)
+	r := newAuthorTestRouter(NewAuthorRepository(nil, newMockUnitOfWork(ctrl, store.Stores{Authors: authors})))
+
+	authors.EXPECT().GetAuthor(gomock.Any(), "missing").Return(models.Author{}, store.ErrNotFound)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/authors/missing", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestFindAuthorsRejectsUnknownSortColumns(t *testing.T) {
+	r := newAuthorTestRouter(NewAuthorRepository(nil, nil))
+
+	for _, sort := range []string{"password", "(SELECT+password+FROM+users+LIMIT+1)"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/authors?sort="+sort+"&order=desc", nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, sort)
+	}
+}
+
+func TestSetBookAuthorsRejectsInvalidCredits(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// No expectations: invalid credits must be rejected before the database is touched
+	mockDB := newMockDatabase(ctrl)
+	repo := NewBookRepository(mockDB, nil, nil, nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.PUT("/books/:id/authors", repo.SetBookAuthors)
+
+	authorID := "123e4567-e89b-12d3-a456-426614174000"
+	tests := map[string]string{
+		"not an array":   `{"author_id":"` + authorID + `"}`,
+		"invalid uuid":   `[{"author_id":"nope"}]`,
+		"unknown role":   `[{"author_id":"` + authorID + `","role":"ghostwriter"}]`,
+		"duplicate role": `[{"author_id":"` + authorID + `"},{"author_id":"` + authorID + `","role":"author"}]`,
+	}
+
+	for name, body := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/books/1/authors", bytes.NewBufferString(body)))
+		assert.Equal(t, http.StatusBadRequest, w.Code, name)
+	}
+}
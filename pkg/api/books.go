@@ -2,43 +2,153 @@ package api
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
 	gin "github.com/gin-gonic/gin"
-	"github.com/kev1nandreas/go-rest-api-template/pkg/response"
+	"github.com/google/uuid"
+	"github.com/kev1nandreas/go-rest-api-template/env"
 	"github.com/kev1nandreas/go-rest-api-template/pkg/cache"
 	"github.com/kev1nandreas/go-rest-api-template/pkg/database"
 	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/moderation"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/response"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/search"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/service"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/storage"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/store"
 )
 
 type BookRepository interface {
 	Healthcheck(c *gin.Context)
+	Readiness(c *gin.Context)
 	FindBooks(c *gin.Context)
+	SearchBooks(c *gin.Context)
 	CreateBook(c *gin.Context)
 	FindBook(c *gin.Context)
+	FindBookByISBN(c *gin.Context)
+	FindBookAuthors(c *gin.Context)
+	SetBookAuthors(c *gin.Context)
+	FindReviews(c *gin.Context)
+	CreateReview(c *gin.Context)
+	UpdateReview(c *gin.Context)
+	DeleteReview(c *gin.Context)
+	FlagReview(c *gin.Context)
+	FindModerationQueue(c *gin.Context)
+	ApproveReview(c *gin.Context)
+	RejectReview(c *gin.Context)
 	UpdateBook(c *gin.Context)
+	PatchBook(c *gin.Context)
 	DeleteBook(c *gin.Context)
+	CreateBooks(c *gin.Context)
+	UpdateBooks(c *gin.Context)
+	DeleteBooks(c *gin.Context)
+	TrashBooks(c *gin.Context)
+	RestoreBook(c *gin.Context)
+	PurgeBook(c *gin.Context)
+	ExportBooks(c *gin.Context)
+	ImportBooks(c *gin.Context)
+	FindImportJob(c *gin.Context)
+	UploadCover(c *gin.Context)
+	DeleteCover(c *gin.Context)
+	ServeBlob(c *gin.Context)
+	FindBookFiles(c *gin.Context)
+	UploadBookFile(c *gin.Context)
+	DeleteBookFile(c *gin.Context)
+	CreateBookFileLink(c *gin.Context)
+	DownloadBookFile(c *gin.Context)
+	UpdateInventory(c *gin.Context)
+	CheckoutBook(c *gin.Context)
+	ReturnLoan(c *gin.Context)
+	RenewLoan(c *gin.Context)
+	FindLoans(c *gin.Context)
+	FindUserLoans(c *gin.Context)
+	CreateReservation(c *gin.Context)
+	FindReservations(c *gin.Context)
+	CancelReservation(c *gin.Context)
+	FindCacheStats(c *gin.Context)
 }
 
 // bookRepository holds shared resources like database and Redis client
 type bookRepository struct {
-	DB          database.Database
-	RedisClient cache.Cache
-	Ctx         *context.Context
+	DB database.Database
+	// Stores runs the writes, in one transaction per request
+	Stores           store.UnitOfWork
+	Cache            cache.Cache
+	Books            *service.BookService
+	Searcher         search.Searcher
+	Blobs            storage.BlobStore
+	IfMatchMode      string
+	BulkLimit        int
+	ImportBatchSize  int
+	ImportMaxBytes   int64
+	ImportAsyncBytes int64
+	CoverMaxBytes    int64
+	EbookMaxBytes    int64
+	FileLinkTTL      time.Duration
+	// Review moderation: whether new reviews wait for a moderator, how many
+	// user flags pull an approved review, and the words that flag it outright
+	ReviewRequireApproval bool
+	ReviewFlagThreshold   int
+	ReviewBlockedWords    *moderation.WordList
+	// Lending: how long a loan lasts, how often it can be renewed and how
+	// long a returned copy is held for the next reservation
+	LoanPeriod            time.Duration
+	LoanMaxRenewals       int
+	ReservationHoldPeriod time.Duration
 }
 
-// NewAppContext creates a new AppContext
-func NewBookRepository(db database.Database, redisClient cache.Cache, ctx *context.Context) *bookRepository {
+// NewBookRepository creates the handlers of the book endpoints, configured
+// from the environment. Every write goes through stores; db only serves
+// reads, like the paged lists of the trash, loans and reviews and the export.
+func NewBookRepository(db database.Database, stores store.UnitOfWork, appCache cache.Cache, searcher search.Searcher, blobs storage.BlobStore) *bookRepository {
 	return &bookRepository{
-		DB:          db,
-		RedisClient: redisClient,
-		Ctx:         ctx,
+		DB:                    db,
+		Stores:                stores,
+		Cache:                 appCache,
+		Books:                 service.NewBookService(stores, appCache, searcher),
+		Searcher:              searcher,
+		Blobs:                 blobs,
+		IfMatchMode:           env.GetEnvString("BOOKS_IF_MATCH", IfMatchOptional),
+		BulkLimit:             env.GetEnvInt("BOOKS_BULK_LIMIT", 100),
+		ImportBatchSize:       env.GetEnvInt("BOOKS_IMPORT_BATCH_SIZE", 500),
+		ImportMaxBytes:        int64(env.GetEnvInt("BOOKS_IMPORT_MAX_BYTES", 32<<20)),
+		ImportAsyncBytes:      int64(env.GetEnvInt("BOOKS_IMPORT_ASYNC_BYTES", 1<<20)),
+		CoverMaxBytes:         int64(env.GetEnvInt("COVER_MAX_BYTES", 5<<20)),
+		EbookMaxBytes:         int64(env.GetEnvInt("EBOOK_MAX_BYTES", 100<<20)),
+		FileLinkTTL:           time.Duration(env.GetEnvInt("FILE_LINK_TTL_SECONDS", 300)) * time.Second,
+		ReviewRequireApproval: env.GetEnvBool("REVIEWS_REQUIRE_APPROVAL", false),
+		ReviewFlagThreshold:   env.GetEnvInt("REVIEWS_FLAG_THRESHOLD", 3),
+		ReviewBlockedWords:    moderation.ParseWordList(env.GetEnvString("REVIEWS_BLOCKED_WORDS", "")),
+		LoanPeriod:            time.Duration(env.GetEnvInt("LOAN_PERIOD_DAYS", 14)) * 24 * time.Hour,
+		LoanMaxRenewals:       env.GetEnvInt("LOAN_MAX_RENEWALS", 2),
+		ReservationHoldPeriod: time.Duration(env.GetEnvInt("RESERVATION_HOLD_DAYS", 3)) * 24 * time.Hour,
 	}
 }
 
+// db returns the database bound to the context of the request, so queries
+// stop when the client goes away
+func (r *bookRepository) db(c *gin.Context) database.Database {
+	return r.DB.WithContext(c.Request.Context())
+}
+
+// sendBookLookupError answers a request whose book couldn't be read
+func sendBookLookupError(c *gin.Context, err error) {
+	if errors.Is(err, store.ErrNotFound) {
+		response.NewErrorResponse(http.StatusNotFound, "Book not found", err.Error()).Send(c)
+		return
+	}
+	response.NewErrorResponse(http.StatusInternalServerError, "Failed to retrieve book", err.Error()).Send(c)
+}
+
+// sendDuplicateISBN answers a write that collides with the unique ISBN index
+func sendDuplicateISBN(c *gin.Context, isbn models.ISBN) {
+	response.NewErrorResponse(http.StatusConflict, "Duplicate ISBN", "a book with ISBN "+string(isbn)+" already exists").Send(c)
+}
+
 // @BasePath /api/v1
 
 // Healthcheck godoc
@@ -54,6 +164,16 @@ func (r *bookRepository) Healthcheck(c *gin.Context) {
 	response.NewSuccessResponse("ok", nil).Send(c)
 }
 
+// WarmBooksCache caches the first page of FindBooks in every sort order, so
+// the busiest page is served from Redis even right after it expires
+func (r *bookRepository) WarmBooksCache(ctx context.Context) (string, error) {
+	warmed, err := r.Books.WarmPages(ctx)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("cached the first page in %d sort orders", warmed), nil
+}
+
 // FindBooks godoc
 // @Summary Get all books with pagination
 // @Description Get a list of all books with optional pagination
@@ -62,11 +182,16 @@ func (r *bookRepository) Healthcheck(c *gin.Context) {
 // @Produce json
 // @Param offset query int false "Offset for pagination" default(0)
 // @Param limit query int false "Limit for pagination" default(10)
+// @Param sort query string false "rating: highest rated first, then most reviewed"
+// @Param If-None-Match header string false "ETag from a previous response"
+// @Param If-Modified-Since header string false "Last-Modified from a previous response"
 // @Success 200 {array} models.Book "Successfully retrieved list of books"
+// @Header 200 {string} ETag "Validator for this page of books"
+// @Header 200 {string} Last-Modified "Time any book was last updated or deleted"
+// @Success 304 {string} string "Not modified"
+// @Failure 400 {string} string "Bad Request"
 // @Router /books [get]
 func (r *bookRepository) FindBooks(c *gin.Context) {
-	var books []models.Book
-
 	// Get query params
 	offsetQuery := c.DefaultQuery("offset", "0")
 	limitQuery := c.DefaultQuery("limit", "10")
@@ -84,37 +209,95 @@ func (r *bookRepository) FindBooks(c *gin.Context) {
 		return
 	}
 
-	// Create a cache key based on query params
-	cacheKey := "books_offset_" + offsetQuery + "_limit_" + limitQuery
+	page, cached, err := r.Books.ListBooks(c.Request.Context(), store.BookQuery{Offset: offset, Limit: limit, Sort: c.Query("sort")})
+	if errors.Is(err, service.ErrInvalidSort) {
+		response.NewErrorResponse(http.StatusBadRequest, "Invalid sort", err.Error()).Send(c)
+		return
+	} else if err != nil {
+		response.NewErrorResponse(http.StatusInternalServerError, "Failed to retrieve books", err.Error()).Send(c)
+		return
+	}
 
-	// Try fetching the data from Redis first
-	cachedBooks, err := r.RedisClient.Get(*r.Ctx, cacheKey).Result()
-	if err == nil {
-		err := json.Unmarshal([]byte(cachedBooks), &books)
-		if err != nil {
-			response.NewErrorResponse(http.StatusInternalServerError, "Failed to unmarshal cached data", err.Error()).Send(c)
-			return
-		}
-		response.NewSuccessResponse("Books retrieved from cache", books).Send(c)
+	if notModified(c, page.ETag, page.LastModified) {
 		return
 	}
+	if cached {
+		response.NewSuccessResponse("Books retrieved from cache", page.Books).Send(c)
+		return
+	}
+	response.NewSuccessResponse("Books retrieved successfully", page.Books).Send(c)
+}
 
-	// If cache missed, fetch data from the database
-	r.DB.Offset(offset).Limit(limit).Find(&books)
+// SearchBooks godoc
+// @Summary Search books
+// @Description Full text search over book titles and authors with author and year facets
+// @Tags books
+// @Security ApiKeyAuth
+// @Produce json
+// @Param q query string false "Search terms"
+// @Param author query string false "Filter by author"
+// @Param year query int false "Filter by year"
+// @Param offset query int false "Offset for pagination" default(0)
+// @Param limit query int false "Limit for pagination" default(10)
+// @Success 200 {array} models.Book "Matching books, with total and facets in meta"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /books/search [get]
+func (r *bookRepository) SearchBooks(c *gin.Context) {
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil {
+		response.NewErrorResponse(http.StatusBadRequest, "Invalid offset format", err.Error()).Send(c)
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil {
+		response.NewErrorResponse(http.StatusBadRequest, "Invalid limit format", err.Error()).Send(c)
+		return
+	}
 
-	// Serialize books object and store it in Redis
-	serializedBooks, err := json.Marshal(books)
+	year, err := strconv.Atoi(c.DefaultQuery("year", "0"))
 	if err != nil {
-		response.NewErrorResponse(http.StatusInternalServerError, "Failed to marshal data", err.Error()).Send(c)
+		response.NewErrorResponse(http.StatusBadRequest, "Invalid year format", err.Error()).Send(c)
 		return
 	}
-	err = r.RedisClient.Set(*r.Ctx, cacheKey, serializedBooks, time.Minute).Err() // Here TTL is set to one hour
+
+	result, err := r.Searcher.Search(c.Request.Context(), search.Query{
+		Text:   c.Query("q"),
+		Author: c.Query("author"),
+		Year:   year,
+		Offset: offset,
+		Limit:  limit,
+	})
 	if err != nil {
-		response.NewErrorResponse(http.StatusInternalServerError, "Failed to set cache", err.Error()).Send(c)
+		response.NewErrorResponse(http.StatusInternalServerError, "Failed to search books", err.Error()).Send(c)
 		return
 	}
 
-	response.NewSuccessResponse("Books retrieved successfully", books).Send(c)
+	books := []models.Book{}
+	if len(result.IDs) > 0 {
+		var found []models.Book
+		if err := r.db(c).Where("id IN ?", result.IDs).Find(&found).Error; err != nil {
+			response.NewErrorResponse(http.StatusInternalServerError, "Failed to load books", err.Error()).Send(c)
+			return
+		}
+
+		// Keep the relevance order returned by the searcher
+		byID := make(map[uuid.UUID]models.Book, len(found))
+		for _, book := range found {
+			byID[book.ID] = book
+		}
+		for _, id := range result.IDs {
+			if book, ok := byID[id]; ok {
+				books = append(books, book)
+			}
+		}
+	}
+
+	response.NewPaginatedResponse("Books retrieved successfully", books, gin.H{
+		"total":  result.Total,
+		"facets": result.Facets,
+	}).Send(c)
 }
 
 // CreateBook godoc
@@ -129,6 +312,7 @@ func (r *bookRepository) FindBooks(c *gin.Context) {
 // @Success 201 {object} models.Book "Successfully created book"
 // @Failure 400 {string} string "Bad Request"
 // @Failure 401 {string} string "Unauthorized"
+// @Failure 409 {string} string "A book with this ISBN already exists"
 // @Router /books [post]
 func (r *bookRepository) CreateBook(c *gin.Context) {
 	appCtx, exists := c.MustGet("appCtx").(*bookRepository)
@@ -143,19 +327,17 @@ func (r *bookRepository) CreateBook(c *gin.Context) {
 		return
 	}
 
-	book := models.Book{Title: input.Title, Author: input.Author}
-
-	appCtx.DB.Create(&book)
-
-	// Invalidate cache
-	keysPattern := "books_offset_*"
-	keys, err := appCtx.RedisClient.Keys(*appCtx.Ctx, keysPattern).Result()
-	if err == nil {
-		for _, key := range keys {
-			appCtx.RedisClient.Del(*appCtx.Ctx, key)
+	book, err := appCtx.Books.CreateBook(c.Request.Context(), input)
+	if err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			sendDuplicateISBN(c, book.ISBN)
+			return
 		}
+		response.NewErrorResponse(http.StatusInternalServerError, "Failed to create book", err.Error()).Send(c)
+		return
 	}
 
+	c.Header("ETag", bookETag(book))
 	response.Response{
 		StatusCode: http.StatusCreated,
 		Success:    true,
@@ -171,14 +353,62 @@ func (r *bookRepository) CreateBook(c *gin.Context) {
 // @Security ApiKeyAuth
 // @Produce json
 // @Param id path string true "Book ID"
+// @Param If-None-Match header string false "ETag from a previous response"
+// @Param If-Modified-Since header string false "Last-Modified from a previous response"
 // @Success 200 {object} models.Book "Successfully retrieved book"
+// @Header 200 {string} ETag "Current version of the book"
+// @Header 200 {string} Last-Modified "Time the book was last updated"
+// @Success 304 {string} string "Not modified"
 // @Failure 404 {string} string "Book not found"
 // @Router /books/{id} [get]
 func (r *bookRepository) FindBook(c *gin.Context) {
-	var book models.Book
+	book, cached, err := r.Books.GetBook(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		sendBookLookupError(c, err)
+		return
+	}
 
-	if err := r.DB.Where("id = ?", c.Param("id")).First(&book).Error(); err != nil {
-		response.NewErrorResponse(http.StatusNotFound, "Book not found", err.Error()).Send(c)
+	if notModified(c, bookETag(book), book.UpdatedAt) {
+		return
+	}
+
+	if cached {
+		response.NewSuccessResponse("Book retrieved from cache", book).Send(c)
+		return
+	}
+	response.NewSuccessResponse("Book retrieved successfully", book).Send(c)
+}
+
+// FindBookByISBN godoc
+// @Summary Find a book by ISBN
+// @Description Get details of a book by its ISBN-10 or ISBN-13, with or without hyphens
+// @Tags books
+// @Security ApiKeyAuth
+// @Produce json
+// @Param isbn path string true "ISBN-10 or ISBN-13"
+// @Param If-None-Match header string false "ETag from a previous response"
+// @Param If-Modified-Since header string false "Last-Modified from a previous response"
+// @Success 200 {object} models.Book "Successfully retrieved book"
+// @Header 200 {string} ETag "Current version of the book"
+// @Header 200 {string} Last-Modified "Time the book was last updated"
+// @Success 304 {string} string "Not modified"
+// @Failure 400 {string} string "Invalid ISBN"
+// @Failure 404 {string} string "Book not found"
+// @Router /books/isbn/{isbn} [get]
+func (r *bookRepository) FindBookByISBN(c *gin.Context) {
+	isbn, err := models.ParseISBN(c.Param("isbn"))
+	if err != nil || isbn == "" {
+		response.NewErrorResponse(http.StatusBadRequest, "Invalid ISBN", models.ErrInvalidISBN.Error()).Send(c)
+		return
+	}
+
+	book, err := r.Books.GetBookByISBN(c.Request.Context(), isbn)
+	if err != nil {
+		sendBookLookupError(c, err)
+		return
+	}
+
+	if notModified(c, bookETag(book), book.UpdatedAt) {
 		return
 	}
 
@@ -186,24 +416,32 @@ func (r *bookRepository) FindBook(c *gin.Context) {
 }
 
 // UpdateBook godoc
-// @Summary Update a book by ID
-// @Description Update the book details for the given ID
+// @Summary Replace a book by ID
+// @Description Replace every writable field of the book with the given ID. Omitted optional fields are cleared.
 // @Tags books
 // @Security ApiKeyAuth
 // @Accept  json
 // @Produce  json
 // @Param id path string true "Book ID"
+// @Param If-Match header string false "ETag of the version being replaced"
 // @Param input body models.UpdateBook true "Update book object"
 // @Success 200 {object} models.Book "Successfully updated book"
 // @Failure 400 {string} string "Bad Request"
 // @Failure 404 {string} string "book not found"
+// @Failure 409 {string} string "A book with this ISBN already exists"
+// @Failure 412 {string} string "If-Match does not match the current version"
+// @Failure 428 {string} string "If-Match header is required"
 // @Router /books/{id} [put]
 func (r *bookRepository) UpdateBook(c *gin.Context) {
-	var book models.Book
 	var input models.UpdateBook
 
-	if err := r.DB.Where("id = ?", c.Param("id")).First(&book).Error(); err != nil {
-		response.NewErrorResponse(http.StatusNotFound, "Book not found", err.Error()).Send(c)
+	book, err := r.Books.LoadBook(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		sendBookLookupError(c, err)
+		return
+	}
+
+	if !r.checkIfMatch(c, book) {
 		return
 	}
 
@@ -212,30 +450,57 @@ func (r *bookRepository) UpdateBook(c *gin.Context) {
 		return
 	}
 
-	r.DB.Model(&book).Updates(models.Book{Title: input.Title, Author: input.Author})
+	book, err = r.Books.UpdateBook(c.Request.Context(), book, input)
+	if err != nil {
+		if errors.Is(err, store.ErrStale) {
+			sendVersionConflict(c)
+			return
+		}
+		if errors.Is(err, store.ErrConflict) {
+			sendDuplicateISBN(c, book.ISBN)
+			return
+		}
+		response.NewErrorResponse(http.StatusInternalServerError, "Failed to update book", err.Error()).Send(c)
+		return
+	}
+
+	c.Header("ETag", bookETag(book))
 
 	response.NewSuccessResponse("Book updated successfully", book).Send(c)
 }
 
 // DeleteBook godoc
 // @Summary Delete a book by ID
-// @Description Delete the book with the given ID
+// @Description Move the book with the given ID to the trash. It stays restorable until it is purged.
 // @Tags books
 // @Security ApiKeyAuth
 // @Produce json
 // @Param id path string true "Book ID"
+// @Param If-Match header string false "ETag of the version being deleted"
 // @Success 204 {string} string "Successfully deleted book"
 // @Failure 404 {string} string "book not found"
+// @Failure 412 {string} string "If-Match does not match the current version"
+// @Failure 428 {string} string "If-Match header is required"
 // @Router /books/{id} [delete]
 func (r *bookRepository) DeleteBook(c *gin.Context) {
-	var book models.Book
+	book, err := r.Books.LoadBook(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		sendBookLookupError(c, err)
+		return
+	}
 
-	if err := r.DB.Where("id = ?", c.Param("id")).First(&book).Error(); err != nil {
-		response.NewErrorResponse(http.StatusNotFound, "Book not found", err.Error()).Send(c)
+	if !r.checkIfMatch(c, book) {
 		return
 	}
 
-	r.DB.Delete(&book)
+	if err := r.Books.DeleteBook(c.Request.Context(), book); err != nil {
+		if errors.Is(err, store.ErrStale) {
+			sendVersionConflict(c)
+			return
+		}
+		response.NewErrorResponse(http.StatusInternalServerError, "Failed to delete book", err.Error()).Send(c)
+		return
+	}
 
 	response.Response{
 		StatusCode: http.StatusNoContent,
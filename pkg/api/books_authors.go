@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/response"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/store"
+)
+
+// FindBookAuthors godoc
+// @Summary List the credits of a book
+// @Description Get the authors credited on a book with their role
+// @Tags books
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Book ID"
+// @Success 200 {array} models.BookAuthor "Credits of the book"
+// @Failure 404 {string} string "Book not found"
+// @Router /books/{id}/authors [get]
+func (r *bookRepository) FindBookAuthors(c *gin.Context) {
+	book, err := r.Books.LoadBook(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		sendBookLookupError(c, err)
+		return
+	}
+
+	credits, err := r.Stores.Stores().Authors.ListCredits(c.Request.Context(), book.ID)
+	if err != nil {
+		response.NewErrorResponse(http.StatusInternalServerError, "Failed to retrieve authors", err.Error()).Send(c)
+		return
+	}
+
+	response.NewSuccessResponse("Authors retrieved successfully", credits).Send(c)
+}
+
+// SetBookAuthors godoc
+// @Summary Replace the credits of a book
+// @Description Replace every credit of a book. Authors are billed in the given order within each role, and role defaults to author. The book's author byline is left as printed.
+// @Tags books
+// @Security ApiKeyAuth
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Book ID"
+// @Param input body []models.SetBookAuthor true "Credits of the book"
+// @Success 200 {array} models.BookAuthor "Credits of the book"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Book not found"
+// @Failure 422 {string} string "Unknown author"
+// @Router /books/{id}/authors [put]
+func (r *bookRepository) SetBookAuthors(c *gin.Context) {
+	var input []models.SetBookAuthor
+
+	body, err := c.GetRawData()
+	if err == nil {
+		err = json.Unmarshal(body, &input)
+	}
+	if err != nil {
+		response.NewErrorResponse(http.StatusBadRequest, "Invalid request body", "expected a JSON array: "+err.Error()).Send(c)
+		return
+	}
+
+	credits := make([]models.BookAuthor, 0, len(input))
+	positions := map[string]int{}
+	seen := map[string]bool{}
+	for i, item := range input {
+		if err := binding.Validator.ValidateStruct(item); err != nil {
+			response.NewErrorResponse(http.StatusBadRequest, "Invalid request body", fmt.Sprintf("item %d: %v", i, err)).Send(c)
+			return
+		}
+		if item.Role == "" {
+			item.Role = models.CreditRoleAuthor
+		}
+
+		key := item.AuthorID + "/" + item.Role
+		if seen[key] {
+			response.NewErrorResponse(http.StatusBadRequest, "Invalid request body", fmt.Sprintf("item %d: author %s is already credited as %s", i, item.AuthorID, item.Role)).Send(c)
+			return
+		}
+		seen[key] = true
+
+		credits = append(credits, models.BookAuthor{AuthorID: uuid.MustParse(item.AuthorID), Role: item.Role, Position: positions[item.Role]})
+		positions[item.Role]++
+	}
+
+	var saved []models.BookAuthor
+	err = r.Stores.WithTx(c.Request.Context(), func(ctx context.Context, tx store.Stores) error {
+		book, err := tx.Books.LockBook(ctx, c.Param("id"))
+		if err != nil {
+			return err
+		}
+
+		err = tx.Authors.ReplaceCredits(ctx, book.ID, credits)
+		if errors.Is(err, store.ErrNotFound) {
+			return &statusError{http.StatusUnprocessableEntity, "Unknown author", errors.New("every author_id must reference an existing author")}
+		}
+		if err != nil {
+			return err
+		}
+
+		saved, err = tx.Authors.ListCredits(ctx, book.ID)
+		return err
+	})
+
+	var serr *statusError
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		response.NewErrorResponse(http.StatusNotFound, "Book not found", err.Error()).Send(c)
+		return
+	case errors.As(err, &serr):
+		response.NewErrorResponse(serr.status, serr.message, serr.Error()).Send(c)
+		return
+	case err != nil:
+		response.NewErrorResponse(http.StatusInternalServerError, "Failed to update authors", err.Error()).Send(c)
+		return
+	}
+
+	response.NewSuccessResponse("Authors updated successfully", saved).Send(c)
+}
@@ -0,0 +1,294 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/response"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/store"
+)
+
+// BulkResult reports the outcome of one item of a bulk request
+type BulkResult struct {
+	Index  int          `json:"index"`
+	Status int          `json:"status"`
+	Book   *models.Book `json:"book,omitempty"`
+	Error  string       `json:"error,omitempty"`
+}
+
+// bulkOperation runs a single item inside the batch transaction and returns
+// the book it wrote
+type bulkOperation func(ctx context.Context, tx store.Stores, index int) (models.Book, error)
+
+// parseBulkRequest decodes the JSON array body into items and validates each
+// of them. Items that fail to decode or validate get an error result instead
+// of being run.
+func parseBulkRequest[T any](r *bookRepository, c *gin.Context, items *[]T) ([]*BulkResult, bool) {
+	var raw []json.RawMessage
+	body, err := c.GetRawData()
+	if err == nil {
+		err = json.Unmarshal(body, &raw)
+	}
+	if err != nil {
+		response.NewErrorResponse(http.StatusBadRequest, "Invalid request body", "expected a JSON array: "+err.Error()).Send(c)
+		return nil, false
+	}
+
+	if len(raw) == 0 {
+		response.NewErrorResponse(http.StatusBadRequest, "Invalid request body", "at least one item is required").Send(c)
+		return nil, false
+	}
+	if len(raw) > r.BulkLimit {
+		response.NewErrorResponse(http.StatusBadRequest, "Too many items", fmt.Sprintf("at most %d items are allowed per request", r.BulkLimit)).Send(c)
+		return nil, false
+	}
+
+	// Items are decoded one by one so that a malformed field, such as an
+	// invalid ISBN, only fails its own item
+	*items = make([]T, len(raw))
+	invalid := make([]*BulkResult, len(raw))
+	for i, data := range raw {
+		err := json.Unmarshal(data, &(*items)[i])
+		if err == nil {
+			err = binding.Validator.ValidateStruct((*items)[i])
+		}
+		if err != nil {
+			invalid[i] = &BulkResult{Index: i, Status: http.StatusBadRequest, Error: err.Error()}
+		}
+	}
+
+	return invalid, true
+}
+
+// runBulk executes operation for every valid item in one transaction. In
+// atomic mode the first failure rolls back the whole batch; otherwise every
+// item runs in its own savepoint and the response is 207 Multi-Status with a
+// result per item. The writes go through BookService, whose search indexing
+// and cache invalidation run once, after the batch has been committed.
+func (r *bookRepository) runBulk(c *gin.Context, invalid []*BulkResult, successStatus int, operation bulkOperation) {
+	atomic, err := strconv.ParseBool(c.DefaultQuery("atomic", "true"))
+	if err != nil {
+		response.NewErrorResponse(http.StatusBadRequest, "Invalid atomic format", err.Error()).Send(c)
+		return
+	}
+
+	results := make([]BulkResult, len(invalid))
+	for i, result := range invalid {
+		if result != nil && atomic {
+			response.Response{
+				StatusCode: result.Status,
+				Success:    false,
+				Message:    fmt.Sprintf("Item %d is invalid, nothing was written", i),
+				Data:       []BulkResult{*result},
+				Error:      result.Error,
+			}.Send(c)
+			return
+		}
+		if result != nil {
+			results[i] = *result
+		}
+	}
+
+	var failed *BulkResult
+	err = r.Stores.WithTx(c.Request.Context(), func(ctx context.Context, _ store.Stores) error {
+		for i, result := range invalid {
+			if result != nil {
+				continue
+			}
+
+			var book models.Book
+			err := r.Stores.WithTx(ctx, func(ctx context.Context, sp store.Stores) error {
+				var err error
+				book, err = operation(ctx, sp, i)
+				return err
+			})
+			if err != nil {
+				results[i] = bulkFailure(i, err)
+				if atomic {
+					failed = &results[i]
+					return err
+				}
+				continue
+			}
+
+			results[i] = BulkResult{Index: i, Status: successStatus, Book: &book}
+		}
+		return nil
+	})
+
+	if failed != nil {
+		response.Response{
+			StatusCode: failed.Status,
+			Success:    false,
+			Message:    fmt.Sprintf("Item %d failed, nothing was written", failed.Index),
+			Data:       []BulkResult{*failed},
+			Error:      failed.Error,
+		}.Send(c)
+		return
+	}
+	if err != nil {
+		response.NewErrorResponse(http.StatusInternalServerError, "Bulk operation failed", err.Error()).Send(c)
+		return
+	}
+
+	written := 0
+	for _, result := range results {
+		if result.Book != nil {
+			written++
+		}
+	}
+
+	status := successStatus
+	if !atomic {
+		status = http.StatusMultiStatus
+	}
+	response.Response{
+		StatusCode: status,
+		Success:    written == len(results),
+		Message:    fmt.Sprintf("%d of %d items succeeded", written, len(results)),
+		Data:       results,
+	}.Send(c)
+}
+
+func bulkFailure(index int, err error) BulkResult {
+	var serr *statusError
+	switch {
+	case errors.As(err, &serr):
+		return BulkResult{Index: index, Status: serr.status, Error: serr.message + ": " + serr.Error()}
+	case errors.Is(err, store.ErrNotFound):
+		return BulkResult{Index: index, Status: http.StatusNotFound, Error: "Book not found"}
+	case errors.Is(err, store.ErrStale):
+		return BulkResult{Index: index, Status: http.StatusConflict, Error: versionConflictMessage}
+	case errors.Is(err, store.ErrConflict):
+		return BulkResult{Index: index, Status: http.StatusConflict, Error: "a book with this ISBN already exists"}
+	default:
+		return BulkResult{Index: index, Status: http.StatusInternalServerError, Error: err.Error()}
+	}
+}
+
+// requireVersions fails the items without a version with 428 when
+// BOOKS_IF_MATCH=required, the way a single write without If-Match is
+func (r *bookRepository) requireVersions(invalid []*BulkResult, version func(i int) *int64) {
+	if r.IfMatchMode != IfMatchRequired {
+		return
+	}
+	for i := range invalid {
+		if invalid[i] == nil && version(i) == nil {
+			invalid[i] = &BulkResult{Index: i, Status: http.StatusPreconditionRequired, Error: "version is required"}
+		}
+	}
+}
+
+// lockBookVersion loads the book for update and checks the expected version
+func lockBookVersion(ctx context.Context, tx store.Stores, id string, version *int64) (models.Book, error) {
+	book, err := tx.Books.LockBook(ctx, id)
+	if err != nil {
+		return book, err
+	}
+	if version != nil && *version != book.Version {
+		return book, &statusError{http.StatusPreconditionFailed, "Precondition failed", fmt.Errorf("expected version %d, current version is %d", *version, book.Version)}
+	}
+	return book, nil
+}
+
+// CreateBooks godoc
+// @Summary Create books in bulk
+// @Description Create up to BOOKS_BULK_LIMIT books in one transaction. With atomic=false every item is attempted and the response is 207 Multi-Status with a result per item.
+// @Tags books
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Accept  json
+// @Produce  json
+// @Param atomic query bool false "Roll back the whole batch when an item fails" default(true)
+// @Param input body []models.CreateBook true "Books to create"
+// @Success 201 {array} BulkResult "All books created"
+// @Success 207 {array} BulkResult "Per item results"
+// @Failure 400 {string} string "Bad Request"
+// @Router /books/bulk [post]
+func (r *bookRepository) CreateBooks(c *gin.Context) {
+	var items []models.CreateBook
+	invalid, ok := parseBulkRequest(r, c, &items)
+	if !ok {
+		return
+	}
+
+	r.runBulk(c, invalid, http.StatusCreated, func(ctx context.Context, _ store.Stores, i int) (models.Book, error) {
+		return r.Books.CreateBook(ctx, items[i])
+	})
+}
+
+// UpdateBooks godoc
+// @Summary Update books in bulk
+// @Description Apply a JSON Merge Patch to up to BOOKS_BULK_LIMIT books in one transaction. A version on an item works like If-Match, and is required when BOOKS_IF_MATCH=required.
+// @Tags books
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Accept  json
+// @Produce  json
+// @Param atomic query bool false "Roll back the whole batch when an item fails" default(true)
+// @Param input body []models.BulkUpdateBook true "Books to update"
+// @Success 200 {array} BulkResult "All books updated"
+// @Success 207 {array} BulkResult "Per item results"
+// @Failure 400 {string} string "Bad Request"
+// @Router /books/bulk [patch]
+func (r *bookRepository) UpdateBooks(c *gin.Context) {
+	var items []models.BulkUpdateBook
+	invalid, ok := parseBulkRequest(r, c, &items)
+	if !ok {
+		return
+	}
+	r.requireVersions(invalid, func(i int) *int64 { return items[i].Version })
+
+	r.runBulk(c, invalid, http.StatusOK, func(ctx context.Context, tx store.Stores, i int) (models.Book, error) {
+		book, err := lockBookVersion(ctx, tx, items[i].ID, items[i].Version)
+		if err != nil {
+			return book, err
+		}
+
+		input, err := applyBookPatch(book, MergePatchContentType, items[i].Patch)
+		if err != nil {
+			return book, err
+		}
+
+		input.ApplyTo(&book)
+		return book, r.Books.SaveBook(ctx, &book)
+	})
+}
+
+// DeleteBooks godoc
+// @Summary Delete books in bulk
+// @Description Move up to BOOKS_BULK_LIMIT books to the trash in one transaction. A version on an item works like If-Match, and is required when BOOKS_IF_MATCH=required.
+// @Tags books
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Accept  json
+// @Produce  json
+// @Param atomic query bool false "Roll back the whole batch when an item fails" default(true)
+// @Param input body []models.BulkDeleteBook true "Books to delete"
+// @Success 200 {array} BulkResult "All books deleted"
+// @Success 207 {array} BulkResult "Per item results"
+// @Failure 400 {string} string "Bad Request"
+// @Router /books/bulk [delete]
+func (r *bookRepository) DeleteBooks(c *gin.Context) {
+	var items []models.BulkDeleteBook
+	invalid, ok := parseBulkRequest(r, c, &items)
+	if !ok {
+		return
+	}
+	r.requireVersions(invalid, func(i int) *int64 { return items[i].Version })
+
+	r.runBulk(c, invalid, http.StatusOK, func(ctx context.Context, tx store.Stores, i int) (models.Book, error) {
+		book, err := lockBookVersion(ctx, tx, items[i].ID, items[i].Version)
+		if err != nil {
+			return book, err
+		}
+		return book, r.Books.DeleteBook(ctx, book)
+	})
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/cache"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/response"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/store"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils/tests"
+)
+
+// dryRunPool lets a dry-run session open transactions and savepoints that
+// never reach a database
+type dryRunPool struct{ gorm.ConnPool }
+
+func (p dryRunPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	return &dryRunTx{p}, nil
+}
+
+type dryRunTx struct{ gorm.ConnPool }
+
+func (*dryRunTx) Commit() error   { return nil }
+func (*dryRunTx) Rollback() error { return nil }
+
+// savepointDialector accepts the savepoints non-atomic batches run items in
+type savepointDialector struct{ tests.DummyDialector }
+
+func (savepointDialector) SavePoint(tx *gorm.DB, name string) error  { return nil }
+func (savepointDialector) RollbackTo(tx *gorm.DB, name string) error { return nil }
+
+// newDryRunTxDB is newDryRunDB for units of work: it opens transactions and
+// savepoints that never reach a database
+func newDryRunTxDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(savepointDialector{}, &gorm.Config{DryRun: true, Logger: logger.Discard})
+	assert.NoError(t, err)
+	db.ConnPool = dryRunPool{}
+	db.Statement.ConnPool = db.ConnPool
+	return db
+}
+
+func newBulkTestRouter(repo *bookRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/books/bulk", repo.CreateBooks)
+	r.PATCH("/books/bulk", repo.UpdateBooks)
+	r.DELETE("/books/bulk", repo.DeleteBooks)
+	return r
+}
+
+func TestBulkRejectsTooManyItems(t *testing.T) {
+	repo := NewBookRepository(nil, nil, nil, nil, nil)
+	repo.BulkLimit = 2
+	r := newBulkTestRouter(repo)
+
+	body := `[{"title":"a","author":"a"},{"title":"b","author":"b"},{"title":"c","author":"c"}]`
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/books/bulk", bytes.NewBufferString(body)))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "at most 2 items")
+}
+
+func TestBulkRejectsNonArrayBody(t *testing.T) {
+	repo := NewBookRepository(nil, nil, nil, nil, nil)
+	r := newBulkTestRouter(repo)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/books/bulk", bytes.NewBufferString(`{"id":"x"}`)))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestBulkAtomicValidationWritesNothing(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// No expectations: an invalid item must stop the batch before the database is touched
+	mockDB := newMockDatabase(ctrl)
+	repo := NewBookRepository(mockDB, nil, nil, nil, nil)
+	r := newBulkTestRouter(repo)
+
+	body := `[{"id":"123e4567-e89b-12d3-a456-426614174000","patch":{"title":"ok"}},{"id":"not-a-uuid","patch":{}}]`
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/books/bulk", bytes.NewBufferString(body)))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var resp struct {
+		response.Response
+		Data []BulkResult `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Len(t, resp.Data, 1)
+	assert.Equal(t, 1, resp.Data[0].Index)
+}
+
+func TestBulkRequiresVersions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Every item lacks a version, so none of them reaches the stores
+	repo := NewBookRepository(nil, newMockUnitOfWork(ctrl, store.Stores{}), nil, nil, nil)
+	repo.IfMatchMode = IfMatchRequired
+	r := newBulkTestRouter(repo)
+
+	for _, tt := range []struct {
+		method string
+		body   string
+	}{
+		{http.MethodPatch, `[{"id":"123e4567-e89b-12d3-a456-426614174000","patch":{"title":"ok"}}]`},
+		{http.MethodDelete, `[{"id":"123e4567-e89b-12d3-a456-426614174000"}]`},
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(tt.method, "/books/bulk?atomic=false", bytes.NewBufferString(tt.body)))
+		assert.Equal(t, http.StatusMultiStatus, w.Code)
+
+		var resp struct {
+			response.Response
+			Data []BulkResult `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, http.StatusPreconditionRequired, resp.Data[0].Status)
+	}
+}
+
+func TestBulkNonAtomicFailsOnlyMalformedItems(t *testing.T) {
+	repo := NewBookRepository(nil, store.NewGormUnitOfWork(newDryRunTxDB(t)), cache.NewMemoryCache(100), nil, nil)
+	r := newBulkTestRouter(repo)
+
+	body := `[{"title":"a","author":"a","isbn":"978-0-306-40615-7"},{"title":"b","author":"b","isbn":"978-0-306-40615-8"}]`
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/books/bulk?atomic=false", bytes.NewBufferString(body)))
+	assert.Equal(t, http.StatusMultiStatus, w.Code, w.Body.String())
+
+	var resp struct {
+		response.Response
+		Data []BulkResult `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Len(t, resp.Data, 2)
+	assert.Equal(t, http.StatusCreated, resp.Data[0].Status, resp.Data[0].Error)
+	assert.Equal(t, http.StatusBadRequest, resp.Data[1].Status)
+	assert.Contains(t, resp.Data[1].Error, "isbn")
+}
@@ -0,0 +1,247 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/imaging"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/response"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/storage"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/store"
+)
+
+const (
+	// coverKeyPrefix is the blob key prefix of cover images, the only blobs
+	// served publicly
+	coverKeyPrefix = "covers/"
+	// coverMaxPixels bounds the decoded size of an upload
+	coverMaxPixels = 40_000_000
+	// Widths of the cover thumbnails, in pixels
+	coverSmallWidth  = 160
+	coverMediumWidth = 480
+	coverJPEGQuality = 85
+)
+
+// storeCover stores the original image and its thumbnails under a new key,
+// so cached URLs of a previous cover never show the new one
+func (r *bookRepository) storeCover(c *gin.Context, book models.Book, data []byte, contentType string) (models.Cover, error) {
+	img, err := imaging.Decode(data, coverMaxPixels)
+	if err != nil {
+		return models.Cover{}, &statusError{http.StatusUnprocessableEntity, "Invalid image", err}
+	}
+
+	dir := coverKeyPrefix + book.ID.String() + "/" + uuid.NewString()
+	cover := models.Cover{
+		Key:    dir + "/original" + imaging.Extensions[contentType],
+		Width:  img.Bounds().Dx(),
+		Height: img.Bounds().Dy(),
+	}
+	keys := cover.Keys()
+
+	ctx := c.Request.Context()
+	if err := r.Blobs.Put(ctx, cover.Key, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+		return cover, err
+	}
+	for i, width := range []int{coverSmallWidth, coverMediumWidth} {
+		var buf bytes.Buffer
+		if err := imaging.EncodeJPEG(&buf, imaging.Resize(img, width), coverJPEGQuality); err != nil {
+			storage.DeleteAll(ctx, r.Blobs, keys)
+			return cover, err
+		}
+		if err := r.Blobs.Put(ctx, keys[i+1], &buf, int64(buf.Len()), "image/jpeg"); err != nil {
+			storage.DeleteAll(ctx, r.Blobs, keys)
+			return cover, err
+		}
+	}
+
+	cover.URL = r.Blobs.URL(keys[0])
+	cover.SmallURL = r.Blobs.URL(keys[1])
+	cover.MediumURL = r.Blobs.URL(keys[2])
+	return cover, nil
+}
+
+// deleteBlobs removes blobs that are no longer referenced. The write that
+// dropped them already succeeded, so failures are only logged and the
+// deletion outlives the cancellation of ctx.
+func (r *bookRepository) deleteBlobs(ctx context.Context, keys []string) {
+	if r.Blobs == nil || len(keys) == 0 {
+		return
+	}
+	if err := storage.DeleteAll(context.WithoutCancel(ctx), r.Blobs, keys); err != nil {
+		log.Printf("Failed to delete blobs %v: %v", keys, err)
+	}
+}
+
+// UploadCover godoc
+// @Summary Upload the cover of a book
+// @Description Replace the cover image of a book with a JPEG, PNG or GIF upload. The type is detected from the content. Small and medium JPEG thumbnails are generated.
+// @Tags books
+// @Security ApiKeyAuth
+// @Accept  multipart/form-data
+// @Produce  json
+// @Param id path string true "Book ID"
+// @Param cover formData file true "Cover image"
+// @Param If-Match header string false "ETag of the version being updated"
+// @Success 200 {object} models.Book "Book with its new cover"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Book not found"
+// @Failure 409 {string} string "Book was modified concurrently"
+// @Failure 413 {string} string "Cover is too large"
+// @Failure 415 {string} string "Unsupported image type"
+// @Failure 422 {string} string "Invalid image"
+// @Router /books/{id}/cover [put]
+func (r *bookRepository) UploadCover(c *gin.Context) {
+	book, err := r.Books.LoadBook(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		sendBookLookupError(c, err)
+		return
+	}
+
+	if !r.checkIfMatch(c, book) {
+		return
+	}
+
+	// Leave room for the multipart framing around the file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, r.CoverMaxBytes+64<<10)
+	file, header, err := c.Request.FormFile("cover")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			response.NewErrorResponse(http.StatusRequestEntityTooLarge, "Cover is too large", "covers are limited to "+strconv.FormatInt(r.CoverMaxBytes, 10)+" bytes").Send(c)
+			return
+		}
+		response.NewErrorResponse(http.StatusBadRequest, "Invalid request body", "expected a multipart form with a cover file: "+err.Error()).Send(c)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, r.CoverMaxBytes+1))
+	if err != nil {
+		response.NewErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()).Send(c)
+		return
+	}
+	if header.Size > r.CoverMaxBytes || int64(len(data)) > r.CoverMaxBytes {
+		response.NewErrorResponse(http.StatusRequestEntityTooLarge, "Cover is too large", "covers are limited to "+strconv.FormatInt(r.CoverMaxBytes, 10)+" bytes").Send(c)
+		return
+	}
+
+	contentType, err := imaging.Sniff(data)
+	if err != nil {
+		response.NewErrorResponse(http.StatusUnsupportedMediaType, "Unsupported image type", "got "+contentType+": "+err.Error()).Send(c)
+		return
+	}
+
+	cover, err := r.storeCover(c, book, data, contentType)
+	if err != nil {
+		var serr *statusError
+		if errors.As(err, &serr) {
+			response.NewErrorResponse(serr.status, serr.message, serr.Error()).Send(c)
+			return
+		}
+		response.NewErrorResponse(http.StatusInternalServerError, "Failed to store cover", err.Error()).Send(c)
+		return
+	}
+
+	previous := book.Cover.Keys()
+	if err := r.Books.SaveCover(c.Request.Context(), &book, cover); err != nil {
+		r.deleteBlobs(c.Request.Context(), cover.Keys())
+		if errors.Is(err, store.ErrStale) {
+			sendVersionConflict(c)
+			return
+		}
+		response.NewErrorResponse(http.StatusInternalServerError, "Failed to update book", err.Error()).Send(c)
+		return
+	}
+	r.deleteBlobs(c.Request.Context(), previous)
+
+	c.Header("ETag", bookETag(book))
+
+	response.NewSuccessResponse("Cover uploaded successfully", book).Send(c)
+}
+
+// DeleteCover godoc
+// @Summary Remove the cover of a book
+// @Description Remove the cover image of a book and its thumbnails
+// @Tags books
+// @Security ApiKeyAuth
+// @Produce  json
+// @Param id path string true "Book ID"
+// @Param If-Match header string false "ETag of the version being updated"
+// @Success 204 {string} string "Successfully removed cover"
+// @Failure 404 {string} string "Book or cover not found"
+// @Failure 409 {string} string "Book was modified concurrently"
+// @Router /books/{id}/cover [delete]
+func (r *bookRepository) DeleteCover(c *gin.Context) {
+	book, err := r.Books.LoadBook(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		sendBookLookupError(c, err)
+		return
+	}
+	if book.Cover.Key == "" {
+		response.NewErrorResponse(http.StatusNotFound, "Cover not found", "the book has no cover").Send(c)
+		return
+	}
+
+	if !r.checkIfMatch(c, book) {
+		return
+	}
+
+	previous := book.Cover.Keys()
+	if err := r.Books.SaveCover(c.Request.Context(), &book, models.Cover{}); err != nil {
+		if errors.Is(err, store.ErrStale) {
+			sendVersionConflict(c)
+			return
+		}
+		response.NewErrorResponse(http.StatusInternalServerError, "Failed to update book", err.Error()).Send(c)
+		return
+	}
+	r.deleteBlobs(c.Request.Context(), previous)
+
+	response.Response{
+		StatusCode: http.StatusNoContent,
+		Success:    true,
+		Message:    "Cover deleted successfully",
+		Data:       true,
+	}.Send(c)
+}
+
+// ServeBlob godoc
+// @Summary Download a cover image
+// @Description Serve a cover image or thumbnail from the blob store. Cover keys change with every upload, so responses can be cached forever.
+// @Tags books
+// @Produce image/jpeg,image/png,image/gif
+// @Param key path string true "Blob key, starting with covers/"
+// @Success 200 {file} file "Image"
+// @Failure 404 {string} string "Blob not found"
+// @Router /blobs/{key} [get]
+func (r *bookRepository) ServeBlob(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	if !strings.HasPrefix(key, coverKeyPrefix) {
+		response.NewErrorResponse(http.StatusNotFound, "Blob not found", "no public blob with this key").Send(c)
+		return
+	}
+
+	body, info, err := r.Blobs.Get(c.Request.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+		response.NewErrorResponse(http.StatusNotFound, "Blob not found", err.Error()).Send(c)
+		return
+	} else if err != nil {
+		response.NewErrorResponse(http.StatusInternalServerError, "Failed to read blob", err.Error()).Send(c)
+		return
+	}
+	defer body.Close()
+
+	c.DataFromReader(http.StatusOK, info.Size, info.ContentType, body, map[string]string{
+		"Cache-Control":          "public, max-age=31536000, immutable",
+		"X-Content-Type-Options": "nosniff",
+	})
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/cache"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/storage"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCoverRequest(t *testing.T, field string, data []byte) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile(field, "cover.png")
+	assert.NoError(t, err)
+	part.Write(data)
+	assert.NoError(t, writer.Close())
+
+	req := httptest.NewRequest(http.MethodPut, "/books/1/cover", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func newCoverImage(t *testing.T, width int, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 128, 255})
+		}
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+// expectCoverBook makes the mock store load book for the cover and file
+// endpoints
+func expectCoverBook(books *store.MockBookStore, book models.Book) {
+	books.EXPECT().GetBook(gomock.Any(), "1").Return(book, nil)
+}
+
+func TestUploadCover(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBooks := store.NewMockBookStore(ctrl)
+	root := t.TempDir()
+	repo := NewBookRepository(nil, newMockUnitOfWork(ctrl, store.Stores{Books: mockBooks}), cache.NewMemoryCache(100), nil, storage.NewLocalStore(root, "/api/v1/blobs"))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.PUT("/books/:id/cover", repo.UploadCover)
+
+	book := models.Book{ID: uuid.New(), Title: "Dune", Version: 3}
+	expectCoverBook(mockBooks, book)
+
+	// The versioned update matches
+	mockBooks.EXPECT().SaveCover(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, saved *models.Book, cover models.Cover) error {
+		assert.Equal(t, int64(3), saved.Version)
+		saved.Cover = cover
+		saved.Version++
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, newCoverRequest(t, "cover", newCoverImage(t, 600, 900)))
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, `"4"`, w.Header().Get("ETag"))
+
+	var body struct {
+		Data models.Book `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	cover := body.Data.Cover
+	assert.Equal(t, 600, cover.Width)
+	assert.Equal(t, 900, cover.Height)
+	assert.True(t, strings.HasPrefix(cover.URL, "/api/v1/blobs/covers/"+book.ID.String()+"/"), cover.URL)
+	assert.True(t, strings.HasSuffix(cover.URL, "/original.png"), cover.URL)
+	assert.True(t, strings.HasSuffix(cover.SmallURL, "/small.jpg"), cover.SmallURL)
+	assert.True(t, strings.HasSuffix(cover.MediumURL, "/medium.jpg"), cover.MediumURL)
+
+	for _, url := range []string{cover.URL, cover.SmallURL, cover.MediumURL} {
+		key := strings.TrimPrefix(url, "/api/v1/blobs/")
+		_, err := os.Stat(filepath.Join(root, filepath.FromSlash(key)))
+		assert.NoError(t, err, key)
+	}
+}
+
+func TestUploadCoverRejectsInvalidFiles(t *testing.T) {
+	tests := []struct {
+		name   string
+		field  string
+		data   []byte
+		status int
+	}{
+		{"missing file", "image", newCoverImage(t, 10, 10), http.StatusBadRequest},
+		{"not an image", "cover", []byte("%PDF-1.7 not a cover"), http.StatusUnsupportedMediaType},
+		{"svg", "cover", []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), http.StatusUnsupportedMediaType},
+		{"corrupt image", "cover", append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 64)...), http.StatusUnprocessableEntity},
+		{"too large", "cover", append(newCoverImage(t, 10, 10), make([]byte, 4096)...), http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		ctrl := gomock.NewController(t)
+		mockBooks := store.NewMockBookStore(ctrl)
+		repo := NewBookRepository(nil, newMockUnitOfWork(ctrl, store.Stores{Books: mockBooks}), nil, nil, storage.NewLocalStore(t.TempDir(), ""))
+		repo.CoverMaxBytes = 2048
+
+		gin.SetMode(gin.TestMode)
+		r := gin.Default()
+		r.PUT("/books/:id/cover", repo.UploadCover)
+
+		// Nothing is written when the upload is rejected
+		expectCoverBook(mockBooks, models.Book{ID: uuid.New(), Version: 1})
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, newCoverRequest(t, tt.field, tt.data))
+		assert.Equal(t, tt.status, w.Code, tt.name)
+		ctrl.Finish()
+	}
+}
+
+func TestServeBlob(t *testing.T) {
+	ctx := context.Background()
+	blobs := storage.NewLocalStore(t.TempDir(), "")
+	assert.NoError(t, blobs.Put(ctx, "covers/1/a/small.jpg", strings.NewReader("jpeg"), 4, "image/jpeg"))
+	assert.NoError(t, blobs.Put(ctx, "ebooks/1/book.epub", strings.NewReader("epub"), 4, ""))
+	repo := NewBookRepository(nil, nil, nil, nil, blobs)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/blobs/*key", repo.ServeBlob)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/blobs/covers/1/a/small.jpg", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "jpeg", w.Body.String())
+	assert.Equal(t, "image/jpeg", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Cache-Control"), "immutable")
+
+	// Only covers are public
+	for _, url := range []string{"/blobs/ebooks/1/book.epub", "/blobs/covers/1/a/missing.jpg", "/blobs/covers/../ebooks/1/book.epub"} {
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		assert.Equal(t, http.StatusNotFound, w.Code, url)
+	}
+}
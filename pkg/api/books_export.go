@@ -0,0 +1,215 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/response"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+	FormatJSON   = "json"
+
+	// exportFlushEvery is how many rows are buffered before flushing to the client
+	exportFlushEvery = 500
+)
+
+var exportContentTypes = map[string]string{
+	FormatCSV:    "text/csv; charset=utf-8",
+	FormatNDJSON: "application/x-ndjson",
+	FormatJSON:   "application/json; charset=utf-8",
+}
+
+// bookExportColumns is the CSV column order. The importer understands the
+// same headers, so an export can be edited and imported back.
+var bookExportColumns = []string{
+	"uuid", "title", "author", "isbn", "publication_date", "language", "page_count", "publisher", "description",
+	"version", "created_at", "updated_at",
+}
+
+// bookWriter encodes books one at a time so exports never hold the whole
+// collection in memory
+type bookWriter interface {
+	Write(book models.Book) error
+	Flush()
+	Close() error
+}
+
+type csvBookWriter struct {
+	writer *csv.Writer
+}
+
+func (w *csvBookWriter) Write(book models.Book) error {
+	publicationDate := ""
+	if book.PublicationDate != nil {
+		publicationDate = book.PublicationDate.String()
+	}
+	pageCount := ""
+	if book.PageCount != 0 {
+		pageCount = strconv.Itoa(book.PageCount)
+	}
+
+	return w.writer.Write([]string{
+		book.ID.String(),
+		book.Title,
+		book.Author,
+		string(book.ISBN),
+		publicationDate,
+		string(book.Language),
+		pageCount,
+		book.Publisher,
+		book.Description,
+		strconv.FormatInt(book.Version, 10),
+		book.CreatedAt.UTC().Format(time.RFC3339),
+		book.UpdatedAt.UTC().Format(time.RFC3339),
+	})
+}
+
+func (w *csvBookWriter) Flush() {
+	w.writer.Flush()
+}
+
+func (w *csvBookWriter) Close() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+type ndjsonBookWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *ndjsonBookWriter) Write(book models.Book) error {
+	return w.encoder.Encode(book)
+}
+
+func (w *ndjsonBookWriter) Flush() {}
+
+func (w *ndjsonBookWriter) Close() error {
+	return nil
+}
+
+type jsonArrayBookWriter struct {
+	out   io.Writer
+	count int
+}
+
+func (w *jsonArrayBookWriter) Write(book models.Book) error {
+	separator := ","
+	if w.count == 0 {
+		separator = "["
+	}
+	w.count++
+
+	data, err := json.Marshal(book)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w.out, separator); err != nil {
+		return err
+	}
+	_, err = w.out.Write(data)
+	return err
+}
+
+func (w *jsonArrayBookWriter) Flush() {}
+
+func (w *jsonArrayBookWriter) Close() error {
+	closing := "]"
+	if w.count == 0 {
+		closing = "[]"
+	}
+	_, err := io.WriteString(w.out, closing)
+	return err
+}
+
+func newBookWriter(format string, out io.Writer) (bookWriter, error) {
+	switch format {
+	case FormatCSV:
+		writer := csv.NewWriter(out)
+		return &csvBookWriter{writer: writer}, writer.Write(bookExportColumns)
+	case FormatNDJSON:
+		return &ndjsonBookWriter{encoder: json.NewEncoder(out)}, nil
+	default:
+		return &jsonArrayBookWriter{out: out}, nil
+	}
+}
+
+// ExportBooks godoc
+// @Summary Export the book catalog
+// @Description Stream every book matching the filters as CSV, NDJSON or a JSON array
+// @Tags books
+// @Security ApiKeyAuth
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Produce json
+// @Param format query string false "csv, ndjson or json" default(csv)
+// @Param author query string false "Only books by this author"
+// @Param q query string false "Only books whose title or author contains this text"
+// @Success 200 {string} string "The exported catalog"
+// @Failure 400 {string} string "Bad Request"
+// @Router /books/export [get]
+func (r *bookRepository) ExportBooks(c *gin.Context) {
+	format := c.DefaultQuery("format", FormatCSV)
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		response.NewErrorResponse(http.StatusBadRequest, "Invalid export format", "format must be csv, ndjson or json").Send(c)
+		return
+	}
+
+	query := r.db(c).Model(&models.Book{})
+	if author := c.Query("author"); author != "" {
+		query = query.Where("author = ?", author)
+	}
+	if text := c.Query("q"); text != "" {
+		query = query.Where("title ILIKE ? OR author ILIKE ?", "%"+text+"%", "%"+text+"%")
+	}
+
+	rows, err := query.Order("created_at, id").Rows()
+	if err != nil {
+		response.NewErrorResponse(http.StatusInternalServerError, "Failed to export books", err.Error()).Send(c)
+		return
+	}
+	defer rows.Close()
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="books.`+format+`"`)
+	c.Status(http.StatusOK)
+
+	writer, err := newBookWriter(format, c.Writer)
+	exported := 0
+	for err == nil && rows.Next() {
+		var book models.Book
+		if err = query.ScanRows(rows, &book); err != nil {
+			break
+		}
+		if err = writer.Write(book); err != nil {
+			break
+		}
+
+		exported++
+		if exported%exportFlushEvery == 0 {
+			writer.Flush()
+			c.Writer.Flush()
+		}
+	}
+	if err == nil {
+		err = rows.Err()
+	}
+
+	// The status line is already sent, so a failure can only cut the stream short
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if err := writer.Close(); err != nil {
+		_ = c.Error(err)
+	}
+}
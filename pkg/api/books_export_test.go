@@ -0,0 +1,71 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func newExportTestBooks() []models.Book {
+	createdAt := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	publicationDate := models.NewDate(2016, time.October, 1)
+	return []models.Book{
+		{
+			ID: uuid.MustParse("123e4567-e89b-12d3-a456-426614174000"), Title: "Go, in Practice", Author: "Matt Butcher",
+			ISBN: "9781633430075", PublicationDate: &publicationDate, Language: "en", PageCount: 288, Publisher: "Manning",
+			Version: 2, CreatedAt: createdAt, UpdatedAt: createdAt,
+		},
+		{ID: uuid.MustParse("123e4567-e89b-12d3-a456-426614174001"), Title: "Effective Go", Author: "Robert Griesemer", Version: 1, CreatedAt: createdAt, UpdatedAt: createdAt},
+	}
+}
+
+func writeBooks(t *testing.T, format string, books []models.Book) string {
+	var out bytes.Buffer
+	writer, err := newBookWriter(format, &out)
+	assert.NoError(t, err)
+	for _, book := range books {
+		assert.NoError(t, writer.Write(book))
+	}
+	assert.NoError(t, writer.Close())
+	return out.String()
+}
+
+func TestBookWriters(t *testing.T) {
+	books := newExportTestBooks()
+
+	assert.Equal(t,
+		"uuid,title,author,isbn,publication_date,language,page_count,publisher,description,version,created_at,updated_at\n"+
+			"123e4567-e89b-12d3-a456-426614174000,\"Go, in Practice\",Matt Butcher,9781633430075,2016-10-01,en,288,Manning,,2,2024-03-01T12:00:00Z,2024-03-01T12:00:00Z\n"+
+			"123e4567-e89b-12d3-a456-426614174001,Effective Go,Robert Griesemer,,,,,,,1,2024-03-01T12:00:00Z,2024-03-01T12:00:00Z\n",
+		writeBooks(t, FormatCSV, books))
+
+	ndjson := writeBooks(t, FormatNDJSON, books)
+	assert.Equal(t, 2, bytes.Count([]byte(ndjson), []byte("\n")))
+	assert.Contains(t, ndjson, `"title":"Effective Go"`)
+
+	assert.JSONEq(t, `[]`, writeBooks(t, FormatJSON, nil))
+	array := writeBooks(t, FormatJSON, books)
+	assert.Contains(t, array, `"isbn":"9781633430075","publication_date":"2016-10-01"`)
+	assert.Equal(t, byte('['), array[0])
+	assert.Equal(t, byte(']'), array[len(array)-1])
+}
+
+func TestExportBooksRejectsUnknownFormat(t *testing.T) {
+	repo := NewBookRepository(nil, nil, nil, nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/books/export", repo.ExportBooks)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/books/export?format=xml", nil))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,393 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/auth"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/response"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/storage"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/store"
+)
+
+// fileDownloadPath is where signed book file links point to
+const fileDownloadPath = "/api/v1/files/"
+
+// fileAccessRoles can read every book file
+var fileAccessRoles = map[string]bool{models.RoleAdmin: true}
+
+// checkFileAccess reports whether the caller may read the files of book:
+// they need a loan of the book that is neither returned nor overdue, or a
+// role that reads every file
+func (r *bookRepository) checkFileAccess(c *gin.Context, book models.Book) error {
+	if fileAccessRoles[c.GetString("role")] {
+		return nil
+	}
+
+	var loans []models.Loan
+	err := r.db(c).Model(&models.Loan{}).
+		Where("book_id = ? AND returned_at IS NULL AND due_at > ? AND user_id = (SELECT id FROM users WHERE username = ?)", book.ID, time.Now(), c.GetString("username")).
+		Limit(1).
+		Find(&loans).Error
+	if err != nil {
+		return err
+	}
+	if len(loans) == 0 {
+		return &statusError{http.StatusForbidden, "Forbidden", errors.New("borrow the book to read its files")}
+	}
+	return nil
+}
+
+// fileResource is what a download link to file is signed for
+func fileResource(id uuid.UUID) string {
+	return "file:" + id.String()
+}
+
+// findBookFile loads the file of the given format of the book in the path
+func (r *bookRepository) findBookFile(c *gin.Context) (models.Book, models.BookFile, error) {
+	var file models.BookFile
+
+	book, err := r.Books.LoadBook(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, store.ErrNotFound) {
+		return book, file, &statusError{http.StatusNotFound, "Book not found", err}
+	}
+	if err != nil {
+		return book, file, err
+	}
+
+	file, err = r.Stores.Stores().Files.GetBookFileByFormat(c.Request.Context(), book.ID, c.Param("format"))
+	if errors.Is(err, store.ErrNotFound) {
+		return book, file, &statusError{http.StatusNotFound, "File not found", err}
+	}
+	return book, file, err
+}
+
+// sendFileError answers a failed book file request
+func sendFileError(c *gin.Context, err error) {
+	var serr *statusError
+	if errors.As(err, &serr) {
+		response.NewErrorResponse(serr.status, serr.message, serr.Error()).Send(c)
+		return
+	}
+	response.NewErrorResponse(http.StatusInternalServerError, "Failed to process file", err.Error()).Send(c)
+}
+
+// spoolUpload copies the multipart field named file to a temporary file,
+// hashing it on the way. Uploads can be large, so they are never held in
+// memory; the temporary file also gives the blob store the size up front.
+func (r *bookRepository) spoolUpload(c *gin.Context) (*os.File, int64, string, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, r.EbookMaxBytes+64<<10)
+	tooLarge := &statusError{http.StatusRequestEntityTooLarge, "File is too large", errors.New("book files are limited to " + strconv.FormatInt(r.EbookMaxBytes, 10) + " bytes")}
+
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, 0, "", &statusError{http.StatusBadRequest, "Invalid request body", err}
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, 0, "", &statusError{http.StatusBadRequest, "Invalid request body", errors.New("expected a multipart form with a file field")}
+		}
+		var maxBytes *http.MaxBytesError
+		if errors.As(err, &maxBytes) {
+			return nil, 0, "", tooLarge
+		} else if err != nil {
+			return nil, 0, "", &statusError{http.StatusBadRequest, "Invalid request body", err}
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+
+		tmp, err := os.CreateTemp("", "book-file-*")
+		if err != nil {
+			return nil, 0, "", err
+		}
+
+		hash := sha256.New()
+		size, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(part, r.EbookMaxBytes+1))
+		switch {
+		case errors.As(err, &maxBytes) || size > r.EbookMaxBytes:
+			err = tooLarge
+		case err != nil:
+			err = &statusError{http.StatusBadRequest, "Invalid request body", err}
+		}
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+			return nil, 0, "", err
+		}
+		return tmp, size, hex.EncodeToString(hash.Sum(nil)), nil
+	}
+}
+
+// FindBookFiles godoc
+// @Summary List the files of a book
+// @Description List the digital editions of a book. Downloading one takes a link from the link endpoint.
+// @Tags files
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Produce json
+// @Param id path string true "Book ID"
+// @Success 200 {array} models.BookFile "Files of the book"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Book not found"
+// @Router /books/{id}/files [get]
+func (r *bookRepository) FindBookFiles(c *gin.Context) {
+	book, err := r.Books.LoadBook(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		sendBookLookupError(c, err)
+		return
+	}
+
+	files, err := r.Stores.Stores().Files.ListBookFiles(c.Request.Context(), book.ID)
+	if err != nil {
+		response.NewErrorResponse(http.StatusInternalServerError, "Failed to retrieve files", err.Error()).Send(c)
+		return
+	}
+
+	response.NewSuccessResponse("Files retrieved successfully", files).Send(c)
+}
+
+// UploadBookFile godoc
+// @Summary Upload a file of a book
+// @Description Upload the PDF or EPUB edition of a book, replacing the previous file of that format. The format is checked against the content.
+// @Tags files
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Accept  multipart/form-data
+// @Produce  json
+// @Param id path string true "Book ID"
+// @Param format path string true "pdf or epub"
+// @Param file formData file true "Book file"
+// @Success 200 {object} models.BookFile "Successfully replaced file"
+// @Success 201 {object} models.BookFile "Successfully uploaded file"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Book not found"
+// @Failure 413 {string} string "File is too large"
+// @Failure 415 {string} string "File does not match the format"
+// @Router /books/{id}/files/{format} [put]
+func (r *bookRepository) UploadBookFile(c *gin.Context) {
+	format := c.Param("format")
+	contentType, ok := models.BookFormatContentTypes[format]
+	if !ok {
+		response.NewErrorResponse(http.StatusBadRequest, "Invalid format", "format must be pdf or epub").Send(c)
+		return
+	}
+
+	book, err := r.Books.LoadBook(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		sendBookLookupError(c, err)
+		return
+	}
+
+	tmp, size, checksum, err := r.spoolUpload(c)
+	if err != nil {
+		sendFileError(c, err)
+		return
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	head := make([]byte, 64)
+	n, _ := tmp.ReadAt(head, 0)
+	if detected := models.DetectBookFormat(head[:n]); detected != format {
+		response.NewErrorResponse(http.StatusUnsupportedMediaType, "File does not match the format", "the uploaded file is not a valid "+format).Send(c)
+		return
+	}
+
+	file := models.BookFile{
+		BookID:      book.ID,
+		Format:      format,
+		Key:         "ebooks/" + book.ID.String() + "/" + uuid.NewString() + "." + format,
+		Filename:    models.BookFilename(book.Title, format),
+		ContentType: contentType,
+		Size:        size,
+		SHA256:      checksum,
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		sendFileError(c, err)
+		return
+	}
+	if err := r.Blobs.Put(c.Request.Context(), file.Key, tmp, size, contentType); err != nil {
+		response.NewErrorResponse(http.StatusInternalServerError, "Failed to store file", err.Error()).Send(c)
+		return
+	}
+
+	// Replace the row of this format, if any, keeping its ID
+	var previous models.BookFile
+	status := http.StatusCreated
+	err = r.Stores.WithTx(c.Request.Context(), func(ctx context.Context, tx store.Stores) error {
+		var err error
+		previous, err = tx.Files.GetBookFileByFormat(ctx, book.ID, format)
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			return tx.Files.CreateBookFile(ctx, &file)
+		case err != nil:
+			return err
+		}
+
+		status = http.StatusOK
+		file.ID = previous.ID
+		file.CreatedAt = previous.CreatedAt
+		return tx.Files.UpdateBookFile(ctx, &file)
+	})
+	if err != nil {
+		r.deleteBlobs(c.Request.Context(), []string{file.Key})
+		if errors.Is(err, store.ErrConflict) {
+			response.NewErrorResponse(http.StatusConflict, "File was uploaded concurrently", "another "+format+" was uploaded at the same time, retry").Send(c)
+			return
+		}
+		response.NewErrorResponse(http.StatusInternalServerError, "Failed to save file", err.Error()).Send(c)
+		return
+	}
+	if previous.Key != "" {
+		r.deleteBlobs(c.Request.Context(), []string{previous.Key})
+	}
+
+	response.Response{
+		StatusCode: status,
+		Success:    true,
+		Message:    "File uploaded successfully",
+		Data:       file,
+	}.Send(c)
+}
+
+// DeleteBookFile godoc
+// @Summary Delete a file of a book
+// @Description Delete the PDF or EPUB edition of a book
+// @Tags files
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Produce  json
+// @Param id path string true "Book ID"
+// @Param format path string true "pdf or epub"
+// @Success 204 {string} string "Successfully deleted file"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Book or file not found"
+// @Router /books/{id}/files/{format} [delete]
+func (r *bookRepository) DeleteBookFile(c *gin.Context) {
+	_, file, err := r.findBookFile(c)
+	if err != nil {
+		sendFileError(c, err)
+		return
+	}
+
+	if err := r.Stores.Stores().Files.DeleteBookFile(c.Request.Context(), file); err != nil {
+		response.NewErrorResponse(http.StatusInternalServerError, "Failed to delete file", err.Error()).Send(c)
+		return
+	}
+	r.deleteBlobs(c.Request.Context(), []string{file.Key})
+
+	response.Response{
+		StatusCode: http.StatusNoContent,
+		Success:    true,
+		Message:    "File deleted successfully",
+		Data:       true,
+	}.Send(c)
+}
+
+// CreateBookFileLink godoc
+// @Summary Get a download link to a file of a book
+// @Description Get a short-lived signed link to a book file. The link needs neither the API key nor a JWT, so it can be handed to a browser or an ebook reader.
+// @Tags files
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Produce json
+// @Param id path string true "Book ID"
+// @Param format path string true "pdf or epub"
+// @Success 200 {object} models.BookFileLink "Download link"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Book or file not found"
+// @Router /books/{id}/files/{format}/link [post]
+func (r *bookRepository) CreateBookFileLink(c *gin.Context) {
+	book, file, err := r.findBookFile(c)
+	if err != nil {
+		sendFileError(c, err)
+		return
+	}
+
+	if err := r.checkFileAccess(c, book); err != nil {
+		sendFileError(c, err)
+		return
+	}
+
+	expires := time.Now().Add(r.FileLinkTTL).Truncate(time.Second)
+	query := url.Values{
+		"expires":   {strconv.FormatInt(expires.Unix(), 10)},
+		"signature": {auth.SignResource(fileResource(file.ID), expires)},
+	}
+
+	response.NewSuccessResponse("Link created successfully", models.BookFileLink{
+		URL:       fileDownloadPath + file.ID.String() + "?" + query.Encode(),
+		ExpiresAt: expires,
+	}).Send(c)
+}
+
+// DownloadBookFile godoc
+// @Summary Download a book file
+// @Description Download a book file through a signed link. Range and If-Range requests resume interrupted downloads.
+// @Tags files
+// @Produce application/pdf,application/epub+zip
+// @Param file_id path string true "File ID"
+// @Param expires query int true "Expiry of the link, as a Unix time"
+// @Param signature query string true "Signature of the link"
+// @Param Range header string false "Byte range to download"
+// @Param If-Range header string false "ETag or date the range is valid for"
+// @Success 200 {file} file "File"
+// @Success 206 {file} file "Part of the file"
+// @Failure 403 {string} string "Invalid or expired link"
+// @Failure 404 {string} string "File not found"
+// @Failure 416 {string} string "Range not satisfiable"
+// @Router /files/{file_id} [get]
+func (r *bookRepository) DownloadBookFile(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("file_id"))
+	if err != nil {
+		response.NewErrorResponse(http.StatusNotFound, "File not found", err.Error()).Send(c)
+		return
+	}
+
+	if err := auth.VerifyResource(fileResource(id), c.Query("expires"), c.Query("signature"), time.Now()); err != nil {
+		response.NewErrorResponse(http.StatusForbidden, "Invalid link", err.Error()).Send(c)
+		return
+	}
+
+	// Files of trashed books can't be downloaded
+	file, err := r.Stores.Stores().Files.GetBookFile(c.Request.Context(), id)
+	if err == nil {
+		_, err = r.Books.LoadBook(c.Request.Context(), file.BookID.String())
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		response.NewErrorResponse(http.StatusNotFound, "File not found", err.Error()).Send(c)
+		return
+	} else if err != nil {
+		response.NewErrorResponse(http.StatusInternalServerError, "Failed to retrieve file", err.Error()).Send(c)
+		return
+	}
+
+	reader := storage.NewReadSeeker(c.Request.Context(), r.Blobs, file.Key, file.Size)
+	defer reader.Close()
+
+	c.Header("Content-Type", file.ContentType)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": file.Filename}))
+	c.Header("ETag", file.ETag())
+	c.Header("Cache-Control", "private, max-age=0")
+	c.Header("X-Content-Type-Options", "nosniff")
+	http.ServeContent(c.Writer, c.Request, "", file.UpdatedAt, reader)
+}
@@ -0,0 +1,287 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/auth"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/storage"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/store"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+const testPDF = "%PDF-1.7\n1 0 obj << /Type /Catalog >> endobj\n%%EOF\n"
+
+func newBookFileRequest(t *testing.T, format string, data []byte) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", "book."+format)
+	assert.NoError(t, err)
+	part.Write(data)
+	assert.NoError(t, writer.Close())
+
+	req := httptest.NewRequest(http.MethodPut, "/books/1/files/"+format, &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+// expectBookFile makes the mock stores load file and its book for a download
+func expectBookFile(books *store.MockBookStore, files *store.MockBookFileStore, book models.Book, file models.BookFile) {
+	files.EXPECT().GetBookFile(gomock.Any(), file.ID).Return(file, nil)
+	books.EXPECT().GetBook(gomock.Any(), book.ID.String()).Return(book, nil)
+}
+
+func TestUploadBookFile(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBooks := store.NewMockBookStore(ctrl)
+	mockFiles := store.NewMockBookFileStore(ctrl)
+	root := t.TempDir()
+	repo := NewBookRepository(nil, newMockUnitOfWork(ctrl, store.Stores{Books: mockBooks, Files: mockFiles}), nil, nil, storage.NewLocalStore(root, ""))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.PUT("/books/:id/files/:format", repo.UploadBookFile)
+
+	book := models.Book{ID: uuid.New(), Title: "The Left Hand of Darkness"}
+	expectCoverBook(mockBooks, book)
+	mockFiles.EXPECT().GetBookFileByFormat(gomock.Any(), book.ID, models.BookFormatPDF).Return(models.BookFile{}, store.ErrNotFound)
+	mockFiles.EXPECT().CreateBookFile(gomock.Any(), gomock.Any()).Return(nil)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, newBookFileRequest(t, models.BookFormatPDF, []byte(testPDF)))
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var body struct {
+		Data models.BookFile `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, book.ID, body.Data.BookID)
+	assert.Equal(t, "application/pdf", body.Data.ContentType)
+	assert.Equal(t, "The-Left-Hand-of-Darkness.pdf", body.Data.Filename)
+	assert.Equal(t, int64(len(testPDF)), body.Data.Size)
+	assert.Len(t, body.Data.SHA256, 64)
+
+	// The file is stored under the book, out of reach of the public blob route
+	matches, err := filepath.Glob(filepath.Join(root, "ebooks", book.ID.String(), "*.pdf"))
+	assert.NoError(t, err)
+	assert.Len(t, matches, 1)
+}
+
+func TestUploadBookFileRejectsInvalidFiles(t *testing.T) {
+	epub := append([]byte("PK\x03\x04"), make([]byte, 26)...)
+	epub = append(epub, "mimetypeapplication/epub+zip"...)
+
+	tests := []struct {
+		name   string
+		format string
+		data   []byte
+		status int
+	}{
+		{"pdf as epub", models.BookFormatEPUB, []byte(testPDF), http.StatusUnsupportedMediaType},
+		{"epub as pdf", models.BookFormatPDF, epub, http.StatusUnsupportedMediaType},
+		{"plain zip", models.BookFormatEPUB, []byte("PK\x03\x04 not an epub"), http.StatusUnsupportedMediaType},
+		{"too large", models.BookFormatPDF, append([]byte(testPDF), make([]byte, 4096)...), http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		ctrl := gomock.NewController(t)
+		mockBooks := store.NewMockBookStore(ctrl)
+		root := t.TempDir()
+		repo := NewBookRepository(nil, newMockUnitOfWork(ctrl, store.Stores{Books: mockBooks}), nil, nil, storage.NewLocalStore(root, ""))
+		repo.EbookMaxBytes = 2048
+
+		gin.SetMode(gin.TestMode)
+		r := gin.Default()
+		r.PUT("/books/:id/files/:format", repo.UploadBookFile)
+
+		// Nothing is stored when the upload is rejected
+		expectCoverBook(mockBooks, models.Book{ID: uuid.New()})
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, newBookFileRequest(t, tt.format, tt.data))
+		assert.Equal(t, tt.status, w.Code, tt.name)
+
+		entries, _ := os.ReadDir(root)
+		assert.Empty(t, entries, tt.name)
+		ctrl.Finish()
+	}
+
+	// Unknown formats are rejected before the book is loaded
+	repo := NewBookRepository(nil, nil, nil, nil, nil)
+	r := gin.Default()
+	r.PUT("/books/:id/files/:format", repo.UploadBookFile)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, newBookFileRequest(t, "mobi", []byte(testPDF)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestCreateBookFileLink(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := newMockDatabase(ctrl)
+	mockBooks := store.NewMockBookStore(ctrl)
+	mockFiles := store.NewMockBookFileStore(ctrl)
+	repo := NewBookRepository(mockDB, newMockUnitOfWork(ctrl, store.Stores{Books: mockBooks, Files: mockFiles}), nil, nil, nil)
+	repo.FileLinkTTL = time.Minute
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/books/:id/files/:format/link", func(c *gin.Context) {
+		c.Set("role", c.GetHeader("X-Role"))
+		c.Next()
+	}, repo.CreateBookFileLink)
+
+	book := models.Book{ID: uuid.New()}
+	file := models.BookFile{ID: uuid.New(), BookID: book.ID, Format: models.BookFormatPDF}
+	mockBooks.EXPECT().GetBook(gomock.Any(), "1").Return(book, nil).Times(2)
+	mockFiles.EXPECT().GetBookFileByFormat(gomock.Any(), book.ID, models.BookFormatPDF).Return(file, nil).Times(2)
+
+	// Users need an active loan to read the files, and a dry run finds none
+	mockDB.EXPECT().Model(gomock.Any()).DoAndReturn(func(model interface{}) *gorm.DB { return newDryRunDB(t).Model(model) })
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/books/1/files/pdf/link", nil)
+	req.Header.Set("X-Role", models.RoleUser)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/books/1/files/pdf/link", nil)
+	req.Header.Set("X-Role", models.RoleAdmin)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var body struct {
+		Data models.BookFileLink `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	link, err := url.Parse(body.Data.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, "/api/v1/files/"+file.ID.String(), link.Path)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), body.Data.ExpiresAt, 2*time.Second)
+	assert.NoError(t, auth.VerifyResource(fileResource(file.ID), link.Query().Get("expires"), link.Query().Get("signature"), time.Now()))
+}
+
+func TestDownloadBookFile(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBooks := store.NewMockBookStore(ctrl)
+	mockFiles := store.NewMockBookFileStore(ctrl)
+	ctx := context.Background()
+	blobs := storage.NewLocalStore(t.TempDir(), "")
+	repo := NewBookRepository(nil, newMockUnitOfWork(ctrl, store.Stores{Books: mockBooks, Files: mockFiles}), nil, nil, blobs)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/files/:file_id", repo.DownloadBookFile)
+	r.HEAD("/files/:file_id", repo.DownloadBookFile)
+
+	book := models.Book{ID: uuid.New()}
+	file := models.BookFile{
+		ID:          uuid.New(),
+		BookID:      book.ID,
+		Key:         "ebooks/" + book.ID.String() + "/a.pdf",
+		Filename:    "dune.pdf",
+		ContentType: "application/pdf",
+		Size:        int64(len(testPDF)),
+		SHA256:      "5d41402abc4b2a76b9719d911017c592",
+		UpdatedAt:   time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+	assert.NoError(t, blobs.Put(ctx, file.Key, strings.NewReader(testPDF), file.Size, file.ContentType))
+
+	expires := time.Now().Add(time.Minute)
+	query := "?expires=" + strconv.FormatInt(expires.Unix(), 10) + "&signature=" + auth.SignResource(fileResource(file.ID), expires)
+	path := "/files/" + file.ID.String() + query
+
+	tests := []struct {
+		name    string
+		method  string
+		headers map[string]string
+		status  int
+		body    string
+	}{
+		{"whole file", http.MethodGet, nil, http.StatusOK, testPDF},
+		{"head", http.MethodHead, nil, http.StatusOK, ""},
+		{"range", http.MethodGet, map[string]string{"Range": "bytes=0-7"}, http.StatusPartialContent, "%PDF-1.7"},
+		{"suffix range", http.MethodGet, map[string]string{"Range": "bytes=-6"}, http.StatusPartialContent, "%%EOF\n"},
+		{"matching if-range", http.MethodGet, map[string]string{"Range": "bytes=1-3", "If-Range": file.ETag()}, http.StatusPartialContent, "PDF"},
+		{"stale if-range", http.MethodGet, map[string]string{"Range": "bytes=1-3", "If-Range": `"old"`}, http.StatusOK, testPDF},
+		{"unsatisfiable range", http.MethodGet, map[string]string{"Range": "bytes=4096-"}, http.StatusRequestedRangeNotSatisfiable, ""},
+	}
+
+	for _, tt := range tests {
+		expectBookFile(mockBooks, mockFiles, book, file)
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(tt.method, path, nil)
+		for name, value := range tt.headers {
+			req.Header.Set(name, value)
+		}
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, tt.status, w.Code, tt.name)
+		if tt.body != "" {
+			assert.Equal(t, tt.body, w.Body.String(), tt.name)
+		}
+		if tt.status != http.StatusRequestedRangeNotSatisfiable {
+			assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"), tt.name)
+			assert.Equal(t, `attachment; filename=dune.pdf`, w.Header().Get("Content-Disposition"), tt.name)
+			assert.Equal(t, file.ETag(), w.Header().Get("ETag"), tt.name)
+			assert.Equal(t, "bytes", w.Header().Get("Accept-Ranges"), tt.name)
+		}
+	}
+}
+
+func TestDownloadBookFileRejectsInvalidLinks(t *testing.T) {
+	repo := NewBookRepository(nil, nil, nil, nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/files/:file_id", repo.DownloadBookFile)
+
+	id := uuid.New()
+	valid := time.Now().Add(time.Minute)
+	expired := time.Now().Add(-time.Minute)
+	sign := func(id uuid.UUID, expires time.Time) string {
+		return "?expires=" + strconv.FormatInt(expires.Unix(), 10) + "&signature=" + auth.SignResource(fileResource(id), expires)
+	}
+
+	// The signature is checked before the database is touched
+	tests := []struct {
+		name   string
+		url    string
+		status int
+	}{
+		{"no signature", "/files/" + id.String(), http.StatusForbidden},
+		{"expired", "/files/" + id.String() + sign(id, expired), http.StatusForbidden},
+		{"other file", "/files/" + id.String() + sign(uuid.New(), valid), http.StatusForbidden},
+		{"extended expiry", "/files/" + id.String() + strings.Replace(sign(id, valid), strconv.FormatInt(valid.Unix(), 10), strconv.FormatInt(valid.Unix()+3600, 10), 1), http.StatusForbidden},
+		{"invalid id", "/files/not-a-uuid" + sign(id, valid), http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.url, nil))
+		assert.Equal(t, tt.status, w.Code, tt.name)
+	}
+}
@@ -0,0 +1,637 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/cache"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/response"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/store"
+)
+
+const (
+	// ImportModeInsert always creates new books and rejects rows whose ISBN already exists
+	ImportModeInsert = "insert"
+	// ImportModeUpsert updates the book with the same ISBN, or creates one
+	ImportModeUpsert = "upsert"
+
+	ImportJobQueued    = "queued"
+	ImportJobRunning   = "running"
+	ImportJobCompleted = "completed"
+	ImportJobFailed    = "failed"
+	// ImportJobInterrupted is a job whose worker stopped before it finished,
+	// e.g. because its server was restarted
+	ImportJobInterrupted = "interrupted"
+
+	importJobKeyPrefix = "books_import_job_"
+	importJobTTL       = 24 * time.Hour
+	// importJobLeaseTTL is how long a job is considered alive after its
+	// worker last renewed its lease
+	importJobLeaseTTL  = 30 * time.Second
+	importMaxErrors    = 1000
+	importMaxLineBytes = 1 << 20
+)
+
+// bookImportFields are the book fields a column can be mapped to
+var bookImportFields = []string{"title", "author", "isbn", "publication_date", "language", "page_count", "publisher", "description"}
+
+// errDryRun rolls back a dry-run batch after it has been fully evaluated
+var errDryRun = errors.New("dry run")
+
+type ImportOptions struct {
+	Format  string            `json:"format"`
+	Mode    string            `json:"mode"`
+	DryRun  bool              `json:"dry_run"`
+	Mapping map[string]string `json:"mapping,omitempty"`
+}
+
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+type ImportResult struct {
+	Rows    int              `json:"rows"`
+	Created int              `json:"created"`
+	Updated int              `json:"updated"`
+	Failed  int              `json:"failed"`
+	DryRun  bool             `json:"dry_run"`
+	Errors  []ImportRowError `json:"errors"`
+}
+
+func (result *ImportResult) fail(row int, err error) {
+	result.Failed++
+	if len(result.Errors) < importMaxErrors {
+		result.Errors = append(result.Errors, ImportRowError{Row: row, Error: err.Error()})
+	}
+}
+
+func (result *ImportResult) merge(batch ImportResult) {
+	result.Rows += batch.Rows
+	result.Created += batch.Created
+	result.Updated += batch.Updated
+	result.Failed += batch.Failed
+	for _, rowErr := range batch.Errors {
+		if len(result.Errors) >= importMaxErrors {
+			break
+		}
+		result.Errors = append(result.Errors, rowErr)
+	}
+}
+
+// ImportJob tracks an import running in the background. Jobs are kept in
+// the cache so their status can be read from any replica. While a job runs,
+// its worker renews a lease next to it; a queued or running job whose lease
+// expired is reported as interrupted.
+type ImportJob struct {
+	ID         string        `json:"id"`
+	Status     string        `json:"status"`
+	Options    ImportOptions `json:"options"`
+	Result     *ImportResult `json:"result,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	CreatedAt  time.Time     `json:"created_at"`
+	FinishedAt *time.Time    `json:"finished_at,omitempty"`
+}
+
+type importRecord struct {
+	row    int
+	values map[string]string
+	err    error
+}
+
+// recordReader returns the next record, or io.EOF once the input is consumed
+type recordReader func() (importRecord, error)
+
+// mappedColumn returns the source column for a book field
+func mappedColumn(mapping map[string]string, field string) string {
+	if column, ok := mapping[field]; ok {
+		return column
+	}
+	return field
+}
+
+func newCSVRecordReader(source io.Reader, mapping map[string]string) (recordReader, error) {
+	reader := csv.NewReader(source)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("could not read CSV header: %w", err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		columns[name] = i
+	}
+
+	indexes := map[string]int{}
+	for _, field := range bookImportFields {
+		if i, ok := columns[strings.ToLower(mappedColumn(mapping, field))]; ok {
+			indexes[field] = i
+		}
+	}
+	if len(indexes) == 0 {
+		return nil, errors.New("no CSV column matches a book field, check the column mapping")
+	}
+
+	// Row numbers match spreadsheet lines, the header being line 1
+	row := 1
+	return func() (importRecord, error) {
+		fields, err := reader.Read()
+		row++
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return importRecord{row: row, err: err}, nil
+		}
+		if err != nil {
+			return importRecord{}, err
+		}
+
+		values := map[string]string{}
+		for field, i := range indexes {
+			if i < len(fields) {
+				values[field] = strings.TrimSpace(fields[i])
+			}
+		}
+		return importRecord{row: row, values: values}, nil
+	}, nil
+}
+
+func newNDJSONRecordReader(source io.Reader, mapping map[string]string) recordReader {
+	scanner := bufio.NewScanner(source)
+	scanner.Buffer(make([]byte, 0, 64*1024), importMaxLineBytes)
+
+	row := 0
+	return func() (importRecord, error) {
+		for scanner.Scan() {
+			row++
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+
+			var object map[string]interface{}
+			decoder := json.NewDecoder(bytes.NewReader(line))
+			decoder.UseNumber()
+			if err := decoder.Decode(&object); err != nil {
+				return importRecord{row: row, err: err}, nil
+			}
+
+			values := map[string]string{}
+			for _, field := range bookImportFields {
+				if value, ok := object[mappedColumn(mapping, field)]; ok && value != nil {
+					values[field] = strings.TrimSpace(fmt.Sprint(value))
+				}
+			}
+			return importRecord{row: row, values: values}, nil
+		}
+
+		if err := scanner.Err(); err != nil {
+			return importRecord{}, err
+		}
+		return importRecord{}, io.EOF
+	}
+}
+
+func newRecordReader(options ImportOptions, source io.Reader) (recordReader, error) {
+	switch options.Format {
+	case FormatCSV:
+		return newCSVRecordReader(source, options.Mapping)
+	case FormatNDJSON:
+		return newNDJSONRecordReader(source, options.Mapping), nil
+	default:
+		return nil, errors.New("format must be csv or ndjson")
+	}
+}
+
+// setImportField parses an imported value onto the writable book fields. An
+// empty value clears the field.
+func setImportField(input *models.UpdateBook, field string, value string) error {
+	var err error
+	switch field {
+	case "title":
+		input.Title = value
+	case "author":
+		input.Author = value
+	case "isbn":
+		input.ISBN, err = models.ParseISBN(value)
+	case "publication_date":
+		input.PublicationDate = nil
+		if value != "" {
+			var date models.Date
+			date, err = models.ParseDate(value)
+			input.PublicationDate = &date
+		}
+	case "language":
+		input.Language, err = models.ParseLanguageCode(value)
+	case "page_count":
+		input.PageCount = 0
+		if value != "" {
+			input.PageCount, err = strconv.Atoi(value)
+		}
+	case "publisher":
+		input.Publisher = value
+	case "description":
+		input.Description = value
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", field, err)
+	}
+	return nil
+}
+
+// importBook writes one record and reports whether it created a book. In
+// upsert mode only the imported columns overwrite an existing book, the rest
+// of it is kept.
+func (r *bookRepository) importBook(ctx context.Context, tx store.Stores, values map[string]string, mode string) (bool, error) {
+	book := models.Book{Version: 1}
+	exists := false
+
+	isbn, err := models.ParseISBN(values["isbn"])
+	if err != nil {
+		return false, err
+	}
+	if isbn != "" {
+		found, err := tx.Books.LockBookByISBN(ctx, isbn)
+		switch {
+		case err == nil && mode != ImportModeUpsert:
+			return false, fmt.Errorf("a book with ISBN %s already exists", isbn)
+		case err == nil:
+			book, exists = found, true
+		case !errors.Is(err, store.ErrNotFound):
+			return false, err
+		}
+	}
+
+	input := book.Writable()
+	for field, value := range values {
+		if err := setImportField(&input, field, value); err != nil {
+			return false, err
+		}
+	}
+	if err := binding.Validator.ValidateStruct(input); err != nil {
+		return false, err
+	}
+	input.ApplyTo(&book)
+
+	if exists {
+		err = r.Books.SaveBook(ctx, &book)
+	} else {
+		err = r.Books.AddBook(ctx, &book)
+	}
+	if errors.Is(err, store.ErrConflict) {
+		err = fmt.Errorf("a book with ISBN %s already exists", book.ISBN)
+	}
+	return !exists, err
+}
+
+// runImport writes every record in batches, one transaction per batch and one
+// savepoint per row so a bad row doesn't abort the rest of its batch. The
+// books of a batch are indexed once it commits.
+func (r *bookRepository) runImport(ctx context.Context, next recordReader, options ImportOptions) (ImportResult, error) {
+	result := ImportResult{DryRun: options.DryRun, Errors: []ImportRowError{}}
+
+	for done := false; !done; {
+		var batch []importRecord
+		for len(batch) < r.ImportBatchSize {
+			record, err := next()
+			if err == io.EOF {
+				done = true
+				break
+			}
+			if err != nil {
+				return result, err
+			}
+			batch = append(batch, record)
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		batchResult := ImportResult{Errors: []ImportRowError{}}
+		err := r.Stores.WithTx(ctx, func(ctx context.Context, _ store.Stores) error {
+			for _, record := range batch {
+				batchResult.Rows++
+				if record.err != nil {
+					batchResult.fail(record.row, record.err)
+					continue
+				}
+
+				var created bool
+				err := r.Stores.WithTx(ctx, func(ctx context.Context, sp store.Stores) error {
+					var err error
+					created, err = r.importBook(ctx, sp, record.values, options.Mode)
+					return err
+				})
+				switch {
+				case err != nil:
+					batchResult.fail(record.row, err)
+				case created:
+					batchResult.Created++
+				default:
+					batchResult.Updated++
+				}
+			}
+
+			if options.DryRun {
+				return errDryRun
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errDryRun) {
+			return result, err
+		}
+
+		result.merge(batchResult)
+	}
+
+	return result, nil
+}
+
+func (r *bookRepository) saveImportJob(ctx context.Context, job ImportJob) {
+	data, err := json.Marshal(job)
+	if err != nil {
+		log.Printf("Failed to marshal import job %s: %v", job.ID, err)
+		return
+	}
+	if err := r.Cache.Set(ctx, importJobKeyPrefix+job.ID, data, importJobTTL); err != nil {
+		log.Printf("Failed to save import job %s: %v", job.ID, err)
+	}
+}
+
+// importJobLeaseKey is the key of the lease held by the worker of a job
+func importJobLeaseKey(id string) string {
+	return importJobKeyPrefix + id + "_lease"
+}
+
+func (r *bookRepository) leaseImportJob(ctx context.Context, id string) {
+	if err := r.Cache.Set(ctx, importJobLeaseKey(id), []byte(id), importJobLeaseTTL); err != nil {
+		log.Printf("Failed to renew the lease of import job %s: %v", id, err)
+	}
+}
+
+// renewImportJobLease renews the lease of job id every third of its TTL
+// until stop is closed
+func (r *bookRepository) renewImportJobLease(ctx context.Context, id string, stop <-chan struct{}) {
+	ticker := time.NewTicker(importJobLeaseTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			r.leaseImportJob(ctx, id)
+		}
+	}
+}
+
+// startImportJob copies the upload to a temporary file and imports it in the
+// background, since the request body is gone once the handler returns. The
+// job keeps the values of ctx but not its cancellation.
+func (r *bookRepository) startImportJob(ctx context.Context, file multipart.File, options ImportOptions) (ImportJob, error) {
+	ctx = context.WithoutCancel(ctx)
+	job := ImportJob{
+		ID:        uuid.NewString(),
+		Status:    ImportJobQueued,
+		Options:   options,
+		CreatedAt: time.Now(),
+	}
+
+	tmp, err := os.CreateTemp("", "books-import-*")
+	if err != nil {
+		return job, err
+	}
+	if _, err := io.Copy(tmp, file); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return job, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return job, err
+	}
+
+	// The lease outlives the job by its TTL, so a reader that saw the job
+	// running just before it finished still finds the lease
+	r.leaseImportJob(ctx, job.ID)
+	r.saveImportJob(ctx, job)
+	stop := make(chan struct{})
+	go r.renewImportJobLease(ctx, job.ID, stop)
+
+	go func() {
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+		defer close(stop)
+
+		job.Status = ImportJobRunning
+		r.saveImportJob(ctx, job)
+
+		var result ImportResult
+		next, err := newRecordReader(options, tmp)
+		if err == nil {
+			result, err = r.runImport(ctx, next, options)
+		}
+
+		finishedAt := time.Now()
+		job.FinishedAt = &finishedAt
+		job.Result = &result
+		job.Status = ImportJobCompleted
+		if err != nil {
+			job.Status = ImportJobFailed
+			job.Error = err.Error()
+		}
+		r.saveImportJob(ctx, job)
+	}()
+
+	return job, nil
+}
+
+func parseImportOptions(c *gin.Context, header *multipart.FileHeader) (ImportOptions, error) {
+	options := ImportOptions{
+		Format: c.PostForm("format"),
+		Mode:   c.DefaultPostForm("mode", ImportModeInsert),
+	}
+
+	if options.Format == "" {
+		switch strings.ToLower(filepath.Ext(header.Filename)) {
+		case ".ndjson", ".jsonl":
+			options.Format = FormatNDJSON
+		default:
+			options.Format = FormatCSV
+		}
+	}
+	if options.Format != FormatCSV && options.Format != FormatNDJSON {
+		return options, errors.New("format must be csv or ndjson")
+	}
+
+	if options.Mode != ImportModeInsert && options.Mode != ImportModeUpsert {
+		return options, errors.New("mode must be insert or upsert")
+	}
+
+	dryRun, err := strconv.ParseBool(c.DefaultPostForm("dry_run", "false"))
+	if err != nil {
+		return options, fmt.Errorf("invalid dry_run: %w", err)
+	}
+	options.DryRun = dryRun
+
+	if mapping := c.PostForm("mapping"); mapping != "" {
+		if err := json.Unmarshal([]byte(mapping), &options.Mapping); err != nil {
+			return options, fmt.Errorf("mapping must be a JSON object of book field to column: %w", err)
+		}
+		for field := range options.Mapping {
+			if !isBookImportField(field) {
+				return options, fmt.Errorf("unknown book field %q in mapping", field)
+			}
+		}
+	}
+
+	return options, nil
+}
+
+func isBookImportField(field string) bool {
+	for _, known := range bookImportFields {
+		if known == field {
+			return true
+		}
+	}
+	return false
+}
+
+// ImportBooks godoc
+// @Summary Import books from CSV or NDJSON
+// @Description Import a multipart CSV or NDJSON upload. Columns are matched to book fields by name unless a mapping is given. Invalid rows are reported and skipped. Large uploads, or async=true, run as a background job.
+// @Tags books
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Accept  multipart/form-data
+// @Produce  json
+// @Param file formData file true "CSV or NDJSON file"
+// @Param format formData string false "csv or ndjson, defaults to the file extension"
+// @Param mode formData string false "insert or upsert (matched by ISBN)" default(insert)
+// @Param dry_run formData bool false "Validate and report without writing" default(false)
+// @Param mapping formData string false "JSON object mapping book fields to columns, e.g. {\"title\":\"Book Title\"}"
+// @Param async formData bool false "Run as a background job" default(false)
+// @Success 200 {object} ImportResult "Import finished"
+// @Success 202 {object} ImportJob "Import queued as a background job"
+// @Failure 400 {string} string "Bad Request"
+// @Router /books/import [post]
+func (r *bookRepository) ImportBooks(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, r.ImportMaxBytes)
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		response.NewErrorResponse(http.StatusBadRequest, "Invalid upload", err.Error()).Send(c)
+		return
+	}
+
+	options, err := parseImportOptions(c, header)
+	if err != nil {
+		response.NewErrorResponse(http.StatusBadRequest, "Invalid import options", err.Error()).Send(c)
+		return
+	}
+
+	async, err := strconv.ParseBool(c.DefaultPostForm("async", "false"))
+	if err != nil {
+		response.NewErrorResponse(http.StatusBadRequest, "Invalid import options", "invalid async: "+err.Error()).Send(c)
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		response.NewErrorResponse(http.StatusBadRequest, "Invalid upload", err.Error()).Send(c)
+		return
+	}
+	defer file.Close()
+
+	if async || header.Size > r.ImportAsyncBytes {
+		job, err := r.startImportJob(c.Request.Context(), file, options)
+		if err != nil {
+			response.NewErrorResponse(http.StatusInternalServerError, "Failed to start import", err.Error()).Send(c)
+			return
+		}
+
+		c.Header("Location", "/api/v1/books/import/jobs/"+job.ID)
+		response.Response{
+			StatusCode: http.StatusAccepted,
+			Success:    true,
+			Message:    "Import started",
+			Data:       job,
+		}.Send(c)
+		return
+	}
+
+	next, err := newRecordReader(options, file)
+	if err != nil {
+		response.NewErrorResponse(http.StatusBadRequest, "Invalid import file", err.Error()).Send(c)
+		return
+	}
+
+	result, err := r.runImport(c.Request.Context(), next, options)
+	if err != nil {
+		response.NewErrorResponse(http.StatusInternalServerError, "Import failed", err.Error()).Send(c)
+		return
+	}
+
+	message := "Import completed"
+	if options.DryRun {
+		message = "Dry run completed, nothing was written"
+	}
+	response.NewSuccessResponse(message, result).Send(c)
+}
+
+// FindImportJob godoc
+// @Summary Get the status of an import job
+// @Description Get the progress and result of a background import. A job whose server stopped before it finished is reported as interrupted.
+// @Tags books
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Produce  json
+// @Param job_id path string true "Import job ID"
+// @Success 200 {object} ImportJob "Import job"
+// @Failure 404 {string} string "Import job not found"
+// @Router /books/import/jobs/{job_id} [get]
+func (r *bookRepository) FindImportJob(c *gin.Context) {
+	var job ImportJob
+
+	data, err := r.Cache.Get(c.Request.Context(), importJobKeyPrefix+c.Param("job_id"))
+	if err != nil {
+		response.NewErrorResponse(http.StatusNotFound, "Import job not found", err.Error()).Send(c)
+		return
+	}
+	if err := json.Unmarshal(data, &job); err != nil {
+		response.NewErrorResponse(http.StatusInternalServerError, "Failed to unmarshal import job", err.Error()).Send(c)
+		return
+	}
+
+	if job.Status == ImportJobQueued || job.Status == ImportJobRunning {
+		if _, err := r.Cache.Get(c.Request.Context(), importJobLeaseKey(job.ID)); errors.Is(err, cache.ErrMiss) {
+			job.Status = ImportJobInterrupted
+			job.Error = "the import stopped before it finished, e.g. because its server was restarted; the rows imported so far were kept, upload the file again with mode=upsert to finish it"
+			r.saveImportJob(c.Request.Context(), job)
+		}
+	}
+
+	response.NewSuccessResponse("Import job retrieved successfully", job).Send(c)
+}
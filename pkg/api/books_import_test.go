@@ -0,0 +1,185 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/cache"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func readAllRecords(t *testing.T, next recordReader) []importRecord {
+	var records []importRecord
+	for {
+		record, err := next()
+		if err == io.EOF {
+			return records
+		}
+		assert.NoError(t, err)
+		records = append(records, record)
+	}
+}
+
+func TestCSVRecordReader(t *testing.T) {
+	input := "Book Title,AUTHOR,Pages\n" +
+		"The Go Programming Language,Alan Donovan,380\n" +
+		"\"broken,Someone\n"
+
+	next, err := newCSVRecordReader(strings.NewReader(input), map[string]string{"title": "book title"})
+	assert.NoError(t, err)
+
+	records := readAllRecords(t, next)
+	assert.Len(t, records, 2)
+	assert.Equal(t, 2, records[0].row)
+	assert.Equal(t, map[string]string{"title": "The Go Programming Language", "author": "Alan Donovan"}, records[0].values)
+	assert.Equal(t, 3, records[1].row)
+	assert.Error(t, records[1].err)
+}
+
+func TestCSVRecordReaderReadsExport(t *testing.T) {
+	next, err := newCSVRecordReader(strings.NewReader(writeBooks(t, FormatCSV, newExportTestBooks())), nil)
+	assert.NoError(t, err)
+
+	records := readAllRecords(t, next)
+	assert.Len(t, records, 2)
+	assert.Equal(t, map[string]string{
+		"title": "Go, in Practice", "author": "Matt Butcher", "isbn": "9781633430075", "publication_date": "2016-10-01",
+		"language": "en", "page_count": "288", "publisher": "Manning", "description": "",
+	}, records[0].values)
+}
+
+func TestCSVRecordReaderRequiresKnownColumn(t *testing.T) {
+	_, err := newCSVRecordReader(strings.NewReader("name,writer\nx,y\n"), nil)
+	assert.Error(t, err)
+}
+
+func TestNDJSONRecordReader(t *testing.T) {
+	input := `{"name":"Effective Go","author":"Robert Griesemer","isbn":9780000000000}` + "\n" +
+		"\n" +
+		`{"name":` + "\n"
+
+	next := newNDJSONRecordReader(strings.NewReader(input), map[string]string{"title": "name"})
+	records := readAllRecords(t, next)
+
+	assert.Len(t, records, 2)
+	assert.Equal(t, map[string]string{"title": "Effective Go", "author": "Robert Griesemer", "isbn": "9780000000000"}, records[0].values)
+	assert.Equal(t, 3, records[1].row)
+	assert.Error(t, records[1].err)
+}
+
+func TestSetImportField(t *testing.T) {
+	var input models.UpdateBook
+
+	assert.NoError(t, setImportField(&input, "isbn", "0-306-40615-2"))
+	assert.NoError(t, setImportField(&input, "publication_date", "1999-12-31"))
+	assert.NoError(t, setImportField(&input, "language", "EN"))
+	assert.NoError(t, setImportField(&input, "page_count", "320"))
+	assert.Equal(t, models.ISBN("9780306406157"), input.ISBN)
+	assert.Equal(t, "1999-12-31", input.PublicationDate.String())
+	assert.Equal(t, models.LanguageCode("en"), input.Language)
+	assert.Equal(t, 320, input.PageCount)
+
+	assert.NoError(t, setImportField(&input, "publication_date", ""))
+	assert.Nil(t, input.PublicationDate)
+
+	assert.Error(t, setImportField(&input, "isbn", "0-306-40615-3"))
+	assert.Error(t, setImportField(&input, "publication_date", "31/12/1999"))
+	assert.Error(t, setImportField(&input, "language", "english"))
+	assert.Error(t, setImportField(&input, "page_count", "many"))
+}
+
+func newImportRequest(t *testing.T, filename string, content string, fields map[string]string) *http.Request {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for name, value := range fields {
+		assert.NoError(t, form.WriteField(name, value))
+	}
+	if filename != "" {
+		part, err := form.CreateFormFile("file", filename)
+		assert.NoError(t, err)
+		_, err = part.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, form.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/books/import", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return req
+}
+
+func TestImportBooksRejectsInvalidRequests(t *testing.T) {
+	repo := NewBookRepository(nil, nil, nil, nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/books/import", repo.ImportBooks)
+
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+		fields   map[string]string
+	}{
+		{"missing file", "", "", nil},
+		{"unknown format", "books.xml", "<books/>", map[string]string{"format": "xml"}},
+		{"unknown mode", "books.csv", "title,author\n", map[string]string{"mode": "merge"}},
+		{"invalid dry_run", "books.csv", "title,author\n", map[string]string{"dry_run": "maybe"}},
+		{"unknown mapped field", "books.csv", "title,author\n", map[string]string{"mapping": `{"pages":"Pages"}`}},
+		{"no matching column", "books.csv", "name,writer\nx,y\n", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, newImportRequest(t, tt.filename, tt.content, tt.fields))
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
+
+func TestFindImportJobReportsInterruptedJobs(t *testing.T) {
+	ctx := context.Background()
+	jobs := cache.NewMemoryCache(100)
+	repo := NewBookRepository(nil, nil, jobs, nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/books/import/jobs/:job_id", repo.FindImportJob)
+
+	find := func(id string) ImportJob {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/books/import/jobs/"+id, nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		var body struct{ Data ImportJob }
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return body.Data
+	}
+
+	// A job whose worker holds its lease is still running
+	live := ImportJob{ID: uuid.NewString(), Status: ImportJobRunning}
+	repo.saveImportJob(ctx, live)
+	repo.leaseImportJob(ctx, live.ID)
+	assert.Equal(t, ImportJobRunning, find(live.ID).Status)
+
+	// The worker of this one went away with its server
+	orphan := ImportJob{ID: uuid.NewString(), Status: ImportJobRunning}
+	repo.saveImportJob(ctx, orphan)
+	job := find(orphan.ID)
+	assert.Equal(t, ImportJobInterrupted, job.Status)
+	assert.NotEmpty(t, job.Error)
+
+	// The new status is saved
+	var saved ImportJob
+	assert.NoError(t, cache.GetJSON(ctx, jobs, importJobKeyPrefix+orphan.ID, &saved))
+	assert.Equal(t, ImportJobInterrupted, saved.Status)
+}
@@ -0,0 +1,447 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/pagination"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/response"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/store"
+)
+
+// loanSortColumns are the columns loan lists can be sorted by
+var loanSortColumns = map[string]bool{"checked_out_at": true, "due_at": true, "returned_at": true}
+
+// loanStatusQuery returns the condition selecting loans in status
+func loanStatusQuery(status string, now time.Time) (string, []interface{}, bool) {
+	switch status {
+	case models.LoanStatusActive:
+		return "returned_at IS NULL", nil, true
+	case models.LoanStatusOverdue:
+		return "returned_at IS NULL AND due_at < ?", []interface{}{now}, true
+	case models.LoanStatusReturned:
+		return "returned_at IS NOT NULL", nil, true
+	}
+	return "", nil, false
+}
+
+// canManageLoans reports whether the caller may act on the loans and
+// reservations of other users
+func canManageLoans(c *gin.Context) bool {
+	return c.GetString("role") == models.RoleAdmin
+}
+
+// sendLoanError answers a failed loan or reservation write
+func sendLoanError(c *gin.Context, err error) {
+	var serr *statusError
+	if errors.As(err, &serr) {
+		response.NewErrorResponse(serr.status, serr.message, serr.Error()).Send(c)
+		return
+	}
+	response.NewErrorResponse(http.StatusInternalServerError, "Failed to save loan", err.Error()).Send(c)
+}
+
+// settleReservations hands the available copies of a locked book to its
+// reservation queue, oldest first, after taking back the copies of expired
+// holds. It only changes the availability of book in memory;
+// BookService.SaveAvailability writes it.
+func (r *bookRepository) settleReservations(ctx context.Context, tx store.Stores, book *models.Book, now time.Time) error {
+	expired, err := tx.Loans.ExpireHolds(ctx, book.ID, now)
+	if err != nil {
+		return err
+	}
+	book.AvailableCopies += expired
+
+	if book.AvailableCopies <= 0 {
+		return nil
+	}
+
+	held, err := tx.Loans.HoldCopies(ctx, book.ID, book.AvailableCopies, now, now.Add(r.ReservationHoldPeriod))
+	if err != nil {
+		return err
+	}
+	book.AvailableCopies -= held
+	return nil
+}
+
+// lockLoan loads the loan in the path and locks its book, trashed or not.
+// Borrowers only reach their own loans.
+func lockLoan(ctx context.Context, tx store.Stores, c *gin.Context) (models.Loan, models.Book, error) {
+	var loan models.Loan
+	var book models.Book
+	notFound := &statusError{http.StatusNotFound, "Loan not found", errors.New("no loan with this ID")}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return loan, book, notFound
+	}
+	if loan, err = tx.Loans.GetLoan(ctx, id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return loan, book, notFound
+		}
+		return loan, book, err
+	}
+
+	if !canManageLoans(c) {
+		user, err := findUser(ctx, tx, c.GetString("username"))
+		if err != nil {
+			return loan, book, err
+		}
+		if loan.UserID != user.ID {
+			return loan, book, notFound
+		}
+	}
+
+	// The book of a returned loan may be purged, leaving nothing to lock and
+	// a loan that can't change anymore
+	if loan.BookID == nil {
+		return loan, book, nil
+	}
+	if book, err = lockedBook(tx.Books.LockAnyBook(ctx, loan.BookID.String())); err != nil {
+		return loan, book, err
+	}
+
+	// Loan writes hold the book lock, so the loan can't change from here on
+	loan, err = tx.Loans.GetLoan(ctx, id)
+	return loan, book, err
+}
+
+// UpdateInventory godoc
+// @Summary Set the number of copies of a book
+// @Description Set how many copies of a book the library owns. Copies on loan or held for a reservation can't be removed; added copies go to the reservation queue first.
+// @Tags loans
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Book ID"
+// @Param input body models.UpdateInventory true "Inventory"
+// @Success 200 {object} models.Book "Successfully updated inventory"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Book not found"
+// @Failure 409 {string} string "Copies are on loan"
+// @Router /books/{id}/inventory [put]
+func (r *bookRepository) UpdateInventory(c *gin.Context) {
+	var input models.UpdateInventory
+	var book models.Book
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.NewErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()).Send(c)
+		return
+	}
+
+	err := r.Stores.WithTx(c.Request.Context(), func(ctx context.Context, tx store.Stores) error {
+		var err error
+		if book, err = lockedBook(tx.Books.LockBook(ctx, c.Param("id"))); err != nil {
+			return err
+		}
+
+		if out := book.Copies - book.AvailableCopies; *input.Copies < out {
+			return &statusError{http.StatusConflict, "Copies are on loan", fmt.Errorf("%d copies are on loan or held for a reservation", out)}
+		}
+		book.AvailableCopies += *input.Copies - book.Copies
+		book.Copies = *input.Copies
+
+		if err := r.settleReservations(ctx, tx, &book, time.Now()); err != nil {
+			return err
+		}
+		return r.Books.SaveAvailability(ctx, &book)
+	})
+	if err != nil {
+		sendLoanError(c, err)
+		return
+	}
+
+	c.Header("ETag", bookETag(book))
+	response.NewSuccessResponse("Inventory updated successfully", book).Send(c)
+}
+
+// CheckoutBook godoc
+// @Summary Borrow a book
+// @Description Check out a copy of a book for the loan period. A copy held for the caller's reservation is used first; otherwise an available copy is taken.
+// @Tags loans
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Produce  json
+// @Param id path string true "Book ID"
+// @Success 201 {object} models.Loan "Successfully checked out book"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Book not found"
+// @Failure 409 {string} string "No copies available or book already borrowed"
+// @Router /books/{id}/checkout [post]
+func (r *bookRepository) CheckoutBook(c *gin.Context) {
+	var loan models.Loan
+	now := time.Now()
+
+	// The book lock makes concurrent checkouts take turns, so two users never
+	// get the last copy
+	err := r.Stores.WithTx(c.Request.Context(), func(ctx context.Context, tx store.Stores) error {
+		book, err := lockedBook(tx.Books.LockBook(ctx, c.Param("id")))
+		if err != nil {
+			return err
+		}
+		user, err := findUser(ctx, tx, c.GetString("username"))
+		if err != nil {
+			return err
+		}
+
+		available := book.AvailableCopies
+		if err := r.settleReservations(ctx, tx, &book, now); err != nil {
+			return err
+		}
+
+		held, err := tx.Loans.FulfillReservation(ctx, book.ID, user.ID, now)
+		if err != nil {
+			return err
+		}
+		if !held {
+			if book.AvailableCopies <= 0 {
+				return &statusError{http.StatusConflict, "No copies available", errors.New("every copy is on loan or held, reserve the book to join the queue")}
+			}
+			book.AvailableCopies--
+		}
+
+		loan = models.Loan{
+			BookID:       &book.ID,
+			UserID:       user.ID,
+			Username:     user.Username,
+			CheckedOutAt: now,
+			DueAt:        now.Add(r.LoanPeriod),
+		}
+		if err := tx.Loans.CreateLoan(ctx, &loan); err != nil {
+			if errors.Is(err, store.ErrConflict) {
+				return &statusError{http.StatusConflict, "Book already borrowed", errors.New("you already have a copy of this book, return it first")}
+			}
+			return err
+		}
+
+		if book.AvailableCopies == available {
+			return nil
+		}
+		return r.Books.SaveAvailability(ctx, &book)
+	})
+	if err != nil {
+		sendLoanError(c, err)
+		return
+	}
+
+	response.Response{
+		StatusCode: http.StatusCreated,
+		Success:    true,
+		Message:    "Book checked out successfully",
+		Data:       loan,
+	}.Send(c)
+}
+
+// ReturnLoan godoc
+// @Summary Return a borrowed book
+// @Description Return the copy of a loan. The copy goes to the next reservation in the queue, if any. Admins can return any loan.
+// @Tags loans
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Produce  json
+// @Param id path string true "Loan ID"
+// @Success 200 {object} models.Loan "Successfully returned book"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Loan not found"
+// @Failure 409 {string} string "Loan already returned"
+// @Router /loans/{id}/return [post]
+func (r *bookRepository) ReturnLoan(c *gin.Context) {
+	var loan models.Loan
+	now := time.Now()
+
+	err := r.Stores.WithTx(c.Request.Context(), func(ctx context.Context, tx store.Stores) error {
+		var book models.Book
+		var err error
+		if loan, book, err = lockLoan(ctx, tx, c); err != nil {
+			return err
+		}
+		if !loan.Active() {
+			return &statusError{http.StatusConflict, "Loan already returned", errors.New("this book was returned on " + loan.ReturnedAt.Format(time.RFC3339))}
+		}
+
+		loan.ReturnedAt = &now
+		if err := tx.Loans.SaveLoan(ctx, loan); err != nil {
+			return err
+		}
+
+		book.AvailableCopies++
+		if err := r.settleReservations(ctx, tx, &book, now); err != nil {
+			return err
+		}
+		return r.Books.SaveAvailability(ctx, &book)
+	})
+	if err != nil {
+		sendLoanError(c, err)
+		return
+	}
+
+	response.NewSuccessResponse("Book returned successfully", loan).Send(c)
+}
+
+// RenewLoan godoc
+// @Summary Renew a loan
+// @Description Extend a loan by the loan period from now. Overdue loans, loans renewed too often and books other users are waiting for can't be renewed.
+// @Tags loans
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Produce  json
+// @Param id path string true "Loan ID"
+// @Success 200 {object} models.Loan "Successfully renewed loan"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Loan not found"
+// @Failure 409 {string} string "Loan can't be renewed"
+// @Router /loans/{id}/renew [post]
+func (r *bookRepository) RenewLoan(c *gin.Context) {
+	var loan models.Loan
+	now := time.Now()
+
+	err := r.Stores.WithTx(c.Request.Context(), func(ctx context.Context, tx store.Stores) error {
+		var book models.Book
+		var err error
+		if loan, book, err = lockLoan(ctx, tx, c); err != nil {
+			return err
+		}
+
+		switch {
+		case !loan.Active():
+			return &statusError{http.StatusConflict, "Loan already returned", errors.New("only active loans can be renewed")}
+		case loan.Overdue(now):
+			return &statusError{http.StatusConflict, "Loan is overdue", errors.New("overdue books must be returned")}
+		case loan.Renewals >= r.LoanMaxRenewals:
+			return &statusError{http.StatusConflict, "Renewal limit reached", fmt.Errorf("a loan can be renewed %d times", r.LoanMaxRenewals)}
+		}
+
+		waiting, err := tx.Loans.CountWaiting(ctx, book.ID)
+		if err != nil {
+			return err
+		}
+		if waiting > 0 {
+			return &statusError{http.StatusConflict, "Book is reserved", errors.New("other users are waiting for this book")}
+		}
+
+		if due := now.Add(r.LoanPeriod); due.After(loan.DueAt) {
+			loan.DueAt = due
+		}
+		loan.Renewals++
+		return tx.Loans.SaveLoan(ctx, loan)
+	})
+	if err != nil {
+		sendLoanError(c, err)
+		return
+	}
+
+	response.NewSuccessResponse("Loan renewed successfully", loan).Send(c)
+}
+
+// sendLoans answers with a page of the loans matching query, filtered by the
+// status parameter, with their books
+func (r *bookRepository) sendLoans(c *gin.Context, query string, args ...interface{}) {
+	var loans []models.Loan
+
+	params := pagination.ParseParams(c)
+	if params.Sort == "" {
+		params.Sort = "checked_out_at"
+	}
+	if !loanSortColumns[params.Sort] {
+		response.NewErrorResponse(http.StatusBadRequest, "Invalid sort column", "sort must be checked_out_at, due_at or returned_at").Send(c)
+		return
+	}
+
+	if status := c.Query("status"); status != "" {
+		condition, values, ok := loanStatusQuery(status, time.Now())
+		if !ok {
+			response.NewErrorResponse(http.StatusBadRequest, "Invalid status", "status must be active, overdue or returned").Send(c)
+			return
+		}
+		query += " AND " + condition
+		args = append(args, values...)
+	}
+
+	_, meta, err := params.ApplyWithQuery(r.db(c).Model(&models.Loan{}), &loans, query, args...)
+	if err != nil {
+		response.NewErrorResponse(http.StatusInternalServerError, "Failed to retrieve loans", err.Error()).Send(c)
+		return
+	}
+
+	// Loans outlive the trashing and the purging of their book
+	if len(loans) > 0 {
+		var books []models.Book
+		ids := make([]uuid.UUID, 0, len(loans))
+		for _, loan := range loans {
+			if loan.BookID != nil {
+				ids = append(ids, *loan.BookID)
+			}
+		}
+		if err := r.db(c).Model(&models.Book{}).Unscoped().Where("id IN ?", ids).Find(&books).Error; err != nil {
+			response.NewErrorResponse(http.StatusInternalServerError, "Failed to retrieve loans", err.Error()).Send(c)
+			return
+		}
+		byID := make(map[uuid.UUID]*models.Book, len(books))
+		for i := range books {
+			byID[books[i].ID] = &books[i]
+		}
+		for i := range loans {
+			if loans[i].BookID != nil {
+				loans[i].Book = byID[*loans[i].BookID]
+			}
+		}
+	}
+
+	response.NewPaginatedResponse("Loans retrieved successfully", loans, meta).Send(c)
+}
+
+// FindLoans godoc
+// @Summary List your loans
+// @Description Get a paginated history of the caller's loans, newest first
+// @Tags loans
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Produce json
+// @Param status query string false "active, overdue or returned"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Param sort query string false "checked_out_at, due_at or returned_at" default(checked_out_at)
+// @Param order query string false "Sort order (asc or desc)"
+// @Success 200 {array} models.Loan "Loans of the caller"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Router /loans [get]
+func (r *bookRepository) FindLoans(c *gin.Context) {
+	r.sendLoans(c, "user_id = (SELECT id FROM users WHERE username = ?)", c.GetString("username"))
+}
+
+// FindUserLoans godoc
+// @Summary List the loans of a user
+// @Description Get a paginated history of a user's loans, newest first
+// @Tags loans
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Param status query string false "active, overdue or returned"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Param sort query string false "checked_out_at, due_at or returned_at" default(checked_out_at)
+// @Param order query string false "Sort order (asc or desc)"
+// @Success 200 {array} models.Loan "Loans of the user"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Router /users/{id}/loans [get]
+func (r *bookRepository) FindUserLoans(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.NewErrorResponse(http.StatusBadRequest, "Invalid user ID", err.Error()).Send(c)
+		return
+	}
+	r.sendLoans(c, "user_id = ?", id)
+}
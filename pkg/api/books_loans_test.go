@@ -0,0 +1,169 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/cache"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/store"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func newLoanTestRouter(repo *bookRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	withUser := func(c *gin.Context) {
+		c.Set("username", "reader")
+		c.Set("role", c.GetHeader("X-Role"))
+		c.Next()
+	}
+	r.PUT("/books/:id/inventory", withUser, repo.UpdateInventory)
+	r.POST("/books/:id/checkout", withUser, repo.CheckoutBook)
+	r.POST("/loans/:id/return", withUser, repo.ReturnLoan)
+	r.POST("/loans/:id/renew", withUser, repo.RenewLoan)
+	r.GET("/loans", withUser, repo.FindLoans)
+	r.GET("/users/:id/loans", withUser, repo.FindUserLoans)
+	r.DELETE("/reservations/:id", withUser, repo.CancelReservation)
+	return r
+}
+
+// newLendingStores runs units of work on a dry run database whose queries
+// load the given book and loan, and which records the SQL it runs
+func newLendingStores(t *testing.T, book models.Book, loan models.Loan, statements *[]string) store.UnitOfWork {
+	db := newDryRunTxDB(t)
+	db.Callback().Query().After("gorm:query").Register("test:load", func(tx *gorm.DB) {
+		switch dest := tx.Statement.Dest.(type) {
+		case *models.Book:
+			*dest = book
+		case *models.Loan:
+			*dest = loan
+		}
+	})
+	record := func(tx *gorm.DB) {
+		*statements = append(*statements, tx.Dialector.Explain(tx.Statement.SQL.String(), tx.Statement.Vars...))
+	}
+	db.Callback().Query().After("gorm:query").Register("test:record", record)
+	db.Callback().Create().After("gorm:create").Register("test:record", record)
+	db.Callback().Update().After("gorm:update").Register("test:record", record)
+	return store.NewGormUnitOfWork(db)
+}
+
+func TestCheckoutBook(t *testing.T) {
+	var statements []string
+	book := models.Book{ID: uuid.New(), Copies: 2, AvailableCopies: 1, Version: 4}
+	repo := NewBookRepository(nil, newLendingStores(t, book, models.Loan{}, &statements), cache.NewMemoryCache(100), nil, nil)
+	repo.LoanPeriod = 7 * 24 * time.Hour
+	r := newLoanTestRouter(repo)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/books/1/checkout", nil))
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var body struct {
+		Data models.Loan `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, &book.ID, body.Data.BookID)
+	assert.Nil(t, body.Data.ReturnedAt)
+	assert.WithinDuration(t, time.Now().Add(7*24*time.Hour), body.Data.DueAt, time.Minute)
+
+	// The book is locked before its copies are counted, and the last copy is
+	// taken with the loan
+	assert.True(t, strings.HasSuffix(statements[0], "FOR UPDATE"), statements[0])
+	assert.Contains(t, statements[0], "FROM `books`")
+	assert.Contains(t, strings.Join(statements, "\n"), "INSERT INTO `loans`")
+	assert.Contains(t, statements[len(statements)-1], "UPDATE `books` SET `available_copies`=0")
+}
+
+func TestCheckoutBookWithoutCopies(t *testing.T) {
+	var statements []string
+	book := models.Book{ID: uuid.New(), Copies: 2, AvailableCopies: 0}
+	r := newLoanTestRouter(NewBookRepository(nil, newLendingStores(t, book, models.Loan{}, &statements), nil, nil, nil))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/books/1/checkout", nil))
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "No copies available")
+	assert.NotContains(t, strings.Join(statements, "\n"), "INSERT INTO `loans`")
+}
+
+func TestReturnLoan(t *testing.T) {
+	var statements []string
+	book := models.Book{ID: uuid.New(), Copies: 1, AvailableCopies: 0}
+	loan := models.Loan{ID: uuid.New(), BookID: &book.ID, DueAt: time.Now().Add(time.Hour)}
+	r := newLoanTestRouter(NewBookRepository(nil, newLendingStores(t, book, loan, &statements), cache.NewMemoryCache(100), nil, nil))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/loans/"+loan.ID.String()+"/return", nil))
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"returned_at":"`)
+
+	// No reservation is waiting in a dry run, so the copy is available again
+	assert.Contains(t, statements[len(statements)-1], "UPDATE `books` SET `available_copies`=1")
+}
+
+func TestLoanWriteConflicts(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		url     string
+		loan    models.Loan
+		message string
+	}{
+		{"returned twice", "/return", models.Loan{DueAt: now.Add(time.Hour), ReturnedAt: &now}, "Loan already returned"},
+		{"renew returned", "/renew", models.Loan{DueAt: now.Add(time.Hour), ReturnedAt: &now}, "Loan already returned"},
+		{"renew overdue", "/renew", models.Loan{DueAt: now.Add(-time.Hour)}, "Loan is overdue"},
+		{"renew too often", "/renew", models.Loan{DueAt: now.Add(time.Hour), Renewals: 2}, "Renewal limit reached"},
+	}
+
+	for _, tt := range tests {
+		var statements []string
+		repo := NewBookRepository(nil, newLendingStores(t, models.Book{ID: uuid.New()}, tt.loan, &statements), nil, nil, nil)
+		repo.LoanMaxRenewals = 2
+		r := newLoanTestRouter(repo)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/loans/"+uuid.NewString()+tt.url, nil))
+		assert.Equal(t, http.StatusConflict, w.Code, tt.name)
+		assert.Contains(t, w.Body.String(), tt.message, tt.name)
+	}
+}
+
+func TestLendingInvalidRequests(t *testing.T) {
+	// Malformed IDs are answered inside the transaction before any query
+	var statements []string
+	r := newLoanTestRouter(NewBookRepository(nil, newLendingStores(t, models.Book{}, models.Loan{}, &statements), nil, nil, nil))
+
+	tests := []struct {
+		method string
+		url    string
+		body   string
+		status int
+	}{
+		{http.MethodPut, "/books/1/inventory", `{}`, http.StatusBadRequest},
+		{http.MethodPut, "/books/1/inventory", `{"copies":-1}`, http.StatusBadRequest},
+		{http.MethodPut, "/books/1/inventory", `{"copies":"2"}`, http.StatusBadRequest},
+		{http.MethodPost, "/loans/not-a-uuid/return", ``, http.StatusNotFound},
+		{http.MethodPost, "/loans/not-a-uuid/renew", ``, http.StatusNotFound},
+		{http.MethodDelete, "/reservations/not-a-uuid", ``, http.StatusNotFound},
+		{http.MethodGet, "/loans?status=lost", ``, http.StatusBadRequest},
+		{http.MethodGet, "/loans?sort=title", ``, http.StatusBadRequest},
+		{http.MethodGet, "/users/not-a-uuid/loans", ``, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(tt.method, tt.url, bytes.NewBufferString(tt.body)))
+		assert.Equal(t, tt.status, w.Code, tt.url+" "+tt.body)
+	}
+	assert.Empty(t, statements)
+}
@@ -34,6 +34,42 @@ func (m *MockBookRepository) EXPECT() *MockBookRepositoryMockRecorder {
 	return m.recorder
 }
 
+// ApproveReview mocks base method.
+func (m *MockBookRepository) ApproveReview(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ApproveReview", c)
+}
+
+// ApproveReview indicates an expected call of ApproveReview.
+func (mr *MockBookRepositoryMockRecorder) ApproveReview(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApproveReview", reflect.TypeOf((*MockBookRepository)(nil).ApproveReview), c)
+}
+
+// CancelReservation mocks base method.
+func (m *MockBookRepository) CancelReservation(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "CancelReservation", c)
+}
+
+// CancelReservation indicates an expected call of CancelReservation.
+func (mr *MockBookRepositoryMockRecorder) CancelReservation(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelReservation", reflect.TypeOf((*MockBookRepository)(nil).CancelReservation), c)
+}
+
+// CheckoutBook mocks base method.
+func (m *MockBookRepository) CheckoutBook(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "CheckoutBook", c)
+}
+
+// CheckoutBook indicates an expected call of CheckoutBook.
+func (mr *MockBookRepositoryMockRecorder) CheckoutBook(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckoutBook", reflect.TypeOf((*MockBookRepository)(nil).CheckoutBook), c)
+}
+
 // CreateBook mocks base method.
 func (m *MockBookRepository) CreateBook(c *gin.Context) {
 	m.ctrl.T.Helper()
@@ -46,6 +82,54 @@ func (mr *MockBookRepositoryMockRecorder) CreateBook(c interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBook", reflect.TypeOf((*MockBookRepository)(nil).CreateBook), c)
 }
 
+// CreateBookFileLink mocks base method.
+func (m *MockBookRepository) CreateBookFileLink(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "CreateBookFileLink", c)
+}
+
+// CreateBookFileLink indicates an expected call of CreateBookFileLink.
+func (mr *MockBookRepositoryMockRecorder) CreateBookFileLink(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBookFileLink", reflect.TypeOf((*MockBookRepository)(nil).CreateBookFileLink), c)
+}
+
+// CreateBooks mocks base method.
+func (m *MockBookRepository) CreateBooks(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "CreateBooks", c)
+}
+
+// CreateBooks indicates an expected call of CreateBooks.
+func (mr *MockBookRepositoryMockRecorder) CreateBooks(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBooks", reflect.TypeOf((*MockBookRepository)(nil).CreateBooks), c)
+}
+
+// CreateReservation mocks base method.
+func (m *MockBookRepository) CreateReservation(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "CreateReservation", c)
+}
+
+// CreateReservation indicates an expected call of CreateReservation.
+func (mr *MockBookRepositoryMockRecorder) CreateReservation(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateReservation", reflect.TypeOf((*MockBookRepository)(nil).CreateReservation), c)
+}
+
+// CreateReview mocks base method.
+func (m *MockBookRepository) CreateReview(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "CreateReview", c)
+}
+
+// CreateReview indicates an expected call of CreateReview.
+func (mr *MockBookRepositoryMockRecorder) CreateReview(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateReview", reflect.TypeOf((*MockBookRepository)(nil).CreateReview), c)
+}
+
 // DeleteBook mocks base method.
 func (m *MockBookRepository) DeleteBook(c *gin.Context) {
 	m.ctrl.T.Helper()
@@ -58,6 +142,78 @@ func (mr *MockBookRepositoryMockRecorder) DeleteBook(c interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBook", reflect.TypeOf((*MockBookRepository)(nil).DeleteBook), c)
 }
 
+// DeleteBookFile mocks base method.
+func (m *MockBookRepository) DeleteBookFile(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "DeleteBookFile", c)
+}
+
+// DeleteBookFile indicates an expected call of DeleteBookFile.
+func (mr *MockBookRepositoryMockRecorder) DeleteBookFile(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBookFile", reflect.TypeOf((*MockBookRepository)(nil).DeleteBookFile), c)
+}
+
+// DeleteBooks mocks base method.
+func (m *MockBookRepository) DeleteBooks(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "DeleteBooks", c)
+}
+
+// DeleteBooks indicates an expected call of DeleteBooks.
+func (mr *MockBookRepositoryMockRecorder) DeleteBooks(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBooks", reflect.TypeOf((*MockBookRepository)(nil).DeleteBooks), c)
+}
+
+// DeleteCover mocks base method.
+func (m *MockBookRepository) DeleteCover(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "DeleteCover", c)
+}
+
+// DeleteCover indicates an expected call of DeleteCover.
+func (mr *MockBookRepositoryMockRecorder) DeleteCover(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteCover", reflect.TypeOf((*MockBookRepository)(nil).DeleteCover), c)
+}
+
+// DeleteReview mocks base method.
+func (m *MockBookRepository) DeleteReview(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "DeleteReview", c)
+}
+
+// DeleteReview indicates an expected call of DeleteReview.
+func (mr *MockBookRepositoryMockRecorder) DeleteReview(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteReview", reflect.TypeOf((*MockBookRepository)(nil).DeleteReview), c)
+}
+
+// DownloadBookFile mocks base method.
+func (m *MockBookRepository) DownloadBookFile(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "DownloadBookFile", c)
+}
+
+// DownloadBookFile indicates an expected call of DownloadBookFile.
+func (mr *MockBookRepositoryMockRecorder) DownloadBookFile(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DownloadBookFile", reflect.TypeOf((*MockBookRepository)(nil).DownloadBookFile), c)
+}
+
+// ExportBooks mocks base method.
+func (m *MockBookRepository) ExportBooks(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ExportBooks", c)
+}
+
+// ExportBooks indicates an expected call of ExportBooks.
+func (mr *MockBookRepositoryMockRecorder) ExportBooks(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportBooks", reflect.TypeOf((*MockBookRepository)(nil).ExportBooks), c)
+}
+
 // FindBook mocks base method.
 func (m *MockBookRepository) FindBook(c *gin.Context) {
 	m.ctrl.T.Helper()
@@ -70,6 +226,42 @@ func (mr *MockBookRepositoryMockRecorder) FindBook(c interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindBook", reflect.TypeOf((*MockBookRepository)(nil).FindBook), c)
 }
 
+// FindBookAuthors mocks base method.
+func (m *MockBookRepository) FindBookAuthors(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "FindBookAuthors", c)
+}
+
+// FindBookAuthors indicates an expected call of FindBookAuthors.
+func (mr *MockBookRepositoryMockRecorder) FindBookAuthors(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindBookAuthors", reflect.TypeOf((*MockBookRepository)(nil).FindBookAuthors), c)
+}
+
+// FindBookByISBN mocks base method.
+func (m *MockBookRepository) FindBookByISBN(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "FindBookByISBN", c)
+}
+
+// FindBookByISBN indicates an expected call of FindBookByISBN.
+func (mr *MockBookRepositoryMockRecorder) FindBookByISBN(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindBookByISBN", reflect.TypeOf((*MockBookRepository)(nil).FindBookByISBN), c)
+}
+
+// FindBookFiles mocks base method.
+func (m *MockBookRepository) FindBookFiles(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "FindBookFiles", c)
+}
+
+// FindBookFiles indicates an expected call of FindBookFiles.
+func (mr *MockBookRepositoryMockRecorder) FindBookFiles(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindBookFiles", reflect.TypeOf((*MockBookRepository)(nil).FindBookFiles), c)
+}
+
 // FindBooks mocks base method.
 func (m *MockBookRepository) FindBooks(c *gin.Context) {
 	m.ctrl.T.Helper()
@@ -82,6 +274,102 @@ func (mr *MockBookRepositoryMockRecorder) FindBooks(c interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindBooks", reflect.TypeOf((*MockBookRepository)(nil).FindBooks), c)
 }
 
+// FindCacheStats mocks base method.
+func (m *MockBookRepository) FindCacheStats(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "FindCacheStats", c)
+}
+
+// FindCacheStats indicates an expected call of FindCacheStats.
+func (mr *MockBookRepositoryMockRecorder) FindCacheStats(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindCacheStats", reflect.TypeOf((*MockBookRepository)(nil).FindCacheStats), c)
+}
+
+// FindImportJob mocks base method.
+func (m *MockBookRepository) FindImportJob(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "FindImportJob", c)
+}
+
+// FindImportJob indicates an expected call of FindImportJob.
+func (mr *MockBookRepositoryMockRecorder) FindImportJob(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindImportJob", reflect.TypeOf((*MockBookRepository)(nil).FindImportJob), c)
+}
+
+// FindLoans mocks base method.
+func (m *MockBookRepository) FindLoans(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "FindLoans", c)
+}
+
+// FindLoans indicates an expected call of FindLoans.
+func (mr *MockBookRepositoryMockRecorder) FindLoans(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindLoans", reflect.TypeOf((*MockBookRepository)(nil).FindLoans), c)
+}
+
+// FindModerationQueue mocks base method.
+func (m *MockBookRepository) FindModerationQueue(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "FindModerationQueue", c)
+}
+
+// FindModerationQueue indicates an expected call of FindModerationQueue.
+func (mr *MockBookRepositoryMockRecorder) FindModerationQueue(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindModerationQueue", reflect.TypeOf((*MockBookRepository)(nil).FindModerationQueue), c)
+}
+
+// FindReservations mocks base method.
+func (m *MockBookRepository) FindReservations(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "FindReservations", c)
+}
+
+// FindReservations indicates an expected call of FindReservations.
+func (mr *MockBookRepositoryMockRecorder) FindReservations(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindReservations", reflect.TypeOf((*MockBookRepository)(nil).FindReservations), c)
+}
+
+// FindReviews mocks base method.
+func (m *MockBookRepository) FindReviews(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "FindReviews", c)
+}
+
+// FindReviews indicates an expected call of FindReviews.
+func (mr *MockBookRepositoryMockRecorder) FindReviews(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindReviews", reflect.TypeOf((*MockBookRepository)(nil).FindReviews), c)
+}
+
+// FindUserLoans mocks base method.
+func (m *MockBookRepository) FindUserLoans(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "FindUserLoans", c)
+}
+
+// FindUserLoans indicates an expected call of FindUserLoans.
+func (mr *MockBookRepositoryMockRecorder) FindUserLoans(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUserLoans", reflect.TypeOf((*MockBookRepository)(nil).FindUserLoans), c)
+}
+
+// FlagReview mocks base method.
+func (m *MockBookRepository) FlagReview(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "FlagReview", c)
+}
+
+// FlagReview indicates an expected call of FlagReview.
+func (mr *MockBookRepositoryMockRecorder) FlagReview(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlagReview", reflect.TypeOf((*MockBookRepository)(nil).FlagReview), c)
+}
+
 // Healthcheck mocks base method.
 func (m *MockBookRepository) Healthcheck(c *gin.Context) {
 	m.ctrl.T.Helper()
@@ -94,6 +382,150 @@ func (mr *MockBookRepositoryMockRecorder) Healthcheck(c interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Healthcheck", reflect.TypeOf((*MockBookRepository)(nil).Healthcheck), c)
 }
 
+// ImportBooks mocks base method.
+func (m *MockBookRepository) ImportBooks(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ImportBooks", c)
+}
+
+// ImportBooks indicates an expected call of ImportBooks.
+func (mr *MockBookRepositoryMockRecorder) ImportBooks(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportBooks", reflect.TypeOf((*MockBookRepository)(nil).ImportBooks), c)
+}
+
+// PatchBook mocks base method.
+func (m *MockBookRepository) PatchBook(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "PatchBook", c)
+}
+
+// PatchBook indicates an expected call of PatchBook.
+func (mr *MockBookRepositoryMockRecorder) PatchBook(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PatchBook", reflect.TypeOf((*MockBookRepository)(nil).PatchBook), c)
+}
+
+// PurgeBook mocks base method.
+func (m *MockBookRepository) PurgeBook(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "PurgeBook", c)
+}
+
+// PurgeBook indicates an expected call of PurgeBook.
+func (mr *MockBookRepositoryMockRecorder) PurgeBook(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeBook", reflect.TypeOf((*MockBookRepository)(nil).PurgeBook), c)
+}
+
+// Readiness mocks base method.
+func (m *MockBookRepository) Readiness(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Readiness", c)
+}
+
+// Readiness indicates an expected call of Readiness.
+func (mr *MockBookRepositoryMockRecorder) Readiness(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Readiness", reflect.TypeOf((*MockBookRepository)(nil).Readiness), c)
+}
+
+// RejectReview mocks base method.
+func (m *MockBookRepository) RejectReview(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RejectReview", c)
+}
+
+// RejectReview indicates an expected call of RejectReview.
+func (mr *MockBookRepositoryMockRecorder) RejectReview(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RejectReview", reflect.TypeOf((*MockBookRepository)(nil).RejectReview), c)
+}
+
+// RenewLoan mocks base method.
+func (m *MockBookRepository) RenewLoan(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RenewLoan", c)
+}
+
+// RenewLoan indicates an expected call of RenewLoan.
+func (mr *MockBookRepositoryMockRecorder) RenewLoan(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenewLoan", reflect.TypeOf((*MockBookRepository)(nil).RenewLoan), c)
+}
+
+// RestoreBook mocks base method.
+func (m *MockBookRepository) RestoreBook(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RestoreBook", c)
+}
+
+// RestoreBook indicates an expected call of RestoreBook.
+func (mr *MockBookRepositoryMockRecorder) RestoreBook(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreBook", reflect.TypeOf((*MockBookRepository)(nil).RestoreBook), c)
+}
+
+// ReturnLoan mocks base method.
+func (m *MockBookRepository) ReturnLoan(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ReturnLoan", c)
+}
+
+// ReturnLoan indicates an expected call of ReturnLoan.
+func (mr *MockBookRepositoryMockRecorder) ReturnLoan(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReturnLoan", reflect.TypeOf((*MockBookRepository)(nil).ReturnLoan), c)
+}
+
+// SearchBooks mocks base method.
+func (m *MockBookRepository) SearchBooks(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SearchBooks", c)
+}
+
+// SearchBooks indicates an expected call of SearchBooks.
+func (mr *MockBookRepositoryMockRecorder) SearchBooks(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchBooks", reflect.TypeOf((*MockBookRepository)(nil).SearchBooks), c)
+}
+
+// ServeBlob mocks base method.
+func (m *MockBookRepository) ServeBlob(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ServeBlob", c)
+}
+
+// ServeBlob indicates an expected call of ServeBlob.
+func (mr *MockBookRepositoryMockRecorder) ServeBlob(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ServeBlob", reflect.TypeOf((*MockBookRepository)(nil).ServeBlob), c)
+}
+
+// SetBookAuthors mocks base method.
+func (m *MockBookRepository) SetBookAuthors(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetBookAuthors", c)
+}
+
+// SetBookAuthors indicates an expected call of SetBookAuthors.
+func (mr *MockBookRepositoryMockRecorder) SetBookAuthors(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetBookAuthors", reflect.TypeOf((*MockBookRepository)(nil).SetBookAuthors), c)
+}
+
+// TrashBooks mocks base method.
+func (m *MockBookRepository) TrashBooks(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "TrashBooks", c)
+}
+
+// TrashBooks indicates an expected call of TrashBooks.
+func (mr *MockBookRepositoryMockRecorder) TrashBooks(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TrashBooks", reflect.TypeOf((*MockBookRepository)(nil).TrashBooks), c)
+}
+
 // UpdateBook mocks base method.
 func (m *MockBookRepository) UpdateBook(c *gin.Context) {
 	m.ctrl.T.Helper()
@@ -105,3 +537,63 @@ func (mr *MockBookRepositoryMockRecorder) UpdateBook(c interface{}) *gomock.Call
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateBook", reflect.TypeOf((*MockBookRepository)(nil).UpdateBook), c)
 }
+
+// UpdateBooks mocks base method.
+func (m *MockBookRepository) UpdateBooks(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UpdateBooks", c)
+}
+
+// UpdateBooks indicates an expected call of UpdateBooks.
+func (mr *MockBookRepositoryMockRecorder) UpdateBooks(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateBooks", reflect.TypeOf((*MockBookRepository)(nil).UpdateBooks), c)
+}
+
+// UpdateInventory mocks base method.
+func (m *MockBookRepository) UpdateInventory(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UpdateInventory", c)
+}
+
+// UpdateInventory indicates an expected call of UpdateInventory.
+func (mr *MockBookRepositoryMockRecorder) UpdateInventory(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateInventory", reflect.TypeOf((*MockBookRepository)(nil).UpdateInventory), c)
+}
+
+// UpdateReview mocks base method.
+func (m *MockBookRepository) UpdateReview(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UpdateReview", c)
+}
+
+// UpdateReview indicates an expected call of UpdateReview.
+func (mr *MockBookRepositoryMockRecorder) UpdateReview(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateReview", reflect.TypeOf((*MockBookRepository)(nil).UpdateReview), c)
+}
+
+// UploadBookFile mocks base method.
+func (m *MockBookRepository) UploadBookFile(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UploadBookFile", c)
+}
+
+// UploadBookFile indicates an expected call of UploadBookFile.
+func (mr *MockBookRepositoryMockRecorder) UploadBookFile(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UploadBookFile", reflect.TypeOf((*MockBookRepository)(nil).UploadBookFile), c)
+}
+
+// UploadCover mocks base method.
+func (m *MockBookRepository) UploadCover(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UploadCover", c)
+}
+
+// UploadCover indicates an expected call of UploadCover.
+func (mr *MockBookRepositoryMockRecorder) UploadCover(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UploadCover", reflect.TypeOf((*MockBookRepository)(nil).UploadCover), c)
+}
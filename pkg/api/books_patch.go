@@ -0,0 +1,174 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/response"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/store"
+)
+
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+)
+
+// bookReadOnlyFields can be referenced by a patch (for example in a test
+// operation) but never changed by it
+var bookReadOnlyFields = []string{"uuid", "version", "rating_average", "review_count", "copies", "available_copies", "cover", "created_at", "updated_at"}
+
+// statusError carries the response a failed book write should be reported with
+type statusError struct {
+	status  int
+	message string
+	err     error
+}
+
+func (e *statusError) Error() string {
+	return e.err.Error()
+}
+
+// applyBookPatch applies a JSON Merge Patch (RFC 7396) or JSON Patch
+// (RFC 6902) document to book and returns the validated result. The patch is
+// applied to a copy, so if any operation fails nothing is changed.
+func applyBookPatch(book models.Book, contentType string, patch []byte) (models.UpdateBook, error) {
+	var input models.UpdateBook
+
+	original, err := json.Marshal(book)
+	if err != nil {
+		return input, err
+	}
+
+	var patched []byte
+	switch contentType {
+	case MergePatchContentType:
+		patched, err = jsonpatch.MergePatch(original, patch)
+	case JSONPatchContentType:
+		var operations jsonpatch.Patch
+		operations, err = jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return input, &statusError{http.StatusBadRequest, "Invalid JSON Patch document", err}
+		}
+		patched, err = operations.Apply(original)
+	default:
+		return input, &statusError{http.StatusUnsupportedMediaType, "Unsupported patch format", errors.New("use " + MergePatchContentType + " or " + JSONPatchContentType)}
+	}
+
+	if errors.Is(err, jsonpatch.ErrTestFailed) {
+		return input, &statusError{http.StatusConflict, "Patch test operation failed", err}
+	}
+	if err != nil {
+		return input, &statusError{http.StatusBadRequest, "Could not apply patch", err}
+	}
+
+	var before, after map[string]interface{}
+	if err := json.Unmarshal(original, &before); err != nil {
+		return input, err
+	}
+	if err := json.Unmarshal(patched, &after); err != nil {
+		return input, &statusError{http.StatusBadRequest, "Could not apply patch", err}
+	}
+	for _, field := range bookReadOnlyFields {
+		if !reflect.DeepEqual(before[field], after[field]) {
+			return input, &statusError{http.StatusUnprocessableEntity, "Read-only field cannot be patched", errors.New(field + " is read-only")}
+		}
+	}
+
+	if err := json.Unmarshal(patched, &input); err != nil {
+		return input, &statusError{http.StatusUnprocessableEntity, "Patched book is invalid", err}
+	}
+	if err := binding.Validator.ValidateStruct(input); err != nil {
+		return input, &statusError{http.StatusUnprocessableEntity, "Patched book is invalid", err}
+	}
+
+	return input, nil
+}
+
+// PatchBook godoc
+// @Summary Partially update a book by ID
+// @Description Apply a JSON Merge Patch (application/merge-patch+json) or JSON Patch (application/json-patch+json) to a book. All operations, including test operations, are applied atomically.
+// @Tags books
+// @Security ApiKeyAuth
+// @Accept  application/merge-patch+json
+// @Accept  application/json-patch+json
+// @Produce  json
+// @Param id path string true "Book ID"
+// @Param If-Match header string false "ETag of the version being patched"
+// @Param patch body object true "Merge patch object or JSON Patch operations"
+// @Success 200 {object} models.Book "Successfully updated book"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Book not found"
+// @Failure 409 {string} string "Test operation failed, or a book with this ISBN already exists"
+// @Failure 412 {string} string "If-Match does not match the current version"
+// @Failure 415 {string} string "Unsupported patch format"
+// @Failure 422 {string} string "Patched book is invalid"
+// @Failure 428 {string} string "If-Match header is required"
+// @Router /books/{id} [patch]
+func (r *bookRepository) PatchBook(c *gin.Context) {
+	contentType := c.ContentType()
+	if contentType != MergePatchContentType && contentType != JSONPatchContentType {
+		response.NewErrorResponse(http.StatusUnsupportedMediaType, "Unsupported patch format", "use "+MergePatchContentType+" or "+JSONPatchContentType).Send(c)
+		return
+	}
+
+	if r.IfMatchMode == IfMatchRequired && c.GetHeader("If-Match") == "" {
+		response.NewErrorResponse(http.StatusPreconditionRequired, "Precondition required", "If-Match header is required").Send(c)
+		return
+	}
+
+	patch, err := c.GetRawData()
+	if err != nil {
+		response.NewErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()).Send(c)
+		return
+	}
+
+	var book models.Book
+	err = r.Stores.WithTx(c.Request.Context(), func(ctx context.Context, tx store.Stores) error {
+		// Lock the row so the patch is applied to the version we read
+		var err error
+		if book, err = tx.Books.LockBook(ctx, c.Param("id")); err != nil {
+			return err
+		}
+
+		if header := c.GetHeader("If-Match"); header != "" && !etagMatches(header, bookETag(book)) {
+			return &statusError{http.StatusPreconditionFailed, "Precondition failed", errors.New("If-Match does not match the current version " + bookETag(book))}
+		}
+
+		input, err := applyBookPatch(book, contentType, patch)
+		if err != nil {
+			return err
+		}
+
+		input.ApplyTo(&book)
+		return r.Books.SaveBook(ctx, &book)
+	})
+
+	var serr *statusError
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		response.NewErrorResponse(http.StatusNotFound, "Book not found", err.Error()).Send(c)
+		return
+	case errors.Is(err, store.ErrStale):
+		sendVersionConflict(c)
+		return
+	case errors.Is(err, store.ErrConflict):
+		sendDuplicateISBN(c, book.ISBN)
+		return
+	case errors.As(err, &serr):
+		response.NewErrorResponse(serr.status, serr.message, serr.Error()).Send(c)
+		return
+	case err != nil:
+		response.NewErrorResponse(http.StatusInternalServerError, "Failed to update book", err.Error()).Send(c)
+		return
+	}
+
+	c.Header("ETag", bookETag(book))
+	response.NewSuccessResponse("Book updated successfully", book).Send(c)
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func patchTestBook() models.Book {
+	return models.Book{
+		ID:     uuid.MustParse("123e4567-e89b-12d3-a456-426614174000"),
+		Title:  "Effective Go",
+		Author: "Robert Griesemer",
+	}
+}
+
+func errorStatus(err error) int {
+	var serr *statusError
+	if errors.As(err, &serr) {
+		return serr.status
+	}
+	return 0
+}
+
+func TestApplyBookMergePatch(t *testing.T) {
+	input, err := applyBookPatch(patchTestBook(), MergePatchContentType, []byte(`{"title":"Effective Go, 2nd Edition"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "Effective Go, 2nd Edition", input.Title)
+	assert.Equal(t, "Robert Griesemer", input.Author)
+
+	// Clearing a required field must fail validation instead of being skipped
+	_, err = applyBookPatch(patchTestBook(), MergePatchContentType, []byte(`{"author":null}`))
+	assert.Equal(t, http.StatusUnprocessableEntity, errorStatus(err))
+
+	_, err = applyBookPatch(patchTestBook(), MergePatchContentType, []byte(`{"uuid":"00000000-0000-0000-0000-000000000000"}`))
+	assert.Equal(t, http.StatusUnprocessableEntity, errorStatus(err))
+}
+
+func TestApplyBookJSONPatch(t *testing.T) {
+	patch := []byte(`[
+		{"op":"test","path":"/title","value":"Effective Go"},
+		{"op":"replace","path":"/title","value":"Go Proverbs"},
+		{"op":"replace","path":"/author","value":"Rob Pike"}
+	]`)
+	input, err := applyBookPatch(patchTestBook(), JSONPatchContentType, patch)
+	assert.NoError(t, err)
+	assert.Equal(t, models.UpdateBook{Title: "Go Proverbs", Author: "Rob Pike"}, input)
+
+	failedTest := []byte(`[
+		{"op":"replace","path":"/author","value":"Rob Pike"},
+		{"op":"test","path":"/title","value":"Something Else"}
+	]`)
+	_, err = applyBookPatch(patchTestBook(), JSONPatchContentType, failedTest)
+	assert.Equal(t, http.StatusConflict, errorStatus(err))
+
+	_, err = applyBookPatch(patchTestBook(), JSONPatchContentType, []byte(`{"op":"replace"}`))
+	assert.Equal(t, http.StatusBadRequest, errorStatus(err))
+
+	_, err = applyBookPatch(patchTestBook(), JSONPatchContentType, []byte(`[{"op":"remove","path":"/missing"}]`))
+	assert.Equal(t, http.StatusBadRequest, errorStatus(err))
+}
+
+func TestPatchBookUnsupportedMediaType(t *testing.T) {
+	repo := NewBookRepository(nil, nil, nil, nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.PATCH("/book/:id", repo.PatchBook)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPatch, "/book/1", bytes.NewBufferString(`{"title":"x"}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+}
+
+func TestUpdateBookRequiresFullRepresentation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBooks := store.NewMockBookStore(ctrl)
+	repo := NewBookRepository(nil, newMockUnitOfWork(ctrl, store.Stores{Books: mockBooks}), nil, nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.PUT("/book/:id", repo.UpdateBook)
+
+	mockBooks.EXPECT().GetBook(gomock.Any(), "1").Return(patchTestBook(), nil).Times(1)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/book/1", bytes.NewBufferString(`{"title":"Only a title"}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,248 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/pagination"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/response"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/store"
+)
+
+// reviewSortColumns are the columns review lists can be sorted by
+var reviewSortColumns = map[string]bool{"created_at": true, "updated_at": true, "rating": true}
+
+// lockedBook reports a book that couldn't be locked because it doesn't
+// exist, see store.BookStore.LockBook, as a 404
+func lockedBook(book models.Book, err error) (models.Book, error) {
+	if errors.Is(err, store.ErrNotFound) {
+		return book, &statusError{http.StatusNotFound, "Book not found", err}
+	}
+	return book, err
+}
+
+// findUser loads the signed-in user
+func findUser(ctx context.Context, tx store.Stores, username string) (models.User, error) {
+	user, err := tx.Users.GetUserByUsername(ctx, username)
+	if errors.Is(err, store.ErrNotFound) {
+		return user, &statusError{http.StatusUnauthorized, "Unauthorized", errors.New("user no longer exists")}
+	}
+	return user, err
+}
+
+// lockReviewedBook locks the book being reviewed and loads the reviewer. The
+// lock serializes review writes on a book so its rating stays consistent.
+func lockReviewedBook(ctx context.Context, tx store.Stores, bookID string, username string) (models.Book, models.User, error) {
+	book, err := lockedBook(tx.Books.LockBook(ctx, bookID))
+	if err != nil {
+		return book, models.User{}, err
+	}
+
+	user, err := findUser(ctx, tx, username)
+	return book, user, err
+}
+
+// sendReviewError answers a failed review write
+func sendReviewError(c *gin.Context, err error) {
+	var serr *statusError
+	switch {
+	case errors.As(err, &serr):
+		response.NewErrorResponse(serr.status, serr.message, serr.Error()).Send(c)
+	case errors.Is(err, store.ErrConflict):
+		response.NewErrorResponse(http.StatusConflict, "Book already reviewed", "you have already reviewed this book, update your review instead").Send(c)
+	default:
+		response.NewErrorResponse(http.StatusInternalServerError, "Failed to save review", err.Error()).Send(c)
+	}
+}
+
+// FindReviews godoc
+// @Summary List the reviews of a book
+// @Description Get a paginated list of the approved reviews of a book
+// @Tags reviews
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Book ID"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Param sort query string false "created_at, updated_at or rating" default(created_at)
+// @Param order query string false "Sort order (asc or desc)"
+// @Success 200 {array} models.Review "Reviews of the book"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Book not found"
+// @Router /books/{id}/reviews [get]
+func (r *bookRepository) FindReviews(c *gin.Context) {
+	var reviews []models.Review
+
+	params := pagination.ParseParams(c)
+	if params.Sort == "" {
+		params.Sort = "created_at"
+	}
+	if !reviewSortColumns[params.Sort] {
+		response.NewErrorResponse(http.StatusBadRequest, "Invalid sort column", "sort must be created_at, updated_at or rating").Send(c)
+		return
+	}
+
+	book, err := r.Books.LoadBook(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		sendBookLookupError(c, err)
+		return
+	}
+
+	if _, meta, err := params.ApplyWithQuery(r.db(c).Model(&models.Review{}), &reviews, "book_id = ? AND status = ?", book.ID, models.ReviewStatusApproved); err != nil {
+		response.NewErrorResponse(http.StatusInternalServerError, "Failed to retrieve reviews", err.Error()).Send(c)
+	} else {
+		response.NewPaginatedResponse("Reviews retrieved successfully", reviews, meta).Send(c)
+	}
+}
+
+// CreateReview godoc
+// @Summary Review a book
+// @Description Rate a book from 1 to 5 with an optional text. Each user reviews a book at most once. The review is approved, or pending when approval is required, or flagged when its text contains a blocked word.
+// @Tags reviews
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Book ID"
+// @Param input body models.CreateReview true "Review"
+// @Success 201 {object} models.Review "Successfully created review"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Book not found"
+// @Failure 409 {string} string "Book already reviewed"
+// @Router /books/{id}/reviews [post]
+func (r *bookRepository) CreateReview(c *gin.Context) {
+	var input models.CreateReview
+	var review models.Review
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.NewErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()).Send(c)
+		return
+	}
+
+	err := r.Stores.WithTx(c.Request.Context(), func(ctx context.Context, tx store.Stores) error {
+		book, user, err := lockReviewedBook(ctx, tx, c.Param("id"), c.GetString("username"))
+		if err != nil {
+			return err
+		}
+
+		review = models.Review{BookID: book.ID, UserID: user.ID, Username: user.Username}
+		input.ApplyTo(&review)
+		r.screenReview(&review)
+		if err := tx.Reviews.CreateReview(ctx, &review); err != nil {
+			return err
+		}
+
+		return r.Books.RefreshRating(ctx, &book)
+	})
+	if err != nil {
+		sendReviewError(c, err)
+		return
+	}
+
+	response.Response{
+		StatusCode: http.StatusCreated,
+		Success:    true,
+		Message:    "Review created successfully",
+		Data:       review,
+	}.Send(c)
+}
+
+// UpdateReview godoc
+// @Summary Replace your review of a book
+// @Description Replace the rating and text of the caller's review of a book. The review is screened again like a new one, and a rejected review goes back to pending.
+// @Tags reviews
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Book ID"
+// @Param input body models.CreateReview true "Review"
+// @Success 200 {object} models.Review "Successfully updated review"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Book or review not found"
+// @Router /books/{id}/reviews [put]
+func (r *bookRepository) UpdateReview(c *gin.Context) {
+	var input models.CreateReview
+	var review models.Review
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.NewErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()).Send(c)
+		return
+	}
+
+	err := r.Stores.WithTx(c.Request.Context(), func(ctx context.Context, tx store.Stores) error {
+		book, user, err := lockReviewedBook(ctx, tx, c.Param("id"), c.GetString("username"))
+		if err != nil {
+			return err
+		}
+
+		review, err = tx.Reviews.GetUserReview(ctx, book.ID, user.ID)
+		if errors.Is(err, store.ErrNotFound) {
+			return &statusError{http.StatusNotFound, "Review not found", errors.New("you have not reviewed this book")}
+		}
+		if err != nil {
+			return err
+		}
+
+		input.ApplyTo(&review)
+		r.screenReview(&review)
+		if err := tx.Reviews.UpdateReview(ctx, &review, "rating", "text", "status", "moderation_reason", "moderated_by", "moderated_at"); err != nil {
+			return err
+		}
+
+		return r.Books.RefreshRating(ctx, &book)
+	})
+	if err != nil {
+		sendReviewError(c, err)
+		return
+	}
+
+	response.NewSuccessResponse("Review updated successfully", review).Send(c)
+}
+
+// DeleteReview godoc
+// @Summary Delete your review of a book
+// @Description Delete the caller's review of a book
+// @Tags reviews
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Produce  json
+// @Param id path string true "Book ID"
+// @Success 204 {string} string "Successfully deleted review"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Book or review not found"
+// @Router /books/{id}/reviews [delete]
+func (r *bookRepository) DeleteReview(c *gin.Context) {
+	err := r.Stores.WithTx(c.Request.Context(), func(ctx context.Context, tx store.Stores) error {
+		book, user, err := lockReviewedBook(ctx, tx, c.Param("id"), c.GetString("username"))
+		if err != nil {
+			return err
+		}
+
+		err = tx.Reviews.DeleteUserReview(ctx, book.ID, user.ID)
+		if errors.Is(err, store.ErrNotFound) {
+			return &statusError{http.StatusNotFound, "Review not found", errors.New("you have not reviewed this book")}
+		}
+		if err != nil {
+			return err
+		}
+
+		return r.Books.RefreshRating(ctx, &book)
+	})
+	if err != nil {
+		sendReviewError(c, err)
+		return
+	}
+
+	response.Response{
+		StatusCode: http.StatusNoContent,
+		Success:    true,
+		Message:    "Review deleted successfully",
+		Data:       true,
+	}.Send(c)
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/cache"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func newReviewTestRouter(repo *bookRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	withUser := func(c *gin.Context) {
+		c.Set("username", "reader")
+		c.Next()
+	}
+	r.GET("/books/:id/reviews", repo.FindReviews)
+	r.POST("/books/:id/reviews", withUser, repo.CreateReview)
+	r.PUT("/books/:id/reviews", withUser, repo.UpdateReview)
+	r.DELETE("/books/:id/reviews", withUser, repo.DeleteReview)
+	return r
+}
+
+func TestCreateReviewValidatesRating(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// No expectations: invalid reviews must be rejected before the database is touched
+	mockDB := newMockDatabase(ctrl)
+	r := newReviewTestRouter(NewBookRepository(mockDB, nil, nil, nil, nil))
+
+	for _, body := range []string{`{}`, `{"rating":0}`, `{"rating":6}`, `{"rating":"5"}`} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/books/1/reviews", bytes.NewBufferString(body)))
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/books/1/reviews", bytes.NewBufferString(body)))
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+}
+
+func TestDeleteReviewNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	books := store.NewMockBookStore(ctrl)
+	users := store.NewMockUserStore(ctrl)
+	reviews := store.NewMockReviewStore(ctrl)
+	stores := newMockUnitOfWork(ctrl, store.Stores{Books: books, Users: users, Reviews: reviews})
+	r := newReviewTestRouter(NewBookRepository(nil, stores, nil, nil, nil))
+
+	book := models.Book{ID: uuid.New()}
+	user := models.User{ID: uuid.New(), Username: "reader"}
+	books.EXPECT().LockBook(gomock.Any(), "1").Return(book, nil)
+	users.EXPECT().GetUserByUsername(gomock.Any(), "reader").Return(user, nil)
+	reviews.EXPECT().DeleteUserReview(gomock.Any(), book.ID, user.ID).Return(store.ErrNotFound)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/books/1/reviews", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "Review not found")
+}
+
+func TestSendReviewError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{store.ErrConflict, http.StatusConflict},
+		{&statusError{http.StatusUnauthorized, "Unauthorized", errors.New("user no longer exists")}, http.StatusUnauthorized},
+		{errors.New("connection reset"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		sendReviewError(c, tt.err)
+		assert.Equal(t, tt.status, w.Code, tt.err.Error())
+	}
+}
+
+func TestFindReviewsRejectsUnknownSort(t *testing.T) {
+	r := newReviewTestRouter(NewBookRepository(nil, nil, nil, nil, nil))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/books/1/reviews?sort=text", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestFindBooksSortByRating(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	appCache := cache.NewMemoryCache(100)
+	repo := NewBookRepository(nil, nil, appCache, nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/books", repo.FindBooks)
+
+	// Sorted pages are cached apart from unsorted ones
+	seedBooksPage(t, appCache, store.BookQuery{Limit: 10, Sort: store.BookSortRating}, []byte(`{"etag":"W/\"x\"","books":[]}`))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/books?sort=rating", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/books?sort=title", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
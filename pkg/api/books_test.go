@@ -4,19 +4,23 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/kev1nandreas/go-rest-api-template/pkg/cache"
 	"github.com/kev1nandreas/go-rest-api-template/pkg/database"
 	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/search"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/service"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/store"
 
 	"gorm.io/gorm"
 
-	"github.com/go-redis/redis/v8"
-
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -26,15 +30,14 @@ func TestNewBookRepository(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	mockDB := database.NewMockDatabase(ctrl)
+	mockDB := newMockDatabase(ctrl)
 	mockCache := cache.NewMockCache(ctrl)
-	mockCtx := context.Background()
 
-	repo := NewBookRepository(mockDB, mockCache, &mockCtx)
+	repo := NewBookRepository(mockDB, nil, mockCache, nil, nil)
 
 	assert.NotNil(t, repo, "NewBookRepository should return a non-nil instance of bookRepository")
 	assert.Equal(t, mockDB, repo.DB, "DB should be set to the mock database instance")
-	assert.Equal(t, mockCache, repo.RedisClient, "RedisClient should be set to the mock cache instance")
+	assert.Equal(t, mockCache, repo.Cache, "Cache should be set to the mock cache instance")
 }
 
 func TestHealthcheck(t *testing.T) {
@@ -69,27 +72,21 @@ func TestFindBooks(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	mockDB := database.NewMockDatabase(ctrl)
-	mockCache := cache.NewMockCache(ctrl)
-	mockGormDB := database.NewMockDatabase(ctrl) // Correct type for GORM DB operations
-	ctx := context.Background()
+	// A cached page never reaches the store
+	mockBooks := store.NewMockBookStore(ctrl)
+	appCache := cache.NewMemoryCache(100)
 
-	repo := NewBookRepository(mockDB, mockCache, &ctx)
+	repo := NewBookRepository(nil, newMockUnitOfWork(ctrl, store.Stores{Books: mockBooks}), appCache, nil, nil)
 
 	// Set up Gin
 	gin.SetMode(gin.TestMode)
 	r := gin.Default()
 	r.GET("/books", repo.FindBooks)
 
-	// Set up common mock expectations
-	mockGormDB.EXPECT().Find(gomock.Any()).DoAndReturn(func(books *[]models.Book) *gorm.DB {
-		*books = append(*books, models.Book{Title: "New Book", Author: "New Author"})
-		return &gorm.DB{Error: nil} // Assume this is the struct provided by the actual Gorm package
-	}).AnyTimes()
-
 	books := []models.Book{{Title: "Book One", Author: "Author One"}}
-	cachedData, _ := json.Marshal(books)
-	mockCache.EXPECT().Get(ctx, "books_offset_0_limit_10").Return(redis.NewStringResult(string(cachedData), nil))
+	page, _ := service.NewBookPage(books, time.Time{})
+	cachedData, _ := json.Marshal(page)
+	seedBooksPage(t, appCache, store.BookQuery{Limit: 10}, cachedData)
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", "/books?offset=0&limit=10", nil)
@@ -99,15 +96,43 @@ func TestFindBooks(t *testing.T) {
 	assert.Contains(t, w.Body.String(), "Book One")
 }
 
-func TestCreateBook(t *testing.T) {
+func TestFindBooksWithoutCache(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	mockDB := database.NewMockDatabase(ctrl)
+	mockBooks := store.NewMockBookStore(ctrl)
 	mockCache := cache.NewMockCache(ctrl)
-	ctx := context.Background()
 
-	repo := NewBookRepository(mockDB, mockCache, &ctx)
+	// Redis is down: the first request trips the breaker, the second one
+	// skips the cache entirely
+	appCache := cache.NewBreakerCache(mockCache, 1, time.Minute)
+	mockCache.EXPECT().TagKey(gomock.Any(), gomock.Any(), service.BooksTag).Return("", errors.New("connection refused")).Times(1)
+	repo := NewBookRepository(nil, newMockUnitOfWork(ctrl, store.Stores{Books: mockBooks}), appCache, nil, nil)
+
+	mockBooks.EXPECT().LastModified(gomock.Any()).Return(time.Time{}, nil).Times(2)
+	mockBooks.EXPECT().ListBooks(gomock.Any(), store.BookQuery{Limit: 10}).Return([]models.Book{{Title: "Dune", Author: "Frank Herbert"}}, nil).Times(2)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/books", repo.FindBooks)
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/books", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "Dune")
+	}
+	assert.Equal(t, cache.HealthDegraded, appCache.Health().Status)
+}
+
+func TestCreateBook(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBooks := store.NewMockBookStore(ctrl)
+	appCache := cache.NewMemoryCache(100)
+
+	repo := NewBookRepository(nil, newMockUnitOfWork(ctrl, store.Stores{Books: mockBooks}), appCache, nil, nil)
 
 	// Set up Gin
 	gin.SetMode(gin.TestMode)
@@ -125,16 +150,14 @@ func TestCreateBook(t *testing.T) {
 		t.Fatalf("Failed to marshal input book data: %v", err)
 	}
 
-	// Set up database mock to simulate successful book creation
-	mockDB.EXPECT().Create(gomock.Any()).DoAndReturn(func(book *models.Book) *gorm.DB {
+	// Set up the store mock to simulate successful book creation
+	mockBooks.EXPECT().CreateBook(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, book *models.Book) error {
 		// Normally, you might simulate setting an ID or other fields modified by the DB
-		return &gorm.DB{Error: nil}
+		book.ID = uuid.New()
+		return nil
 	})
 
-	// Set up cache mock to simulate key retrieval and deletion
-	keyPattern := "books_offset_*"
-	mockCache.EXPECT().Keys(ctx, keyPattern).Return(redis.NewStringSliceResult([]string{"books_offset_0_limit_10"}, nil))
-	mockCache.EXPECT().Del(ctx, "books_offset_0_limit_10").Return(redis.NewIntResult(1, nil))
+	cached := seedBooksPage(t, appCache, store.BookQuery{Limit: 10}, []byte(`{"books":[]}`))
 
 	w := httptest.NewRecorder()
 	req, err := http.NewRequest("POST", "/books", bytes.NewBuffer(requestBody))
@@ -149,15 +172,15 @@ func TestCreateBook(t *testing.T) {
 	// Assertions to check the response
 	assert.Equal(t, http.StatusCreated, w.Code, "Expected HTTP status code 201")
 	assert.Contains(t, w.Body.String(), "New Book", "Response body should contain the book title")
+	assertBooksCacheInvalidated(t, appCache, store.BookQuery{Limit: 10}, cached)
 }
 
 func TestFindBook(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	mockDB := database.NewMockDatabase(ctrl)
-	ctx := context.Background()
-	repo := NewBookRepository(mockDB, nil, &ctx)
+	mockBooks := store.NewMockBookStore(ctrl)
+	repo := NewBookRepository(nil, newMockUnitOfWork(ctrl, store.Stores{Books: mockBooks}), nil, nil, nil)
 
 	// Set up Gin
 	gin.SetMode(gin.TestMode)
@@ -171,31 +194,8 @@ func TestFindBook(t *testing.T) {
 		Author: "Robert Griesemer",
 	}
 
-	// Mock expectations
-
-	// Mock the Where method
-	mockDB.EXPECT().
-		Where("id = ?", "1").
-		DoAndReturn(func(query interface{}, args ...interface{}) database.Database {
-			// Return mockDB to allow method chaining
-			return mockDB
-		}).Times(1)
-
-	// Mock the First method
-	mockDB.EXPECT().
-		First(gomock.Any()).
-		DoAndReturn(func(dest interface{}, conds ...interface{}) database.Database {
-			if b, ok := dest.(*models.Book); ok {
-				*b = expectedBook
-			}
-			return mockDB
-		}).Times(1)
-
-	// Mock the Error method or field access
-	mockDB.EXPECT().
-		Error().
-		Return(nil).
-		Times(1)
+	// IDs that aren't UUIDs bypass the cache
+	mockBooks.EXPECT().GetBook(gomock.Any(), "1").Return(expectedBook, nil).Times(1)
 
 	// Perform the request
 	w := httptest.NewRecorder()
@@ -218,14 +218,74 @@ func TestFindBook(t *testing.T) {
 	assert.Equal(t, expectedBook.Author, response.Data.Author)
 }
 
+func TestFindBookByISBN(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBooks := store.NewMockBookStore(ctrl)
+	repo := NewBookRepository(nil, newMockUnitOfWork(ctrl, store.Stores{Books: mockBooks}), nil, nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/books/isbn/:isbn", repo.FindBookByISBN)
+
+	expectedBook := models.Book{
+		ID:     uuid.MustParse("123e4567-e89b-12d3-a456-426614174000"),
+		Title:  "The Go Programming Language",
+		Author: "Alan Donovan",
+		ISBN:   "9780134190440",
+	}
+
+	// The ISBN-10 form is normalized before the lookup
+	mockBooks.EXPECT().GetBookByISBN(gomock.Any(), models.ISBN("9780134190440")).Return(expectedBook, nil)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/books/isbn/0-13-419044-0", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"isbn":"9780134190440"`)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/books/isbn/0-13-419044-1", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestCreateBookDuplicateISBN(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBooks := store.NewMockBookStore(ctrl)
+	repo := NewBookRepository(nil, newMockUnitOfWork(ctrl, store.Stores{Books: mockBooks}), nil, nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/books", func(c *gin.Context) {
+		c.Set("appCtx", repo)
+		repo.CreateBook(c)
+	})
+
+	mockBooks.EXPECT().CreateBook(gomock.Any(), gomock.Any()).Return(store.ErrConflict)
+
+	w := httptest.NewRecorder()
+	body := `{"title":"The Go Programming Language","author":"Alan Donovan","isbn":"978-0-13-419044-0"}`
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/books", bytes.NewBufferString(body)))
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "9780134190440")
+
+	// Invalid metadata is rejected while binding, before reaching the database
+	w = httptest.NewRecorder()
+	body = `{"title":"The Go Programming Language","author":"Alan Donovan","isbn":"978-0-13-419044-1"}`
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/books", bytes.NewBufferString(body)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
 func TestDeleteBook(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	// Create mock for the database
-	mockDB := database.NewMockDatabase(ctrl)
-	ctx := context.Background()
-	repo := NewBookRepository(mockDB, nil, &ctx)
+	// Create mocks for the store and cache
+	mockBooks := store.NewMockBookStore(ctrl)
+	appCache := cache.NewMemoryCache(100)
+	repo := NewBookRepository(nil, newMockUnitOfWork(ctrl, store.Stores{Books: mockBooks}), appCache, nil, nil)
 
 	// Set up Gin for testing
 	gin.SetMode(gin.TestMode)
@@ -234,39 +294,236 @@ func TestDeleteBook(t *testing.T) {
 
 	// Prepare the book data
 	existingBook := models.Book{
+		ID:      uuid.MustParse("123e4567-e89b-12d3-a456-426614174000"),
+		Title:   "Test Book",
+		Author:  "Test Author",
+		Version: 1,
+	}
+
+	// The book is read, then deleted at the version that was read
+	mockBooks.EXPECT().GetBook(gomock.Any(), "1").Return(existingBook, nil).Times(1)
+	mockBooks.EXPECT().DeleteBook(gomock.Any(), existingBook).Return(nil).Times(1)
+
+	cached := seedBooksPage(t, appCache, store.BookQuery{Limit: 10}, []byte(`{"books":[]}`))
+
+	// Perform the DELETE request
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodDelete, "/book/1", nil)
+	r.ServeHTTP(w, req)
+
+	// Assert the response
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assertBooksCacheInvalidated(t, appCache, store.BookQuery{Limit: 10}, cached)
+}
+
+func TestSearchBooks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := newMockDatabase(ctrl)
+	ctx := context.Background()
+
+	index, err := search.OpenIndex(filepath.Join(t.TempDir(), "books.idx"))
+	assert.NoError(t, err)
+
+	book := models.Book{
 		ID:     uuid.MustParse("123e4567-e89b-12d3-a456-426614174000"),
-		Title:  "Test Book",
-		Author: "Test Author",
+		Title:  "Effective Go",
+		Author: "Robert Griesemer",
 	}
+	assert.NoError(t, index.Index(ctx, book))
+
+	repo := NewBookRepository(mockDB, nil, nil, index, nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/books/search", repo.SearchBooks)
 
-	// Mock Where to return the existingBook for chaining
 	mockDB.EXPECT().
-		Where("id = ?", "1").
+		Where("id IN ?", []uuid.UUID{book.ID}).
 		Return(mockDB).Times(1)
 
-	// Mock First to load the existingBook and return mockDB
 	mockDB.EXPECT().
-		First(gomock.Any()).
-		DoAndReturn(func(dest interface{}, conds ...interface{}) database.Database {
-			if b, ok := dest.(*models.Book); ok {
-				*b = existingBook
+		Find(gomock.Any()).
+		DoAndReturn(func(dest interface{}, conds ...interface{}) *gorm.DB {
+			if b, ok := dest.(*[]models.Book); ok {
+				*b = append(*b, book)
 			}
-			return mockDB
+			return &gorm.DB{Error: nil}
 		}).Times(1)
 
-	// Mock Delete method
-	mockDB.EXPECT().
-		Delete(&existingBook).
-		Return(&gorm.DB{Error: nil}).Times(1)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/books/search?q=effective", nil)
+	r.ServeHTTP(w, req)
 
-	// Mock Error method to return nil
-	mockDB.EXPECT().Error().Return(nil).AnyTimes()
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Effective Go")
+	assert.Contains(t, w.Body.String(), "Robert Griesemer")
+}
 
-	// Perform the DELETE request
+// newMockUnitOfWork returns a unit of work over stores whose transactions
+// run fn on stores as is, so after commit hooks run right away
+// newMockDatabase returns a mock database that handlers can bind to the
+// context of the request
+func newMockDatabase(ctrl *gomock.Controller) *database.MockDatabase {
+	db := database.NewMockDatabase(ctrl)
+	db.EXPECT().WithContext(gomock.Any()).Return(db).AnyTimes()
+	return db
+}
+
+func newMockUnitOfWork(ctrl *gomock.Controller, stores store.Stores) *store.MockUnitOfWork {
+	uow := store.NewMockUnitOfWork(ctrl)
+	uow.EXPECT().Stores().Return(stores).AnyTimes()
+	uow.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, fn func(context.Context, store.Stores) error) error {
+		return fn(ctx, stores)
+	}).AnyTimes()
+	return uow
+}
+
+// seedBooksPage caches data as the FindBooks page of query and returns the
+// tagged key it is stored under
+func seedBooksPage(t *testing.T, appCache cache.Cache, query store.BookQuery, data []byte) string {
+	tagged, err := appCache.TagKey(context.Background(), service.PageKey(query), service.BooksTag)
+	assert.NoError(t, err)
+	assert.NoError(t, cache.NewLoader(appCache, cache.Policy{TTL: time.Minute}).Set(context.Background(), tagged, data))
+	return tagged
+}
+
+// assertBooksCacheInvalidated checks that the FindBooks page of query stored
+// under tagged is no longer the one readers get
+func assertBooksCacheInvalidated(t *testing.T, appCache cache.Cache, query store.BookQuery, tagged string) {
+	current, err := appCache.TagKey(context.Background(), service.PageKey(query), service.BooksTag)
+	assert.NoError(t, err)
+	assert.NotEqual(t, tagged, current)
+}
+
+func TestFindBooksReadsItsWrites(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// The books table
+	stored := []models.Book{{ID: uuid.New(), Title: "Dune", Author: "Frank Herbert", Version: 1}}
+	db := newDryRunDB(t)
+	db.Callback().Query().After("gorm:query").Register("test:load", func(tx *gorm.DB) {
+		switch dest := tx.Statement.Dest.(type) {
+		case *[]models.Book:
+			*dest = append([]models.Book(nil), stored...)
+		case *models.Book:
+			*dest = stored[0]
+		}
+	})
+	db.Callback().Create().After("gorm:create").Register("test:create", func(tx *gorm.DB) {
+		// Creating a book also creates its authors
+		if book, ok := tx.Statement.Dest.(*models.Book); ok {
+			book.ID = uuid.New()
+			stored = append(stored, *book)
+		}
+	})
+	db.Callback().Update().After("gorm:update").Register("test:save", func(tx *gorm.DB) {
+		stored[0].Title = tx.Statement.Dest.(map[string]interface{})["title"].(string)
+		tx.RowsAffected = 1
+	})
+	db.Callback().Delete().After("gorm:delete").Register("test:delete", func(tx *gorm.DB) {
+		stored = stored[1:]
+		tx.RowsAffected = 1
+	})
+
+	repo := NewBookRepository(nil, newMockUnitOfWork(ctrl, store.Stores{Books: store.NewGormBookStore(db)}), cache.NewMemoryCache(100), nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.Use(ContextMiddleware(repo))
+	r.GET("/books", repo.FindBooks)
+	r.POST("/books", repo.CreateBook)
+	r.PUT("/books/:id", repo.UpdateBook)
+	r.DELETE("/books/:id", repo.DeleteBook)
+
+	list := func() string {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/books", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		return w.Body.String()
+	}
+	send := func(method string, target string, body string, status int) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		assert.Equal(t, status, w.Code, w.Body.String())
+	}
+
+	assert.Contains(t, list(), "Dune")
+	assert.Contains(t, list(), "retrieved from cache")
+
+	send(http.MethodPost, "/books", `{"title":"Hyperion","author":"Dan Simmons"}`, http.StatusCreated)
+	assert.Contains(t, list(), "Hyperion")
+
+	send(http.MethodPut, "/books/"+stored[0].ID.String(), `{"title":"Dune Messiah","author":"Frank Herbert"}`, http.StatusOK)
+	assert.Contains(t, list(), "Dune Messiah")
+
+	send(http.MethodDelete, "/books/"+stored[0].ID.String(), "", http.StatusNoContent)
+	page := list()
+	assert.NotContains(t, page, "Dune")
+	assert.Contains(t, page, "Hyperion")
+}
+
+func TestFindBookReadsThroughCache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// The books table, and how often it was read
+	stored := models.Book{ID: uuid.New(), Title: "Dune", Author: "Frank Herbert", Version: 1}
+	missing := uuid.New()
+	queries := 0
+	db := newDryRunDB(t)
+	db.Callback().Query().After("gorm:query").Register("test:load", func(tx *gorm.DB) {
+		book, ok := tx.Statement.Dest.(*models.Book)
+		if !ok {
+			// The byline lookup of updates finds nothing to re-credit
+			return
+		}
+		queries++
+		if tx.Statement.Vars[0] == stored.ID.String() {
+			*book = stored
+			return
+		}
+		tx.AddError(gorm.ErrRecordNotFound)
+	})
+	db.Callback().Update().After("gorm:update").Register("test:save", func(tx *gorm.DB) {
+		stored.Title = tx.Statement.Dest.(map[string]interface{})["title"].(string)
+		tx.RowsAffected = 1
+	})
+
+	repo := NewBookRepository(nil, newMockUnitOfWork(ctrl, store.Stores{Books: store.NewGormBookStore(db)}), cache.NewMemoryCache(100), nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/books/:id", repo.FindBook)
+	r.PUT("/books/:id", repo.UpdateBook)
+
+	get := func(id uuid.UUID, status int) string {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/books/"+id.String(), nil))
+		assert.Equal(t, status, w.Code)
+		return w.Body.String()
+	}
+
+	assert.Contains(t, get(stored.ID, http.StatusOK), "retrieved successfully")
+	body := get(stored.ID, http.StatusOK)
+	assert.Contains(t, body, "retrieved from cache")
+	assert.Contains(t, body, "Dune")
+	assert.Equal(t, 1, queries)
+
+	// Missing books are cached too
+	get(missing, http.StatusNotFound)
+	get(missing, http.StatusNotFound)
+	assert.Equal(t, 2, queries)
+
+	// Writes evict the book
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest(http.MethodDelete, "/book/1", nil)
+	req := httptest.NewRequest(http.MethodPut, "/books/"+stored.ID.String(), bytes.NewBufferString(`{"title":"Dune Messiah","author":"Frank Herbert"}`))
+	req.Header.Set("Content-Type", "application/json")
 	r.ServeHTTP(w, req)
-
-	// Assert the response
-	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, get(stored.ID, http.StatusOK), "Dune Messiah")
 }
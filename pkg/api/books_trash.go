@@ -0,0 +1,120 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/pagination"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/response"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/store"
+)
+
+// trashSortColumns are the columns the trash can be sorted by
+var trashSortColumns = map[string]bool{"deleted_at": true, "title": true, "created_at": true}
+
+// TrashBooks godoc
+// @Summary List deleted books
+// @Description List soft-deleted books that can still be restored or purged
+// @Tags trash
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Param sort query string false "Sort column (deleted_at, title or created_at)" default(deleted_at)
+// @Param order query string false "Sort order (asc or desc)"
+// @Success 200 {array} models.Book "Deleted books"
+// @Failure 400 {string} string "Invalid sort column"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Router /books/trash [get]
+func (r *bookRepository) TrashBooks(c *gin.Context) {
+	var books []models.Book
+
+	params := pagination.ParseParams(c)
+	if params.Sort == "" {
+		params.Sort = "deleted_at"
+	}
+	if !trashSortColumns[params.Sort] {
+		response.NewErrorResponse(http.StatusBadRequest, "Invalid sort column", "sort must be deleted_at, title or created_at").Send(c)
+		return
+	}
+
+	if _, meta, err := params.ApplyWithQuery(r.db(c).Model(&models.Book{}).Unscoped(), &books, "deleted_at IS NOT NULL"); err != nil {
+		response.NewErrorResponse(http.StatusInternalServerError, "Failed to retrieve deleted books", err.Error()).Send(c)
+	} else {
+		response.NewPaginatedResponse("Deleted books retrieved successfully", books, meta).Send(c)
+	}
+}
+
+// RestoreBook godoc
+// @Summary Restore a deleted book
+// @Description Move a soft-deleted book out of the trash
+// @Tags trash
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Produce json
+// @Param id path string true "Book ID"
+// @Success 200 {object} models.Book "Restored book"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Book not found in trash"
+// @Failure 409 {string} string "Another book has the same ISBN"
+// @Router /books/{id}/restore [post]
+func (r *bookRepository) RestoreBook(c *gin.Context) {
+	book, err := r.Books.RestoreBook(c.Request.Context(), c.Param("id"))
+	switch {
+	case errors.Is(err, store.ErrConflict):
+		// A book created while this one was in the trash took its ISBN
+		response.NewErrorResponse(http.StatusConflict, "Duplicate ISBN", "another book has this book's ISBN, change or delete it before restoring this one").Send(c)
+		return
+	case errors.Is(err, store.ErrNotFound):
+		response.NewErrorResponse(http.StatusNotFound, "Book not found in trash", "no deleted book with this ID").Send(c)
+		return
+	case err != nil:
+		response.NewErrorResponse(http.StatusInternalServerError, "Failed to restore book", err.Error()).Send(c)
+		return
+	}
+
+	response.NewSuccessResponse("Book restored successfully", book).Send(c)
+}
+
+// PurgeBook godoc
+// @Summary Permanently delete a book
+// @Description Permanently delete a book that is already in the trash, with its cover and files. Its loan history is kept and its open reservations are cancelled. A book can't be purged while a copy is on loan. This cannot be undone.
+// @Tags trash
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Produce json
+// @Param id path string true "Book ID"
+// @Success 204 {string} string "Book purged"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Book not found in trash"
+// @Failure 409 {string} string "Book is on loan"
+// @Router /books/{id}/purge [delete]
+func (r *bookRepository) PurgeBook(c *gin.Context) {
+	keys, err := r.Books.PurgeBook(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, store.ErrNotFound) {
+		response.NewErrorResponse(http.StatusNotFound, "Book not found in trash", "only deleted books can be purged").Send(c)
+		return
+	}
+	if errors.Is(err, store.ErrConflict) {
+		response.NewErrorResponse(http.StatusConflict, "Book is on loan", "a book can only be purged once every copy is returned").Send(c)
+		return
+	}
+	if err != nil {
+		response.NewErrorResponse(http.StatusInternalServerError, "Failed to purge book", err.Error()).Send(c)
+		return
+	}
+	r.deleteBlobs(c.Request.Context(), keys)
+
+	response.Response{
+		StatusCode: http.StatusNoContent,
+		Success:    true,
+		Message:    "Book purged successfully",
+		Data:       true,
+	}.Send(c)
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/middleware"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/store"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils/tests"
+)
+
+// newDryRunDB returns a gorm handle that builds statements without running
+// them, so every write reports zero affected rows
+func newDryRunDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true, Logger: logger.Discard})
+	assert.NoError(t, err)
+	return db
+}
+
+func TestTrashRoutesRequireAdmin(t *testing.T) {
+	repo := NewBookRepository(nil, nil, nil, nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	withRole := func(role string) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			c.Set("role", role)
+			c.Next()
+		}
+	}
+	r.GET("/user/books/trash", withRole(models.RoleUser), middleware.RequireRole(models.RoleAdmin), repo.TrashBooks)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/user/books/trash", nil))
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestTrashBooksRejectsUnknownSortColumns(t *testing.T) {
+	repo := NewBookRepository(nil, nil, nil, nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/books/trash", repo.TrashBooks)
+
+	for _, sort := range []string{"title%3B--", "isbn", "(SELECT+password+FROM+users+LIMIT+1)"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/books/trash?sort="+sort, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, sort)
+	}
+}
+
+func TestRestoreBookNotInTrash(t *testing.T) {
+	// A dry run restores nothing, like a book that is not in the trash
+	repo := NewBookRepository(nil, store.NewGormUnitOfWork(newDryRunTxDB(t)), nil, nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/book/:id/restore", repo.RestoreBook)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/book/1/restore", nil))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestPurgeBookNotInTrash(t *testing.T) {
+	// A dry run purges nothing, like a book that is not in the trash
+	repo := NewBookRepository(nil, store.NewGormUnitOfWork(newDryRunTxDB(t)), nil, nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.DELETE("/book/:id/purge", repo.PurgeBook)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/book/1/purge", nil))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestPurgeBookOnLoan(t *testing.T) {
+	// Nothing is purged and a copy of the book is still out
+	db := newDryRunTxDB(t)
+	assert.NoError(t, db.Callback().Query().After("gorm:query").Register("test:on_loan", func(tx *gorm.DB) {
+		if count, ok := tx.Statement.Dest.(*int64); ok && tx.Statement.Table == "loans" {
+			*count = 1
+			tx.RowsAffected = 1
+		}
+	}))
+	repo := NewBookRepository(nil, store.NewGormUnitOfWork(db), nil, nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.DELETE("/book/:id/purge", repo.PurgeBook)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/book/1/purge", nil))
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "Book is on loan")
+}
+
+func TestRestoreBookDuplicateISBN(t *testing.T) {
+	// A live book took the ISBN while this one was in the trash, so the
+	// unique index rejects the restore
+	db := newDryRunTxDB(t)
+	assert.NoError(t, db.Callback().Update().Before("gorm:update").Register("test:duplicate_isbn", func(tx *gorm.DB) {
+		tx.AddError(gorm.ErrDuplicatedKey)
+	}))
+	repo := NewBookRepository(nil, store.NewGormUnitOfWork(db), nil, nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/book/:id/restore", repo.RestoreBook)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/book/1/restore", nil))
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "Duplicate ISBN")
+}
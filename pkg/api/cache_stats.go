@@ -0,0 +1,30 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/cache"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/response"
+)
+
+// FindCacheStats godoc
+// @Summary Get cache statistics
+// @Description Get the hit, miss and eviction counters of the in-process cache of the instance that answers
+// @Tags cache
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Produce json
+// @Success 200 {object} cache.Stats "Successfully retrieved cache statistics"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "The cache backend keeps no entries in process"
+// @Router /cache/stats [get]
+func (r *bookRepository) FindCacheStats(c *gin.Context) {
+	reporter, ok := r.Cache.(cache.StatsReporter)
+	if !ok {
+		response.NewErrorResponse(http.StatusNotFound, "Cache statistics are not available", "the cache backend keeps no entries in process").Send(c)
+		return
+	}
+	response.NewSuccessResponse("Cache statistics retrieved successfully", reporter.Stats()).Send(c)
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindCacheStats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	gin.SetMode(gin.TestMode)
+
+	appCache := cache.NewMemoryCache(100)
+	assert.NoError(t, appCache.Set(ctx, "key", []byte("value"), 0))
+	_, _ = appCache.Get(ctx, "key")
+	_, _ = appCache.Get(ctx, "missing")
+
+	r := gin.Default()
+	r.GET("/cache/stats", NewBookRepository(nil, nil, appCache, nil, nil).FindCacheStats)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/cache/stats", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var body struct {
+		Data cache.Stats `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, cache.Stats{Entries: 1, MaxEntries: 100, Hits: 1, Misses: 1}, body.Data)
+
+	// Redis alone keeps nothing in process
+	r = gin.Default()
+	r.GET("/cache/stats", NewBookRepository(nil, nil, cache.NewMockCache(ctrl), nil, nil).FindCacheStats)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/cache/stats", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,237 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kev1nandreas/go-rest-api-template/env"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/cache"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/database"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/pagination"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/response"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/scheduler"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/search"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/storage"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/store"
+	"gorm.io/gorm"
+)
+
+type JobRepository interface {
+	FindJobs(c *gin.Context)
+	RunJob(c *gin.Context)
+	FindJobRuns(c *gin.Context)
+}
+
+// jobRepository holds shared resources for the background job endpoints
+type jobRepository struct {
+	DB        database.Database
+	Scheduler *scheduler.Scheduler
+}
+
+func NewJobRepository(db database.Database, jobs *scheduler.Scheduler) *jobRepository {
+	return &jobRepository{
+		DB:        db,
+		Scheduler: jobs,
+	}
+}
+
+// db returns the database bound to the context of the request
+func (r *jobRepository) db(c *gin.Context) database.Database {
+	return r.DB.WithContext(c.Request.Context())
+}
+
+// jobRunSortColumns are the columns job run lists can be sorted by
+var jobRunSortColumns = map[string]bool{"started_at": true, "duration_ms": true}
+
+// RegisterJobs registers the background jobs of the API with s. Schedules
+// can be changed or turned off with SCHEDULE_<JOB NAME>.
+func RegisterJobs(s *scheduler.Scheduler, db *gorm.DB, appCache cache.Cache, searcher search.Searcher, blobs storage.BlobStore) error {
+	books := NewBookRepository(&database.GormDatabase{DB: db}, store.NewGormUnitOfWork(db), appCache, searcher, blobs)
+	runs := scheduler.NewGormRunStore(db)
+
+	retentionDays := env.GetEnvInt("TRASH_RETENTION_DAYS", 30)
+	purgeSpec := fmt.Sprintf("@every %dm", env.GetEnvInt("TRASH_PURGE_INTERVAL_MINUTES", 60))
+	if retentionDays <= 0 {
+		purgeSpec = ""
+	}
+	historyDays := env.GetEnvInt("JOB_HISTORY_DAYS", 30)
+	reindexBatchSize := env.GetEnvInt("SEARCH_REINDEX_BATCH_SIZE", 500)
+
+	jobs := []scheduler.Job{
+		{
+			Name:        "purge-trash",
+			Description: "Permanently delete books and users trashed more than TRASH_RETENTION_DAYS ago",
+			Spec:        scheduler.ConfiguredSpec("purge-trash", purgeSpec),
+			Timeout:     30 * time.Minute,
+			Run: func(ctx context.Context) (string, error) {
+				if retentionDays <= 0 {
+					return "", errors.New("trash retention is disabled")
+				}
+				purged, err := database.PurgeDeleted(ctx, db, blobs, time.Now().AddDate(0, 0, -retentionDays))
+				return fmt.Sprintf("purged %d rows", purged), err
+			},
+		},
+		{
+			Name:        "expire-reservation-holds",
+			Description: "Pass the copies held for uncollected reservations down the queue",
+			Spec:        scheduler.ConfiguredSpec("expire-reservation-holds", "@every 15m"),
+			Run:         books.ExpireReservationHolds,
+		},
+		{
+			Name:        "warm-books-cache",
+			Description: "Cache the first page of the book list in every sort order",
+			Spec:        scheduler.ConfiguredSpec("warm-books-cache", "@every 1m"),
+			Timeout:     time.Minute,
+			Run:         books.WarmBooksCache,
+		},
+		{
+			Name:        "reindex-search",
+			Description: "Rebuild the search index from the books table",
+			Spec:        scheduler.ConfiguredSpec("reindex-search", ""),
+			Timeout:     30 * time.Minute,
+			Run: func(ctx context.Context) (string, error) {
+				indexed, err := search.Reindex(ctx, searcher, db, reindexBatchSize)
+				return fmt.Sprintf("indexed %d books", indexed), err
+			},
+		},
+		{
+			Name:        "prune-job-runs",
+			Description: "Delete the job run history older than JOB_HISTORY_DAYS",
+			Spec:        scheduler.ConfiguredSpec("prune-job-runs", "@daily"),
+			Run: func(ctx context.Context) (string, error) {
+				pruned, err := runs.PruneRuns(ctx, time.Now().AddDate(0, 0, -historyDays))
+				return fmt.Sprintf("pruned %d runs", pruned), err
+			},
+		},
+	}
+
+	for _, job := range jobs {
+		if err := s.Register(job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findJob looks up a registered job by name
+func (r *jobRepository) findJob(name string) (models.Job, bool) {
+	for _, job := range r.Scheduler.Jobs() {
+		if job.Name == name {
+			return job, true
+		}
+	}
+	return models.Job{}, false
+}
+
+// FindJobs godoc
+// @Summary List background jobs
+// @Description Get the registered background jobs with their schedules, next run and last run
+// @Tags jobs
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Produce json
+// @Success 200 {array} models.Job "Successfully retrieved jobs"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Router /jobs [get]
+func (r *jobRepository) FindJobs(c *gin.Context) {
+	jobs := r.Scheduler.Jobs()
+
+	for i := range jobs {
+		var runs []models.JobRun
+		err := r.db(c).Model(&models.JobRun{}).Where("job = ?", jobs[i].Name).Order("started_at DESC").Limit(1).Find(&runs).Error
+		if err != nil {
+			response.NewErrorResponse(http.StatusInternalServerError, "Failed to retrieve jobs", err.Error()).Send(c)
+			return
+		}
+		if len(runs) > 0 {
+			jobs[i].LastRun = &runs[0]
+		}
+	}
+
+	response.NewSuccessResponse("Jobs retrieved successfully", jobs).Send(c)
+}
+
+// RunJob godoc
+// @Summary Run a background job now
+// @Description Start a run of a job in the background, outside its schedule. The run is recorded in the job history like scheduled runs.
+// @Tags jobs
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Produce json
+// @Param name path string true "Job name"
+// @Success 202 {object} models.JobRun "Job started"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Job not found"
+// @Failure 409 {string} string "Job is already running"
+// @Router /jobs/{name}/run [post]
+func (r *jobRepository) RunJob(c *gin.Context) {
+	run, err := r.Scheduler.Trigger(c.Request.Context(), c.Param("name"), c.GetString("username"))
+	if errors.Is(err, scheduler.ErrUnknownJob) {
+		response.NewErrorResponse(http.StatusNotFound, "Job not found", err.Error()).Send(c)
+		return
+	} else if errors.Is(err, scheduler.ErrJobRunning) {
+		response.NewErrorResponse(http.StatusConflict, "Job is already running", err.Error()).Send(c)
+		return
+	} else if err != nil {
+		response.NewErrorResponse(http.StatusInternalServerError, "Failed to start job", err.Error()).Send(c)
+		return
+	}
+
+	response.Response{
+		StatusCode: http.StatusAccepted,
+		Success:    true,
+		Message:    "Job started",
+		Data:       run,
+	}.Send(c)
+}
+
+// FindJobRuns godoc
+// @Summary List the runs of a background job
+// @Description Get a paginated history of the runs of a job, newest first
+// @Tags jobs
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Produce json
+// @Param name path string true "Job name"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Param sort query string false "started_at or duration_ms" default(started_at)
+// @Param order query string false "Sort order (asc or desc)"
+// @Success 200 {array} models.JobRun "Runs of the job"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Job not found"
+// @Router /jobs/{name}/runs [get]
+func (r *jobRepository) FindJobRuns(c *gin.Context) {
+	var runs []models.JobRun
+
+	name := c.Param("name")
+	if _, ok := r.findJob(name); !ok {
+		response.NewErrorResponse(http.StatusNotFound, "Job not found", scheduler.ErrUnknownJob.Error()).Send(c)
+		return
+	}
+
+	params := pagination.ParseParams(c)
+	if params.Sort == "" {
+		params.Sort = "started_at"
+	}
+	if !jobRunSortColumns[params.Sort] {
+		response.NewErrorResponse(http.StatusBadRequest, "Invalid sort column", "sort must be started_at or duration_ms").Send(c)
+		return
+	}
+
+	_, meta, err := params.ApplyWithQuery(r.db(c).Model(&models.JobRun{}), &runs, "job = ?", name)
+	if err != nil {
+		response.NewErrorResponse(http.StatusInternalServerError, "Failed to retrieve job runs", err.Error()).Send(c)
+		return
+	}
+	response.NewPaginatedResponse("Job runs retrieved successfully", runs, meta).Send(c)
+}
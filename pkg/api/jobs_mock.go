@@ -0,0 +1,71 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: pkg/api/jobs.go
+
+// Package api is a generated GoMock package.
+package api
+
+import (
+	reflect "reflect"
+
+	gin "github.com/gin-gonic/gin"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockJobRepository is a mock of JobRepository interface.
+type MockJobRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockJobRepositoryMockRecorder
+}
+
+// MockJobRepositoryMockRecorder is the mock recorder for MockJobRepository.
+type MockJobRepositoryMockRecorder struct {
+	mock *MockJobRepository
+}
+
+// NewMockJobRepository creates a new mock instance.
+func NewMockJobRepository(ctrl *gomock.Controller) *MockJobRepository {
+	mock := &MockJobRepository{ctrl: ctrl}
+	mock.recorder = &MockJobRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockJobRepository) EXPECT() *MockJobRepositoryMockRecorder {
+	return m.recorder
+}
+
+// FindJobRuns mocks base method.
+func (m *MockJobRepository) FindJobRuns(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "FindJobRuns", c)
+}
+
+// FindJobRuns indicates an expected call of FindJobRuns.
+func (mr *MockJobRepositoryMockRecorder) FindJobRuns(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindJobRuns", reflect.TypeOf((*MockJobRepository)(nil).FindJobRuns), c)
+}
+
+// FindJobs mocks base method.
+func (m *MockJobRepository) FindJobs(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "FindJobs", c)
+}
+
+// FindJobs indicates an expected call of FindJobs.
+func (mr *MockJobRepositoryMockRecorder) FindJobs(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindJobs", reflect.TypeOf((*MockJobRepository)(nil).FindJobs), c)
+}
+
+// RunJob mocks base method.
+func (m *MockJobRepository) RunJob(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RunJob", c)
+}
+
+// RunJob indicates an expected call of RunJob.
+func (mr *MockJobRepositoryMockRecorder) RunJob(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunJob", reflect.TypeOf((*MockJobRepository)(nil).RunJob), c)
+}
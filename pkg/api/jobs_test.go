@@ -0,0 +1,138 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/cache"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/scheduler"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func newJobTestRouter(repo *jobRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		c.Set("username", "admin")
+		c.Next()
+	})
+	r.GET("/jobs", repo.FindJobs)
+	r.POST("/jobs/:name/run", repo.RunJob)
+	r.GET("/jobs/:name/runs", repo.FindJobRuns)
+	return r
+}
+
+func TestRunJob(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := newMockDatabase(ctrl)
+	mockCache := cache.NewMockCache(ctrl)
+
+	jobs := scheduler.New(mockCache, scheduler.NewGormRunStore(newDryRunDB(t)))
+	proceed := make(chan struct{})
+	assert.NoError(t, jobs.Register(scheduler.Job{Name: "report", Run: func(ctx context.Context) (string, error) {
+		<-proceed
+		return "done", nil
+	}}))
+	r := newJobTestRouter(NewJobRepository(mockDB, jobs))
+
+	released := make(chan struct{})
+	gomock.InOrder(
+		mockCache.EXPECT().SetNX(gomock.Any(), "scheduler:lock:report", gomock.Any(), gomock.Any()).Return(true, nil),
+		mockCache.EXPECT().SetNX(gomock.Any(), "scheduler:lock:report", gomock.Any(), gomock.Any()).Return(false, nil),
+	)
+	mockCache.EXPECT().DeleteIfValue(gomock.Any(), "scheduler:lock:report", gomock.Any()).
+		DoAndReturn(func(ctx context.Context, key string, value []byte) (bool, error) {
+			close(released)
+			return true, nil
+		})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/jobs/report/run", nil))
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	var body struct {
+		Data models.JobRun `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, models.JobRunRunning, body.Data.Status)
+	assert.Equal(t, models.JobTriggerManual, body.Data.Trigger)
+	assert.Equal(t, "admin", body.Data.TriggeredBy)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/jobs/report/run", nil))
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/jobs/missing/run", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	close(proceed)
+	select {
+	case <-released:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the job lock was not released")
+	}
+}
+
+func TestFindJobs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := newMockDatabase(ctrl)
+
+	jobs := scheduler.New(cache.NewMockCache(ctrl), nil)
+	noop := func(ctx context.Context) (string, error) { return "", nil }
+	assert.NoError(t, jobs.Register(scheduler.Job{Name: "purge-trash", Spec: "@hourly", Run: noop}))
+	assert.NoError(t, jobs.Register(scheduler.Job{Name: "report", Run: noop}))
+	r := newJobTestRouter(NewJobRepository(mockDB, jobs))
+
+	db := newDryRunDB(t)
+	db.Callback().Query().After("gorm:query").Register("test:load", func(tx *gorm.DB) {
+		if dest, ok := tx.Statement.Dest.(*[]models.JobRun); ok && tx.Statement.Vars[0] == "purge-trash" {
+			*dest = []models.JobRun{{Job: "purge-trash", Status: models.JobRunSucceeded, Output: "purged 3 rows"}}
+		}
+	})
+	mockDB.EXPECT().Model(gomock.Any()).Times(2).DoAndReturn(func(model interface{}) *gorm.DB { return db.Model(model) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/jobs", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var body struct {
+		Data []models.Job `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Len(t, body.Data, 2)
+	assert.Equal(t, "@hourly", body.Data[0].Schedule)
+	assert.NotNil(t, body.Data[0].NextRunAt)
+	assert.Equal(t, "purged 3 rows", body.Data[0].LastRun.Output)
+	assert.Nil(t, body.Data[1].NextRunAt)
+	assert.Nil(t, body.Data[1].LastRun)
+}
+
+func TestFindJobRunsRejectsUnknownJobsAndSorts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	jobs := scheduler.New(cache.NewMockCache(ctrl), nil)
+	assert.NoError(t, jobs.Register(scheduler.Job{Name: "report", Run: func(ctx context.Context) (string, error) { return "", nil }}))
+	r := newJobTestRouter(NewJobRepository(newMockDatabase(ctrl), jobs))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/jobs/missing/runs", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/jobs/report/runs?sort=output", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
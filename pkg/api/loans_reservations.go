@@ -0,0 +1,246 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/response"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/store"
+)
+
+// CreateReservation godoc
+// @Summary Reserve a book
+// @Description Join the queue for a book with no copy available. When a copy comes back it is held for the first reservation in the queue until the hold expires; check the book out to take it.
+// @Tags loans
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Produce  json
+// @Param id path string true "Book ID"
+// @Success 201 {object} models.Reservation "Successfully reserved book"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Book not found"
+// @Failure 409 {string} string "Copies available, book already borrowed or already reserved"
+// @Router /books/{id}/reservations [post]
+func (r *bookRepository) CreateReservation(c *gin.Context) {
+	var reservation models.Reservation
+	now := time.Now()
+
+	err := r.Stores.WithTx(c.Request.Context(), func(ctx context.Context, tx store.Stores) error {
+		book, err := lockedBook(tx.Books.LockBook(ctx, c.Param("id")))
+		if err != nil {
+			return err
+		}
+		user, err := findUser(ctx, tx, c.GetString("username"))
+		if err != nil {
+			return err
+		}
+
+		available := book.AvailableCopies
+		if err := r.settleReservations(ctx, tx, &book, now); err != nil {
+			return err
+		}
+		if book.AvailableCopies != available {
+			if err := r.Books.SaveAvailability(ctx, &book); err != nil {
+				return err
+			}
+		}
+		if book.AvailableCopies > 0 {
+			return &statusError{http.StatusConflict, "Copies available", errors.New("a copy is available, check the book out instead")}
+		}
+
+		borrowed, err := tx.Loans.HasActiveLoan(ctx, book.ID, user.ID)
+		if err != nil {
+			return err
+		}
+		if borrowed {
+			return &statusError{http.StatusConflict, "Book already borrowed", errors.New("you already have a copy of this book")}
+		}
+
+		reservation = models.Reservation{
+			BookID:   &book.ID,
+			UserID:   user.ID,
+			Username: user.Username,
+			Status:   models.ReservationStatusWaiting,
+		}
+		if err := tx.Loans.CreateReservation(ctx, &reservation); err != nil {
+			if errors.Is(err, store.ErrConflict) {
+				return &statusError{http.StatusConflict, "Book already reserved", errors.New("you are already in the queue for this book")}
+			}
+			return err
+		}
+		return tx.Loans.SetPosition(ctx, &reservation)
+	})
+	if err != nil {
+		sendLoanError(c, err)
+		return
+	}
+
+	response.Response{
+		StatusCode: http.StatusCreated,
+		Success:    true,
+		Message:    "Book reserved successfully",
+		Data:       reservation,
+	}.Send(c)
+}
+
+// FindReservations godoc
+// @Summary List your reservations
+// @Description Get the caller's open reservations with their place in the queue. Ready reservations hold a copy until they expire.
+// @Tags loans
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Produce json
+// @Success 200 {array} models.Reservation "Open reservations of the caller"
+// @Failure 401 {string} string "Unauthorized"
+// @Router /reservations [get]
+func (r *bookRepository) FindReservations(c *gin.Context) {
+	reservations := []models.Reservation{}
+	now := time.Now()
+
+	err := r.db(c).Model(&models.Reservation{}).
+		Where("user_id = (SELECT id FROM users WHERE username = ?) AND closed_at IS NULL", c.GetString("username")).
+		Order("created_at").
+		Find(&reservations).Error
+	if err != nil {
+		response.NewErrorResponse(http.StatusInternalServerError, "Failed to retrieve reservations", err.Error()).Send(c)
+		return
+	}
+
+	loans := r.Stores.Stores().Loans
+	for i := range reservations {
+		// Expired holds are only closed by the next write on their book
+		if reservation := &reservations[i]; reservation.Status == models.ReservationStatusReady && reservation.ExpiresAt != nil && reservation.ExpiresAt.Before(now) {
+			reservation.Status = models.ReservationStatusExpired
+		}
+		if err := loans.SetPosition(c.Request.Context(), &reservations[i]); err != nil {
+			response.NewErrorResponse(http.StatusInternalServerError, "Failed to retrieve reservations", err.Error()).Send(c)
+			return
+		}
+	}
+
+	response.NewSuccessResponse("Reservations retrieved successfully", reservations).Send(c)
+}
+
+// CancelReservation godoc
+// @Summary Cancel a reservation
+// @Description Leave the queue for a book. A copy held for the reservation goes to the next one. Admins can cancel any reservation.
+// @Tags loans
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Produce  json
+// @Param id path string true "Reservation ID"
+// @Success 204 {string} string "Successfully cancelled reservation"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Reservation not found"
+// @Router /reservations/{id} [delete]
+func (r *bookRepository) CancelReservation(c *gin.Context) {
+	var reservation models.Reservation
+	notFound := &statusError{http.StatusNotFound, "Reservation not found", errors.New("no open reservation with this ID")}
+	now := time.Now()
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		sendLoanError(c, notFound)
+		return
+	}
+
+	err = r.Stores.WithTx(c.Request.Context(), func(ctx context.Context, tx store.Stores) error {
+		var err error
+		if reservation, err = tx.Loans.GetReservation(ctx, id); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return notFound
+			}
+			return err
+		}
+		if !canManageLoans(c) {
+			user, err := findUser(ctx, tx, c.GetString("username"))
+			if err != nil {
+				return err
+			}
+			if reservation.UserID != user.ID {
+				return notFound
+			}
+		}
+
+		// Purging the book closed the reservation
+		if reservation.BookID == nil {
+			return notFound
+		}
+
+		// Reservation writes hold the book lock, so the reservation can't
+		// change once it is taken
+		book, err := lockedBook(tx.Books.LockAnyBook(ctx, reservation.BookID.String()))
+		if err != nil {
+			return err
+		}
+		if reservation, err = tx.Loans.GetReservation(ctx, id); err != nil {
+			return err
+		}
+		if !reservation.Open() {
+			return notFound
+		}
+
+		held := reservation.Status == models.ReservationStatusReady
+		err = tx.Loans.CloseReservation(ctx, &reservation, models.ReservationStatusCancelled, now)
+		if err != nil || !held {
+			return err
+		}
+
+		book.AvailableCopies++
+		if err := r.settleReservations(ctx, tx, &book, now); err != nil {
+			return err
+		}
+		return r.Books.SaveAvailability(ctx, &book)
+	})
+	if err != nil {
+		sendLoanError(c, err)
+		return
+	}
+
+	response.Response{
+		StatusCode: http.StatusNoContent,
+		Success:    true,
+		Message:    "Reservation cancelled successfully",
+		Data:       true,
+	}.Send(c)
+}
+
+// ExpireReservationHolds takes back the copies held for reservations that
+// weren't collected in time and passes them down the queue. Lending writes
+// settle holds too; this catches the books nobody borrows or returns.
+func (r *bookRepository) ExpireReservationHolds(ctx context.Context) (string, error) {
+	now := time.Now()
+
+	bookIDs, err := r.Stores.Stores().Loans.BooksWithExpiredHolds(ctx, now)
+	if err != nil {
+		return "", err
+	}
+
+	for _, bookID := range bookIDs {
+		err := r.Stores.WithTx(ctx, func(ctx context.Context, tx store.Stores) error {
+			book, err := tx.Books.LockAnyBook(ctx, bookID.String())
+			if err != nil {
+				return err
+			}
+			available := book.AvailableCopies
+			if err := r.settleReservations(ctx, tx, &book, now); err != nil {
+				return err
+			}
+			if book.AvailableCopies == available {
+				return nil
+			}
+			return r.Books.SaveAvailability(ctx, &book)
+		})
+		if err != nil {
+			return "", fmt.Errorf("book %s: %w", bookID, err)
+		}
+	}
+
+	return fmt.Sprintf("settled expired holds on %d books", len(bookIDs)), nil
+}
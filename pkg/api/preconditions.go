@@ -0,0 +1,111 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/response"
+)
+
+const (
+	// IfMatchOptional checks If-Match when the client sends it
+	IfMatchOptional = "optional"
+	// IfMatchRequired rejects writes without If-Match with 428 Precondition Required
+	IfMatchRequired = "required"
+)
+
+// versionConflictMessage describes store.ErrStale to clients
+const versionConflictMessage = "book was modified by another request"
+
+// bookETag is a strong validator for the current representation of a book
+func bookETag(book models.Book) string {
+	return `"` + strconv.FormatInt(book.Version, 10) + `"`
+}
+
+// etagMatches reports whether an If-Match header value matches etag. Weak
+// validators never match because If-Match requires strong comparison.
+func etagMatches(header string, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIfMatch enforces the If-Match precondition for a write to book. It
+// sends the error response itself and returns false when the request must stop.
+func (r *bookRepository) checkIfMatch(c *gin.Context, book models.Book) bool {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		if r.IfMatchMode == IfMatchRequired {
+			response.NewErrorResponse(http.StatusPreconditionRequired, "Precondition required", "If-Match header is required").Send(c)
+			return false
+		}
+		return true
+	}
+
+	if !etagMatches(header, bookETag(book)) {
+		response.NewErrorResponse(http.StatusPreconditionFailed, "Precondition failed", "If-Match does not match the current version "+bookETag(book)).Send(c)
+		return false
+	}
+
+	return true
+}
+
+// sendVersionConflict answers a write that lost a race against another writer
+func sendVersionConflict(c *gin.Context) {
+	status := http.StatusConflict
+	if c.GetHeader("If-Match") != "" {
+		status = http.StatusPreconditionFailed
+	}
+	response.NewErrorResponse(status, "Book was modified concurrently", versionConflictMessage).Send(c)
+}
+
+// etagMatchesWeak implements the weak comparison used by If-None-Match
+func etagMatchesWeak(header string, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified sets the validators on a GET response and evaluates
+// If-None-Match and If-Modified-Since against them. It returns true when a
+// 304 Not Modified has been sent and the handler should stop.
+func notModified(c *gin.Context, etag string, lastModified time.Time) bool {
+	if etag != "" {
+		c.Header("ETag", etag)
+	}
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	// If-None-Match takes precedence, If-Modified-Since is ignored when it is present
+	if header := c.GetHeader("If-None-Match"); header != "" {
+		if etag != "" && etagMatchesWeak(header, etag) {
+			c.AbortWithStatus(http.StatusNotModified)
+			return true
+		}
+		return false
+	}
+
+	if header := c.GetHeader("If-Modified-Since"); header != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(header)
+		if err == nil && !lastModified.Truncate(time.Second).After(since) {
+			c.AbortWithStatus(http.StatusNotModified)
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/cache"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/middleware"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/service"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEtagMatches(t *testing.T) {
+	etag := bookETag(models.Book{Version: 3})
+	assert.Equal(t, `"3"`, etag)
+
+	assert.True(t, etagMatches(`"3"`, etag))
+	assert.True(t, etagMatches(`"1", "3"`, etag))
+	assert.True(t, etagMatches(`*`, etag))
+	assert.False(t, etagMatches(`"2"`, etag))
+	assert.False(t, etagMatches(`W/"3"`, etag), "weak validators must not match If-Match")
+}
+
+func expectBookLookup(mockBooks *store.MockBookStore, book models.Book) {
+	mockBooks.EXPECT().GetBook(gomock.Any(), "1").Return(book, nil).Times(1)
+}
+
+func TestUpdateBookIfMatchMismatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBooks := store.NewMockBookStore(ctrl)
+	repo := NewBookRepository(nil, newMockUnitOfWork(ctrl, store.Stores{Books: mockBooks}), nil, nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.PUT("/book/:id", repo.UpdateBook)
+
+	expectBookLookup(mockBooks, models.Book{ID: uuid.New(), Title: "Title", Author: "Author", Version: 2})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/book/1", bytes.NewBufferString(`{"title":"New","author":"Author"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `"1"`)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+}
+
+func TestDeleteBookIfMatchRequired(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBooks := store.NewMockBookStore(ctrl)
+	repo := NewBookRepository(nil, newMockUnitOfWork(ctrl, store.Stores{Books: mockBooks}), nil, nil, nil)
+	repo.IfMatchMode = IfMatchRequired
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.DELETE("/book/:id", repo.DeleteBook)
+
+	expectBookLookup(mockBooks, models.Book{ID: uuid.New(), Title: "Title", Author: "Author", Version: 1})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodDelete, "/book/1", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+}
+
+func TestPatchBookIfMatchRequired(t *testing.T) {
+	repo := NewBookRepository(nil, nil, nil, nil, nil)
+	repo.IfMatchMode = IfMatchRequired
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.PATCH("/book/:id", repo.PatchBook)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPatch, "/book/1", bytes.NewBufferString(`{"title":"x"}`))
+	req.Header.Set("Content-Type", MergePatchContentType)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+}
+
+func TestFindBooksNotModifiedFromCache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	appCache := cache.NewMemoryCache(100)
+	repo := NewBookRepository(nil, nil, appCache, nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/books", middleware.CacheControl("public, max-age=60"), repo.FindBooks)
+
+	updatedAt := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	page, _ := service.NewBookPage([]models.Book{{Title: "Book One", Author: "Author One", UpdatedAt: updatedAt}}, updatedAt)
+	cachedData, _ := json.Marshal(page)
+	seedBooksPage(t, appCache, store.BookQuery{Limit: 10}, cachedData)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/books", nil)
+	req.Header.Set("If-None-Match", page.ETag)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, page.ETag, w.Header().Get("ETag"))
+	assert.Equal(t, "public, max-age=60", w.Header().Get("Cache-Control"))
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/books", nil)
+	req.Header.Set("If-Modified-Since", updatedAt.Format(http.TimeFormat))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, updatedAt.Format(http.TimeFormat), w.Header().Get("Last-Modified"))
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/books", nil)
+	req.Header.Set("If-Modified-Since", updatedAt.Add(-time.Hour).Format(http.TimeFormat))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, updatedAt.Format(http.TimeFormat), w.Header().Get("Last-Modified"))
+	assert.Contains(t, w.Body.String(), "Book One")
+}
+
+func TestFindBookConditionalGet(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBooks := store.NewMockBookStore(ctrl)
+	repo := NewBookRepository(nil, newMockUnitOfWork(ctrl, store.Stores{Books: mockBooks}), nil, nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/book/:id", middleware.CacheControl("public, max-age=60"), repo.FindBook)
+
+	updatedAt := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	book := models.Book{ID: uuid.New(), Title: "Title", Author: "Author", Version: 4, UpdatedAt: updatedAt}
+
+	expectBookLookup(mockBooks, book)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/book/1", nil)
+	req.Header.Set("If-None-Match", `W/"4"`)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	expectBookLookup(mockBooks, book)
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/book/1", nil)
+	req.Header.Set("If-Modified-Since", updatedAt.Format(http.TimeFormat))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	expectBookLookup(mockBooks, book)
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/book/1", nil)
+	req.Header.Set("If-None-Match", `"3"`)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"4"`, w.Header().Get("ETag"))
+	assert.Equal(t, "public, max-age=60", w.Header().Get("Cache-Control"))
+}
+
+func TestCacheControlSkipsErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/missing", middleware.CacheControl("public, max-age=60"), func(c *gin.Context) {
+		c.JSON(http.StatusNotFound, gin.H{})
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/cache"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/response"
+)
+
+// Readiness is the state of the dependencies of an instance. The instance
+// is degraded, but still serves requests, while the cache is unavailable.
+type Readiness struct {
+	Status   string       `json:"status"`
+	Database string       `json:"database"`
+	Cache    cache.Health `json:"cache"`
+}
+
+// Readiness godoc
+// @Summary Readiness check
+// @Description Report whether the instance can serve requests. The status is degraded while the cache is unavailable and requests are served straight from the database.
+// @Tags example
+// @Produce json
+// @Success 200 {object} Readiness "Ready, possibly degraded"
+// @Failure 503 {object} Readiness "The database is unavailable"
+// @Router /ready [get]
+func (r *bookRepository) Readiness(c *gin.Context) {
+	readiness := Readiness{
+		Status:   cache.HealthOK,
+		Database: cache.HealthOK,
+		Cache:    cache.Health{Status: cache.HealthOK, Breaker: cache.BreakerClosed},
+	}
+	if reporter, ok := r.Cache.(cache.HealthReporter); ok {
+		readiness.Cache = reporter.Health()
+	}
+	if readiness.Cache.Status != cache.HealthOK {
+		readiness.Status = cache.HealthDegraded
+	}
+
+	if err := r.db(c).Model(nil).Exec("SELECT 1").Error; err != nil {
+		readiness.Status = "unavailable"
+		readiness.Database = "unavailable"
+		response.Response{
+			StatusCode: http.StatusServiceUnavailable,
+			Success:    false,
+			Message:    "Database is unavailable",
+			Data:       readiness,
+			Error:      err.Error(),
+		}.Send(c)
+		return
+	}
+	response.NewSuccessResponse("Ready", readiness).Send(c)
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/cache"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestReadiness(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := newMockDatabase(ctrl)
+	mockCache := cache.NewMockCache(ctrl)
+	ctx := context.Background()
+	appCache := cache.NewBreakerCache(mockCache, 1, time.Minute)
+	repo := NewBookRepository(mockDB, nil, appCache, nil, nil)
+
+	db := newDryRunDB(t)
+	var dbErr error
+	db.Callback().Raw().After("gorm:raw").Register("test:ping", func(tx *gorm.DB) {
+		if dbErr != nil {
+			tx.AddError(dbErr)
+		}
+	})
+	mockDB.EXPECT().Model(nil).DoAndReturn(func(model interface{}) *gorm.DB { return db.Model(model) }).AnyTimes()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/ready", repo.Readiness)
+	ready := func() (int, Readiness) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ready", nil))
+		var body struct {
+			Data Readiness `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return w.Code, body.Data
+	}
+
+	status, readiness := ready()
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, cache.HealthOK, readiness.Status)
+
+	// Requests are still served while Redis is down
+	mockCache.EXPECT().Get(gomock.Any(), "key").Return(nil, errors.New("connection refused"))
+	_, _ = appCache.Get(ctx, "key")
+	status, readiness = ready()
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, cache.HealthDegraded, readiness.Status)
+	assert.Equal(t, cache.BreakerOpen, readiness.Cache.Breaker)
+	assert.Equal(t, "connection refused", readiness.Cache.LastError)
+
+	dbErr = errors.New("connection reset")
+	status, readiness = ready()
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, "unavailable", readiness.Database)
+}
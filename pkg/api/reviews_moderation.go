@@ -0,0 +1,297 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/pagination"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/response"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/store"
+)
+
+// moderationSortColumns are the columns the moderation queue can be sorted by
+var moderationSortColumns = map[string]bool{"created_at": true, "updated_at": true, "flag_count": true}
+
+// moderationStatuses are the review states the moderation queue can list
+var moderationStatuses = map[string]bool{
+	models.ReviewStatusPending:  true,
+	models.ReviewStatusFlagged:  true,
+	models.ReviewStatusApproved: true,
+	models.ReviewStatusRejected: true,
+}
+
+// screenReview sets the status a new or edited review starts in. Blocked
+// words and enough user flags hold the review for a moderator, a rejected
+// review has to be looked at again, and otherwise the review is approved
+// unless approval is required.
+func (r *bookRepository) screenReview(review *models.Review) {
+	review.ModeratedBy = ""
+	review.ModeratedAt = nil
+	review.ModerationReason = ""
+
+	switch words := r.ReviewBlockedWords.Match(review.Text); {
+	case len(words) > 0:
+		review.Status = models.ReviewStatusFlagged
+		review.ModerationReason = "contains blocked words: " + strings.Join(words, ", ")
+	case review.FlagCount > 0 && review.FlagCount >= r.ReviewFlagThreshold:
+		review.Status = models.ReviewStatusFlagged
+		review.ModerationReason = fmt.Sprintf("flagged by %d users", review.FlagCount)
+	case r.ReviewRequireApproval || review.Status == models.ReviewStatusRejected:
+		review.Status = models.ReviewStatusPending
+	default:
+		review.Status = models.ReviewStatusApproved
+	}
+}
+
+// parseReviewID reads the review_id path parameter
+func parseReviewID(c *gin.Context) (uuid.UUID, error) {
+	id, err := uuid.Parse(c.Param("review_id"))
+	if err != nil {
+		return id, &statusError{http.StatusNotFound, "Review not found", err}
+	}
+	return id, nil
+}
+
+// FlagReview godoc
+// @Summary Flag a review
+// @Description Report a review to the moderators with a reason. Each user flags a review at most once, and an approved review is pulled from the book's rating once it reaches the flag threshold.
+// @Tags reviews
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Book ID"
+// @Param review_id path string true "Review ID"
+// @Param input body models.FlagReview true "Flag"
+// @Success 201 {object} models.ReviewFlag "Successfully flagged review"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Book or review not found"
+// @Failure 409 {string} string "Review already flagged"
+// @Router /books/{id}/reviews/{review_id}/flag [post]
+func (r *bookRepository) FlagReview(c *gin.Context) {
+	var input models.FlagReview
+	var flag models.ReviewFlag
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.NewErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()).Send(c)
+		return
+	}
+
+	reviewID, err := parseReviewID(c)
+	if err != nil {
+		sendReviewError(c, err)
+		return
+	}
+
+	err = r.Stores.WithTx(c.Request.Context(), func(ctx context.Context, tx store.Stores) error {
+		book, user, err := lockReviewedBook(ctx, tx, c.Param("id"), c.GetString("username"))
+		if err != nil {
+			return err
+		}
+
+		// Only reviews the public can see can be flagged
+		review, err := tx.Reviews.GetReview(ctx, reviewID)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return err
+		}
+		visible := review.Status == models.ReviewStatusApproved || review.Status == models.ReviewStatusFlagged
+		if err != nil || review.BookID != book.ID || !visible {
+			return &statusError{http.StatusNotFound, "Review not found", errors.New("no visible review with this ID on this book")}
+		}
+		if review.UserID == user.ID {
+			return &statusError{http.StatusBadRequest, "Invalid flag", errors.New("you cannot flag your own review")}
+		}
+
+		flag = models.ReviewFlag{ReviewID: review.ID, UserID: user.ID, Username: user.Username, Reason: input.Reason}
+		if err := tx.Reviews.CreateFlag(ctx, &flag); err != nil {
+			if errors.Is(err, store.ErrConflict) {
+				return &statusError{http.StatusConflict, "Review already flagged", errors.New("you have already flagged this review")}
+			}
+			return err
+		}
+
+		// The book lock serializes flags, so the count read above is current
+		review.FlagCount++
+		columns := []string{"flag_count"}
+		pulled := review.Status == models.ReviewStatusApproved && review.FlagCount >= r.ReviewFlagThreshold
+		if pulled {
+			review.Status = models.ReviewStatusFlagged
+			review.ModerationReason = fmt.Sprintf("flagged by %d users", review.FlagCount)
+			columns = append(columns, "status", "moderation_reason")
+		}
+		if err := tx.Reviews.UpdateReview(ctx, &review, columns...); err != nil {
+			return err
+		}
+
+		if !pulled {
+			return nil
+		}
+		return r.Books.RefreshRating(ctx, &book)
+	})
+	if err != nil {
+		sendReviewError(c, err)
+		return
+	}
+
+	response.Response{
+		StatusCode: http.StatusCreated,
+		Success:    true,
+		Message:    "Review flagged successfully",
+		Data:       flag,
+	}.Send(c)
+}
+
+// FindModerationQueue godoc
+// @Summary List reviews awaiting moderation
+// @Description Get a paginated list of pending and flagged reviews with their flags, oldest first. Moderators and admins only.
+// @Tags reviews
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Produce json
+// @Param status query string false "Only reviews in this state (pending, flagged, approved or rejected)"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Param sort query string false "created_at, updated_at or flag_count" default(created_at)
+// @Param order query string false "Sort order (asc or desc)"
+// @Success 200 {array} models.Review "Reviews awaiting moderation"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Router /reviews/moderation [get]
+func (r *bookRepository) FindModerationQueue(c *gin.Context) {
+	var reviews []models.Review
+
+	params := pagination.ParseParams(c)
+	if params.Sort == "" {
+		params.Sort = "created_at"
+		if c.Query("order") == "" {
+			params.Order = "asc"
+		}
+	}
+	if !moderationSortColumns[params.Sort] {
+		response.NewErrorResponse(http.StatusBadRequest, "Invalid sort column", "sort must be created_at, updated_at or flag_count").Send(c)
+		return
+	}
+
+	statuses := []string{models.ReviewStatusPending, models.ReviewStatusFlagged}
+	if status := c.Query("status"); status != "" {
+		if !moderationStatuses[status] {
+			response.NewErrorResponse(http.StatusBadRequest, "Invalid status", "status must be pending, flagged, approved or rejected").Send(c)
+			return
+		}
+		statuses = []string{status}
+	}
+
+	if _, meta, err := params.ApplyWithQuery(r.db(c).Model(&models.Review{}).Preload("Flags"), &reviews, "status IN ?", statuses); err != nil {
+		response.NewErrorResponse(http.StatusInternalServerError, "Failed to retrieve reviews", err.Error()).Send(c)
+	} else {
+		response.NewPaginatedResponse("Reviews retrieved successfully", reviews, meta).Send(c)
+	}
+}
+
+// ApproveReview godoc
+// @Summary Approve a review
+// @Description Publish a review and count it toward the book's rating. Its flags are kept on record and the flag count starts over. Moderators and admins only.
+// @Tags reviews
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Accept  json
+// @Produce  json
+// @Param review_id path string true "Review ID"
+// @Param input body models.ModerateReview false "Moderation note"
+// @Success 200 {object} models.Review "Successfully approved review"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Review not found"
+// @Router /reviews/{review_id}/approve [post]
+func (r *bookRepository) ApproveReview(c *gin.Context) {
+	r.moderateReview(c, models.ReviewStatusApproved, "Review approved successfully")
+}
+
+// RejectReview godoc
+// @Summary Reject a review
+// @Description Hide a review and leave it out of the book's rating. The author can edit it to send it back for moderation. Moderators and admins only.
+// @Tags reviews
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Accept  json
+// @Produce  json
+// @Param review_id path string true "Review ID"
+// @Param input body models.ModerateReview false "Moderation note"
+// @Success 200 {object} models.Review "Successfully rejected review"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Review not found"
+// @Router /reviews/{review_id}/reject [post]
+func (r *bookRepository) RejectReview(c *gin.Context) {
+	r.moderateReview(c, models.ReviewStatusRejected, "Review rejected successfully")
+}
+
+// moderateReview records a moderator's decision on a review and recomputes
+// the rating of its book
+func (r *bookRepository) moderateReview(c *gin.Context, status string, message string) {
+	var input models.ModerateReview
+	var review models.Review
+
+	// The moderation note is optional, so is the body
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			response.NewErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()).Send(c)
+			return
+		}
+	}
+
+	reviewID, err := parseReviewID(c)
+	if err != nil {
+		sendReviewError(c, err)
+		return
+	}
+
+	err = r.Stores.WithTx(c.Request.Context(), func(ctx context.Context, tx store.Stores) error {
+		var err error
+		review, err = tx.Reviews.GetReview(ctx, reviewID)
+		if errors.Is(err, store.ErrNotFound) {
+			return &statusError{http.StatusNotFound, "Review not found", err}
+		}
+		if err != nil {
+			return err
+		}
+
+		book, moderator, err := lockReviewedBook(ctx, tx, review.BookID.String(), c.GetString("username"))
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		review.Status = status
+		review.ModerationReason = input.Reason
+		review.ModeratedBy = moderator.Username
+		review.ModeratedAt = &now
+		columns := []string{"status", "moderation_reason", "moderated_by", "moderated_at"}
+		if status == models.ReviewStatusApproved {
+			review.FlagCount = 0
+			columns = append(columns, "flag_count")
+		}
+		if err := tx.Reviews.UpdateReview(ctx, &review, columns...); err != nil {
+			return err
+		}
+
+		return r.Books.RefreshRating(ctx, &book)
+	})
+	if err != nil {
+		sendReviewError(c, err)
+		return
+	}
+
+	response.NewSuccessResponse(message, review).Send(c)
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/middleware"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/moderation"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScreenReview(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name            string
+		requireApproval bool
+		review          models.Review
+		status          string
+	}{
+		{"clean text", false, models.Review{Text: "A fine read"}, models.ReviewStatusApproved},
+		{"approval required", true, models.Review{Text: "A fine read"}, models.ReviewStatusPending},
+		{"blocked word", false, models.Review{Text: "Cheap CASINO bonus"}, models.ReviewStatusFlagged},
+		{"rejected review edited", false, models.Review{Text: "Better now", Status: models.ReviewStatusRejected, ModeratedBy: "mod", ModeratedAt: &now}, models.ReviewStatusPending},
+		{"flags over threshold", false, models.Review{Text: "A fine read", FlagCount: 2}, models.ReviewStatusFlagged},
+		{"flags under threshold", false, models.Review{Text: "A fine read", FlagCount: 1}, models.ReviewStatusApproved},
+	}
+
+	for _, tt := range tests {
+		repo := &bookRepository{
+			ReviewRequireApproval: tt.requireApproval,
+			ReviewFlagThreshold:   2,
+			ReviewBlockedWords:    moderation.ParseWordList("casino"),
+		}
+		review := tt.review
+		repo.screenReview(&review)
+
+		assert.Equal(t, tt.status, review.Status, tt.name)
+		assert.Empty(t, review.ModeratedBy, tt.name)
+		assert.Nil(t, review.ModeratedAt, tt.name)
+		if tt.status == models.ReviewStatusFlagged {
+			assert.NotEmpty(t, review.ModerationReason, tt.name)
+		}
+	}
+}
+
+func TestModerationRoutesRequireModerator(t *testing.T) {
+	repo := NewBookRepository(nil, nil, nil, nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	withRole := func(c *gin.Context) {
+		c.Set("role", c.GetHeader("X-Role"))
+		c.Next()
+	}
+	r.GET("/reviews/moderation", withRole, middleware.RequireRole(models.RoleModerator, models.RoleAdmin), repo.FindModerationQueue)
+
+	// An unknown status is rejected before the database is touched
+	for role, status := range map[string]int{
+		models.RoleUser:      http.StatusForbidden,
+		models.RoleModerator: http.StatusBadRequest,
+		models.RoleAdmin:     http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/reviews/moderation?status=spam", nil)
+		req.Header.Set("X-Role", role)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, status, w.Code, role)
+	}
+}
+
+func TestModerationInvalidRequests(t *testing.T) {
+	repo := NewBookRepository(nil, nil, nil, nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/books/:id/reviews/:review_id/flag", repo.FlagReview)
+	r.POST("/reviews/:review_id/approve", repo.ApproveReview)
+	r.POST("/reviews/:review_id/reject", repo.RejectReview)
+	r.GET("/reviews/moderation", repo.FindModerationQueue)
+
+	tests := []struct {
+		method string
+		url    string
+		body   string
+		status int
+	}{
+		{http.MethodPost, "/books/1/reviews/2a7f3c1e-6b4d-4f6a-9d0e-1c2b3a4d5e6f/flag", `{}`, http.StatusBadRequest},
+		{http.MethodPost, "/books/1/reviews/not-a-uuid/flag", `{"reason":"spam"}`, http.StatusNotFound},
+		{http.MethodPost, "/reviews/not-a-uuid/approve", ``, http.StatusNotFound},
+		{http.MethodPost, "/reviews/2a7f3c1e-6b4d-4f6a-9d0e-1c2b3a4d5e6f/reject", `{"reason":1}`, http.StatusBadRequest},
+		{http.MethodGet, "/reviews/moderation?sort=rating", ``, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(tt.method, tt.url, bytes.NewBufferString(tt.body)))
+		assert.Equal(t, tt.status, w.Code, tt.url)
+	}
+}
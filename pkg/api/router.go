@@ -1,13 +1,17 @@
 package api
 
 import (
-	"context"
 	"time"
 
 	"github.com/kev1nandreas/go-rest-api-template/env"
 	"github.com/kev1nandreas/go-rest-api-template/pkg/cache"
 	"github.com/kev1nandreas/go-rest-api-template/pkg/database"
 	"github.com/kev1nandreas/go-rest-api-template/pkg/middleware"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/scheduler"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/search"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/storage"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/store"
 
 	docs "github.com/kev1nandreas/go-rest-api-template/docs"
 
@@ -27,10 +31,15 @@ func ContextMiddleware(bookRepository BookRepository) gin.HandlerFunc {
 	}
 }
 
-func NewRouter(logger *zap.Logger, mongoCollection *mongo.Collection, db database.Database, redisClient cache.Cache, ctx *context.Context) *gin.Engine {
+func NewRouter(logger *zap.Logger, mongoCollection *mongo.Collection, db database.Database, stores store.UnitOfWork, appCache cache.Cache, searcher search.Searcher, blobs storage.BlobStore, jobs *scheduler.Scheduler) *gin.Engine {
 	isLogging := env.GetEnvBool("APP_MONGO_LOGGING", false)
-	bookRepository := NewBookRepository(db, redisClient, ctx)
-	userRepository := NewUserRepository(db, ctx)
+	booksCacheControl := env.GetEnvString("CACHE_CONTROL_BOOKS", "no-cache")
+	bookCacheControl := env.GetEnvString("CACHE_CONTROL_BOOK", "no-cache")
+	searchCacheControl := env.GetEnvString("CACHE_CONTROL_BOOKS_SEARCH", "no-cache")
+	bookRepository := NewBookRepository(db, stores, appCache, searcher, blobs)
+	userRepository := NewUserRepository(stores)
+	authorRepository := NewAuthorRepository(db, stores)
+	jobRepository := NewJobRepository(db, jobs)
 
 	r := gin.Default()
 	r.Use(ContextMiddleware(bookRepository))
@@ -54,11 +63,74 @@ func NewRouter(logger *zap.Logger, mongoCollection *mongo.Collection, db databas
 	v1 := r.Group("/api/v1")
 	{
 		v1.GET("/", bookRepository.Healthcheck)
-		v1.GET("/books", middleware.APIKeyAuth(), bookRepository.FindBooks)
+		v1.GET("/ready", bookRepository.Readiness)
+		v1.GET("/books", middleware.APIKeyAuth(), middleware.CacheControl(booksCacheControl), bookRepository.FindBooks)
+		v1.GET("/books/search", middleware.APIKeyAuth(), middleware.CacheControl(searchCacheControl), bookRepository.SearchBooks)
 		v1.POST("/books", middleware.APIKeyAuth(), middleware.JWTAuth(), bookRepository.CreateBook)
-		v1.GET("/books/:id", middleware.APIKeyAuth(), bookRepository.FindBook)
+		v1.GET("/books/:id", middleware.APIKeyAuth(), middleware.CacheControl(bookCacheControl), bookRepository.FindBook)
+		v1.GET("/books/isbn/:isbn", middleware.APIKeyAuth(), middleware.CacheControl(bookCacheControl), bookRepository.FindBookByISBN)
 		v1.PUT("/books/:id", middleware.APIKeyAuth(), bookRepository.UpdateBook)
+		v1.PATCH("/books/:id", middleware.APIKeyAuth(), bookRepository.PatchBook)
 		v1.DELETE("/books/:id", middleware.APIKeyAuth(), bookRepository.DeleteBook)
+		v1.PUT("/books/:id/cover", middleware.APIKeyAuth(), bookRepository.UploadCover)
+		v1.DELETE("/books/:id/cover", middleware.APIKeyAuth(), bookRepository.DeleteCover)
+		v1.GET("/blobs/*key", bookRepository.ServeBlob)
+
+		v1.GET("/books/:id/files", middleware.APIKeyAuth(), middleware.JWTAuth(), bookRepository.FindBookFiles)
+		v1.PUT("/books/:id/files/:format", middleware.APIKeyAuth(), middleware.JWTAuth(), middleware.RequireRole(models.RoleAdmin), bookRepository.UploadBookFile)
+		v1.DELETE("/books/:id/files/:format", middleware.APIKeyAuth(), middleware.JWTAuth(), middleware.RequireRole(models.RoleAdmin), bookRepository.DeleteBookFile)
+		v1.POST("/books/:id/files/:format/link", middleware.APIKeyAuth(), middleware.JWTAuth(), bookRepository.CreateBookFileLink)
+		v1.GET("/files/:file_id", bookRepository.DownloadBookFile)
+		v1.HEAD("/files/:file_id", bookRepository.DownloadBookFile)
+
+		v1.PUT("/books/:id/inventory", middleware.APIKeyAuth(), middleware.JWTAuth(), middleware.RequireRole(models.RoleAdmin), bookRepository.UpdateInventory)
+		v1.POST("/books/:id/checkout", middleware.APIKeyAuth(), middleware.JWTAuth(), bookRepository.CheckoutBook)
+		v1.POST("/books/:id/reservations", middleware.APIKeyAuth(), middleware.JWTAuth(), bookRepository.CreateReservation)
+		v1.GET("/loans", middleware.APIKeyAuth(), middleware.JWTAuth(), bookRepository.FindLoans)
+		v1.POST("/loans/:id/return", middleware.APIKeyAuth(), middleware.JWTAuth(), bookRepository.ReturnLoan)
+		v1.POST("/loans/:id/renew", middleware.APIKeyAuth(), middleware.JWTAuth(), bookRepository.RenewLoan)
+		v1.GET("/reservations", middleware.APIKeyAuth(), middleware.JWTAuth(), bookRepository.FindReservations)
+		v1.DELETE("/reservations/:id", middleware.APIKeyAuth(), middleware.JWTAuth(), bookRepository.CancelReservation)
+		v1.GET("/users/:id/loans", middleware.APIKeyAuth(), middleware.JWTAuth(), middleware.RequireRole(models.RoleAdmin), bookRepository.FindUserLoans)
+		v1.PUT("/users/:id/role", middleware.APIKeyAuth(), middleware.JWTAuth(), middleware.RequireRole(models.RoleAdmin), userRepository.SetRoleHandler)
+
+		v1.POST("/books/bulk", middleware.APIKeyAuth(), middleware.JWTAuth(), bookRepository.CreateBooks)
+		v1.PATCH("/books/bulk", middleware.APIKeyAuth(), middleware.JWTAuth(), bookRepository.UpdateBooks)
+		v1.DELETE("/books/bulk", middleware.APIKeyAuth(), middleware.JWTAuth(), bookRepository.DeleteBooks)
+
+		v1.GET("/books/:id/authors", middleware.APIKeyAuth(), bookRepository.FindBookAuthors)
+		v1.PUT("/books/:id/authors", middleware.APIKeyAuth(), bookRepository.SetBookAuthors)
+
+		v1.GET("/books/:id/reviews", middleware.APIKeyAuth(), bookRepository.FindReviews)
+		v1.POST("/books/:id/reviews", middleware.APIKeyAuth(), middleware.JWTAuth(), bookRepository.CreateReview)
+		v1.PUT("/books/:id/reviews", middleware.APIKeyAuth(), middleware.JWTAuth(), bookRepository.UpdateReview)
+		v1.DELETE("/books/:id/reviews", middleware.APIKeyAuth(), middleware.JWTAuth(), bookRepository.DeleteReview)
+		v1.POST("/books/:id/reviews/:review_id/flag", middleware.APIKeyAuth(), middleware.JWTAuth(), bookRepository.FlagReview)
+
+		v1.GET("/reviews/moderation", middleware.APIKeyAuth(), middleware.JWTAuth(), middleware.RequireRole(models.RoleModerator, models.RoleAdmin), bookRepository.FindModerationQueue)
+		v1.POST("/reviews/:review_id/approve", middleware.APIKeyAuth(), middleware.JWTAuth(), middleware.RequireRole(models.RoleModerator, models.RoleAdmin), bookRepository.ApproveReview)
+		v1.POST("/reviews/:review_id/reject", middleware.APIKeyAuth(), middleware.JWTAuth(), middleware.RequireRole(models.RoleModerator, models.RoleAdmin), bookRepository.RejectReview)
+
+		v1.GET("/authors", middleware.APIKeyAuth(), authorRepository.FindAuthors)
+		v1.POST("/authors", middleware.APIKeyAuth(), middleware.JWTAuth(), authorRepository.CreateAuthor)
+		v1.GET("/authors/:id", middleware.APIKeyAuth(), authorRepository.FindAuthor)
+		v1.PUT("/authors/:id", middleware.APIKeyAuth(), authorRepository.UpdateAuthor)
+		v1.DELETE("/authors/:id", middleware.APIKeyAuth(), authorRepository.DeleteAuthor)
+		v1.GET("/authors/:id/books", middleware.APIKeyAuth(), authorRepository.FindAuthorBooks)
+
+		v1.GET("/books/export", middleware.APIKeyAuth(), bookRepository.ExportBooks)
+		v1.POST("/books/import", middleware.APIKeyAuth(), middleware.JWTAuth(), bookRepository.ImportBooks)
+		v1.GET("/books/import/jobs/:job_id", middleware.APIKeyAuth(), middleware.JWTAuth(), bookRepository.FindImportJob)
+
+		v1.GET("/books/trash", middleware.APIKeyAuth(), middleware.JWTAuth(), middleware.RequireRole(models.RoleAdmin), bookRepository.TrashBooks)
+		v1.POST("/books/:id/restore", middleware.APIKeyAuth(), middleware.JWTAuth(), middleware.RequireRole(models.RoleAdmin), bookRepository.RestoreBook)
+		v1.DELETE("/books/:id/purge", middleware.APIKeyAuth(), middleware.JWTAuth(), middleware.RequireRole(models.RoleAdmin), bookRepository.PurgeBook)
+
+		v1.GET("/jobs", middleware.APIKeyAuth(), middleware.JWTAuth(), middleware.RequireRole(models.RoleAdmin), jobRepository.FindJobs)
+		v1.POST("/jobs/:name/run", middleware.APIKeyAuth(), middleware.JWTAuth(), middleware.RequireRole(models.RoleAdmin), jobRepository.RunJob)
+		v1.GET("/jobs/:name/runs", middleware.APIKeyAuth(), middleware.JWTAuth(), middleware.RequireRole(models.RoleAdmin), jobRepository.FindJobRuns)
+
+		v1.GET("/cache/stats", middleware.APIKeyAuth(), middleware.JWTAuth(), middleware.RequireRole(models.RoleAdmin), bookRepository.FindCacheStats)
 
 		v1.POST("/login", middleware.APIKeyAuth(), userRepository.LoginHandler)
 		v1.POST("/register", middleware.APIKeyAuth(), userRepository.RegisterHandler)
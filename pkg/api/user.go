@@ -1,37 +1,32 @@
 package api
 
 import (
-	"context"
 	"errors"
-	"fmt"
 	"net/http"
 
-	"github.com/kev1nandreas/go-rest-api-template/pkg/response"
-	"github.com/kev1nandreas/go-rest-api-template/pkg/auth"
-	"github.com/kev1nandreas/go-rest-api-template/pkg/database"
 	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
-
-	"gorm.io/gorm"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/response"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/service"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/store"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/google/uuid"
 )
 
 type UserRepository interface {
 	LoginHandler(c *gin.Context)
 	RegisterHandler(c *gin.Context)
+	SetRoleHandler(c *gin.Context)
 }
 
-// bookRepository holds shared resources like database and Redis client
+// userRepository serves the account endpoints on top of the user service
 type userRepository struct {
-	DB  database.Database
-	Ctx *context.Context
+	Users *service.UserService
 }
 
-func NewUserRepository(db database.Database, ctx *context.Context) *userRepository {
+func NewUserRepository(stores store.UnitOfWork) *userRepository {
 	return &userRepository{
-		DB:  db,
-		Ctx: ctx,
+		Users: service.NewUserService(stores),
 	}
 }
 
@@ -52,35 +47,20 @@ func NewUserRepository(db database.Database, ctx *context.Context) *userReposito
 // @Failure 500 {string} string "Internal Server Error"
 // @Router /login [post]
 func (r *userRepository) LoginHandler(c *gin.Context) {
-	var incomingUser models.User
-	var dbUser models.User
+	var user models.LoginUser
 
 	// Get JSON body
-	if err := c.ShouldBindJSON(&incomingUser); err != nil {
+	if err := c.ShouldBindJSON(&user); err != nil {
 		response.NewErrorResponse(http.StatusBadRequest, "Bad Request", err.Error()).Send(c)
 		return
 	}
 
-	// Fetch the user from the database
-	if err := r.DB.Where("username = ?", incomingUser.Username).First(&dbUser).Error(); err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			response.NewErrorResponse(http.StatusUnauthorized, "Invalid username or password", "User not found").Send(c)
-		} else {
-			response.NewErrorResponse(http.StatusInternalServerError, "Internal Server Error", err.Error()).Send(c)
-		}
-		return
-	}
-
-	// Verify password
-	if err := bcrypt.CompareHashAndPassword([]byte(dbUser.Password), []byte(incomingUser.Password)); err != nil {
-		response.NewErrorResponse(http.StatusUnauthorized, "Invalid username or password", "Password verification failed").Send(c)
+	token, err := r.Users.Login(c.Request.Context(), user)
+	if errors.Is(err, service.ErrInvalidCredentials) {
+		response.NewErrorResponse(http.StatusUnauthorized, "Invalid username or password", err.Error()).Send(c)
 		return
-	}
-
-	// Generate JWT token
-	token, err := auth.GenerateToken(dbUser.Username)
-	if err != nil {
-		response.NewErrorResponse(http.StatusInternalServerError, "Error generating token", err.Error()).Send(c)
+	} else if err != nil {
+		response.NewErrorResponse(http.StatusInternalServerError, "Internal Server Error", err.Error()).Send(c)
 		return
 	}
 
@@ -98,6 +78,7 @@ func (r *userRepository) LoginHandler(c *gin.Context) {
 // @Param   user     body    models.LoginUser     true        "User registration object"
 // @Success 201 {string} string	"Successfully registered"
 // @Failure 400 {string} string "Bad Request"
+// @Failure 409 {string} string "Username taken"
 // @Failure 500 {string} string "Internal Server Error"
 // @Router /register [post]
 func (r *userRepository) RegisterHandler(c *gin.Context) {
@@ -108,19 +89,12 @@ func (r *userRepository) RegisterHandler(c *gin.Context) {
 		return
 	}
 
-	// Hash the password
-	hashedPassword, err := auth.HashPassword(user.Password)
-	if err != nil {
-		response.NewErrorResponse(http.StatusInternalServerError, "Could not hash password", err.Error()).Send(c)
-		return
-	}
-
-	// Create new user
-	newUser := models.User{Username: user.Username, Password: hashedPassword}
-
-	// Save the user to the database
-	if err := r.DB.Create(&newUser).Error; err != nil {
-		response.NewErrorResponse(http.StatusInternalServerError, "Could not save user", fmt.Sprintf("%v", err)).Send(c)
+	if _, err := r.Users.Register(c.Request.Context(), user); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			response.NewErrorResponse(http.StatusConflict, "Username taken", "a user named "+user.Username+" already exists").Send(c)
+			return
+		}
+		response.NewErrorResponse(http.StatusInternalServerError, "Could not save user", err.Error()).Send(c)
 		return
 	}
 
@@ -131,3 +105,44 @@ func (r *userRepository) RegisterHandler(c *gin.Context) {
 		Data:       nil,
 	}.Send(c)
 }
+
+// SetRoleHandler godoc
+// @Summary Set the role of a user
+// @Description Make a user a moderator or an admin, or demote them. The new role applies from the user's next login. Admins only.
+// @Tags user
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Accept  json
+// @Produce  json
+// @Param id path string true "User ID"
+// @Param input body models.SetUserRole true "New role"
+// @Success 200 {string} string "Role updated"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "User not found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /users/{id}/role [put]
+func (r *userRepository) SetRoleHandler(c *gin.Context) {
+	var input models.SetUserRole
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.NewErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()).Send(c)
+		return
+	}
+	if _, err := uuid.Parse(c.Param("id")); err != nil {
+		response.NewErrorResponse(http.StatusNotFound, "User not found", err.Error()).Send(c)
+		return
+	}
+
+	user, err := r.Users.SetRole(c.Request.Context(), c.Param("id"), input.Role)
+	if errors.Is(err, store.ErrNotFound) {
+		response.NewErrorResponse(http.StatusNotFound, "User not found", err.Error()).Send(c)
+		return
+	} else if err != nil {
+		response.NewErrorResponse(http.StatusInternalServerError, "Could not update role", err.Error()).Send(c)
+		return
+	}
+
+	response.NewSuccessResponse("Role updated", gin.H{"id": user.ID, "username": user.Username, "role": user.Role}).Send(c)
+}
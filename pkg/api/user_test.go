@@ -1 +1,123 @@
 package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/auth"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/cache"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/middleware"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/store"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestSetRoleHandlerInvalidRequests(t *testing.T) {
+	repo := NewUserRepository(nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.PUT("/users/:id/role", repo.SetRoleHandler)
+
+	tests := []struct {
+		url    string
+		body   string
+		status int
+	}{
+		{"/users/" + uuid.NewString() + "/role", `{"role":"owner"}`, http.StatusBadRequest},
+		{"/users/" + uuid.NewString() + "/role", `{}`, http.StatusBadRequest},
+		{"/users/not-a-uuid/role", `{"role":"moderator"}`, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, tt.url, bytes.NewBufferString(tt.body)))
+		assert.Equal(t, tt.status, w.Code, tt.body)
+	}
+}
+
+func TestModeratorCanApproveReviews(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	users := store.NewMockUserStore(ctrl)
+	userRepo := NewUserRepository(newMockUnitOfWork(ctrl, store.Stores{Users: users}))
+
+	var user models.User
+	db := newDryRunTxDB(t)
+	db.Callback().Query().After("gorm:query").Register("test:moderation", func(tx *gorm.DB) {
+		switch dest := tx.Statement.Dest.(type) {
+		case *models.Review:
+			*dest = models.Review{ID: uuid.New(), BookID: uuid.New(), Status: models.ReviewStatusFlagged, FlagCount: 3}
+		case *models.Book:
+			dest.ID = uuid.New()
+		case *models.User:
+			*dest = user
+		}
+	})
+	bookRepo := NewBookRepository(nil, store.NewGormUnitOfWork(db), cache.NewMemoryCache(100), nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.PUT("/users/:id/role", middleware.JWTAuth(), middleware.RequireRole(models.RoleAdmin), userRepo.SetRoleHandler)
+	r.POST("/login", userRepo.LoginHandler)
+	r.POST("/reviews/:review_id/approve", middleware.JWTAuth(), middleware.RequireRole(models.RoleModerator, models.RoleAdmin), bookRepo.ApproveReview)
+
+	send := func(method, url, token, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	hashed, err := auth.HashPassword("secret")
+	assert.NoError(t, err)
+	user = models.User{ID: uuid.New(), Username: "mod", Password: hashed, Role: models.RoleUser}
+	reviewURL := "/reviews/" + uuid.NewString() + "/approve"
+
+	// Only admins grant roles
+	userToken, err := auth.GenerateToken(user.Username, user.Role)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, send(http.MethodPut, "/users/"+user.ID.String()+"/role", userToken, `{"role":"moderator"}`).Code)
+	assert.Equal(t, http.StatusForbidden, send(http.MethodPost, reviewURL, userToken, "").Code)
+
+	users.EXPECT().SetUserRole(gomock.Any(), user.ID.String(), models.RoleModerator).DoAndReturn(func(ctx context.Context, id string, role string) (models.User, error) {
+		user.Role = role
+		return user, nil
+	})
+	adminToken, err := auth.GenerateToken("admin", models.RoleAdmin)
+	assert.NoError(t, err)
+	w := send(http.MethodPut, "/users/"+user.ID.String()+"/role", adminToken, `{"role":"moderator"}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NotContains(t, w.Body.String(), "password")
+
+	// The new role applies from the next login
+	users.EXPECT().GetUserByUsername(gomock.Any(), user.Username).DoAndReturn(func(ctx context.Context, username string) (models.User, error) {
+		return user, nil
+	})
+	w = send(http.MethodPost, "/login", "", `{"username":"mod","password":"secret"}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var login struct {
+		Data struct{ Token string }
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &login))
+
+	w = send(http.MethodPost, reviewURL, login.Data.Token, "")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var approved struct{ Data models.Review }
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &approved))
+	assert.Equal(t, models.ReviewStatusApproved, approved.Data.Status)
+	assert.Equal(t, "mod", approved.Data.ModeratedBy)
+}
@@ -13,6 +13,7 @@ import (
 // Claims struct to be encoded to JWT
 type Claims struct {
 	Username string `json:"username"`
+	Role     string `json:"role"`
 	jwt.StandardClaims
 }
 
@@ -23,15 +24,19 @@ func HashPassword(password string) (string, error) {
 	return string(bytes), err
 }
 
-func GenerateToken(username string) (string, error) {
+func GenerateToken(username string, role string) (string, error) {
 	// The expiration time after which the token will be invalid.
 	expirationTime := time.Now().Add(5 * time.Minute).Unix()
 
-	// Create the JWT claims, which includes the username and expiration time
-	claims := &jwt.StandardClaims{
-		// In JWT, the expiry time is expressed as unix milliseconds
-		ExpiresAt: expirationTime,
-		Issuer:    username,
+	// Create the JWT claims, which includes the username, role and expiration time
+	claims := &Claims{
+		Username: username,
+		Role:     role,
+		StandardClaims: jwt.StandardClaims{
+			// In JWT, the expiry time is expressed as unix milliseconds
+			ExpiresAt: expirationTime,
+			Issuer:    username,
+		},
 	}
 
 	// Declare the token with the algorithm used for signing, and the claims
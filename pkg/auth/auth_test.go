@@ -1,8 +1,11 @@
 package auth
 
 import (
+	"strconv"
 	"testing"
+	"time"
 
+	"github.com/golang-jwt/jwt"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -15,9 +18,17 @@ func TestHashPassword(t *testing.T) {
 
 func TestGenerateToken(t *testing.T) {
 	user := "chud"
-	token, err := GenerateToken(user)
+	token, err := GenerateToken(user, "admin")
 	assert.Nil(t, err)
 	assert.NotEmpty(t, token)
+
+	claims := &Claims{}
+	_, err = jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+		return JwtKey, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, user, claims.Username)
+	assert.Equal(t, "admin", claims.Role)
 }
 
 func TestGenerateRandomKey(t *testing.T) {
@@ -25,3 +36,16 @@ func TestGenerateRandomKey(t *testing.T) {
 	assert.NotEmpty(t, randomKey)
 	assert.Len(t, randomKey, 44)
 }
+
+func TestSignResource(t *testing.T) {
+	now := time.Now()
+	expires := now.Add(time.Minute)
+	unix := strconv.FormatInt(expires.Unix(), 10)
+	signature := SignResource("file:1", expires)
+
+	assert.NoError(t, VerifyResource("file:1", unix, signature, now))
+	assert.ErrorIs(t, VerifyResource("file:2", unix, signature, now), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifyResource("file:1", strconv.FormatInt(expires.Unix()+60, 10), signature, now), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifyResource("file:1", "soon", signature, now), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifyResource("file:1", unix, signature, now.Add(2*time.Minute)), ErrLinkExpired)
+}
@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/kev1nandreas/go-rest-api-template/env"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrLinkExpired      = errors.New("link has expired")
+)
+
+// URLSigningKey signs the short-lived links that grant access to a resource
+// without the API key or a JWT. It defaults to the JWT key.
+var URLSigningKey = []byte(env.GetEnvString("URL_SIGNING_KEY", string(JwtKey)))
+
+// SignResource returns the signature granting access to resource until expires
+func SignResource(resource string, expires time.Time) string {
+	mac := hmac.New(sha256.New, URLSigningKey)
+	mac.Write([]byte(resource + "\n" + strconv.FormatInt(expires.Unix(), 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyResource checks a signature made by SignResource. expires is the
+// Unix time the link carries.
+func VerifyResource(resource string, expires string, signature string, now time.Time) error {
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	expected := SignResource(resource, time.Unix(unix, 0))
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	if now.Unix() > unix {
+		return ErrLinkExpired
+	}
+	return nil
+}
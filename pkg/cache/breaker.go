@@ -0,0 +1,319 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// States of a circuit breaker
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// Health statuses of a cache
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+)
+
+// ErrUnavailable is returned without calling the backend while its circuit
+// breaker is open
+var ErrUnavailable = errors.New("cache unavailable")
+
+// Health describes whether a cache can reach its backend. Failures counts
+// the failed calls, Trips how often the breaker opened and Skipped the calls
+// that failed fast while it was open.
+type Health struct {
+	Status        string     `json:"status"`
+	Breaker       string     `json:"breaker"`
+	Failures      int64      `json:"failures"`
+	Trips         int64      `json:"trips"`
+	Skipped       int64      `json:"skipped"`
+	LastError     string     `json:"last_error,omitempty"`
+	DegradedSince *time.Time `json:"degraded_since,omitempty"`
+}
+
+// HealthReporter is implemented by the caches that track their backend
+type HealthReporter interface {
+	Health() Health
+}
+
+// BreakerCache fails fast while its backend is failing, so callers can fall
+// back to the source of the data without waiting for timeouts. After
+// threshold consecutive failures the breaker opens and every call fails
+// with ErrUnavailable for cooldown; then a single call probes the backend,
+// and closes the breaker if it succeeds or opens it again if it fails.
+//
+// Tags and keys that couldn't be invalidated are invalidated before the next
+// call goes through, so entries written before an outage aren't served after
+// it.
+type BreakerCache struct {
+	next      Cache
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu          sync.Mutex
+	state       string
+	consecutive int
+	openedAt    time.Time
+	retryAt     time.Time
+	lastErr     error
+	failures    int64
+	trips       int64
+	skipped     int64
+	pendingTags map[string]bool
+	pendingKeys map[string]bool
+	replaying   bool
+}
+
+func NewBreakerCache(next Cache, threshold int, cooldown time.Duration) *BreakerCache {
+	if threshold <= 0 {
+		threshold = 1
+	}
+	return &BreakerCache{
+		next:        next,
+		threshold:   threshold,
+		cooldown:    cooldown,
+		now:         time.Now,
+		state:       BreakerClosed,
+		pendingTags: map[string]bool{},
+		pendingKeys: map[string]bool{},
+	}
+}
+
+// allow reports whether a call may go to the backend, turning an open
+// breaker half-open once the cooldown is over
+func (b *BreakerCache) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerClosed:
+		return true
+	case BreakerOpen:
+		if !b.now().Before(b.retryAt) {
+			b.state = BreakerHalfOpen
+			return true
+		}
+	}
+	b.skipped++
+	return false
+}
+
+// failed records a failed call. Calls cancelled by their caller say nothing
+// about the backend.
+func (b *BreakerCache) failed(err error) {
+	if errors.Is(err, context.Canceled) {
+		b.mu.Lock()
+		if b.state == BreakerHalfOpen {
+			b.state = BreakerOpen
+		}
+		b.mu.Unlock()
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.lastErr = err
+	b.consecutive++
+	switch {
+	case b.state == BreakerHalfOpen:
+		b.state = BreakerOpen
+		b.retryAt = b.now().Add(b.cooldown)
+	case b.state == BreakerClosed && b.consecutive >= b.threshold:
+		b.state = BreakerOpen
+		b.trips++
+		b.openedAt = b.now()
+		b.retryAt = b.openedAt.Add(b.cooldown)
+		log.Printf("Cache backend failed %d times in a row, skipping it for %s: %v", b.consecutive, b.cooldown, err)
+	}
+}
+
+// succeeded records a successful call, which closes the breaker
+func (b *BreakerCache) succeeded() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.consecutive = 0
+	if b.state != BreakerClosed {
+		log.Printf("Cache backend recovered after %s, %d calls skipped", b.now().Sub(b.openedAt).Round(time.Second), b.skipped)
+		b.state = BreakerClosed
+	}
+}
+
+// replay invalidates the tags and keys that couldn't be invalidated before
+func (b *BreakerCache) replay(ctx context.Context) error {
+	b.mu.Lock()
+	if b.replaying || len(b.pendingTags)+len(b.pendingKeys) == 0 {
+		b.mu.Unlock()
+		return nil
+	}
+	b.replaying = true
+	tags := keysOf(b.pendingTags)
+	keys := keysOf(b.pendingKeys)
+	b.mu.Unlock()
+
+	var err error
+	if len(tags) > 0 {
+		err = b.next.InvalidateTags(ctx, tags...)
+	}
+	if err == nil && len(keys) > 0 {
+		err = b.next.Delete(ctx, keys...)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.replaying = false
+	if err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		delete(b.pendingTags, tag)
+	}
+	for _, key := range keys {
+		delete(b.pendingKeys, key)
+	}
+	return nil
+}
+
+// do runs call on the backend unless the breaker is open. Pending
+// invalidations are replayed first, so a probe can't read entries they
+// retire. Misses are successful calls.
+func (b *BreakerCache) do(ctx context.Context, call func() error) error {
+	if !b.allow() {
+		return ErrUnavailable
+	}
+	err := b.replay(ctx)
+	if err == nil {
+		err = call()
+	}
+	if err != nil && !errors.Is(err, ErrMiss) {
+		b.failed(err)
+		return err
+	}
+	b.succeeded()
+	return err
+}
+
+func (b *BreakerCache) Get(ctx context.Context, key string) ([]byte, error) {
+	var value []byte
+	err := b.do(ctx, func() (err error) {
+		value, err = b.next.Get(ctx, key)
+		return err
+	})
+	return value, err
+}
+
+func (b *BreakerCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	err := b.do(ctx, func() error {
+		return b.next.Set(ctx, key, value, ttl)
+	})
+	if err == nil {
+		b.written(key)
+	}
+	return err
+}
+
+func (b *BreakerCache) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	var ok bool
+	err := b.do(ctx, func() (err error) {
+		ok, err = b.next.SetNX(ctx, key, value, ttl)
+		return err
+	})
+	if ok {
+		b.written(key)
+	}
+	return ok, err
+}
+
+// written drops the pending delete of a key that was written since, so it
+// isn't replayed over the new value
+func (b *BreakerCache) written(key string) {
+	b.mu.Lock()
+	delete(b.pendingKeys, key)
+	b.mu.Unlock()
+}
+
+func (b *BreakerCache) Delete(ctx context.Context, keys ...string) error {
+	err := b.do(ctx, func() error {
+		return b.next.Delete(ctx, keys...)
+	})
+	if err != nil {
+		b.mu.Lock()
+		for _, key := range keys {
+			b.pendingKeys[key] = true
+		}
+		b.mu.Unlock()
+	}
+	return err
+}
+
+func (b *BreakerCache) DeleteIfValue(ctx context.Context, key string, value []byte) (bool, error) {
+	var deleted bool
+	err := b.do(ctx, func() (err error) {
+		deleted, err = b.next.DeleteIfValue(ctx, key, value)
+		return err
+	})
+	return deleted, err
+}
+
+func (b *BreakerCache) TagKey(ctx context.Context, key string, tags ...string) (string, error) {
+	var tagged string
+	err := b.do(ctx, func() (err error) {
+		tagged, err = b.next.TagKey(ctx, key, tags...)
+		return err
+	})
+	return tagged, err
+}
+
+func (b *BreakerCache) InvalidateTags(ctx context.Context, tags ...string) error {
+	err := b.do(ctx, func() error {
+		return b.next.InvalidateTags(ctx, tags...)
+	})
+	if err != nil {
+		b.mu.Lock()
+		for _, tag := range tags {
+			b.pendingTags[tag] = true
+		}
+		b.mu.Unlock()
+	}
+	return err
+}
+
+func (b *BreakerCache) Health() Health {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	health := Health{
+		Status:   HealthOK,
+		Breaker:  b.state,
+		Failures: b.failures,
+		Trips:    b.trips,
+		Skipped:  b.skipped,
+	}
+	if b.lastErr != nil {
+		health.LastError = b.lastErr.Error()
+	}
+	if b.state != BreakerClosed {
+		health.Status = HealthDegraded
+		since := b.openedAt
+		health.DegradedSince = &since
+	}
+	return health
+}
+
+func keysOf(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	return keys
+}
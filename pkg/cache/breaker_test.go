@@ -0,0 +1,132 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyCache fails every call while down
+type flakyCache struct {
+	*MemoryCache
+	down  bool
+	calls int
+}
+
+func (c *flakyCache) check() error {
+	c.calls++
+	if c.down {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func (c *flakyCache) Get(ctx context.Context, key string) ([]byte, error) {
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+	return c.MemoryCache.Get(ctx, key)
+}
+
+func (c *flakyCache) TagKey(ctx context.Context, key string, tags ...string) (string, error) {
+	if err := c.check(); err != nil {
+		return "", err
+	}
+	return c.MemoryCache.TagKey(ctx, key, tags...)
+}
+
+func (c *flakyCache) InvalidateTags(ctx context.Context, tags ...string) error {
+	if err := c.check(); err != nil {
+		return err
+	}
+	return c.MemoryCache.InvalidateTags(ctx, tags...)
+}
+
+func TestBreakerCacheOpensAndRecovers(t *testing.T) {
+	ctx := context.Background()
+	backend := &flakyCache{MemoryCache: NewMemoryCache(10)}
+	b := NewBreakerCache(backend, 3, 10*time.Second)
+	now := time.Date(2024, time.May, 1, 8, 0, 0, 0, time.UTC)
+	b.now = func() time.Time { return now }
+
+	// Misses are not failures
+	_, err := b.Get(ctx, "missing")
+	assert.ErrorIs(t, err, ErrMiss)
+	assert.Equal(t, HealthOK, b.Health().Status)
+
+	backend.down = true
+	for i := 0; i < 3; i++ {
+		_, err = b.Get(ctx, "key")
+		assert.EqualError(t, err, "connection refused")
+	}
+	health := b.Health()
+	assert.Equal(t, HealthDegraded, health.Status)
+	assert.Equal(t, BreakerOpen, health.Breaker)
+	assert.Equal(t, int64(1), health.Trips)
+	assert.Equal(t, now, *health.DegradedSince)
+
+	// Open, calls fail fast without reaching the backend
+	_, err = b.Get(ctx, "key")
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.Equal(t, 3, backend.calls-1)
+	assert.Equal(t, int64(1), b.Health().Skipped)
+
+	// After the cooldown one call probes; a failed probe opens it again
+	now = now.Add(10 * time.Second)
+	_, err = b.Get(ctx, "key")
+	assert.EqualError(t, err, "connection refused")
+	_, err = b.Get(ctx, "key")
+	assert.ErrorIs(t, err, ErrUnavailable)
+
+	backend.down = false
+	now = now.Add(10 * time.Second)
+	_, err = b.Get(ctx, "key")
+	assert.ErrorIs(t, err, ErrMiss)
+	health = b.Health()
+	assert.Equal(t, HealthOK, health.Status)
+	assert.Equal(t, BreakerClosed, health.Breaker)
+	assert.Equal(t, int64(4), health.Failures)
+	assert.Nil(t, health.DegradedSince)
+}
+
+func TestBreakerCacheReplaysInvalidations(t *testing.T) {
+	ctx := context.Background()
+	backend := &flakyCache{MemoryCache: NewMemoryCache(10)}
+	b := NewBreakerCache(backend, 1, time.Second)
+	now := time.Date(2024, time.May, 1, 8, 0, 0, 0, time.UTC)
+	b.now = func() time.Time { return now }
+
+	key, _ := b.TagKey(ctx, "page", "books")
+	assert.NoError(t, b.Set(ctx, key, []byte("v1"), 0))
+	assert.NoError(t, b.Set(ctx, "job", []byte("running"), 0))
+
+	// Writes during the outage can't invalidate anything
+	backend.down = true
+	assert.Error(t, b.InvalidateTags(ctx, "books"))
+	assert.ErrorIs(t, b.Delete(ctx, "job"), ErrUnavailable)
+
+	// The entries written before it are gone once it is over
+	backend.down = false
+	now = now.Add(time.Second)
+	again, err := b.TagKey(ctx, "page", "books")
+	assert.NoError(t, err)
+	assert.NotEqual(t, key, again)
+	_, err = b.Get(ctx, "job")
+	assert.ErrorIs(t, err, ErrMiss)
+	assert.Equal(t, BreakerClosed, b.Health().Breaker)
+}
+
+func TestBreakerCacheIgnoresCancelledCalls(t *testing.T) {
+	ctx := context.Background()
+	b := NewBreakerCache(NewMemoryCache(10), 1, time.Second)
+
+	b.failed(context.Canceled)
+	assert.Equal(t, BreakerClosed, b.Health().Breaker)
+	assert.Zero(t, b.Health().Failures)
+
+	_, err := b.Get(ctx, "key")
+	assert.ErrorIs(t, err, ErrMiss)
+}
@@ -2,27 +2,107 @@ package cache
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/go-redis/redis/v8"
 	"github.com/kev1nandreas/go-rest-api-template/env"
 )
 
+const (
+	BackendRedis  = "redis"
+	BackendMemory = "memory"
+)
+
+// ErrMiss is returned by Get for keys that are missing or expired
+var ErrMiss = errors.New("cache miss")
+
+// Cache stores expiring values shared by the API. A ttl of zero keeps an
+// entry until it is deleted or evicted.
+//
+// Tags group entries so they can be invalidated together. TagKey qualifies a
+// key with the current version of each tag, and InvalidateTags moves tags to
+// new versions, which orphans every key taken before; orphaned entries are
+// left to expire. Take the key before reading the data to cache, so data read
+// before an invalidation is never stored under a key taken after it.
 type Cache interface {
-	Get(ctx context.Context, key string) *redis.StringCmd
-	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
-	Keys(context.Context, string) *redis.StringSliceCmd
-	Del(context.Context, ...string) *redis.IntCmd
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// SetNX stores value only if key is missing and reports whether it did
+	SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+	Delete(ctx context.Context, keys ...string) error
+	// DeleteIfValue deletes key only while it holds value and reports
+	// whether it did
+	DeleteIfValue(ctx context.Context, key string, value []byte) (bool, error)
+	TagKey(ctx context.Context, key string, tags ...string) (string, error)
+	InvalidateTags(ctx context.Context, tags ...string) error
 }
 
-func NewRedisClient() *redis.Client {
-	redis_host := env.GetEnvString("REDIS_HOST", "localhost")
-	redis_port := env.GetEnvString("REDIS_PORT", "6379")
-	redis_password := env.GetEnvString("REDIS_PASSWORD", "")
+// NewCache builds the backend selected by CACHE_BACKEND. Redis is behind a
+// circuit breaker unless CACHE_BREAKER_THRESHOLD is 0, and gets an
+// in-process L1 in front unless CACHE_L1_MAX_ENTRIES is 0.
+func NewCache() (Cache, error) {
+	switch backend := env.GetEnvString("CACHE_BACKEND", BackendRedis); backend {
+	case BackendRedis:
+		client, err := NewRedisClient()
+		if err != nil {
+			return nil, err
+		}
+		var l2 Cache = NewRedisCache(client)
+		if threshold := env.GetEnvInt("CACHE_BREAKER_THRESHOLD", 5); threshold > 0 {
+			cooldown := time.Duration(env.GetEnvInt("CACHE_BREAKER_COOLDOWN_SECONDS", 10)) * time.Second
+			l2 = NewBreakerCache(l2, threshold, cooldown)
+		}
+
+		l1MaxEntries := env.GetEnvInt("CACHE_L1_MAX_ENTRIES", 10000)
+		if l1MaxEntries <= 0 {
+			return l2, nil
+		}
+		l1TTL := time.Duration(env.GetEnvInt("CACHE_L1_TTL_SECONDS", 10)) * time.Second
+		log.Printf("Using an in-process L1 cache with at most %d entries for %s in front of Redis", l1MaxEntries, l1TTL)
+		tiered := NewTieredCache(l2, NewRedisInvalidationBus(client), l1MaxEntries, l1TTL)
+		go tiered.Listen(context.Background())
+		return tiered, nil
+	case BackendMemory:
+		maxEntries := env.GetEnvInt("CACHE_MEMORY_MAX_ENTRIES", 10000)
+		log.Printf("Using the in-memory cache with at most %d entries", maxEntries)
+		return NewMemoryCache(maxEntries), nil
+	default:
+		return nil, errors.New("unknown cache backend " + backend)
+	}
+}
+
+// GetJSON decodes the value of key into dest. Values that can't be decoded,
+// e.g. written by an older release, are reported as a miss.
+func GetJSON(ctx context.Context, c Cache, key string, dest interface{}) error {
+	data, err := c.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	if json.Unmarshal(data, dest) != nil {
+		return ErrMiss
+	}
+	return nil
+}
+
+// SetJSON stores value as JSON under key
+func SetJSON(ctx context.Context, c Cache, key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return c.Set(ctx, key, data, ttl)
+}
 
-	return redis.NewClient(&redis.Options{
-		Addr:     redis_host + ":" + redis_port, // Redis server address (change to localhost when running local)
-		Password: redis_password,                // Password, leave empty if none
-		DB:       0,                             // Default DB
-	})
+// taggedKey appends the versions of tags to key
+func taggedKey(key string, tags []string, versions []int64) string {
+	var b strings.Builder
+	b.WriteString(key)
+	for i, tag := range tags {
+		b.WriteString("@" + tag + ":" + strconv.FormatInt(versions[i], 10))
+	}
+	return b.String()
 }
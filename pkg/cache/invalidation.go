@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// InvalidationChannel is the Redis channel invalidations are published on
+const InvalidationChannel = "cache:invalidations"
+
+// Invalidation tells the other instances to evict keys and tagged entries
+// from their in-process tier
+type Invalidation struct {
+	Origin string   `json:"origin"`
+	Keys   []string `json:"keys,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+}
+
+// InvalidationBus carries invalidations between instances
+type InvalidationBus interface {
+	Publish(ctx context.Context, msg Invalidation) error
+	// Subscribe calls handle with the invalidations of every instance until
+	// ctx is done. connected reports whether the subscription is live;
+	// invalidations published while it isn't are lost.
+	Subscribe(ctx context.Context, handle func(Invalidation), connected func(bool))
+}
+
+// RedisInvalidationBus publishes invalidations with Redis pub/sub
+type RedisInvalidationBus struct {
+	client  redis.UniversalClient
+	channel string
+}
+
+func NewRedisInvalidationBus(client redis.UniversalClient) *RedisInvalidationBus {
+	return &RedisInvalidationBus{client: client, channel: InvalidationChannel}
+}
+
+func (b *RedisInvalidationBus) Publish(ctx context.Context, msg Invalidation) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(ctx, b.channel, data).Err()
+}
+
+func (b *RedisInvalidationBus) Subscribe(ctx context.Context, handle func(Invalidation), connected func(bool)) {
+	pubsub := b.client.Subscribe(ctx, b.channel)
+	defer pubsub.Close()
+
+	for {
+		received, err := pubsub.Receive(ctx)
+		if err != nil {
+			// The client reconnects and subscribes again on the next
+			// Receive, which confirms it with a subscription message
+			connected(false)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		switch received := received.(type) {
+		case *redis.Subscription:
+			connected(received.Kind == "subscribe")
+		case *redis.Message:
+			var msg Invalidation
+			if json.Unmarshal([]byte(received.Payload), &msg) == nil {
+				handle(msg)
+			}
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package cache
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"log"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kev1nandreas/go-rest-api-template/env"
+	"golang.org/x/sync/singleflight"
+)
+
+// loadTimeout bounds a load, which may outlive the caller that started it
+const loadTimeout = time.Minute
+
+// ErrNotFound is returned by a LoadFunc whose source has no value. The
+// Loader caches it like a value, for NotFoundTTL, and returns it from Fetch.
+var ErrNotFound = errors.New("not found")
+
+// Policy sets how the entries of a cache namespace are loaded and refreshed
+type Policy struct {
+	// TTL is how long an entry is fresh
+	TTL time.Duration
+	// StaleTTL is how long an entry is still served once it went stale,
+	// while a single background refresh replaces it. Zero turns it off.
+	StaleTTL time.Duration
+	// EarlyRefresh makes reads refresh a fresh entry ahead of time, more
+	// likely the closer it is to going stale and the slower it was to load.
+	// Zero turns it off; 1 is the usual setting, higher refreshes earlier.
+	EarlyRefresh float64
+	// NotFoundTTL is how long a value the source doesn't have is remembered,
+	// so lookups of missing values don't all reach the source. Zero turns it
+	// off. Not found entries are never served stale.
+	NotFoundTTL time.Duration
+}
+
+// ConfiguredPolicy returns the policy of namespace from
+// CACHE_<NAMESPACE>_TTL_SECONDS, CACHE_<NAMESPACE>_STALE_SECONDS,
+// CACHE_<NAMESPACE>_EARLY_REFRESH and CACHE_<NAMESPACE>_NOT_FOUND_SECONDS,
+// with dashes as underscores, falling back to defaults for the unset ones
+func ConfiguredPolicy(namespace string, defaults Policy) Policy {
+	prefix := "CACHE_" + strings.ToUpper(strings.ReplaceAll(namespace, "-", "_")) + "_"
+	policy := Policy{
+		TTL:          time.Duration(env.GetEnvInt(prefix+"TTL_SECONDS", int(defaults.TTL/time.Second))) * time.Second,
+		StaleTTL:     time.Duration(env.GetEnvInt(prefix+"STALE_SECONDS", int(defaults.StaleTTL/time.Second))) * time.Second,
+		EarlyRefresh: defaults.EarlyRefresh,
+		NotFoundTTL:  time.Duration(env.GetEnvInt(prefix+"NOT_FOUND_SECONDS", int(defaults.NotFoundTTL/time.Second))) * time.Second,
+	}
+	if beta, err := strconv.ParseFloat(env.GetEnvString(prefix+"EARLY_REFRESH", ""), 64); err == nil && beta >= 0 {
+		policy.EarlyRefresh = beta
+	}
+	return policy
+}
+
+// LoadFunc loads the value of a cache entry from its source
+type LoadFunc func(ctx context.Context) ([]byte, error)
+
+// Loader reads the entries of one namespace through the cache and loads
+// them when they are missing. Concurrent loads of a key are coalesced into
+// one per instance, and stale entries are refreshed by one instance at a
+// time. Entries are stored with their freshness, so only values written by
+// a Loader can be read by one.
+type Loader struct {
+	cache  Cache
+	policy Policy
+	group  singleflight.Group
+	now    func() time.Time
+	random func() float64
+
+	mu         sync.Mutex
+	refreshing map[string]bool
+	background sync.WaitGroup
+}
+
+func NewLoader(c Cache, policy Policy) *Loader {
+	return &Loader{
+		cache:      c,
+		policy:     policy,
+		now:        time.Now,
+		random:     rand.Float64,
+		refreshing: map[string]bool{},
+	}
+}
+
+// entryHeaderSize is the size of the kind byte, the end of freshness and
+// the load duration that precede the value of an entry
+const entryHeaderSize = 17
+
+// Kinds of entries, stored in their first byte
+const (
+	entryValue    = 1
+	entryNotFound = 2
+)
+
+// entry is a cached value, or the memory that the source had none
+type entry struct {
+	value      []byte
+	notFound   bool
+	freshUntil time.Time
+	loadTime   time.Duration
+}
+
+func (e entry) encode() []byte {
+	data := make([]byte, entryHeaderSize, entryHeaderSize+len(e.value))
+	data[0] = entryValue
+	if e.notFound {
+		data[0] = entryNotFound
+	}
+	binary.BigEndian.PutUint64(data[1:9], uint64(e.freshUntil.UnixNano()))
+	binary.BigEndian.PutUint64(data[9:17], uint64(e.loadTime))
+	return append(data, e.value...)
+}
+
+func decodeEntry(data []byte) (entry, bool) {
+	if len(data) < entryHeaderSize || (data[0] != entryValue && data[0] != entryNotFound) {
+		return entry{}, false
+	}
+	return entry{
+		value:      data[entryHeaderSize:],
+		notFound:   data[0] == entryNotFound,
+		freshUntil: time.Unix(0, int64(binary.BigEndian.Uint64(data[1:9]))),
+		loadTime:   time.Duration(binary.BigEndian.Uint64(data[9:17])),
+	}, true
+}
+
+// Fetch returns the value of key and whether it came from the cache. A
+// missing entry, or one the cache fails to read, is loaded with load and
+// stored if the cache can. The callers that miss the same key meanwhile wait
+// for that load and share its result. A stale entry within StaleTTL is
+// returned as is while a background refresh replaces it. Values the source
+// doesn't have are reported with ErrNotFound.
+func (l *Loader) Fetch(ctx context.Context, key string, load LoadFunc) ([]byte, bool, error) {
+	if data, err := l.cache.Get(ctx, key); err == nil {
+		if e, ok := decodeEntry(data); ok {
+			now := l.now()
+			if now.Before(e.freshUntil) {
+				if l.refreshEarly(now, e.freshUntil, e.loadTime) {
+					l.refreshInBackground(ctx, key, load)
+				}
+				return e.result(true)
+			}
+			if !e.notFound && now.Before(e.freshUntil.Add(l.policy.StaleTTL)) {
+				l.refreshInBackground(ctx, key, load)
+				return e.result(true)
+			}
+		}
+	}
+
+	result, err := l.loadShared(ctx, key, load)
+	if err != nil {
+		return nil, false, err
+	}
+	// The cache failing to store the value is not the caller's problem
+	return result.result(false)
+}
+
+// result returns the value of the entry as Fetch does
+func (e entry) result(cached bool) ([]byte, bool, error) {
+	if e.notFound {
+		return nil, cached, ErrNotFound
+	}
+	return e.value, cached, nil
+}
+
+// Refresh loads key and stores it, whether or not it is cached
+func (l *Loader) Refresh(ctx context.Context, key string, load LoadFunc) error {
+	result, err := l.loadShared(ctx, key, load)
+	if err != nil {
+		return err
+	}
+	return result.storeErr
+}
+
+// Set stores value as the fresh entry of key
+func (l *Loader) Set(ctx context.Context, key string, value []byte) error {
+	e := entry{value: value, freshUntil: l.now().Add(l.policy.TTL)}
+	return l.cache.Set(ctx, key, e.encode(), l.policy.TTL+l.policy.StaleTTL)
+}
+
+// loaded is a loaded entry and the error storing it in the cache, if any
+type loaded struct {
+	entry
+	storeErr error
+}
+
+// loadShared loads key once for all its concurrent callers. Since the others
+// wait for it, the load isn't cancelled with the caller that started it and
+// runs for up to loadTimeout; each caller stops waiting when its ctx is done.
+func (l *Loader) loadShared(ctx context.Context, key string, load LoadFunc) (loaded, error) {
+	results := l.group.DoChan(key, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), loadTimeout)
+		defer cancel()
+		return l.load(ctx, key, load)
+	})
+
+	select {
+	case <-ctx.Done():
+		return loaded{}, ctx.Err()
+	case result := <-results:
+		if result.Err != nil {
+			return loaded{}, result.Err
+		}
+		return result.Val.(loaded), nil
+	}
+}
+
+func (l *Loader) load(ctx context.Context, key string, load LoadFunc) (loaded, error) {
+	started := l.now()
+	value, err := load(ctx)
+	notFound := errors.Is(err, ErrNotFound) && l.policy.NotFoundTTL > 0
+	if err != nil && !notFound {
+		return loaded{}, err
+	}
+
+	now := l.now()
+	e := entry{value: value, freshUntil: now.Add(l.policy.TTL), loadTime: now.Sub(started)}
+	ttl := l.policy.TTL + l.policy.StaleTTL
+	if notFound {
+		e = entry{notFound: true, freshUntil: now.Add(l.policy.NotFoundTTL), loadTime: now.Sub(started)}
+		ttl = l.policy.NotFoundTTL
+	}
+	return loaded{entry: e, storeErr: l.cache.Set(ctx, key, e.encode(), ttl)}, nil
+}
+
+// refreshEarly decides whether a read of a fresh entry refreshes it. The
+// chance grows exponentially as the entry nears the end of its freshness,
+// scaled by how long it took to load, so that one read refreshes a busy
+// entry shortly before it goes stale and the others keep hitting it.
+func (l *Loader) refreshEarly(now time.Time, freshUntil time.Time, loadTime time.Duration) bool {
+	if l.policy.EarlyRefresh <= 0 || loadTime <= 0 {
+		return false
+	}
+	gap := -float64(loadTime) * l.policy.EarlyRefresh * math.Log(1-l.random())
+	return now.Add(time.Duration(gap)).After(freshUntil)
+}
+
+// refreshInBackground reloads key unless a refresh of it is already running,
+// here or on another instance. It outlives ctx, up to loadTimeout.
+func (l *Loader) refreshInBackground(ctx context.Context, key string, load LoadFunc) {
+	l.mu.Lock()
+	if l.refreshing[key] {
+		l.mu.Unlock()
+		return
+	}
+	l.refreshing[key] = true
+	l.mu.Unlock()
+
+	l.background.Add(1)
+	go func() {
+		defer l.background.Done()
+		defer func() {
+			l.mu.Lock()
+			delete(l.refreshing, key)
+			l.mu.Unlock()
+		}()
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), loadTimeout)
+		defer cancel()
+
+		lock := key + ":refresh"
+		token := []byte(strconv.FormatInt(rand.Int63(), 36))
+		if ok, err := l.cache.SetNX(ctx, lock, token, loadTimeout); err != nil || !ok {
+			return
+		}
+		defer l.cache.DeleteIfValue(context.WithoutCancel(ctx), lock, token)
+
+		if err := l.Refresh(ctx, key, load); err != nil {
+			log.Printf("Failed to refresh cache entry %s: %v", key, err)
+		}
+	}()
+}
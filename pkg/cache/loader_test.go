@@ -0,0 +1,304 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestLoader returns a loader over a memory cache whose clock is at *now
+func newTestLoader(policy Policy, now *time.Time) *Loader {
+	l := NewLoader(NewMemoryCache(100), policy)
+	l.now = func() time.Time { return *now }
+	return l
+}
+
+func TestLoaderCoalescesMisses(t *testing.T) {
+	ctx := context.Background()
+	l := NewLoader(NewMemoryCache(100), Policy{TTL: time.Minute})
+
+	var loads int32
+	release := make(chan struct{})
+	load := func(ctx context.Context) ([]byte, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return []byte("page"), nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, cached, err := l.Fetch(ctx, "books", load)
+			assert.NoError(t, err)
+			assert.False(t, cached)
+			assert.Equal(t, []byte("page"), value)
+		}()
+	}
+	// Let the callers pile up behind the first load
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads))
+
+	value, cached, err := l.Fetch(ctx, "books", load)
+	assert.NoError(t, err)
+	assert.True(t, cached)
+	assert.Equal(t, []byte("page"), value)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads))
+}
+
+func TestLoaderSharedLoadOutlivesCaller(t *testing.T) {
+	l := NewLoader(NewMemoryCache(100), Policy{TTL: time.Minute})
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	load := func(ctx context.Context) ([]byte, error) {
+		close(started)
+		select {
+		case <-release:
+			return []byte("page"), nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	// The caller that started the load gives up, the one waiting with it
+	// still gets the page
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error)
+	go func() {
+		_, _, err := l.Fetch(ctx, "books", load)
+		first <- err
+	}()
+	<-started
+	second := make(chan []byte)
+	go func() {
+		value, _, err := l.Fetch(context.Background(), "books", load)
+		assert.NoError(t, err)
+		second <- value
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-first, context.Canceled)
+	close(release)
+	assert.Equal(t, []byte("page"), <-second)
+}
+
+func TestLoaderDoesNotCacheFailedLoads(t *testing.T) {
+	ctx := context.Background()
+	l := NewLoader(NewMemoryCache(100), Policy{TTL: time.Minute})
+
+	_, _, err := l.Fetch(ctx, "books", func(ctx context.Context) ([]byte, error) {
+		return nil, errors.New("database is down")
+	})
+	assert.EqualError(t, err, "database is down")
+
+	value, cached, err := l.Fetch(ctx, "books", func(ctx context.Context) ([]byte, error) {
+		return []byte("page"), nil
+	})
+	assert.NoError(t, err)
+	assert.False(t, cached)
+	assert.Equal(t, []byte("page"), value)
+}
+
+func TestLoaderServesStaleWhileRevalidating(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, time.May, 1, 8, 0, 0, 0, time.UTC)
+	l := newTestLoader(Policy{TTL: time.Minute, StaleTTL: time.Minute}, &now)
+	assert.NoError(t, l.Set(ctx, "books", []byte("v1")))
+
+	var loads int32
+	release := make(chan struct{})
+	load := func(ctx context.Context) ([]byte, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return []byte("v2"), nil
+	}
+
+	// Stale reads get the old value at once, and only one of them refreshes
+	now = now.Add(90 * time.Second)
+	for i := 0; i < 5; i++ {
+		value, cached, err := l.Fetch(ctx, "books", load)
+		assert.NoError(t, err)
+		assert.True(t, cached)
+		assert.Equal(t, []byte("v1"), value)
+	}
+	close(release)
+	l.background.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads))
+
+	value, cached, _ := l.Fetch(ctx, "books", load)
+	assert.True(t, cached)
+	assert.Equal(t, []byte("v2"), value)
+
+	// Past the stale window, reads wait for the load
+	now = now.Add(3 * time.Minute)
+	value, cached, _ = l.Fetch(ctx, "books", func(ctx context.Context) ([]byte, error) {
+		return []byte("v3"), nil
+	})
+	assert.False(t, cached)
+	assert.Equal(t, []byte("v3"), value)
+}
+
+func TestLoaderRefreshesOnceAcrossInstances(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, time.May, 1, 8, 0, 0, 0, time.UTC)
+	shared := NewMemoryCache(100)
+	first, second := NewLoader(shared, Policy{TTL: time.Minute, StaleTTL: time.Minute}), NewLoader(shared, Policy{TTL: time.Minute, StaleTTL: time.Minute})
+	first.now = func() time.Time { return now }
+	second.now = first.now
+	assert.NoError(t, first.Set(ctx, "books", []byte("v1")))
+	now = now.Add(90 * time.Second)
+
+	var loads int32
+	release := make(chan struct{})
+	load := func(ctx context.Context) ([]byte, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return []byte("v2"), nil
+	}
+
+	_, _, _ = first.Fetch(ctx, "books", load)
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&loads) == 1 }, time.Second, time.Millisecond)
+	value, cached, _ := second.Fetch(ctx, "books", load)
+	assert.True(t, cached)
+	assert.Equal(t, []byte("v1"), value)
+	second.background.Wait()
+
+	close(release)
+	first.background.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads))
+	_, err := shared.Get(ctx, "books:refresh")
+	assert.ErrorIs(t, err, ErrMiss, "the refresh lock is released")
+}
+
+func TestLoaderRefreshesEarly(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, time.May, 1, 8, 0, 0, 0, time.UTC)
+	l := newTestLoader(Policy{TTL: time.Minute, EarlyRefresh: 1}, &now)
+
+	// Loading takes a second
+	var loads int32
+	load := func(ctx context.Context) ([]byte, error) {
+		atomic.AddInt32(&loads, 1)
+		now = now.Add(time.Second)
+		return []byte("page"), nil
+	}
+	_, _, err := l.Fetch(ctx, "books", load)
+	assert.NoError(t, err)
+
+	// Two seconds before going stale, a read refreshes only if it draws a
+	// gap of more than two load times
+	now = now.Add(58 * time.Second)
+	l.random = func() float64 { return 1 - math.Exp(-1) }
+	_, cached, _ := l.Fetch(ctx, "books", load)
+	l.background.Wait()
+	assert.True(t, cached)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads))
+
+	l.random = func() float64 { return 1 - math.Exp(-3) }
+	_, cached, _ = l.Fetch(ctx, "books", load)
+	l.background.Wait()
+	assert.True(t, cached)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&loads))
+}
+
+func TestLoaderTreatsOtherValuesAsMisses(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(100)
+	l := NewLoader(c, Policy{TTL: time.Minute})
+	assert.NoError(t, c.Set(ctx, "books", []byte(`{"books":[]}`), 0))
+
+	value, cached, err := l.Fetch(ctx, "books", func(ctx context.Context) ([]byte, error) {
+		return []byte("page"), nil
+	})
+	assert.NoError(t, err)
+	assert.False(t, cached)
+	assert.Equal(t, []byte("page"), value)
+}
+
+func TestConfiguredPolicy(t *testing.T) {
+	defaults := Policy{TTL: time.Minute, StaleTTL: 30 * time.Second}
+	assert.Equal(t, defaults, ConfiguredPolicy("book-pages", defaults))
+
+	t.Setenv("CACHE_BOOK_PAGES_TTL_SECONDS", "120")
+	t.Setenv("CACHE_BOOK_PAGES_STALE_SECONDS", "0")
+	t.Setenv("CACHE_BOOK_PAGES_EARLY_REFRESH", "1.5")
+	t.Setenv("CACHE_BOOK_PAGES_NOT_FOUND_SECONDS", "5")
+	assert.Equal(t, Policy{TTL: 2 * time.Minute, EarlyRefresh: 1.5, NotFoundTTL: 5 * time.Second}, ConfiguredPolicy("book-pages", defaults))
+}
+
+func TestLoaderFailsOpen(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	c := NewMockCache(ctrl)
+	l := NewLoader(c, Policy{TTL: time.Minute})
+	c.EXPECT().Get(gomock.Any(), "books").Return(nil, ErrUnavailable)
+	c.EXPECT().Set(gomock.Any(), "books", gomock.Any(), time.Minute).Return(ErrUnavailable).Times(2)
+
+	load := func(ctx context.Context) ([]byte, error) { return []byte("page"), nil }
+	value, cached, err := l.Fetch(ctx, "books", load)
+	assert.NoError(t, err)
+	assert.False(t, cached)
+	assert.Equal(t, []byte("page"), value)
+
+	// Refreshing is all about storing, so it reports the failure
+	assert.ErrorIs(t, l.Refresh(ctx, "books", load), ErrUnavailable)
+}
+
+func TestLoaderCachesNotFound(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, time.May, 1, 8, 0, 0, 0, time.UTC)
+	l := newTestLoader(Policy{TTL: time.Minute, StaleTTL: time.Minute, NotFoundTTL: 10 * time.Second}, &now)
+
+	var loads int32
+	found := false
+	load := func(ctx context.Context) ([]byte, error) {
+		atomic.AddInt32(&loads, 1)
+		if !found {
+			return nil, ErrNotFound
+		}
+		return []byte("book"), nil
+	}
+
+	_, cached, err := l.Fetch(ctx, "book", load)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.False(t, cached)
+	_, cached, err = l.Fetch(ctx, "book", load)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.True(t, cached)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads))
+
+	// Not found entries expire after NotFoundTTL and aren't served stale
+	found = true
+	now = now.Add(10 * time.Second)
+	value, cached, err := l.Fetch(ctx, "book", load)
+	assert.NoError(t, err)
+	assert.False(t, cached)
+	assert.Equal(t, []byte("book"), value)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&loads))
+}
+
+func TestLoaderWithoutNotFoundTTL(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(100)
+	l := NewLoader(c, Policy{TTL: time.Minute})
+
+	_, _, err := l.Fetch(ctx, "book", func(ctx context.Context) ([]byte, error) {
+		return nil, ErrNotFound
+	})
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = c.Get(ctx, "book")
+	assert.ErrorIs(t, err, ErrMiss)
+}
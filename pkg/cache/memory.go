@@ -0,0 +1,214 @@
+package cache
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// Stats counts the activity of an in-process cache. Evictions are entries
+// dropped to make room or because another instance changed them.
+type Stats struct {
+	Entries       int   `json:"entries"`
+	MaxEntries    int   `json:"max_entries"`
+	Hits          int64 `json:"hits"`
+	Misses        int64 `json:"misses"`
+	Evictions     int64 `json:"evictions"`
+	Invalidations int64 `json:"invalidations"`
+}
+
+// StatsReporter is implemented by the backends that keep entries in process
+type StatsReporter interface {
+	Stats() Stats
+}
+
+// MemoryCache keeps entries in process, evicting the least recently used one
+// beyond maxEntries. It suits single-node deployments and tests: replicas
+// don't share its entries, locks or tags.
+type MemoryCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[string]*list.Element
+	recency    *list.List // most recently used first
+	tags       map[string]int64
+	now        func() time.Time
+
+	hits, misses, evictions int64
+}
+
+type memoryEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	if maxEntries <= 0 {
+		maxEntries = 1
+	}
+	return &MemoryCache{
+		maxEntries: maxEntries,
+		entries:    map[string]*list.Element{},
+		recency:    list.New(),
+		tags:       map[string]int64{},
+		now:        time.Now,
+	}
+}
+
+// lookup returns the live entry of key, dropping it if it has expired. The
+// caller holds the lock.
+func (c *MemoryCache) lookup(key string) (*memoryEntry, bool) {
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*memoryEntry)
+	if !entry.expiresAt.IsZero() && !c.now().Before(entry.expiresAt) {
+		c.remove(element)
+		return nil, false
+	}
+	c.recency.MoveToFront(element)
+	return entry, true
+}
+
+// store sets key and evicts the least recently used entries over the limit.
+// The caller holds the lock.
+func (c *MemoryCache) store(key string, value []byte, ttl time.Duration) {
+	entry := &memoryEntry{key: key, value: bytes.Clone(value)}
+	if ttl > 0 {
+		entry.expiresAt = c.now().Add(ttl)
+	}
+
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.recency.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.recency.PushFront(entry)
+	for c.recency.Len() > c.maxEntries {
+		c.remove(c.recency.Back())
+		c.evictions++
+	}
+}
+
+func (c *MemoryCache) remove(element *list.Element) {
+	c.recency.Remove(element)
+	delete(c.entries, element.Value.(*memoryEntry).key)
+}
+
+// evict drops keys, counting them as evictions
+func (c *MemoryCache) evict(keys []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		if element, ok := c.entries[key]; ok {
+			c.remove(element)
+			c.evictions++
+		}
+	}
+}
+
+// evictAll drops every entry, counting them as evictions
+func (c *MemoryCache) evictAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.evictions += int64(c.recency.Len())
+	c.entries = map[string]*list.Element{}
+	c.recency.Init()
+}
+
+func (c *MemoryCache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return Stats{
+		Entries:    c.recency.Len(),
+		MaxEntries: c.maxEntries,
+		Hits:       c.hits,
+		Misses:     c.misses,
+		Evictions:  c.evictions,
+	}
+}
+
+func (c *MemoryCache) Get(ctx context.Context, key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.lookup(key)
+	if !ok {
+		c.misses++
+		return nil, ErrMiss
+	}
+	c.hits++
+	return bytes.Clone(entry.value), nil
+}
+
+func (c *MemoryCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.store(key, value, ttl)
+	return nil
+}
+
+func (c *MemoryCache) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.lookup(key); ok {
+		return false, nil
+	}
+	c.store(key, value, ttl)
+	return true, nil
+}
+
+func (c *MemoryCache) Delete(ctx context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		if element, ok := c.entries[key]; ok {
+			c.remove(element)
+		}
+	}
+	return nil
+}
+
+func (c *MemoryCache) DeleteIfValue(ctx context.Context, key string, value []byte) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.lookup(key)
+	if !ok || !bytes.Equal(entry.value, value) {
+		return false, nil
+	}
+	c.remove(c.entries[key])
+	return true, nil
+}
+
+// TagKey qualifies key with tag versions. Tag versions live outside the
+// entries, so eviction can't reset them.
+func (c *MemoryCache) TagKey(ctx context.Context, key string, tags ...string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	versions := make([]int64, len(tags))
+	for i, tag := range tags {
+		versions[i] = c.tags[tag]
+	}
+	return taggedKey(key, tags, versions), nil
+}
+
+func (c *MemoryCache) InvalidateTags(ctx context.Context, tags ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, tag := range tags {
+		c.tags[tag]++
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryCacheExpiresEntries(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(10)
+	now := time.Date(2024, time.May, 1, 8, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	assert.NoError(t, c.Set(ctx, "short", []byte("a"), time.Minute))
+	assert.NoError(t, c.Set(ctx, "forever", []byte("b"), 0))
+
+	value, err := c.Get(ctx, "short")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("a"), value)
+
+	now = now.Add(time.Minute)
+	_, err = c.Get(ctx, "short")
+	assert.ErrorIs(t, err, ErrMiss)
+	value, err = c.Get(ctx, "forever")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("b"), value)
+
+	// An expired entry no longer blocks SetNX
+	assert.NoError(t, c.Set(ctx, "lock", []byte("x"), time.Second))
+	ok, _ := c.SetNX(ctx, "lock", []byte("y"), time.Second)
+	assert.False(t, ok)
+	now = now.Add(time.Second)
+	ok, _ = c.SetNX(ctx, "lock", []byte("y"), time.Second)
+	assert.True(t, ok)
+}
+
+func TestMemoryCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(2)
+
+	assert.NoError(t, c.Set(ctx, "a", []byte("1"), 0))
+	assert.NoError(t, c.Set(ctx, "b", []byte("2"), 0))
+	_, err := c.Get(ctx, "a")
+	assert.NoError(t, err)
+
+	assert.NoError(t, c.Set(ctx, "c", []byte("3"), 0))
+	_, err = c.Get(ctx, "b")
+	assert.ErrorIs(t, err, ErrMiss, "b was used least recently")
+	_, err = c.Get(ctx, "a")
+	assert.NoError(t, err)
+	_, err = c.Get(ctx, "c")
+	assert.NoError(t, err)
+}
+
+func TestMemoryCacheCopiesValues(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(10)
+
+	value := []byte("abc")
+	assert.NoError(t, c.Set(ctx, "key", value, 0))
+	value[0] = 'x'
+
+	stored, _ := c.Get(ctx, "key")
+	assert.Equal(t, []byte("abc"), stored)
+	stored[0] = 'y'
+	stored, _ = c.Get(ctx, "key")
+	assert.Equal(t, []byte("abc"), stored)
+}
+
+func TestMemoryCacheDeleteIfValue(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(10)
+
+	assert.NoError(t, c.Set(ctx, "lock", []byte("mine"), 0))
+	deleted, err := c.DeleteIfValue(ctx, "lock", []byte("theirs"))
+	assert.NoError(t, err)
+	assert.False(t, deleted)
+
+	deleted, err = c.DeleteIfValue(ctx, "lock", []byte("mine"))
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+	_, err = c.Get(ctx, "lock")
+	assert.ErrorIs(t, err, ErrMiss)
+
+	assert.NoError(t, c.Set(ctx, "a", []byte("1"), 0))
+	assert.NoError(t, c.Delete(ctx, "a", "missing"))
+	_, err = c.Get(ctx, "a")
+	assert.ErrorIs(t, err, ErrMiss)
+}
+
+func TestMemoryCacheTags(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(10)
+
+	books, err := c.TagKey(ctx, "page", "books")
+	assert.NoError(t, err)
+	both, err := c.TagKey(ctx, "page", "books", "authors")
+	assert.NoError(t, err)
+	untagged, err := c.TagKey(ctx, "page")
+	assert.NoError(t, err)
+	assert.Equal(t, "page", untagged)
+	assert.NoError(t, c.Set(ctx, books, []byte("1"), 0))
+
+	// A key taken before the invalidation is never handed out again
+	assert.NoError(t, c.InvalidateTags(ctx, "authors"))
+	again, _ := c.TagKey(ctx, "page", "books")
+	assert.Equal(t, books, again)
+	again, _ = c.TagKey(ctx, "page", "books", "authors")
+	assert.NotEqual(t, both, again)
+
+	assert.NoError(t, c.InvalidateTags(ctx, "books"))
+	again, _ = c.TagKey(ctx, "page", "books")
+	assert.NotEqual(t, books, again)
+	_, err = c.Get(ctx, again)
+	assert.ErrorIs(t, err, ErrMiss)
+}
+
+func TestJSONHelpers(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(10)
+
+	type page struct {
+		ETag string `json:"etag"`
+	}
+	assert.NoError(t, SetJSON(ctx, c, "page", page{ETag: "v1"}, 0))
+
+	var got page
+	assert.NoError(t, GetJSON(ctx, c, "page", &got))
+	assert.Equal(t, "v1", got.ETag)
+
+	// Values that don't decode are a miss
+	assert.NoError(t, c.Set(ctx, "old", []byte("not json"), 0))
+	assert.ErrorIs(t, GetJSON(ctx, c, "old", &got), ErrMiss)
+	assert.ErrorIs(t, GetJSON(ctx, c, "missing", &got), ErrMiss)
+}
+
+func TestNewCache(t *testing.T) {
+	t.Setenv("CACHE_BACKEND", BackendMemory)
+	c, err := NewCache()
+	assert.NoError(t, err)
+	assert.IsType(t, &MemoryCache{}, c)
+
+	t.Setenv("CACHE_BACKEND", "memcached")
+	_, err = NewCache()
+	assert.Error(t, err)
+}
@@ -0,0 +1,269 @@
+package cache
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/kev1nandreas/go-rest-api-template/env"
+)
+
+// deleteIfValueScript deletes a key only if it still holds the given value
+const deleteIfValueScript = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("del", KEYS[1]) else return 0 end`
+
+// RedisCache keeps entries in Redis, where every replica sees them
+type RedisCache struct {
+	client redis.UniversalClient
+}
+
+func NewRedisCache(client redis.UniversalClient) *RedisCache {
+	return &RedisCache{client: client}
+}
+
+// Redis deployment modes
+const (
+	RedisStandalone = "standalone"
+	RedisSentinel   = "sentinel"
+	RedisCluster    = "cluster"
+)
+
+// RedisConfig describes how to reach Redis. Addrs holds the server in
+// standalone mode, the sentinels in sentinel mode and some of the nodes in
+// cluster mode. Zero pool sizes and timeouts leave the client defaults.
+type RedisConfig struct {
+	Mode             string
+	Addrs            []string
+	MasterName       string
+	SentinelUsername string
+	SentinelPassword string
+	Username         string
+	Password         string
+	DB               int
+
+	TLS           bool
+	TLSCAFile     string
+	TLSServerName string
+
+	PoolSize     int
+	MinIdleConns int
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	PoolTimeout  time.Duration
+}
+
+// LoadRedisConfig reads the Redis configuration from the REDIS_* variables.
+// Without REDIS_ADDRS, the address is REDIS_HOST:REDIS_PORT.
+func LoadRedisConfig() RedisConfig {
+	addrs := env.GetEnvString("REDIS_ADDRS", "")
+	if addrs == "" {
+		addrs = env.GetEnvString("REDIS_HOST", "localhost") + ":" + env.GetEnvString("REDIS_PORT", "6379")
+	}
+	milliseconds := func(key string) time.Duration {
+		return time.Duration(env.GetEnvInt(key, 0)) * time.Millisecond
+	}
+
+	config := RedisConfig{
+		Mode:             env.GetEnvString("REDIS_MODE", RedisStandalone),
+		MasterName:       env.GetEnvString("REDIS_SENTINEL_MASTER", ""),
+		SentinelUsername: env.GetEnvString("REDIS_SENTINEL_USERNAME", ""),
+		SentinelPassword: env.GetEnvString("REDIS_SENTINEL_PASSWORD", ""),
+		Username:         env.GetEnvString("REDIS_USERNAME", ""),
+		Password:         env.GetEnvString("REDIS_PASSWORD", ""),
+		DB:               env.GetEnvInt("REDIS_DB", 0),
+		TLS:              env.GetEnvBool("REDIS_TLS", false),
+		TLSCAFile:        env.GetEnvString("REDIS_TLS_CA_FILE", ""),
+		TLSServerName:    env.GetEnvString("REDIS_TLS_SERVER_NAME", ""),
+		PoolSize:         env.GetEnvInt("REDIS_POOL_SIZE", 0),
+		MinIdleConns:     env.GetEnvInt("REDIS_MIN_IDLE_CONNS", 0),
+		DialTimeout:      milliseconds("REDIS_DIAL_TIMEOUT_MS"),
+		ReadTimeout:      milliseconds("REDIS_READ_TIMEOUT_MS"),
+		WriteTimeout:     milliseconds("REDIS_WRITE_TIMEOUT_MS"),
+		PoolTimeout:      milliseconds("REDIS_POOL_TIMEOUT_MS"),
+	}
+	for _, addr := range strings.Split(addrs, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			config.Addrs = append(config.Addrs, addr)
+		}
+	}
+	return config
+}
+
+// NewRedisClient connects to Redis as configured by the REDIS_* variables
+func NewRedisClient() (redis.UniversalClient, error) {
+	return NewRedisClientWithConfig(LoadRedisConfig())
+}
+
+// NewRedisClientWithConfig returns a client for the deployment described by
+// config. Connections are made lazily, so an unreachable server is only
+// reported by the first command.
+func NewRedisClientWithConfig(config RedisConfig) (redis.UniversalClient, error) {
+	if len(config.Addrs) == 0 {
+		return nil, errors.New("no Redis address configured")
+	}
+	tlsConfig, err := config.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	switch config.Mode {
+	case RedisStandalone:
+		if len(config.Addrs) > 1 {
+			return nil, errors.New("standalone Redis takes a single address")
+		}
+		return redis.NewClient(&redis.Options{
+			Addr:         config.Addrs[0],
+			Username:     config.Username,
+			Password:     config.Password,
+			DB:           config.DB,
+			TLSConfig:    tlsConfig,
+			PoolSize:     config.PoolSize,
+			MinIdleConns: config.MinIdleConns,
+			DialTimeout:  config.DialTimeout,
+			ReadTimeout:  config.ReadTimeout,
+			WriteTimeout: config.WriteTimeout,
+			PoolTimeout:  config.PoolTimeout,
+		}), nil
+	case RedisSentinel:
+		if config.MasterName == "" {
+			return nil, errors.New("sentinel mode needs REDIS_SENTINEL_MASTER")
+		}
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       config.MasterName,
+			SentinelAddrs:    config.Addrs,
+			SentinelUsername: config.SentinelUsername,
+			SentinelPassword: config.SentinelPassword,
+			Username:         config.Username,
+			Password:         config.Password,
+			DB:               config.DB,
+			TLSConfig:        tlsConfig,
+			PoolSize:         config.PoolSize,
+			MinIdleConns:     config.MinIdleConns,
+			DialTimeout:      config.DialTimeout,
+			ReadTimeout:      config.ReadTimeout,
+			WriteTimeout:     config.WriteTimeout,
+			PoolTimeout:      config.PoolTimeout,
+		}), nil
+	case RedisCluster:
+		if config.DB != 0 {
+			return nil, errors.New("cluster mode only supports REDIS_DB=0")
+		}
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        config.Addrs,
+			Username:     config.Username,
+			Password:     config.Password,
+			TLSConfig:    tlsConfig,
+			PoolSize:     config.PoolSize,
+			MinIdleConns: config.MinIdleConns,
+			DialTimeout:  config.DialTimeout,
+			ReadTimeout:  config.ReadTimeout,
+			WriteTimeout: config.WriteTimeout,
+			PoolTimeout:  config.PoolTimeout,
+		}), nil
+	default:
+		return nil, errors.New("unknown Redis mode " + config.Mode)
+	}
+}
+
+// tlsConfig returns the TLS settings of the connections, nil without TLS.
+// Servers are verified against TLSCAFile if set, or the system roots.
+func (config RedisConfig) tlsConfig() (*tls.Config, error) {
+	if !config.TLS {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: config.TLSServerName}
+	if config.TLSCAFile != "" {
+		pem, err := os.ReadFile(config.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the Redis CA: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificate found in " + config.TLSCAFile)
+		}
+	}
+	return tlsConfig, nil
+}
+
+// tagCounterKey is the key of the version counter of tag. The braces put
+// every counter in the same cluster slot, so they can be read with one MGET.
+func tagCounterKey(tag string) string {
+	return "{cache-tags}:" + tag
+}
+
+func (c *RedisCache) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := c.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrMiss
+	}
+	return value, err
+}
+
+func (c *RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return c.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (c *RedisCache) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	return c.client.SetNX(ctx, key, value, ttl).Result()
+}
+
+func (c *RedisCache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return c.client.Del(ctx, keys...).Err()
+}
+
+func (c *RedisCache) DeleteIfValue(ctx context.Context, key string, value []byte) (bool, error) {
+	deleted, err := c.client.Eval(ctx, deleteIfValueScript, []string{key}, value).Int64()
+	return deleted == 1, err
+}
+
+func (c *RedisCache) TagKey(ctx context.Context, key string, tags ...string) (string, error) {
+	if len(tags) == 0 {
+		return key, nil
+	}
+	counters := make([]string, len(tags))
+	for i, tag := range tags {
+		counters[i] = tagCounterKey(tag)
+	}
+
+	values, err := c.client.MGet(ctx, counters...).Result()
+	if err != nil {
+		return "", err
+	}
+	versions := make([]int64, len(tags))
+	for i, value := range values {
+		if value == nil {
+			// A counter that was evicted or never set starts from the clock
+			// rather than from zero, so it can't come back to a version
+			// whose entries are still cached
+			c.client.SetNX(ctx, counters[i], time.Now().UnixNano(), 0)
+			if versions[i], err = c.client.Get(ctx, counters[i]).Int64(); err != nil {
+				return "", err
+			}
+			continue
+		}
+		if versions[i], err = strconv.ParseInt(value.(string), 10, 64); err != nil {
+			return "", err
+		}
+	}
+	return taggedKey(key, tags, versions), nil
+}
+
+func (c *RedisCache) InvalidateTags(ctx context.Context, tags ...string) error {
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, tag := range tags {
+			pipe.Incr(ctx, tagCounterKey(tag))
+		}
+		return nil
+	})
+	return err
+}
@@ -0,0 +1,304 @@
+package cache
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// redisStub is an in-process stand-in for Redis. It speaks enough RESP for
+// the commands the cache sends, and can answer as a sentinel or as a
+// cluster that owns every slot.
+type redisStub struct {
+	listener net.Listener
+	username string
+	password string
+	// masterName is the master the stub reports itself as, as a sentinel
+	masterName string
+
+	mu          sync.Mutex
+	data        map[string]string
+	subscribers map[string][]*stubConn
+	commands    []string
+}
+
+type stubConn struct {
+	net.Conn
+	mu     sync.Mutex
+	writer *bufio.Writer
+}
+
+type redisStubOption func(*redisStub)
+
+// withAuth makes the stub require AUTH, with an ACL user if username is set
+func withAuth(username string, password string) redisStubOption {
+	return func(s *redisStub) { s.username, s.password = username, password }
+}
+
+// withSentinel makes the stub answer as a sentinel of masterName that points
+// at itself
+func withSentinel(masterName string) redisStubOption {
+	return func(s *redisStub) { s.masterName = masterName }
+}
+
+// startRedisStub starts a stub on a local port until the test ends. With a
+// TLS config it only accepts TLS connections.
+func startRedisStub(t *testing.T, tlsConfig *tls.Config, options ...redisStubOption) *redisStub {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+
+	s := &redisStub{listener: listener, data: map[string]string{}, subscribers: map[string][]*stubConn{}}
+	for _, option := range options {
+		option(s)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(&stubConn{Conn: conn, writer: bufio.NewWriter(conn)})
+		}
+	}()
+	return s
+}
+
+func (s *redisStub) addr() string {
+	return s.listener.Addr().String()
+}
+
+// received returns the names of the commands received so far
+func (s *redisStub) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.commands...)
+}
+
+func (s *redisStub) serve(conn *stubConn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	authenticated := s.password == ""
+
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		name := strings.ToUpper(args[0])
+		s.mu.Lock()
+		s.commands = append(s.commands, name)
+		s.mu.Unlock()
+
+		if name == "AUTH" {
+			username, password := "default", args[len(args)-1]
+			if len(args) == 3 {
+				username = args[1]
+			}
+			if password != s.password || (s.username != "" && username != s.username) {
+				conn.reply("-WRONGPASS invalid username-password pair\r\n")
+				continue
+			}
+			authenticated = true
+			conn.reply("+OK\r\n")
+			continue
+		}
+		if !authenticated {
+			conn.reply("-NOAUTH Authentication required.\r\n")
+			continue
+		}
+		conn.reply(s.execute(conn, name, args[1:]))
+	}
+}
+
+// execute runs a command and returns its encoded reply
+func (s *redisStub) execute(conn *stubConn, name string, args []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch name {
+	case "PING":
+		return "+PONG\r\n"
+	case "SELECT", "READONLY":
+		return "+OK\r\n"
+	case "GET":
+		value, ok := s.data[args[0]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return bulk(value)
+	case "SET":
+		for _, option := range args[2:] {
+			if _, exists := s.data[args[0]]; strings.ToUpper(option) == "NX" && exists {
+				return "$-1\r\n"
+			}
+		}
+		s.data[args[0]] = args[1]
+		return "+OK\r\n"
+	case "SETNX":
+		if _, exists := s.data[args[0]]; exists {
+			return ":0\r\n"
+		}
+		s.data[args[0]] = args[1]
+		return ":1\r\n"
+	case "DEL":
+		deleted := 0
+		for _, key := range args {
+			if _, ok := s.data[key]; ok {
+				delete(s.data, key)
+				deleted++
+			}
+		}
+		return ":" + strconv.Itoa(deleted) + "\r\n"
+	case "MGET":
+		reply := "*" + strconv.Itoa(len(args)) + "\r\n"
+		for _, key := range args {
+			if value, ok := s.data[key]; ok {
+				reply += bulk(value)
+			} else {
+				reply += "$-1\r\n"
+			}
+		}
+		return reply
+	case "INCR":
+		n, _ := strconv.ParseInt(s.data[args[0]], 10, 64)
+		s.data[args[0]] = strconv.FormatInt(n+1, 10)
+		return ":" + strconv.FormatInt(n+1, 10) + "\r\n"
+	case "EVAL":
+		// The only script is deleteIfValueScript
+		if args[0] != deleteIfValueScript {
+			return "-ERR unknown script\r\n"
+		}
+		if s.data[args[2]] != args[3] {
+			return ":0\r\n"
+		}
+		delete(s.data, args[2])
+		return ":1\r\n"
+	case "PUBLISH":
+		subscribers := s.subscribers[args[0]]
+		for _, subscriber := range subscribers {
+			go subscriber.reply("*3\r\n" + bulk("message") + bulk(args[0]) + bulk(args[1]))
+		}
+		return ":" + strconv.Itoa(len(subscribers)) + "\r\n"
+	case "SUBSCRIBE":
+		reply := ""
+		for i, channel := range args {
+			s.subscribers[channel] = append(s.subscribers[channel], conn)
+			reply += "*3\r\n" + bulk("subscribe") + bulk(channel) + ":" + strconv.Itoa(i+1) + "\r\n"
+		}
+		return reply
+	case "SENTINEL":
+		switch strings.ToLower(args[0]) {
+		case "get-master-addr-by-name":
+			if args[1] != s.masterName {
+				return "*-1\r\n"
+			}
+			host, port, _ := net.SplitHostPort(s.addr())
+			return "*2\r\n" + bulk(host) + bulk(port)
+		case "sentinels":
+			return "*0\r\n"
+		}
+	case "CLUSTER":
+		if strings.ToLower(args[0]) == "slots" {
+			host, port, _ := net.SplitHostPort(s.addr())
+			return "*1\r\n*3\r\n:0\r\n:16383\r\n*3\r\n" + bulk(host) + ":" + port + "\r\n" + bulk("stub")
+		}
+	}
+	return "-ERR unknown command '" + name + "'\r\n"
+}
+
+func (c *stubConn) reply(data string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writer.WriteString(data)
+	c.writer.Flush()
+}
+
+func bulk(value string) string {
+	return "$" + strconv.Itoa(len(value)) + "\r\n" + value + "\r\n"
+}
+
+// readCommand reads a command sent as an array of bulk strings
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return nil, fmt.Errorf("unexpected %q", line)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("unexpected %q", line)
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(header, "$")))
+		if err != nil {
+			return nil, fmt.Errorf("unexpected %q", header)
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
+
+// newTestTLS returns a server TLS config with a self-signed certificate for
+// 127.0.0.1, and the path of its CA file
+func newTestTLS(t *testing.T) (*tls.Config, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "redis-stub"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}, caFile
+}
@@ -0,0 +1,189 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+// newStubClient returns a client of config connected to the stub, closed when
+// the test ends
+func newStubClient(t *testing.T, config RedisConfig) redis.UniversalClient {
+	client, err := NewRedisClientWithConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestRedisCache(t *testing.T) {
+	ctx := context.Background()
+	stub := startRedisStub(t, nil)
+	c := NewRedisCache(newStubClient(t, RedisConfig{Mode: RedisStandalone, Addrs: []string{stub.addr()}}))
+
+	_, err := c.Get(ctx, "key")
+	assert.ErrorIs(t, err, ErrMiss)
+	assert.NoError(t, c.Set(ctx, "key", []byte("v1"), time.Minute))
+	value, err := c.Get(ctx, "key")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("v1"), value)
+
+	ok, err := c.SetNX(ctx, "key", []byte("v2"), time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = c.SetNX(ctx, "lock", []byte("token"), 0)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	deleted, err := c.DeleteIfValue(ctx, "lock", []byte("other"))
+	assert.NoError(t, err)
+	assert.False(t, deleted)
+	deleted, err = c.DeleteIfValue(ctx, "lock", []byte("token"))
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+
+	assert.NoError(t, c.Delete(ctx, "key"))
+	_, err = c.Get(ctx, "key")
+	assert.ErrorIs(t, err, ErrMiss)
+
+	key, err := c.TagKey(ctx, "page", "books")
+	assert.NoError(t, err)
+	again, _ := c.TagKey(ctx, "page", "books")
+	assert.Equal(t, key, again)
+	assert.NoError(t, c.InvalidateTags(ctx, "books"))
+	again, _ = c.TagKey(ctx, "page", "books")
+	assert.NotEqual(t, key, again)
+}
+
+func TestRedisInvalidationBus(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stub := startRedisStub(t, nil)
+	config := RedisConfig{Mode: RedisStandalone, Addrs: []string{stub.addr()}}
+	bus := NewRedisInvalidationBus(newStubClient(t, config))
+
+	var mu sync.Mutex
+	var received []Invalidation
+	live := make(chan struct{})
+	go bus.Subscribe(ctx, func(msg Invalidation) {
+		mu.Lock()
+		received = append(received, msg)
+		mu.Unlock()
+	}, func(connected bool) {
+		if connected {
+			close(live)
+		}
+	})
+	<-live
+
+	msg := Invalidation{Origin: "replica-1", Tags: []string{"books"}}
+	assert.NoError(t, NewRedisInvalidationBus(newStubClient(t, config)).Publish(ctx, msg))
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 1 && received[0].Origin == msg.Origin
+	}, time.Second, time.Millisecond)
+}
+
+func TestNewRedisClientWithACL(t *testing.T) {
+	ctx := context.Background()
+	stub := startRedisStub(t, nil, withAuth("app", "secret"))
+
+	client := newStubClient(t, RedisConfig{Mode: RedisStandalone, Addrs: []string{stub.addr()}, Username: "app", Password: "secret", DB: 2})
+	assert.NoError(t, client.Ping(ctx).Err())
+	assert.Contains(t, stub.received(), "SELECT")
+
+	client = newStubClient(t, RedisConfig{Mode: RedisStandalone, Addrs: []string{stub.addr()}, Username: "app", Password: "wrong"})
+	assert.ErrorContains(t, client.Ping(ctx).Err(), "WRONGPASS")
+}
+
+func TestNewRedisClientWithSentinel(t *testing.T) {
+	ctx := context.Background()
+	stub := startRedisStub(t, nil, withSentinel("cache"))
+
+	client := newStubClient(t, RedisConfig{Mode: RedisSentinel, Addrs: []string{stub.addr()}, MasterName: "cache"})
+	c := NewRedisCache(client)
+	assert.NoError(t, c.Set(ctx, "key", []byte("v1"), 0))
+	value, err := c.Get(ctx, "key")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("v1"), value)
+	assert.Contains(t, stub.received(), "SENTINEL")
+
+	client = newStubClient(t, RedisConfig{Mode: RedisSentinel, Addrs: []string{stub.addr()}, MasterName: "other"})
+	assert.Error(t, client.Ping(ctx).Err())
+}
+
+func TestNewRedisClientWithCluster(t *testing.T) {
+	ctx := context.Background()
+	stub := startRedisStub(t, nil)
+
+	c := NewRedisCache(newStubClient(t, RedisConfig{Mode: RedisCluster, Addrs: []string{stub.addr()}}))
+	assert.NoError(t, c.Set(ctx, "key", []byte("v1"), 0))
+	value, err := c.Get(ctx, "key")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("v1"), value)
+	_, err = c.TagKey(ctx, "page", "books", "authors")
+	assert.NoError(t, err)
+	assert.Contains(t, stub.received(), "CLUSTER")
+}
+
+func TestNewRedisClientWithTLS(t *testing.T) {
+	ctx := context.Background()
+	serverTLS, caFile := newTestTLS(t)
+	stub := startRedisStub(t, serverTLS)
+
+	client := newStubClient(t, RedisConfig{Mode: RedisStandalone, Addrs: []string{stub.addr()}, TLS: true, TLSCAFile: caFile})
+	assert.NoError(t, client.Ping(ctx).Err())
+
+	// The stub's certificate isn't trusted without its CA
+	client = newStubClient(t, RedisConfig{Mode: RedisStandalone, Addrs: []string{stub.addr()}, TLS: true})
+	assert.Error(t, client.Ping(ctx).Err())
+}
+
+func TestNewRedisClientRejectsInvalidConfig(t *testing.T) {
+	for name, config := range map[string]RedisConfig{
+		"no address":              {Mode: RedisStandalone},
+		"standalone addresses":    {Mode: RedisStandalone, Addrs: []string{"a:6379", "b:6379"}},
+		"sentinel without master": {Mode: RedisSentinel, Addrs: []string{"a:26379"}},
+		"cluster database":        {Mode: RedisCluster, Addrs: []string{"a:6379"}, DB: 1},
+		"unknown mode":            {Mode: "replicated", Addrs: []string{"a:6379"}},
+		"missing CA":              {Mode: RedisStandalone, Addrs: []string{"a:6379"}, TLS: true, TLSCAFile: "missing.pem"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewRedisClientWithConfig(config)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoadRedisConfig(t *testing.T) {
+	t.Setenv("REDIS_HOST", "redis")
+	t.Setenv("REDIS_PORT", "6380")
+	config := LoadRedisConfig()
+	assert.Equal(t, RedisStandalone, config.Mode)
+	assert.Equal(t, []string{"redis:6380"}, config.Addrs)
+
+	t.Setenv("REDIS_MODE", RedisSentinel)
+	t.Setenv("REDIS_ADDRS", "sentinel-1:26379, sentinel-2:26379,")
+	t.Setenv("REDIS_SENTINEL_MASTER", "cache")
+	t.Setenv("REDIS_USERNAME", "app")
+	t.Setenv("REDIS_DB", "3")
+	t.Setenv("REDIS_TLS", "true")
+	t.Setenv("REDIS_POOL_SIZE", "20")
+	t.Setenv("REDIS_READ_TIMEOUT_MS", "250")
+	config = LoadRedisConfig()
+	assert.Equal(t, RedisSentinel, config.Mode)
+	assert.Equal(t, []string{"sentinel-1:26379", "sentinel-2:26379"}, config.Addrs)
+	assert.Equal(t, "cache", config.MasterName)
+	assert.Equal(t, "app", config.Username)
+	assert.Equal(t, 3, config.DB)
+	assert.True(t, config.TLS)
+	assert.Equal(t, 20, config.PoolSize)
+	assert.Equal(t, 250*time.Millisecond, config.ReadTimeout)
+	assert.Zero(t, config.DialTimeout)
+}
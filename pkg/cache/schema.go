@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// SchemaVersion returns a short hash of the shape of the type of v: the
+// names, types and tags of its exported fields, recursively. Keys that embed
+// it change whenever a deploy changes the type, so values serialized by an
+// older release are never decoded into the new one. Changes to how a field
+// type marshals itself aren't seen.
+func SchemaVersion(v interface{}) string {
+	hash := sha256.New()
+	writeSchema(hash, reflect.TypeOf(v), map[reflect.Type]bool{})
+	return hex.EncodeToString(hash.Sum(nil)[:4])
+}
+
+func writeSchema(w io.Writer, t reflect.Type, seen map[reflect.Type]bool) {
+	fmt.Fprintf(w, "%s(%s)", t, t.Kind())
+	switch t.Kind() {
+	case reflect.Map:
+		writeSchema(w, t.Key(), seen)
+		writeSchema(w, t.Elem(), seen)
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		writeSchema(w, t.Elem(), seen)
+	case reflect.Struct:
+		// Recursive types are described once
+		if seen[t] {
+			return
+		}
+		seen[t] = true
+		fmt.Fprint(w, "{")
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" && !field.Anonymous {
+				continue
+			}
+			fmt.Fprintf(w, "%s %q ", field.Name, field.Tag)
+			writeSchema(w, field.Type, seen)
+			fmt.Fprint(w, ";")
+		}
+		fmt.Fprint(w, "}")
+	}
+}
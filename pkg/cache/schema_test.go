@@ -0,0 +1,41 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type schemaNode struct {
+	Name     string        `json:"name"`
+	Children []*schemaNode `json:"children"`
+	Updated  time.Time     `json:"updated"`
+	internal int
+}
+
+func TestSchemaVersion(t *testing.T) {
+	version := SchemaVersion(schemaNode{})
+	assert.Len(t, version, 8)
+	assert.Equal(t, version, SchemaVersion(schemaNode{Name: "root"}))
+
+	// Renamed fields, retyped fields and changed tags all change it
+	type renamed struct {
+		Title    string        `json:"name"`
+		Children []*schemaNode `json:"children"`
+		Updated  time.Time     `json:"updated"`
+	}
+	type retyped struct {
+		Name     []byte        `json:"name"`
+		Children []*schemaNode `json:"children"`
+		Updated  time.Time     `json:"updated"`
+	}
+	type retagged struct {
+		Name     string        `json:"title"`
+		Children []*schemaNode `json:"children"`
+		Updated  time.Time     `json:"updated"`
+	}
+	for _, other := range []interface{}{renamed{}, retyped{}, retagged{}} {
+		assert.NotEqual(t, SchemaVersion(schemaNode{}), SchemaVersion(other))
+	}
+}
@@ -0,0 +1,259 @@
+package cache
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TieredCache serves reads from an in-process L1 in front of a shared L2.
+// Writes go to the L2 and evict the L1 copies of this instance. Deletes and
+// tag invalidations are also published on the bus, so every other instance
+// evicts its copies too. Sets are not: most of them fill tag versioned keys,
+// which never change, and broadcasting every cache miss would evict the
+// other instances' copies of the value just computed. A key overwritten in
+// place is picked up elsewhere once their L1 copies expire.
+//
+// The L1 is only used while the bus subscription is live, and it is emptied
+// whenever the subscription comes up or drops, since invalidations may have
+// been missed in between. L1 entries live for ttl at most, which bounds how
+// stale a copy can get if a publish is lost.
+type TieredCache struct {
+	l1     *MemoryCache
+	l2     Cache
+	bus    InvalidationBus
+	ttl    time.Duration
+	origin string
+
+	mu        sync.Mutex
+	connected bool
+	// epoch moves on every eviction. A read from the L2 only fills the L1
+	// if no eviction happened meanwhile, so it can't store a value that was
+	// superseded while it was in flight.
+	epoch         uint64
+	tagVersions   map[string]tagVersion
+	invalidations int64
+}
+
+// tagVersion is the L2 version of a tag, as the suffix it adds to keys
+type tagVersion struct {
+	suffix    string
+	expiresAt time.Time
+}
+
+func NewTieredCache(l2 Cache, bus InvalidationBus, maxEntries int, ttl time.Duration) *TieredCache {
+	hostname, _ := os.Hostname()
+	return &TieredCache{
+		l1:          NewMemoryCache(maxEntries),
+		l2:          l2,
+		bus:         bus,
+		ttl:         ttl,
+		origin:      hostname + "-" + strconv.Itoa(os.Getpid()) + "-" + strconv.FormatInt(time.Now().UnixNano(), 36),
+		tagVersions: map[string]tagVersion{},
+	}
+}
+
+// Listen applies the invalidations of the other instances until ctx is done.
+// The L1 stays unused until it runs.
+func (c *TieredCache) Listen(ctx context.Context) {
+	c.bus.Subscribe(ctx, c.handle, c.setConnected)
+	c.setConnected(false)
+}
+
+func (c *TieredCache) handle(msg Invalidation) {
+	if msg.Origin == c.origin {
+		return
+	}
+	c.evict(msg.Keys, msg.Tags)
+	c.mu.Lock()
+	c.invalidations++
+	c.mu.Unlock()
+}
+
+func (c *TieredCache) setConnected(connected bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.connected == connected {
+		return
+	}
+	c.connected = connected
+	c.epoch++
+	c.tagVersions = map[string]tagVersion{}
+	c.l1.evictAll()
+}
+
+// snapshot returns the current epoch and whether the L1 can be used
+func (c *TieredCache) snapshot() (uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.epoch, c.connected
+}
+
+// evict drops keys and the versions of tags from the L1. The entries of the
+// old tag versions can't be reached anymore and age out on their own.
+func (c *TieredCache) evict(keys []string, tags []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.epoch++
+	for _, tag := range tags {
+		delete(c.tagVersions, tag)
+	}
+	c.l1.evict(keys)
+}
+
+// invalidate evicts keys and tags here and on every other instance. The L2
+// write went through either way, so a failed publish is only logged: the
+// other instances catch up once their L1 copies expire.
+func (c *TieredCache) invalidate(ctx context.Context, keys []string, tags []string) {
+	c.evict(keys, tags)
+	if err := c.bus.Publish(ctx, Invalidation{Origin: c.origin, Keys: keys, Tags: tags}); err != nil {
+		log.Printf("Failed to publish cache invalidation: %v", err)
+	}
+}
+
+func (c *TieredCache) Get(ctx context.Context, key string) ([]byte, error) {
+	epoch, connected := c.snapshot()
+	if connected {
+		if value, err := c.l1.Get(ctx, key); err == nil {
+			return value, nil
+		}
+	}
+
+	value, err := c.l2.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.connected && c.epoch == epoch {
+		c.l1.Set(ctx, key, value, c.ttl)
+	}
+	return value, nil
+}
+
+func (c *TieredCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := c.l2.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+	c.evict([]string{key}, nil)
+	return nil
+}
+
+func (c *TieredCache) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	ok, err := c.l2.SetNX(ctx, key, value, ttl)
+	if err != nil || !ok {
+		return ok, err
+	}
+	c.evict([]string{key}, nil)
+	return true, nil
+}
+
+// Delete evicts the L1 copies of keys even if the L2 fails, so this instance
+// at least stops serving them
+func (c *TieredCache) Delete(ctx context.Context, keys ...string) error {
+	if err := c.l2.Delete(ctx, keys...); err != nil {
+		c.evict(keys, nil)
+		return err
+	}
+	c.invalidate(ctx, keys, nil)
+	return nil
+}
+
+func (c *TieredCache) DeleteIfValue(ctx context.Context, key string, value []byte) (bool, error) {
+	deleted, err := c.l2.DeleteIfValue(ctx, key, value)
+	if err != nil || !deleted {
+		return deleted, err
+	}
+	c.invalidate(ctx, []string{key}, nil)
+	return true, nil
+}
+
+// TagKey qualifies key with the tag versions of the L2, which are kept in the
+// L1 like entries, so a cache hit doesn't need an L2 round trip at all
+func (c *TieredCache) TagKey(ctx context.Context, key string, tags ...string) (string, error) {
+	suffixes := make([]string, len(tags))
+	var missing []int
+
+	c.mu.Lock()
+	epoch, connected, now := c.epoch, c.connected, c.l1.now()
+	for i, tag := range tags {
+		if cached, ok := c.tagVersions[tag]; connected && ok && now.Before(cached.expiresAt) {
+			suffixes[i] = cached.suffix
+		} else {
+			missing = append(missing, i)
+		}
+	}
+	c.mu.Unlock()
+
+	for _, i := range missing {
+		suffix, err := c.l2.TagKey(ctx, "", tags[i])
+		if err != nil {
+			return "", err
+		}
+		suffixes[i] = suffix
+	}
+
+	if len(missing) > 0 {
+		c.mu.Lock()
+		if c.connected && c.epoch == epoch {
+			c.pruneTagVersions()
+			for _, i := range missing {
+				c.tagVersions[tags[i]] = tagVersion{suffix: suffixes[i], expiresAt: now.Add(c.ttl)}
+			}
+		}
+		c.mu.Unlock()
+	}
+	return key + strings.Join(suffixes, ""), nil
+}
+
+// pruneTagVersions keeps the cached tag versions within the size of the L1,
+// there is one per cached book. Expired versions go first, and all of them if
+// that isn't enough: they are only a shortcut to the L2.
+func (c *TieredCache) pruneTagVersions() {
+	if len(c.tagVersions) < c.l1.maxEntries {
+		return
+	}
+	now := c.l1.now()
+	for tag, cached := range c.tagVersions {
+		if !now.Before(cached.expiresAt) {
+			delete(c.tagVersions, tag)
+		}
+	}
+	if len(c.tagVersions) >= c.l1.maxEntries {
+		c.tagVersions = map[string]tagVersion{}
+	}
+}
+
+// InvalidateTags evicts the L1 entries of tags even if the L2 fails, like
+// Delete
+func (c *TieredCache) InvalidateTags(ctx context.Context, tags ...string) error {
+	if err := c.l2.InvalidateTags(ctx, tags...); err != nil {
+		c.evict(nil, tags)
+		return err
+	}
+	c.invalidate(ctx, nil, tags)
+	return nil
+}
+
+func (c *TieredCache) Stats() Stats {
+	stats := c.l1.Stats()
+	c.mu.Lock()
+	stats.Invalidations = c.invalidations
+	c.mu.Unlock()
+	return stats
+}
+
+// Health reports the health of the L2
+func (c *TieredCache) Health() Health {
+	if reporter, ok := c.l2.(HealthReporter); ok {
+		return reporter.Health()
+	}
+	return Health{Status: HealthOK, Breaker: BreakerClosed}
+}
@@ -0,0 +1,261 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+// localBus delivers invalidations to the subscribers in the same process
+type localBus struct {
+	mu       sync.Mutex
+	handlers []func(Invalidation)
+}
+
+func (b *localBus) Publish(ctx context.Context, msg Invalidation) error {
+	b.mu.Lock()
+	handlers := append([]func(Invalidation){}, b.handlers...)
+	b.mu.Unlock()
+
+	for _, handle := range handlers {
+		handle(msg)
+	}
+	return nil
+}
+
+func (b *localBus) Subscribe(ctx context.Context, handle func(Invalidation), connected func(bool)) {
+	b.mu.Lock()
+	b.handlers = append(b.handlers, handle)
+	b.mu.Unlock()
+	connected(true)
+	<-ctx.Done()
+}
+
+// newReplica returns a tiered cache listening to bus until the test ends
+func newReplica(t *testing.T, l2 Cache, bus InvalidationBus) *TieredCache {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	c := NewTieredCache(l2, bus, 100, time.Minute)
+	go c.Listen(ctx)
+	assert.Eventually(t, func() bool {
+		_, connected := c.snapshot()
+		return connected
+	}, time.Second, time.Millisecond)
+	return c
+}
+
+func TestTieredCacheServesHitsFromL1(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	l2 := NewMockCache(ctrl)
+	c := newReplica(t, l2, &localBus{})
+
+	l2.EXPECT().TagKey(gomock.Any(), "", "books").Return("@books:7", nil).Times(1)
+	l2.EXPECT().Get(gomock.Any(), "page@books:7").Return([]byte("cached"), nil).Times(1)
+
+	for i := 0; i < 3; i++ {
+		key, err := c.TagKey(ctx, "page", "books")
+		assert.NoError(t, err)
+		assert.Equal(t, "page@books:7", key)
+		value, err := c.Get(ctx, key)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("cached"), value)
+	}
+
+	stats := c.Stats()
+	assert.Equal(t, int64(2), stats.Hits)
+	assert.Equal(t, int64(1), stats.Misses)
+	assert.Equal(t, 1, stats.Entries)
+}
+
+func TestTieredCacheInvalidatesOtherReplicas(t *testing.T) {
+	ctx := context.Background()
+	l2 := NewMemoryCache(100)
+	bus := &localBus{}
+	writer, reader := newReplica(t, l2, bus), newReplica(t, l2, bus)
+
+	key, _ := reader.TagKey(ctx, "page", "books")
+	assert.NoError(t, writer.Set(ctx, key, []byte("v1"), 0))
+	value, _ := reader.Get(ctx, key)
+	assert.Equal(t, []byte("v1"), value)
+
+	// Filling the cache is not broadcast, the reader keeps its copy
+	assert.NoError(t, writer.Set(ctx, key, []byte("v1"), 0))
+	assert.Equal(t, int64(0), reader.Stats().Invalidations)
+	assert.Equal(t, 1, reader.Stats().Entries)
+
+	// Invalidated tags drop their versions, the entries under the old ones
+	// can't be reached anymore
+	assert.NoError(t, writer.InvalidateTags(ctx, "books"))
+	assert.Equal(t, int64(1), reader.Stats().Invalidations)
+	again, _ := reader.TagKey(ctx, "page", "books")
+	assert.NotEqual(t, key, again)
+	_, err := reader.Get(ctx, again)
+	assert.ErrorIs(t, err, ErrMiss)
+
+	// Deletes reach the other replicas
+	assert.NoError(t, reader.Set(ctx, "import", []byte("running"), 0))
+	_, _ = writer.Get(ctx, "import")
+	assert.NoError(t, reader.Delete(ctx, "import"))
+	_, err = writer.Get(ctx, "import")
+	assert.ErrorIs(t, err, ErrMiss)
+}
+
+// failingBus can't publish
+type failingBus struct{ localBus }
+
+func (b *failingBus) Publish(ctx context.Context, msg Invalidation) error {
+	return errors.New("connection refused")
+}
+
+func TestTieredCacheWritesDespiteFailedPublish(t *testing.T) {
+	ctx := context.Background()
+	l2 := NewMemoryCache(100)
+	c := newReplica(t, l2, &failingBus{})
+
+	// The L2 has the write, the other replicas catch up with the L1 ttl
+	assert.NoError(t, c.Set(ctx, "key", []byte("v1"), 0))
+	assert.NoError(t, c.Delete(ctx, "key"))
+	assert.NoError(t, c.InvalidateTags(ctx, "books"))
+	_, err := l2.Get(ctx, "key")
+	assert.ErrorIs(t, err, ErrMiss)
+}
+
+func TestTieredCacheBypassesL1WhileDisconnected(t *testing.T) {
+	ctx := context.Background()
+	l2 := NewMemoryCache(100)
+	c := NewTieredCache(l2, &localBus{}, 100, time.Minute)
+
+	assert.NoError(t, l2.Set(ctx, "key", []byte("v1"), 0))
+	_, _ = c.Get(ctx, "key")
+	assert.Equal(t, 0, c.Stats().Entries)
+
+	c.setConnected(true)
+	_, _ = c.Get(ctx, "key")
+	assert.Equal(t, 1, c.Stats().Entries)
+
+	// Invalidations may be missed while the subscription is down
+	c.setConnected(false)
+	assert.Equal(t, 0, c.Stats().Entries)
+	assert.NoError(t, l2.Set(ctx, "key", []byte("v2"), 0))
+	value, _ := c.Get(ctx, "key")
+	assert.Equal(t, []byte("v2"), value)
+}
+
+func TestTieredCacheExpiresL1Entries(t *testing.T) {
+	ctx := context.Background()
+	l2 := NewMemoryCache(100)
+	c := NewTieredCache(l2, &localBus{}, 100, time.Second)
+	c.setConnected(true)
+	now := time.Date(2024, time.May, 1, 8, 0, 0, 0, time.UTC)
+	c.l1.now = func() time.Time { return now }
+
+	assert.NoError(t, l2.Set(ctx, "key", []byte("v1"), 0))
+	_, _ = c.Get(ctx, "key")
+	key, _ := c.TagKey(ctx, "page", "books")
+
+	// A publish that never arrives is outlived by the L1 ttl
+	assert.NoError(t, l2.Set(ctx, "key", []byte("v2"), 0))
+	assert.NoError(t, l2.InvalidateTags(ctx, "books"))
+	now = now.Add(time.Second)
+	value, _ := c.Get(ctx, "key")
+	assert.Equal(t, []byte("v2"), value)
+	again, _ := c.TagKey(ctx, "page", "books")
+	assert.NotEqual(t, key, again)
+}
+
+// blockingCache holds Get calls until released
+type blockingCache struct {
+	*MemoryCache
+	started chan struct{}
+	release chan struct{}
+}
+
+func (c *blockingCache) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := c.MemoryCache.Get(ctx, key)
+	c.started <- struct{}{}
+	<-c.release
+	return value, err
+}
+
+func TestTieredCacheDoesNotFillL1WithSupersededValues(t *testing.T) {
+	ctx := context.Background()
+	l2 := &blockingCache{MemoryCache: NewMemoryCache(100), started: make(chan struct{}), release: make(chan struct{})}
+	bus := &localBus{}
+	reader, writer := newReplica(t, l2, bus), newReplica(t, l2.MemoryCache, bus)
+	assert.NoError(t, l2.Set(ctx, "key", []byte("old"), 0))
+
+	// The reader reads the old value, then the writer deletes it before
+	// the reader gets to fill its L1
+	read := make(chan []byte)
+	go func() {
+		value, _ := reader.Get(ctx, "key")
+		read <- value
+	}()
+	<-l2.started
+	assert.NoError(t, writer.Delete(ctx, "key"))
+	close(l2.release)
+	assert.Equal(t, []byte("old"), <-read)
+
+	go func() { <-l2.started }()
+	_, err := reader.Get(ctx, "key")
+	assert.ErrorIs(t, err, ErrMiss)
+}
+
+func TestTieredCacheConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+	l2 := NewMemoryCache(1000)
+	bus := &localBus{}
+	replicas := []*TieredCache{newReplica(t, l2, bus), newReplica(t, l2, bus), newReplica(t, l2, bus)}
+
+	// version stands in for the database: writers change it, then
+	// invalidate the tag, and readers cache what they read under a key
+	// taken before reading
+	var version int64
+	var wg sync.WaitGroup
+	for _, replica := range replicas {
+		wg.Add(2)
+		go func(c *TieredCache) {
+			defer wg.Done()
+			for n := 0; n < 200; n++ {
+				atomic.AddInt64(&version, 1)
+				assert.NoError(t, c.InvalidateTags(ctx, "books"))
+			}
+		}(replica)
+		go func(c *TieredCache) {
+			defer wg.Done()
+			for n := 0; n < 500; n++ {
+				key, err := c.TagKey(ctx, "page", "books")
+				assert.NoError(t, err)
+				if _, err := c.Get(ctx, key); err == ErrMiss {
+					current := strconv.FormatInt(atomic.LoadInt64(&version), 10)
+					assert.NoError(t, c.Set(ctx, key, []byte(current), time.Minute))
+				}
+			}
+		}(replica)
+	}
+	wg.Wait()
+
+	// Once the writes are over, every replica reads the last version
+	final := strconv.FormatInt(atomic.LoadInt64(&version), 10)
+	for _, c := range replicas {
+		key, err := c.TagKey(ctx, "page", "books")
+		assert.NoError(t, err)
+		value, err := c.Get(ctx, key)
+		if err == nil {
+			assert.Equal(t, final, string(value))
+		} else {
+			assert.ErrorIs(t, err, ErrMiss)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package database
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -24,6 +26,9 @@ type Database interface {
 	Updates(interface{}) *gorm.DB
 	Order(value interface{}) *gorm.DB
 	Error() error
+	// WithContext returns the database bound to ctx, e.g. the context of
+	// the request being served
+	WithContext(ctx context.Context) Database
 }
 
 type GormDatabase struct {
@@ -42,8 +47,35 @@ func (db *GormDatabase) Error() error {
 	return db.DB.Error
 }
 
+func (db *GormDatabase) WithContext(ctx context.Context) Database {
+	return &GormDatabase{db.DB.WithContext(ctx)}
+}
+
+// NewDatabase connects to the database and makes sure its schema is current.
+// With APP_MIGRATIONS the pending migrations are applied, otherwise startup
+// fails until they are applied with the migrate command.
 func NewDatabase() *gorm.DB {
-	isUseMigration := env.GetEnvBool("APP_MIGRATIONS", false)
+	database := Connect()
+
+	migrator, err := migration.New(database)
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
+	}
+	if env.GetEnvBool("APP_MIGRATIONS", false) {
+		if err := migrator.Up(context.Background()); err != nil {
+			log.Fatalf("Failed to migrate database: %v", err)
+		}
+	} else if err := migrator.Check(context.Background()); errors.Is(err, migration.ErrSchemaBehind) {
+		log.Fatalf("%v; apply them with the migrate command or set APP_MIGRATIONS=true", err)
+	} else if err != nil {
+		log.Fatalf("Failed to check database schema: %v", err)
+	}
+
+	return database
+}
+
+// Connect connects to the database as is, for the migrate command
+func Connect() *gorm.DB {
 	var database *gorm.DB
 	var err error
 
@@ -63,7 +95,7 @@ func NewDatabase() *gorm.DB {
 	)
 
 	for i := 1; i <= 3; i++ {
-		database, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+		database, err = gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
 		if err == nil {
 			break
 		} else {
@@ -72,11 +104,5 @@ func NewDatabase() *gorm.DB {
 		}
 	}
 
-	if isUseMigration {
-		if err := migration.Migrate(database); err != nil {
-			log.Fatalf("Failed to migrate database: %v", err)
-		}
-	}
-
 	return database
 }
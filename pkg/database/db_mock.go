@@ -5,6 +5,7 @@
 package database
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -207,3 +208,17 @@ func (mr *MockDatabaseMockRecorder) Where(query interface{}, args ...interface{}
 	varargs := append([]interface{}{query}, args...)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Where", reflect.TypeOf((*MockDatabase)(nil).Where), varargs...)
 }
+
+// WithContext mocks base method.
+func (m *MockDatabase) WithContext(ctx context.Context) Database {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithContext", ctx)
+	ret0, _ := ret[0].(Database)
+	return ret0
+}
+
+// WithContext indicates an expected call of WithContext.
+func (mr *MockDatabaseMockRecorder) WithContext(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithContext", reflect.TypeOf((*MockDatabase)(nil).WithContext), ctx)
+}
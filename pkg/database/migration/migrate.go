@@ -1,15 +1,430 @@
 package migration
 
 import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
 	"gorm.io/gorm"
 )
 
-func Migrate(db *gorm.DB) error {
-	// Enable UUID extension for PostgreSQL
-	if err := db.Exec("CREATE EXTENSION IF NOT EXISTS \"uuid-ossp\"").Error; err != nil {
+// Dir is where new migrations are created, relative to the repository root
+const Dir = "pkg/database/migration/migrations"
+
+//go:embed migrations/*.sql
+var files embed.FS
+
+// lockID is the key of the advisory lock that keeps replicas from migrating
+// at the same time
+const lockID int64 = 7_304_825_512
+
+var ErrSchemaBehind = errors.New("database schema is behind")
+
+// Migration is one version of the schema. Most are a pair of up and down SQL
+// files in Dir; the few that need the models are written in Go.
+type Migration struct {
+	Version int64
+	Name    string
+	up      func(tx *gorm.DB) error
+	down    func(tx *gorm.DB) error
+}
+
+func (migration Migration) String() string {
+	return fmt.Sprintf("%04d_%s", migration.Version, migration.Name)
+}
+
+// goMigrations are the migrations written in Go
+var goMigrations = []Migration{
+	{
+		Version: 2,
+		Name:    "backfill_book_authors",
+		up:      func(tx *gorm.DB) error { return BackfillBookAuthors(tx, 500) },
+		// The credits are kept, they are what new books get anyway
+		down: func(*gorm.DB) error { return nil },
+	},
+}
+
+// Status is a migration and when it was applied. A migration applied by a
+// newer build than this one is Missing, it has no file here.
+type Status struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+	Missing   bool
+}
+
+type appliedMigration struct {
+	Version   int64
+	Name      string
+	AppliedAt time.Time
+}
+
+const createTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version bigint PRIMARY KEY,
+	name text NOT NULL,
+	applied_at timestamptz NOT NULL DEFAULT now()
+)`
+
+// Migrator applies and rolls back migrations, recording the applied ones in
+// the schema_migrations table. Each migration runs in its own transaction.
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// New returns a migrator for the migrations embedded in the binary
+func New(db *gorm.DB) (*Migrator, error) {
+	sqlFiles, err := fs.Sub(files, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return newMigrator(db, sqlFiles, goMigrations)
+}
+
+func newMigrator(db *gorm.DB, sqlFiles fs.FS, coded []Migration) (*Migrator, error) {
+	migrations, err := load(sqlFiles, coded)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// load reads the SQL migrations in sqlFiles and merges them with the coded
+// ones, ordered by version
+func load(sqlFiles fs.FS, coded []Migration) ([]Migration, error) {
+	entries, err := fs.ReadDir(sqlFiles, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migration %s is not named VERSION_NAME.up.sql or VERSION_NAME.down.sql", entry.Name())
+		}
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		content, err := fs.ReadFile(sqlFiles, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d is used by %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.up = execSQL(string(content))
+		} else {
+			migration.down = execSQL(string(content))
+		}
+	}
+
+	for _, migration := range coded {
+		if existing, ok := byVersion[migration.Version]; ok {
+			return nil, fmt.Errorf("migration version %d is used by %s and %s", migration.Version, existing.Name, migration.Name)
+		}
+		byVersion[migration.Version] = &migration
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.up == nil || migration.down == nil {
+			return nil, fmt.Errorf("migration %s needs both an up and a down file", migration)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// execSQL runs a migration file. Without arguments the statements are sent
+// in one round trip, so a file can hold several of them.
+func execSQL(statements string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		return tx.Exec(statements).Error
+	}
+}
+
+// Migrations returns every known migration, oldest first
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// Up applies every pending migration
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.locked(ctx, func(conn *gorm.DB, applied map[int64]appliedMigration) error {
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err := apply(conn, migration); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Down rolls back the last steps applied migrations
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.locked(ctx, func(conn *gorm.DB, applied map[int64]appliedMigration) error {
+		versions := appliedVersions(applied)
+		for i := len(versions) - 1; i >= 0 && steps > 0; i, steps = i-1, steps-1 {
+			if err := m.rollback(conn, applied[versions[i]]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// To applies or rolls back migrations until version is the latest applied
+// one. Version 0 rolls back every migration.
+func (m *Migrator) To(ctx context.Context, version int64) error {
+	if version != 0 && m.find(version) == nil {
+		return fmt.Errorf("no migration has version %d", version)
+	}
+
+	return m.locked(ctx, func(conn *gorm.DB, applied map[int64]appliedMigration) error {
+		versions := appliedVersions(applied)
+		for i := len(versions) - 1; i >= 0 && versions[i] > version; i-- {
+			if err := m.rollback(conn, applied[versions[i]]); err != nil {
+				return err
+			}
+		}
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok || migration.Version > version {
+				continue
+			}
+			if err := apply(conn, migration); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Status returns every migration, known or applied, oldest first
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := readApplied(m.db.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []Status
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if row, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &row.AppliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	for _, row := range applied {
+		if m.find(row.Version) == nil {
+			statuses = append(statuses, Status{Version: row.Version, Name: row.Name, AppliedAt: &row.AppliedAt, Missing: true})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Pending returns the migrations that are not applied yet, oldest first
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	applied, err := readApplied(m.db.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Check fails with ErrSchemaBehind if a migration is pending. A schema that
+// is ahead, migrated by a newer build during a rolling deploy, passes.
+func (m *Migrator) Check(ctx context.Context) error {
+	pending, err := m.Pending(ctx)
+	if err != nil {
 		return err
 	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %d pending migrations up to %s", ErrSchemaBehind, len(pending), pending[len(pending)-1])
+	}
+	return nil
+}
+
+func (m *Migrator) find(version int64) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+// locked runs fn on a connection that holds the advisory lock, with the
+// migrations applied so far. Replicas that start together wait for the first
+// one and then find nothing left to do.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *gorm.DB, applied map[int64]appliedMigration) error) error {
+	return m.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		// The lock belongs to the session, so everything runs on this connection
+		conn = conn.Session(&gorm.Session{})
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", lockID).Error; err != nil {
+			return err
+		}
+		defer conn.WithContext(context.WithoutCancel(ctx)).Exec("SELECT pg_advisory_unlock(?)", lockID)
+
+		if err := conn.Exec(createTable).Error; err != nil {
+			return err
+		}
+		applied, err := readApplied(conn)
+		if err != nil {
+			return err
+		}
+		return fn(conn, applied)
+	})
+}
+
+func readApplied(db *gorm.DB) (map[int64]appliedMigration, error) {
+	applied := make(map[int64]appliedMigration)
+
+	// A database nothing was applied to has no table yet
+	var exists bool
+	if err := db.Raw("SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists).Error; err != nil {
+		return nil, err
+	}
+	if !exists {
+		return applied, nil
+	}
+
+	var rows []appliedMigration
+	if err := db.Raw("SELECT version, name, applied_at FROM schema_migrations ORDER BY version").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+func appliedVersions(applied map[int64]appliedMigration) []int64 {
+	versions := make([]int64, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	return versions
+}
+
+func apply(conn *gorm.DB, migration Migration) error {
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		if err := migration.up(tx); err != nil {
+			return err
+		}
+		return tx.Exec("INSERT INTO schema_migrations (version, name) VALUES (?, ?)", migration.Version, migration.Name).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply migration %s: %w", migration, err)
+	}
+	log.Printf("Applied migration %s", migration)
+	return nil
+}
+
+func (m *Migrator) rollback(conn *gorm.DB, row appliedMigration) error {
+	migration := m.find(row.Version)
+	if migration == nil {
+		return fmt.Errorf("migration %04d_%s was applied by a newer build and can't be rolled back by this one", row.Version, row.Name)
+	}
+
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		if err := migration.down(tx); err != nil {
+			return err
+		}
+		return tx.Exec("DELETE FROM schema_migrations WHERE version = ?", migration.Version).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to roll back migration %s: %w", migration, err)
+	}
+	log.Printf("Rolled back migration %s", migration)
+	return nil
+}
+
+var nonWord = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Create writes an empty pair of up and down files to dir for a migration
+// named name, numbered after the latest one, and returns their paths
+func Create(dir, name string) ([]string, error) {
+	name = strings.Trim(nonWord.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return nil, errors.New("migration name is empty")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var latest int64
+	for _, migration := range goMigrations {
+		latest = max(latest, migration.Version)
+	}
+	for _, entry := range entries {
+		if match := fileName.FindStringSubmatch(entry.Name()); match != nil {
+			version, _ := strconv.ParseInt(match[1], 10, 64)
+			latest = max(latest, version)
+		}
+	}
+
+	migration := Migration{Version: latest + 1, Name: name}
+	var paths []string
+	for _, direction := range []string{"up", "down"} {
+		path := filepath.Join(dir, fmt.Sprintf("%s.%s.sql", migration, direction))
+		content := fmt.Sprintf("-- %s %s\n", migration, direction)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// BackfillBookAuthors credits the byline authors of every book that has no
+// credits yet, trashed books included. It is idempotent and cheap to rerun
+// once every book is credited.
+func BackfillBookAuthors(db *gorm.DB, batchSize int) error {
+	var books []models.Book
 
-	return db.AutoMigrate(&models.Book{}, &models.User{})
-}
\ No newline at end of file
+	return db.Unscoped().
+		Select("id", "author").
+		Where("author <> '' AND NOT EXISTS (SELECT 1 FROM book_authors WHERE book_authors.book_id = books.id)").
+		FindInBatches(&books, batchSize, func(_ *gorm.DB, _ int) error {
+			return db.Transaction(func(tx *gorm.DB) error {
+				for _, book := range books {
+					if err := models.CreditBylineAuthors(tx, book); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		}).Error
+}
@@ -0,0 +1,473 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+	"gorm.io/gorm/utils/tests"
+)
+
+// fakePostgres is a database/sql driver that keeps schema_migrations in
+// memory and records every other statement it runs. A transaction that is
+// rolled back restores schema_migrations. Given a schema, it also runs the
+// statements against it.
+type fakePostgres struct {
+	mu       sync.Mutex
+	runs     []string
+	created  bool
+	applied  map[int64]string
+	snapshot map[int64]string
+	fail     string
+	schema   *fakeSchema
+}
+
+func newFakePostgres() *fakePostgres {
+	return &fakePostgres{applied: make(map[int64]string)}
+}
+
+func (p *fakePostgres) recorded() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.runs...)
+}
+
+func (p *fakePostgres) versions() []int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var versions []int64
+	for version := range p.applied {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	return versions
+}
+
+func (p *fakePostgres) Connect(context.Context) (driver.Conn, error) { return fakeConn{p}, nil }
+func (p *fakePostgres) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{ p *fakePostgres }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{c.p, query}, nil }
+func (c fakeConn) Close() error                              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error) {
+	c.p.mu.Lock()
+	defer c.p.mu.Unlock()
+	c.p.snapshot = make(map[int64]string)
+	for version, name := range c.p.applied {
+		c.p.snapshot[version] = name
+	}
+	return fakeTx{c.p}, nil
+}
+
+type fakeTx struct{ p *fakePostgres }
+
+func (t fakeTx) Commit() error { return nil }
+func (t fakeTx) Rollback() error {
+	t.p.mu.Lock()
+	defer t.p.mu.Unlock()
+	t.p.applied = t.p.snapshot
+	return nil
+}
+
+type fakeStmt struct {
+	p     *fakePostgres
+	query string
+}
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.p.mu.Lock()
+	defer s.p.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(s.query, "CREATE TABLE IF NOT EXISTS schema_migrations"):
+		s.p.created = true
+	case strings.HasPrefix(s.query, "INSERT INTO schema_migrations"):
+		s.p.applied[args[0].(int64)] = args[1].(string)
+	case strings.HasPrefix(s.query, "DELETE FROM schema_migrations"):
+		delete(s.p.applied, args[0].(int64))
+	case strings.HasPrefix(s.query, "SELECT pg_advisory_lock"):
+		s.p.runs = append(s.p.runs, "LOCK")
+	case strings.HasPrefix(s.query, "SELECT pg_advisory_unlock"):
+		s.p.runs = append(s.p.runs, "UNLOCK")
+	default:
+		s.p.runs = append(s.p.runs, s.query)
+		if s.p.fail != "" && s.query == s.p.fail {
+			return nil, errors.New("syntax error")
+		}
+		if s.p.schema != nil {
+			if err := s.p.schema.exec(s.query); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	s.p.mu.Lock()
+	defer s.p.mu.Unlock()
+
+	if strings.HasPrefix(s.query, "SELECT to_regclass") {
+		return &fakeRows{columns: []string{"exists"}, values: [][]driver.Value{{s.p.created}}}, nil
+	}
+	if !strings.Contains(s.query, "schema_migrations") {
+		return &fakeRows{}, nil
+	}
+	rows := &fakeRows{columns: []string{"version", "name", "applied_at"}}
+	for version, name := range s.p.applied {
+		rows.values = append(rows.values, []driver.Value{version, name, time.Now()})
+	}
+	return rows, nil
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+// fakeSchema follows the tables and columns that migration statements
+// create, and fails statements that need a column that doesn't exist
+type fakeSchema struct {
+	tables map[string]map[string]bool
+}
+
+// newFakeSchema returns a schema holding tables, given with their columns
+func newFakeSchema(tables map[string][]string) *fakeSchema {
+	schema := &fakeSchema{tables: make(map[string]map[string]bool)}
+	for table, columns := range tables {
+		schema.tables[table] = make(map[string]bool)
+		for _, column := range columns {
+			schema.tables[table][column] = true
+		}
+	}
+	return schema
+}
+
+var (
+	createTableStatement = regexp.MustCompile(`(?s)^CREATE TABLE IF NOT EXISTS (\w+) \((.*)\)$`)
+	alterTableStatement  = regexp.MustCompile(`(?s)^ALTER TABLE (\w+)\s+(.*)$`)
+	createIndexStatement = regexp.MustCompile(`(?s)^CREATE (?:UNIQUE )?INDEX (?:IF NOT EXISTS )?\w+ ON (\w+) \(([^)]*)\)(?: WHERE (.*))?$`)
+	dropStatement        = regexp.MustCompile(`^DROP (TABLE|INDEX) IF EXISTS (\w+)$`)
+	addColumnAction      = regexp.MustCompile(`^ADD COLUMN (IF NOT EXISTS )?(\w+)`)
+	predicateIdentifier  = regexp.MustCompile(`'[^']*'|[a-z_]+`)
+)
+
+// exec runs every statement of a migration file. Elements of a table
+// definition and actions of ALTER TABLE are expected one per line.
+func (s *fakeSchema) exec(statements string) error {
+	var lines []string
+	for _, line := range strings.Split(statements, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "--") {
+			lines = append(lines, line)
+		}
+	}
+
+	for _, statement := range strings.Split(strings.Join(lines, "\n"), ";") {
+		statement = strings.TrimSpace(statement)
+		if statement == "" || strings.HasPrefix(statement, "CREATE EXTENSION") {
+			continue
+		}
+		if err := s.run(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *fakeSchema) run(statement string) error {
+	if match := createTableStatement.FindStringSubmatch(statement); match != nil {
+		if _, ok := s.tables[match[1]]; ok {
+			return nil
+		}
+		columns := make(map[string]bool)
+		for _, element := range strings.Split(match[2], ",\n") {
+			name := strings.Fields(element)[0]
+			if name != "CONSTRAINT" && name != "PRIMARY" {
+				columns[name] = true
+			}
+		}
+		s.tables[match[1]] = columns
+		return nil
+	}
+
+	if match := alterTableStatement.FindStringSubmatch(statement); match != nil {
+		columns, ok := s.tables[match[1]]
+		if !ok {
+			return fmt.Errorf("relation %q does not exist", match[1])
+		}
+		for _, action := range strings.Split(match[2], ",\n") {
+			add := addColumnAction.FindStringSubmatch(strings.TrimSpace(action))
+			if add == nil {
+				continue
+			}
+			if columns[add[2]] && add[1] == "" {
+				return fmt.Errorf("column %q of relation %q already exists", add[2], match[1])
+			}
+			columns[add[2]] = true
+		}
+		return nil
+	}
+
+	if match := createIndexStatement.FindStringSubmatch(statement); match != nil {
+		columns, ok := s.tables[match[1]]
+		if !ok {
+			return fmt.Errorf("relation %q does not exist", match[1])
+		}
+		used := strings.Split(match[2], ",")
+		for _, identifier := range predicateIdentifier.FindAllString(match[3], -1) {
+			if !strings.HasPrefix(identifier, "'") {
+				used = append(used, identifier)
+			}
+		}
+		for _, column := range used {
+			column = strings.TrimSpace(column)
+			if !columns[column] && !strings.Contains(" and or is not null ", " "+column+" ") {
+				return fmt.Errorf("column %q does not exist", column)
+			}
+		}
+		return nil
+	}
+
+	if match := dropStatement.FindStringSubmatch(statement); match != nil {
+		if match[1] == "TABLE" {
+			delete(s.tables, match[2])
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unexpected statement: %s", statement)
+}
+
+// assertSchemaHasModels checks that every column of every model exists
+func assertSchemaHasModels(t *testing.T, s *fakeSchema) {
+	all := []interface{}{
+		&models.Book{}, &models.User{}, &models.Author{}, &models.BookAuthor{}, &models.Review{},
+		&models.ReviewFlag{}, &models.BookFile{}, &models.Loan{}, &models.Reservation{}, &models.JobRun{},
+	}
+	for _, model := range all {
+		parsed, err := schema.Parse(model, &sync.Map{}, schema.NamingStrategy{})
+		assert.NoError(t, err)
+		columns, ok := s.tables[parsed.Table]
+		if !assert.True(t, ok, "table %s is missing", parsed.Table) {
+			continue
+		}
+		for _, column := range parsed.DBNames {
+			assert.True(t, columns[column], "column %s.%s is missing", parsed.Table, column)
+		}
+	}
+}
+
+// newSchemaMigrator returns a migrator for the embedded migrations over a
+// fake database holding tables
+func newSchemaMigrator(t *testing.T, tables map[string][]string) (*fakeSchema, *Migrator) {
+	p := newFakePostgres()
+	p.schema = newFakeSchema(tables)
+	pool := sql.OpenDB(p)
+	t.Cleanup(func() { pool.Close() })
+	db, err := gorm.Open(tests.DummyDialector{}, &gorm.Config{ConnPool: pool, Logger: logger.Discard})
+	assert.NoError(t, err)
+
+	m, err := New(db)
+	assert.NoError(t, err)
+	return p.schema, m
+}
+
+func TestMigrationsCreateModelColumns(t *testing.T) {
+	s, m := newSchemaMigrator(t, nil)
+	assert.NoError(t, m.Up(context.Background()))
+	assertSchemaHasModels(t, s)
+}
+
+func TestMigrationsUpgradeBaselineSchema(t *testing.T) {
+	// The tables AutoMigrate built in the first release
+	s, m := newSchemaMigrator(t, map[string][]string{
+		"books": {"id", "title", "author", "created_at", "updated_at"},
+		"users": {"id", "username", "password", "created_at", "updated_at"},
+	})
+	assert.NoError(t, m.Up(context.Background()))
+	assertSchemaHasModels(t, s)
+}
+
+func TestInitialMigrationRefusesToRollBack(t *testing.T) {
+	// 0001 may have adopted the tables of the first release, so its down
+	// migration must not drop them
+	down, err := files.ReadFile("migrations/0001_initial.down.sql")
+	assert.NoError(t, err)
+	assert.NotContains(t, strings.ToUpper(string(down)), "DROP TABLE")
+	assert.Contains(t, string(down), "RAISE EXCEPTION")
+}
+
+// newFakeMigrator returns a migrator over a fake database for two SQL
+// migrations and a coded one
+func newFakeMigrator(t *testing.T) (*fakePostgres, *Migrator) {
+	p := newFakePostgres()
+	pool := sql.OpenDB(p)
+	t.Cleanup(func() { pool.Close() })
+	db, err := gorm.Open(tests.DummyDialector{}, &gorm.Config{ConnPool: pool, Logger: logger.Discard})
+	assert.NoError(t, err)
+
+	sqlFiles := fstest.MapFS{
+		"0001_shelves.up.sql":       {Data: []byte("CREATE TABLE shelves")},
+		"0001_shelves.down.sql":     {Data: []byte("DROP TABLE shelves")},
+		"0003_shelf_books.up.sql":   {Data: []byte("CREATE TABLE shelf_books")},
+		"0003_shelf_books.down.sql": {Data: []byte("DROP TABLE shelf_books")},
+	}
+	coded := []Migration{{
+		Version: 2,
+		Name:    "backfill_shelves",
+		up:      func(tx *gorm.DB) error { return tx.Exec("INSERT INTO shelves").Error },
+		down:    func(tx *gorm.DB) error { return tx.Exec("DELETE FROM shelves").Error },
+	}}
+	m, err := newMigrator(db, sqlFiles, coded)
+	assert.NoError(t, err)
+	return p, m
+}
+
+func TestMigratorUp(t *testing.T) {
+	ctx := context.Background()
+	p, m := newFakeMigrator(t)
+
+	// Nothing is applied to a new database
+	pending, err := m.Pending(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 3)
+	assert.ErrorIs(t, m.Check(ctx), ErrSchemaBehind)
+
+	assert.NoError(t, m.Up(ctx))
+	assert.Equal(t, []int64{1, 2, 3}, p.versions())
+	assert.NoError(t, m.Check(ctx))
+
+	// Another replica waits for the lock and finds nothing to do
+	assert.NoError(t, m.Up(ctx))
+	assert.Equal(t, []string{
+		"LOCK", "CREATE TABLE shelves", "INSERT INTO shelves", "CREATE TABLE shelf_books", "UNLOCK",
+		"LOCK", "UNLOCK",
+	}, p.recorded())
+}
+
+func TestMigratorFailedMigrationRollsBack(t *testing.T) {
+	ctx := context.Background()
+	p, m := newFakeMigrator(t)
+	p.fail = "INSERT INTO shelves"
+
+	err := m.Up(ctx)
+	assert.ErrorContains(t, err, "0002_backfill_shelves")
+
+	// The failed migration is not recorded, the ones after it don't run and
+	// the lock is released
+	assert.Equal(t, []int64{1}, p.versions())
+	assert.Equal(t, []string{"LOCK", "CREATE TABLE shelves", "INSERT INTO shelves", "UNLOCK"}, p.recorded())
+}
+
+func TestMigratorDownAndTo(t *testing.T) {
+	ctx := context.Background()
+	p, m := newFakeMigrator(t)
+
+	assert.NoError(t, m.To(ctx, 2))
+	assert.Equal(t, []int64{1, 2}, p.versions())
+
+	assert.NoError(t, m.Down(ctx, 1))
+	assert.Equal(t, []int64{1}, p.versions())
+
+	assert.NoError(t, m.Up(ctx))
+	assert.NoError(t, m.To(ctx, 0))
+	assert.Empty(t, p.versions())
+
+	assert.ErrorContains(t, m.To(ctx, 7), "no migration has version 7")
+	assert.Equal(t, []string{
+		"LOCK", "CREATE TABLE shelves", "INSERT INTO shelves", "UNLOCK",
+		"LOCK", "DELETE FROM shelves", "UNLOCK",
+		"LOCK", "INSERT INTO shelves", "CREATE TABLE shelf_books", "UNLOCK",
+		"LOCK", "DROP TABLE shelf_books", "DELETE FROM shelves", "DROP TABLE shelves", "UNLOCK",
+	}, p.recorded())
+}
+
+func TestMigratorStatus(t *testing.T) {
+	ctx := context.Background()
+	p, m := newFakeMigrator(t)
+
+	assert.NoError(t, m.To(ctx, 1))
+	// A newer build applied a migration this one doesn't know
+	p.applied[4] = "shelf_labels"
+
+	statuses, err := m.Status(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, statuses, 4)
+	assert.NotNil(t, statuses[0].AppliedAt)
+	assert.Nil(t, statuses[1].AppliedAt)
+	assert.Equal(t, "shelf_labels", statuses[3].Name)
+	assert.True(t, statuses[3].Missing)
+
+	// It can't be rolled back from here
+	assert.ErrorContains(t, m.Down(ctx, 1), "0004_shelf_labels was applied by a newer build")
+}
+
+func TestLoadMigrations(t *testing.T) {
+	_, err := load(fstest.MapFS{"0001_shelves.up.sql": {}}, nil)
+	assert.ErrorContains(t, err, "needs both an up and a down file")
+
+	_, err = load(fstest.MapFS{"shelves.sql": {}}, nil)
+	assert.ErrorContains(t, err, "is not named")
+
+	_, err = load(fstest.MapFS{"0002_shelves.up.sql": {}, "0002_shelves.down.sql": {}}, goMigrations)
+	assert.ErrorContains(t, err, "version 2 is used by shelves and backfill_book_authors")
+
+	// The embedded migrations load and are numbered without gaps
+	m, err := New(nil)
+	assert.NoError(t, err)
+	for i, migration := range m.Migrations() {
+		assert.Equal(t, int64(i+1), migration.Version)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "0001_initial.up.sql"), nil, 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "0001_initial.down.sql"), nil, 0o644))
+
+	// Versions follow the coded migrations too
+	paths, err := Create(dir, "Add shelf labels!")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "0003_add_shelf_labels.up.sql"),
+		filepath.Join(dir, "0003_add_shelf_labels.down.sql"),
+	}, paths)
+	for _, path := range paths {
+		assert.FileExists(t, path)
+	}
+
+	_, err = Create(dir, "!!")
+	assert.Error(t, err)
+}
@@ -0,0 +1,8 @@
+-- 0001 adopts the books and users tables of the first release, so it can't
+-- tell whether it created them. Rolling it back would drop the catalog and
+-- the accounts, so it refuses; drop the schema by hand to start over.
+DO $$
+BEGIN
+    RAISE EXCEPTION '0001_initial can''t be rolled back: it would drop the books and users tables, which may predate it';
+END
+$$;
@@ -0,0 +1,169 @@
+-- The schema of the models. The first release built the books and users
+-- tables with AutoMigrate, so they are created with the columns they had then
+-- and every later column is added where it is missing. Those databases adopt
+-- versioned migrations in place.
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+CREATE TABLE IF NOT EXISTS books (
+    id uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+    title text,
+    author text,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+ALTER TABLE books
+    ADD COLUMN IF NOT EXISTS isbn varchar(13),
+    ADD COLUMN IF NOT EXISTS publication_date date,
+    ADD COLUMN IF NOT EXISTS language varchar(2),
+    ADD COLUMN IF NOT EXISTS page_count bigint,
+    ADD COLUMN IF NOT EXISTS publisher text,
+    ADD COLUMN IF NOT EXISTS description text,
+    ADD COLUMN IF NOT EXISTS rating_average numeric(3,2) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS review_count bigint NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS copies bigint NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS available_copies bigint NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS cover_key varchar(255),
+    ADD COLUMN IF NOT EXISTS cover_url varchar(1024),
+    ADD COLUMN IF NOT EXISTS cover_small_url varchar(1024),
+    ADD COLUMN IF NOT EXISTS cover_medium_url varchar(1024),
+    ADD COLUMN IF NOT EXISTS cover_width integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS cover_height integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS deleted_at timestamptz,
+    DROP CONSTRAINT IF EXISTS chk_books_available_copies,
+    ADD CONSTRAINT chk_books_available_copies CHECK (available_copies BETWEEN 0 AND copies);
+-- Trashed books don't hold their ISBN. Older builds indexed them too, so the
+-- index is rebuilt rather than skipped when it exists.
+DROP INDEX IF EXISTS idx_books_isbn_unique;
+CREATE UNIQUE INDEX idx_books_isbn_unique ON books (isbn) WHERE isbn <> '' AND deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_books_deleted_at ON books (deleted_at);
+-- Superseded by idx_books_isbn_unique
+DROP INDEX IF EXISTS idx_books_isbn;
+
+CREATE TABLE IF NOT EXISTS users (
+    id uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+    username text,
+    password text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT uni_users_username UNIQUE (username)
+);
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS role text NOT NULL DEFAULT 'user',
+    ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS authors (
+    id uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+    name text NOT NULL,
+    name_key text NOT NULL,
+    bio text,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_authors_name_key ON authors (name_key);
+
+CREATE TABLE IF NOT EXISTS book_authors (
+    book_id uuid,
+    author_id uuid,
+    role varchar(32),
+    position bigint,
+    PRIMARY KEY (book_id, author_id, role),
+    CONSTRAINT fk_book_authors_book FOREIGN KEY (book_id) REFERENCES books (id) ON DELETE CASCADE,
+    CONSTRAINT fk_book_authors_author FOREIGN KEY (author_id) REFERENCES authors (id) ON DELETE RESTRICT
+);
+CREATE INDEX IF NOT EXISTS idx_book_authors_author_id ON book_authors (author_id);
+
+CREATE TABLE IF NOT EXISTS reviews (
+    id uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+    book_id uuid NOT NULL,
+    user_id uuid NOT NULL,
+    username text NOT NULL,
+    rating bigint NOT NULL,
+    text text,
+    status varchar(16) NOT NULL DEFAULT 'approved',
+    flag_count bigint NOT NULL DEFAULT 0,
+    moderation_reason text,
+    moderated_by text,
+    moderated_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT chk_reviews_rating CHECK (rating BETWEEN 1 AND 5),
+    CONSTRAINT fk_reviews_book FOREIGN KEY (book_id) REFERENCES books (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reviews_book_user ON reviews (book_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_reviews_status ON reviews (status);
+
+CREATE TABLE IF NOT EXISTS review_flags (
+    id uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+    review_id uuid NOT NULL,
+    user_id uuid NOT NULL,
+    username text NOT NULL,
+    reason text NOT NULL,
+    created_at timestamptz,
+    CONSTRAINT fk_reviews_flags FOREIGN KEY (review_id) REFERENCES reviews (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_review_flags_review_user ON review_flags (review_id, user_id);
+
+CREATE TABLE IF NOT EXISTS book_files (
+    id uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+    book_id uuid NOT NULL,
+    format varchar(8) NOT NULL,
+    key varchar(255) NOT NULL,
+    filename varchar(255) NOT NULL,
+    content_type varchar(64) NOT NULL,
+    size bigint NOT NULL,
+    sha256 varchar(64) NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_book_files_book FOREIGN KEY (book_id) REFERENCES books (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_book_files_book_format ON book_files (book_id, format);
+
+CREATE TABLE IF NOT EXISTS loans (
+    id uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+    book_id uuid,
+    user_id uuid NOT NULL,
+    username text NOT NULL,
+    checked_out_at timestamptz NOT NULL,
+    due_at timestamptz NOT NULL,
+    returned_at timestamptz,
+    renewals bigint NOT NULL DEFAULT 0,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_loans_book FOREIGN KEY (book_id) REFERENCES books (id) ON DELETE SET NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_loans_active_book_user ON loans (book_id, user_id) WHERE returned_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_loans_user_id ON loans (user_id);
+
+CREATE TABLE IF NOT EXISTS reservations (
+    id uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+    book_id uuid,
+    user_id uuid NOT NULL,
+    username text NOT NULL,
+    status varchar(16) NOT NULL DEFAULT 'waiting',
+    ready_at timestamptz,
+    expires_at timestamptz,
+    closed_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_reservations_book FOREIGN KEY (book_id) REFERENCES books (id) ON DELETE SET NULL
+);
+CREATE INDEX IF NOT EXISTS idx_reservations_book_id ON reservations (book_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reservations_open_book_user ON reservations (book_id, user_id) WHERE closed_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_reservations_status ON reservations (status);
+
+CREATE TABLE IF NOT EXISTS job_runs (
+    id uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+    job varchar(64) NOT NULL,
+    trigger varchar(16) NOT NULL,
+    triggered_by text,
+    status varchar(16) NOT NULL,
+    instance text,
+    output text,
+    error text,
+    started_at timestamptz NOT NULL,
+    finished_at timestamptz,
+    duration_ms bigint
+);
+CREATE INDEX IF NOT EXISTS idx_job_runs_job_started ON job_runs (job, started_at);
@@ -0,0 +1,91 @@
+package database
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/storage"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// notOnLoan matches the books with no copy on loan. A book is only purged
+// once every copy is back: the loan history stays, without its book.
+const notOnLoan = "NOT EXISTS (SELECT 1 FROM loans WHERE loans.book_id = books.id AND loans.returned_at IS NULL)"
+
+// PurgeBooks permanently deletes the books matching query, trashed or not,
+// with their files, and cancels their open reservations. Books with a copy on
+// loan are skipped. It returns the number of books deleted and the blob keys
+// they referenced, which the caller deletes once the transaction commits.
+func PurgeBooks(tx *gorm.DB, query interface{}, args ...interface{}) (int64, []string, error) {
+	var files []models.BookFile
+	var books []models.Book
+
+	// Files would go with their book through ON DELETE CASCADE, but their
+	// keys are needed, so they are deleted first
+	matching := tx.Session(&gorm.Session{NewDB: true}).Unscoped().Model(&models.Book{}).Select("id").Where(query, args...).Where(notOnLoan)
+	if err := tx.Clauses(clause.Returning{Columns: []clause.Column{{Name: "key"}}}).
+		Where("book_id IN (?)", matching).
+		Delete(&files).Error; err != nil {
+		return 0, nil, err
+	}
+
+	// Reservations outlive their book like loans do, but nobody can be
+	// served from the queue of a purged book anymore
+	if err := tx.Model(&models.Reservation{}).
+		Where("book_id IN (?) AND closed_at IS NULL", matching).
+		Updates(map[string]interface{}{"status": models.ReservationStatusCancelled, "closed_at": time.Now()}).Error; err != nil {
+		return 0, nil, err
+	}
+
+	result := tx.Unscoped().
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}, {Name: "cover_key"}}}).
+		Where(query, args...).
+		Where(notOnLoan).
+		Delete(&books)
+	if result.Error != nil {
+		return 0, nil, result.Error
+	}
+
+	var keys []string
+	for _, file := range files {
+		keys = append(keys, file.Key)
+	}
+	for _, book := range books {
+		keys = append(keys, book.Cover.Keys()...)
+	}
+	return result.RowsAffected, keys, nil
+}
+
+// PurgeDeleted permanently removes books and users that were soft-deleted
+// before cutoff. The blobs of purged books are deleted from blobs when it is
+// set.
+func PurgeDeleted(ctx context.Context, db *gorm.DB, blobs storage.BlobStore, cutoff time.Time) (int64, error) {
+	var purged int64
+	var keys []string
+
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		purged, keys, err = PurgeBooks(tx, "deleted_at IS NOT NULL AND deleted_at < ?", cutoff)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if blobs != nil {
+		if err := storage.DeleteAll(ctx, blobs, keys); err != nil {
+			log.Printf("Failed to delete the blobs of purged books: %v", err)
+		}
+	}
+
+	result := db.WithContext(ctx).Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Delete(&models.User{})
+	if result.Error != nil {
+		return purged, result.Error
+	}
+	return purged + result.RowsAffected, nil
+}
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils/tests"
+)
+
+// dryRunPool lets a dry-run session open transactions that never reach a
+// database
+type dryRunPool struct{ gorm.ConnPool }
+
+func (p dryRunPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	return &dryRunTx{p}, nil
+}
+
+type dryRunTx struct{ gorm.ConnPool }
+
+func (*dryRunTx) Commit() error   { return nil }
+func (*dryRunTx) Rollback() error { return nil }
+
+func TestPurgeDeleted(t *testing.T) {
+	var statements []string
+
+	db, err := gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true, Logger: logger.Discard})
+	assert.NoError(t, err)
+	db.ConnPool = dryRunPool{}
+	db.Statement.ConnPool = db.ConnPool
+	db.Callback().Delete().After("gorm:delete").Register("test:record", func(tx *gorm.DB) {
+		statements = append(statements, tx.Statement.SQL.String())
+	})
+	var updates []string
+	db.Callback().Update().After("gorm:update").Register("test:record", func(tx *gorm.DB) {
+		updates = append(updates, tx.Statement.SQL.String())
+	})
+
+	_, err = PurgeDeleted(context.Background(), db, nil, time.Now())
+	assert.NoError(t, err)
+
+	assert.Len(t, statements, 3)
+	for _, statement := range statements {
+		assert.True(t, strings.HasPrefix(statement, "DELETE FROM"), statement)
+		assert.Contains(t, statement, "deleted_at IS NOT NULL AND deleted_at <")
+	}
+
+	// Files are deleted with their book, before it
+	assert.Contains(t, statements[0], "`book_files`")
+	assert.Contains(t, statements[0], "book_id IN (SELECT `id` FROM `books`")
+	assert.Contains(t, statements[1], "`books`")
+	assert.Contains(t, statements[2], "`users`")
+
+	// Books on loan are kept, their open reservations are cancelled
+	assert.Contains(t, statements[0], notOnLoan)
+	assert.Contains(t, statements[1], notOnLoan)
+	assert.Len(t, updates, 1)
+	assert.Contains(t, updates[0], "UPDATE `reservations` SET")
+	assert.Contains(t, updates[0], "closed_at IS NULL")
+}
@@ -0,0 +1,20 @@
+package seeders
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// Authors are created from the bylines of seeded books, so there is no
+// SeedAuthors; clearing them runs after the books are gone.
+func ClearAuthors(db *gorm.DB) error {
+	if err := db.Exec("DELETE FROM book_authors").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM authors").Error; err != nil {
+		return err
+	}
+	log.Println("Successfully cleared authors table")
+	return nil
+}
@@ -2,6 +2,8 @@ package seeders
 
 import (
 	"log"
+	"strconv"
+	"time"
 
 	"github.com/brianvoe/gofakeit/v7"
 	"github.com/google/uuid"
@@ -9,14 +11,29 @@ import (
 	"gorm.io/gorm"
 )
 
+// seedLanguages are the ISO 639-1 codes picked for seeded books
+var seedLanguages = []string{"en", "en", "en", "fr", "de", "es", "it", "pt", "ja", "zh"}
+
 func SeedBooks(db *gorm.DB, count int) error {
 	gofakeit.Seed(0) // Use 0 for random seed or set a fixed number for reproducible data
 
 	for i := 0; i < count; i++ {
+		published := gofakeit.DateRange(time.Date(1950, time.January, 1, 0, 0, 0, 0, time.UTC), time.Now())
+		publicationDate := models.NewDate(published.Year(), published.Month(), published.Day())
+		copies := gofakeit.IntRange(1, 5)
+
 		book := models.Book{
-			ID:     uuid.New(),
-			Title:  gofakeit.BookTitle(),
-			Author: gofakeit.BookAuthor(),
+			ID:              uuid.New(),
+			Title:           gofakeit.BookTitle(),
+			Author:          gofakeit.BookAuthor(),
+			ISBN:            fakeISBN(),
+			PublicationDate: &publicationDate,
+			Language:        models.LanguageCode(gofakeit.RandomString(seedLanguages)),
+			PageCount:       gofakeit.IntRange(64, 1200),
+			Publisher:       gofakeit.Company(),
+			Description:     gofakeit.Paragraph(),
+			Copies:          copies,
+			AvailableCopies: copies,
 		}
 
 		if err := db.Create(&book).Error; err != nil {
@@ -29,6 +46,27 @@ func SeedBooks(db *gorm.DB, count int) error {
 	return nil
 }
 
+// fakeISBN generates an ISBN-13 with a valid check digit
+func fakeISBN() models.ISBN {
+	isbn10 := gofakeit.Numerify("#########")
+	sum := 0
+	for i, digit := range isbn10 {
+		sum += (10 - i) * int(digit-'0')
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		isbn10 += "X"
+	} else {
+		isbn10 += strconv.Itoa(check)
+	}
+
+	isbn, err := models.ParseISBN(isbn10)
+	if err != nil {
+		log.Fatalf("Generated an invalid ISBN %s: %v", isbn10, err)
+	}
+	return isbn
+}
+
 func ClearBooks(db *gorm.DB) error {
 	if err := db.Exec("DELETE FROM books").Error; err != nil {
 		return err
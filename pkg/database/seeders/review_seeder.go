@@ -0,0 +1,67 @@
+package seeders
+
+import (
+	"log"
+	"math/rand"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"gorm.io/gorm"
+)
+
+// SeedReviews has every seeded user review a random subset of the books,
+// then fills in the rating aggregates of the books
+func SeedReviews(db *gorm.DB, maxPerUser int) error {
+	var users []models.User
+	var books []models.Book
+
+	if err := db.Where("role = ?", models.RoleUser).Find(&users).Error; err != nil {
+		return err
+	}
+	if err := db.Select("id").Find(&books).Error; err != nil {
+		return err
+	}
+
+	count := 0
+	for _, user := range users {
+		reviews := gofakeit.IntRange(0, maxPerUser)
+		for _, i := range rand.Perm(len(books)) {
+			if reviews == 0 {
+				break
+			}
+			reviews--
+
+			review := models.Review{
+				BookID:   books[i].ID,
+				UserID:   user.ID,
+				Username: user.Username,
+				Rating:   gofakeit.IntRange(1, 5),
+				Text:     gofakeit.Sentence(),
+				Status:   models.ReviewStatusApproved,
+			}
+			if err := db.Create(&review).Error; err != nil {
+				log.Printf("Failed to create review: %v", err)
+				return err
+			}
+			count++
+		}
+	}
+
+	if err := db.Exec(`UPDATE books SET
+		review_count = (SELECT count(*) FROM reviews WHERE reviews.book_id = books.id AND status = ?),
+		rating_average = (SELECT coalesce(avg(rating), 0) FROM reviews WHERE reviews.book_id = books.id AND status = ?)`,
+		models.ReviewStatusApproved, models.ReviewStatusApproved).Error; err != nil {
+		return err
+	}
+
+	log.Printf("Successfully seeded %d reviews", count)
+	return nil
+}
+
+func ClearReviews(db *gorm.DB) error {
+	if err := db.Exec("DELETE FROM reviews").Error; err != nil {
+		return err
+	}
+	log.Println("Successfully cleared reviews table")
+	return nil
+}
@@ -3,6 +3,7 @@ package seeders
 import (
 	"log"
 
+	"github.com/kev1nandreas/go-rest-api-template/env"
 	"gorm.io/gorm"
 )
 
@@ -13,10 +14,20 @@ func RunAllSeeders(db *gorm.DB) error {
 		return err
 	}
 
+	adminUsername := env.GetEnvString("SEED_ADMIN_USERNAME", "admin")
+	adminPassword := env.GetEnvString("SEED_ADMIN_PASSWORD", "password123")
+	if err := SeedAdmin(db, adminUsername, adminPassword); err != nil {
+		return err
+	}
+
 	if err := SeedBooks(db, 20); err != nil {
 		return err
 	}
 
+	if err := SeedReviews(db, 5); err != nil {
+		return err
+	}
+
 	log.Println("Database seeding completed successfully!")
 	return nil
 }
@@ -24,10 +35,18 @@ func RunAllSeeders(db *gorm.DB) error {
 func ClearAllData(db *gorm.DB) error {
 	log.Println("Clearing all seeded data...")
 
+	if err := ClearReviews(db); err != nil {
+		return err
+	}
+
 	if err := ClearBooks(db); err != nil {
 		return err
 	}
 
+	if err := ClearAuthors(db); err != nil {
+		return err
+	}
+
 	if err := ClearUsers(db); err != nil {
 		return err
 	}
@@ -23,6 +23,7 @@ func SeedUsers(db *gorm.DB, count int) error {
 			ID:       uuid.New(),
 			Username: gofakeit.Username(),
 			Password: string(hashedPassword),
+			Role:     models.RoleUser,
 		}
 
 		if err := db.Create(&user).Error; err != nil {
@@ -35,6 +36,30 @@ func SeedUsers(db *gorm.DB, count int) error {
 	return nil
 }
 
+// SeedAdmin creates the administrator account used to manage the trash and
+// other restricted endpoints
+func SeedAdmin(db *gorm.DB, username string, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	admin := models.User{
+		ID:       uuid.New(),
+		Username: username,
+		Password: string(hashedPassword),
+		Role:     models.RoleAdmin,
+	}
+
+	if err := db.Create(&admin).Error; err != nil {
+		log.Printf("Failed to create admin user: %v", err)
+		return err
+	}
+
+	log.Printf("Successfully seeded admin user %s", username)
+	return nil
+}
+
 func ClearUsers(db *gorm.DB) error {
 	if err := db.Exec("DELETE FROM users").Error; err != nil {
 		return err
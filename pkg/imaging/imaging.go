@@ -0,0 +1,102 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"io"
+	"net/http"
+
+	_ "image/gif"
+	_ "image/png"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported image format, use JPEG, PNG or GIF")
+	ErrTooLarge          = errors.New("image dimensions are too large")
+)
+
+// Extensions maps the content types that can be decoded to the extension
+// their files are stored with
+var Extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// Sniff returns the content type of an image from its first bytes, ignoring
+// whatever the client claimed it to be
+func Sniff(head []byte) (string, error) {
+	contentType := http.DetectContentType(head)
+	if _, ok := Extensions[contentType]; !ok {
+		return contentType, ErrUnsupportedFormat
+	}
+	return contentType, nil
+}
+
+// Decode decodes an image after checking from its header that it has at
+// most maxPixels, so a small file can't expand into a huge bitmap
+func Decode(data []byte, maxPixels int) (image.Image, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxPixels {
+		return nil, ErrTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// Resize scales an image down to width, keeping its aspect ratio. Every
+// output pixel is the average of the source pixels it covers, which keeps
+// thumbnails smooth without an external library. Images already narrower
+// than width are copied unscaled.
+func Resize(src image.Image, width int) *image.RGBA {
+	bounds := src.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+
+	sw, sh := bounds.Dx(), bounds.Dy()
+	if width <= 0 || width >= sw {
+		return rgba
+	}
+	height := max(1, (sh*width+sw/2)/sw)
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for dy := 0; dy < height; dy++ {
+		y0, y1 := dy*sh/height, max((dy+1)*sh/height, dy*sh/height+1)
+		for dx := 0; dx < width; dx++ {
+			x0, x1 := dx*sw/width, max((dx+1)*sw/width, dx*sw/width+1)
+
+			var r, g, b, a, n int
+			for y := y0; y < y1; y++ {
+				row := rgba.Pix[y*rgba.Stride:]
+				for x := x0; x < x1; x++ {
+					p := row[x*4 : x*4+4]
+					r += int(p[0])
+					g += int(p[1])
+					b += int(p[2])
+					a += int(p[3])
+					n++
+				}
+			}
+
+			q := dst.Pix[dy*dst.Stride+dx*4:]
+			q[0], q[1], q[2], q[3] = uint8(r/n), uint8(g/n), uint8(b/n), uint8(a/n)
+		}
+	}
+	return dst
+}
+
+// EncodeJPEG writes the image as a JPEG. Transparent areas come out white
+// rather than black, since JPEG has no alpha channel.
+func EncodeJPEG(w io.Writer, img *image.RGBA, quality int) error {
+	flat := image.NewRGBA(img.Bounds())
+	draw.Draw(flat, flat.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+	return jpeg.Encode(w, flat, &jpeg.Options{Quality: quality})
+}
@@ -0,0 +1,72 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestSniff(t *testing.T) {
+	data := encodePNG(t, image.NewRGBA(image.Rect(0, 0, 1, 1)))
+
+	contentType, err := Sniff(data)
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", contentType)
+
+	_, err = Sniff([]byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>"))
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+	_, err = Sniff([]byte("%PDF-1.7"))
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+}
+
+func TestDecodeLimitsPixels(t *testing.T) {
+	data := encodePNG(t, image.NewRGBA(image.Rect(0, 0, 100, 50)))
+
+	img, err := Decode(data, 5000)
+	assert.NoError(t, err)
+	assert.Equal(t, 100, img.Bounds().Dx())
+
+	_, err = Decode(data, 4999)
+	assert.ErrorIs(t, err, ErrTooLarge)
+	_, err = Decode([]byte("not an image"), 5000)
+	assert.Error(t, err)
+}
+
+func TestResize(t *testing.T) {
+	// Left half black, right half white
+	src := image.NewRGBA(image.Rect(10, 10, 410, 210))
+	for y := 10; y < 210; y++ {
+		for x := 10; x < 410; x++ {
+			if x >= 210 {
+				src.Set(x, y, color.White)
+			} else {
+				src.Set(x, y, color.Black)
+			}
+		}
+	}
+
+	dst := Resize(src, 100)
+	assert.Equal(t, image.Rect(0, 0, 100, 50), dst.Bounds())
+	assert.Equal(t, color.RGBA{0, 0, 0, 255}, dst.RGBAAt(10, 25))
+	assert.Equal(t, color.RGBA{255, 255, 255, 255}, dst.RGBAAt(90, 25))
+
+	// Never scaled up
+	assert.Equal(t, image.Rect(0, 0, 400, 200), Resize(src, 800).Bounds())
+
+	var buf bytes.Buffer
+	assert.NoError(t, EncodeJPEG(&buf, dst, 80))
+	decoded, err := jpeg.Decode(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, dst.Bounds(), decoded.Bounds())
+}
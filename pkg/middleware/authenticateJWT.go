@@ -63,6 +63,7 @@ func JWTAuth() gin.HandlerFunc {
 		}
 
 		c.Set("username", claims.Username)
+		c.Set("role", claims.Role)
 		c.Next()
 	}
 }
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type cacheControlWriter struct {
+	gin.ResponseWriter
+	value   string
+	applied bool
+}
+
+// apply runs right before the headers are flushed, once the handler has
+// picked a status, so error responses are never stored by caches.
+func (w *cacheControlWriter) apply() {
+	if w.applied {
+		return
+	}
+	w.applied = true
+
+	status := w.Status()
+	if (status >= 200 && status < 300) || status == http.StatusNotModified {
+		w.Header().Set("Cache-Control", w.value)
+	} else {
+		w.Header().Set("Cache-Control", "no-store")
+	}
+}
+
+func (w *cacheControlWriter) WriteHeaderNow() {
+	w.apply()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *cacheControlWriter) Write(data []byte) (int, error) {
+	w.apply()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *cacheControlWriter) WriteString(s string) (int, error) {
+	w.apply()
+	return w.ResponseWriter.WriteString(s)
+}
+
+// CacheControl sets the Cache-Control header of successful GET and HEAD
+// responses to value. An empty value leaves the response untouched.
+func CacheControl(value string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if value == "" || (c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead) {
+			c.Next()
+			return
+		}
+
+		c.Writer = &cacheControlWriter{ResponseWriter: c.Writer, value: value}
+		c.Next()
+	}
+}
@@ -20,10 +20,10 @@ func Cors() gin.HandlerFunc {
 	}
 
 	return cors.New(cors.Config{
-		AllowOrigins: allowedOrigins,
-		AllowMethods: []string{"*"},
-		AllowHeaders: []string{"*"},
-		//ExposeHeaders:    []string{"Content-Length"},
+		AllowOrigins:     allowedOrigins,
+		AllowMethods:     []string{"*"},
+		AllowHeaders:     []string{"*"},
+		ExposeHeaders:    []string{"ETag"},
 		AllowCredentials: true,
 		//AllowOriginFunc: func(origin string) bool {
 		//	return origin == "https://github.com"
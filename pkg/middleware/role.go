@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/response"
+)
+
+// RequireRole only lets requests through when the JWT role is one of roles.
+// It must run after JWTAuth, which stores the role in the context.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+
+		response.NewErrorResponse(
+			http.StatusForbidden,
+			"Forbidden",
+			"Insufficient role",
+		).Send(c)
+		c.Abort()
+	}
+}
@@ -0,0 +1,159 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	CreditRoleAuthor      = "author"
+	CreditRoleEditor      = "editor"
+	CreditRoleTranslator  = "translator"
+	CreditRoleIllustrator = "illustrator"
+)
+
+type Author struct {
+	ID        uuid.UUID `json:"uuid" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	Name      string    `json:"name" gorm:"not null"`
+	NameKey   string    `json:"-" gorm:"not null;uniqueIndex"`
+	Bio       string    `json:"bio" gorm:"type:text"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// CreateAuthor is the payload of POST /authors and PUT /authors/:id
+type CreateAuthor struct {
+	Name string `json:"name" binding:"required,max=255"`
+	Bio  string `json:"bio" binding:"max=10000"`
+}
+
+func (input CreateAuthor) ApplyTo(author *Author) {
+	author.Name = CleanAuthorName(input.Name)
+	author.NameKey = AuthorNameKey(input.Name)
+	author.Bio = input.Bio
+}
+
+// BookAuthor credits an author on a book. The same author can be credited
+// more than once on a book with different roles, e.g. author and editor.
+type BookAuthor struct {
+	BookID   uuid.UUID `json:"book_id" gorm:"type:uuid;primaryKey"`
+	AuthorID uuid.UUID `json:"author_id" gorm:"type:uuid;primaryKey;index"`
+	Role     string    `json:"role" gorm:"primaryKey;size:32"`
+	Position int       `json:"position"`
+	Book     *Book     `json:"book,omitempty" gorm:"constraint:OnDelete:CASCADE"`
+	Author   *Author   `json:"author,omitempty" gorm:"constraint:OnDelete:RESTRICT"`
+}
+
+// SetBookAuthor is one credit of PUT /books/:id/authors
+type SetBookAuthor struct {
+	AuthorID string `json:"author_id" binding:"required,uuid"`
+	Role     string `json:"role" binding:"omitempty,oneof=author editor translator illustrator"`
+}
+
+// CleanAuthorName trims a name and collapses its inner whitespace
+func CleanAuthorName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// AuthorNameKey identifies an author regardless of case, punctuation and
+// spacing, so "J.K. Rowling" and "j. k. rowling" are the same author
+func AuthorNameKey(name string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}), " ")
+}
+
+// SplitByline splits a free-text author string into individual names
+func SplitByline(byline string) []string {
+	var names []string
+	for _, part := range strings.FieldsFunc(strings.ReplaceAll(byline, " and ", ";"), func(r rune) bool {
+		return r == ';' || r == '&'
+	}) {
+		if name := CleanAuthorName(part); AuthorNameKey(name) != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// FindOrCreateAuthor returns the author matching name, creating it if needed.
+// Concurrent callers converge on the same row through the unique name key.
+func FindOrCreateAuthor(tx *gorm.DB, name string) (Author, error) {
+	var author Author
+	CreateAuthor{Name: name}.ApplyTo(&author)
+
+	if err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "name_key"}}, DoNothing: true}).Create(&author).Error; err != nil {
+		return author, err
+	}
+	err := tx.Where("name_key = ?", author.NameKey).First(&author).Error
+	return author, err
+}
+
+// CreditBylineAuthors links the names in the book's author string to author
+// records. Books created through the API are credited this way; explicit
+// credits are set with PUT /books/:id/authors.
+func CreditBylineAuthors(tx *gorm.DB, book Book) error {
+	for position, name := range SplitByline(book.Author) {
+		author, err := FindOrCreateAuthor(tx, name)
+		if err != nil {
+			return err
+		}
+
+		credit := BookAuthor{BookID: book.ID, AuthorID: author.ID, Role: CreditRoleAuthor, Position: position}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&credit).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResyncBylineAuthors re-credits the byline authors of a book whose author
+// string changed from previous. Author credits that don't match the previous
+// byline were set with PUT /books/:id/authors, so they are kept.
+func ResyncBylineAuthors(tx *gorm.DB, previous string, book Book) error {
+	before := bylineKeys(previous)
+	if slices.Equal(before, bylineKeys(book.Author)) {
+		return nil
+	}
+
+	var credited []string
+	err := tx.Model(&BookAuthor{}).
+		Joins("JOIN authors ON authors.id = book_authors.author_id").
+		Where("book_authors.book_id = ? AND book_authors.role = ?", book.ID, CreditRoleAuthor).
+		Order("book_authors.position").
+		Pluck("authors.name_key", &credited).Error
+	if err != nil {
+		return err
+	}
+	if !slices.Equal(credited, before) {
+		return nil
+	}
+
+	if err := tx.Where("book_id = ? AND role = ?", book.ID, CreditRoleAuthor).Delete(&BookAuthor{}).Error; err != nil {
+		return err
+	}
+	return CreditBylineAuthors(tx, book)
+}
+
+// bylineKeys returns the name keys of the authors credited from a byline, in
+// billing order. A name repeated in the byline is credited once.
+func bylineKeys(byline string) []string {
+	var keys []string
+	for _, name := range SplitByline(byline) {
+		if key := AuthorNameKey(name); !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// AfterCreate credits the byline authors of a new book in the same transaction
+func (b *Book) AfterCreate(tx *gorm.DB) error {
+	return CreditBylineAuthors(tx.Session(&gorm.Session{NewDB: true}), *b)
+}
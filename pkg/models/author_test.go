@@ -0,0 +1,76 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils/tests"
+)
+
+func TestAuthorNameKey(t *testing.T) {
+	assert.Equal(t, "j k rowling", AuthorNameKey("J.K. Rowling"))
+	assert.Equal(t, AuthorNameKey("J.K. Rowling"), AuthorNameKey("  j. k.  ROWLING "))
+	assert.Equal(t, "gabriel garcía márquez", AuthorNameKey("Gabriel García-Márquez"))
+	assert.Equal(t, "", AuthorNameKey(" .- "))
+}
+
+func TestSplitByline(t *testing.T) {
+	assert.Equal(t, []string{"Harold Abelson", "Gerald Jay Sussman"}, SplitByline("Harold  Abelson and Gerald Jay Sussman"))
+	assert.Equal(t, []string{"Kernighan", "Ritchie", "Pike"}, SplitByline("Kernighan & Ritchie; Pike;"))
+	assert.Equal(t, []string{"Rowling, J.K."}, SplitByline("Rowling, J.K."))
+	assert.Empty(t, SplitByline(" - "))
+}
+
+func TestCreateAuthorApplyTo(t *testing.T) {
+	var author Author
+	CreateAuthor{Name: "  Ursula   K. Le Guin ", Bio: "bio"}.ApplyTo(&author)
+
+	assert.Equal(t, "Ursula K. Le Guin", author.Name)
+	assert.Equal(t, "ursula k le guin", author.NameKey)
+	assert.Equal(t, "bio", author.Bio)
+}
+
+// newCreditsDB returns a dry-run gorm handle whose book has the author
+// credits credited, as name keys, and the writes made through it
+func newCreditsDB(t *testing.T, credited []string) (*gorm.DB, *[]string) {
+	db, err := gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true, Logger: logger.Discard})
+	assert.NoError(t, err)
+
+	var writes []string
+	db.Callback().Query().After("gorm:query").Register("test:credits", func(tx *gorm.DB) {
+		if keys, ok := tx.Statement.Dest.(*[]string); ok {
+			*keys = credited
+		}
+	})
+	db.Callback().Delete().After("gorm:delete").Register("test:delete", func(tx *gorm.DB) {
+		writes = append(writes, "delete credits")
+	})
+	db.Callback().Create().After("gorm:create").Register("test:create", func(tx *gorm.DB) {
+		if author, ok := tx.Statement.Dest.(*Author); ok {
+			writes = append(writes, "credit "+author.Name)
+		}
+	})
+	return db, &writes
+}
+
+func TestResyncBylineAuthors(t *testing.T) {
+	book := Book{ID: uuid.New(), Author: "Brian Kernighan and Rob Pike"}
+
+	// Credits made from the previous byline follow the new one
+	db, writes := newCreditsDB(t, []string{"brian kernighan"})
+	assert.NoError(t, ResyncBylineAuthors(db, "Brian Kernighan", book))
+	assert.Equal(t, []string{"delete credits", "credit Brian Kernighan", "credit Rob Pike"}, *writes)
+
+	// Explicit credits are kept
+	db, writes = newCreditsDB(t, []string{"alan donovan"})
+	assert.NoError(t, ResyncBylineAuthors(db, "Brian Kernighan", book))
+	assert.Empty(t, *writes)
+
+	// A byline that only changed its spelling keeps its credits
+	db, writes = newCreditsDB(t, []string{"brian kernighan", "rob pike"})
+	assert.NoError(t, ResyncBylineAuthors(db, "brian  kernighan; rob pike", book))
+	assert.Empty(t, *writes)
+}
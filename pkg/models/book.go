@@ -1,25 +1,124 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type Book struct {
-	ID        uuid.UUID `json:"uuid" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
-	Title     string    `json:"title"`
-	Author    string    `json:"author"`
-	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+	ID              uuid.UUID      `json:"uuid" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	Title           string         `json:"title"`
+	Author          string         `json:"author"`
+	ISBN            ISBN           `json:"isbn" gorm:"size:13;uniqueIndex:idx_books_isbn_unique,where:isbn <> '' AND deleted_at IS NULL" swaggertype:"string" example:"9780134190440"`
+	PublicationDate *Date          `json:"publication_date" gorm:"type:date" swaggertype:"string" format:"date" example:"2015-10-26"`
+	Language        LanguageCode   `json:"language" gorm:"size:2" swaggertype:"string" example:"en"`
+	PageCount       int            `json:"page_count"`
+	Publisher       string         `json:"publisher"`
+	Description     string         `json:"description" gorm:"type:text"`
+	RatingAverage   float64        `json:"rating_average" gorm:"type:numeric(3,2);not null;default:0"`
+	ReviewCount     int64          `json:"review_count" gorm:"not null;default:0"`
+	Copies          int            `json:"copies" gorm:"not null;default:1"`
+	AvailableCopies int            `json:"available_copies" gorm:"not null;default:1;check:chk_books_available_copies,available_copies BETWEEN 0 AND copies"`
+	Cover           Cover          `json:"cover,omitzero" gorm:"embedded;embeddedPrefix:cover_"`
+	Version         int64          `json:"version" gorm:"not null;default:1"`
+	CreatedAt       time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt       time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt       gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index" swaggertype:"string"`
 }
 
+// CreateBook is the payload of POST /books. ISBN and Language are validated
+// and normalized while decoding.
 type CreateBook struct {
-	Title  string `json:"title" binding:"required"`
-	Author string `json:"author" binding:"required"`
+	Title           string       `json:"title" binding:"required"`
+	Author          string       `json:"author" binding:"required"`
+	ISBN            ISBN         `json:"isbn" swaggertype:"string" example:"978-0-13-419044-0"`
+	PublicationDate *Date        `json:"publication_date" swaggertype:"string" format:"date" example:"2015-10-26"`
+	Language        LanguageCode `json:"language" swaggertype:"string" example:"en"`
+	PageCount       int          `json:"page_count" binding:"omitempty,min=1,max=100000"`
+	Publisher       string       `json:"publisher" binding:"max=255"`
+	Description     string       `json:"description" binding:"max=10000"`
 }
 
+// ToBook builds a new book at its first version from the create payload
+func (input CreateBook) ToBook() Book {
+	book := Book{Version: 1}
+	UpdateBook(input).ApplyTo(&book)
+	return book
+}
+
+// UpdateBook is the full writable representation of a book used by PUT and PATCH
 type UpdateBook struct {
-	Title  string `json:"title"`
-	Author string `json:"author"`
+	Title           string       `json:"title" binding:"required"`
+	Author          string       `json:"author" binding:"required"`
+	ISBN            ISBN         `json:"isbn" swaggertype:"string" example:"978-0-13-419044-0"`
+	PublicationDate *Date        `json:"publication_date" swaggertype:"string" format:"date" example:"2015-10-26"`
+	Language        LanguageCode `json:"language" swaggertype:"string" example:"en"`
+	PageCount       int          `json:"page_count" binding:"omitempty,min=1,max=100000"`
+	Publisher       string       `json:"publisher" binding:"max=255"`
+	Description     string       `json:"description" binding:"max=10000"`
+}
+
+// BulkUpdateBook is one item of PATCH /books/bulk. Patch is a JSON Merge
+// Patch and Version, when set, must match like an If-Match header.
+type BulkUpdateBook struct {
+	ID      string          `json:"id" binding:"required,uuid"`
+	Version *int64          `json:"version"`
+	Patch   json.RawMessage `json:"patch" binding:"required" swaggertype:"object"`
+}
+
+// BulkDeleteBook is one item of DELETE /books/bulk
+type BulkDeleteBook struct {
+	ID      string `json:"id" binding:"required,uuid"`
+	Version *int64 `json:"version"`
+}
+
+func (input UpdateBook) ApplyTo(book *Book) {
+	book.Title = input.Title
+	book.Author = input.Author
+	book.ISBN = input.ISBN
+	book.PublicationDate = input.PublicationDate
+	book.Language = input.Language
+	book.PageCount = input.PageCount
+	book.Publisher = input.Publisher
+	book.Description = input.Description
+}
+
+// Writable returns the writable representation of the book
+func (b Book) Writable() UpdateBook {
+	return UpdateBook{
+		Title:           b.Title,
+		Author:          b.Author,
+		ISBN:            b.ISBN,
+		PublicationDate: b.PublicationDate,
+		Language:        b.Language,
+		PageCount:       b.PageCount,
+		Publisher:       b.Publisher,
+		Description:     b.Description,
+	}
+}
+
+// PublicationYear returns the year the book was published, or 0 when unknown
+func (b Book) PublicationYear() int {
+	if b.PublicationDate == nil {
+		return 0
+	}
+	return b.PublicationDate.Year()
+}
+
+// UpdatableValues returns every writable column of the book. Updating with a
+// map writes zero values too, which is what PUT and PATCH need.
+func (b Book) UpdatableValues() map[string]interface{} {
+	return map[string]interface{}{
+		"title":            b.Title,
+		"author":           b.Author,
+		"isbn":             b.ISBN,
+		"publication_date": b.PublicationDate,
+		"language":         b.Language,
+		"page_count":       b.PageCount,
+		"publisher":        b.Publisher,
+		"description":      b.Description,
+	}
 }
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"bytes"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// Formats of the digital editions a book can have
+const (
+	BookFormatPDF  = "pdf"
+	BookFormatEPUB = "epub"
+)
+
+// BookFormatContentTypes maps each format to the content type it is served with
+var BookFormatContentTypes = map[string]string{
+	BookFormatPDF:  "application/pdf",
+	BookFormatEPUB: "application/epub+zip",
+}
+
+// BookFile is a digital edition of a book, stored in the blob store. A book
+// has at most one file per format.
+type BookFile struct {
+	ID          uuid.UUID `json:"uuid" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	BookID      uuid.UUID `json:"book_id" gorm:"type:uuid;not null;uniqueIndex:idx_book_files_book_format"`
+	Format      string    `json:"format" gorm:"size:8;not null;uniqueIndex:idx_book_files_book_format"`
+	Key         string    `json:"-" gorm:"size:255;not null"`
+	Filename    string    `json:"filename" gorm:"size:255;not null"`
+	ContentType string    `json:"content_type" gorm:"size:64;not null"`
+	Size        int64     `json:"size" gorm:"not null"`
+	SHA256      string    `json:"sha256" gorm:"size:64;not null"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+	Book        *Book     `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+}
+
+// ETag is a strong validator of the file content
+func (file BookFile) ETag() string {
+	return `"` + file.SHA256 + `"`
+}
+
+// BookFileLink is a short-lived download link to a book file
+type BookFileLink struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// DetectBookFormat returns the format of a file from its first bytes, or ""
+// when it is neither a PDF nor an EPUB. An EPUB is a ZIP archive whose first
+// entry is an uncompressed "mimetype" file holding its media type.
+func DetectBookFormat(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte("%PDF-")):
+		return BookFormatPDF
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")) && len(head) >= 58 &&
+		string(head[30:38]) == "mimetype" && string(head[38:58]) == "application/epub+zip":
+		return BookFormatEPUB
+	default:
+		return ""
+	}
+}
+
+// BookFilename builds the download name of a book file from its title
+func BookFilename(title string, format string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '-', r == '_':
+			return r
+		case unicode.IsSpace(r), r == '.':
+			return '-'
+		default:
+			return -1
+		}
+	}, title)
+	name = strings.Trim(strings.Join(strings.FieldsFunc(name, func(r rune) bool { return r == '-' }), "-"), "-")
+	if name == "" {
+		name = "book"
+	}
+	if runes := []rune(name); len(runes) > 100 {
+		name = strings.TrimRight(string(runes[:100]), "-")
+	}
+	return name + "." + format
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseISBN(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected ISBN
+		valid    bool
+	}{
+		{"978-0-13-419044-0", "9780134190440", true},
+		{"9780134190440", "9780134190440", true},
+		{"0-306-40615-2", "9780306406157", true},
+		{"0 8044 2957 x", "9780804429573", true},
+		{"", "", true},
+		{"978-0-13-419044-1", "", false},
+		{"0-306-40615-3", "", false},
+		{"X306406152", "", false},
+		{"12345", "", false},
+	}
+
+	for _, tt := range tests {
+		isbn, err := ParseISBN(tt.input)
+		if tt.valid {
+			assert.NoError(t, err, tt.input)
+		} else {
+			assert.ErrorIs(t, err, ErrInvalidISBN, tt.input)
+		}
+		assert.Equal(t, tt.expected, isbn, tt.input)
+	}
+}
+
+func TestParseLanguageCode(t *testing.T) {
+	code, err := ParseLanguageCode(" FR ")
+	assert.NoError(t, err)
+	assert.Equal(t, LanguageCode("fr"), code)
+
+	_, err = ParseLanguageCode("fra")
+	assert.Error(t, err)
+	_, err = ParseLanguageCode("xx")
+	assert.Error(t, err)
+}
+
+func TestCreateBookDecoding(t *testing.T) {
+	var input CreateBook
+	err := json.Unmarshal([]byte(`{"title":"t","author":"a","isbn":"0-306-40615-2","publication_date":"1999-12-31","language":"EN","page_count":320}`), &input)
+	assert.NoError(t, err)
+
+	book := input.ToBook()
+	assert.Equal(t, ISBN("9780306406157"), book.ISBN)
+	assert.Equal(t, NewDate(1999, time.December, 31), *book.PublicationDate)
+	assert.Equal(t, 1999, book.PublicationYear())
+	assert.Equal(t, LanguageCode("en"), book.Language)
+	assert.Equal(t, int64(1), book.Version)
+
+	data, err := json.Marshal(book.Writable())
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"isbn":"9780306406157","publication_date":"1999-12-31","language":"en"`)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"isbn":"123"}`), &input))
+	assert.Error(t, json.Unmarshal([]byte(`{"publication_date":"1999-12-31T00:00:00Z"}`), &input))
+	assert.Error(t, json.Unmarshal([]byte(`{"language":"klingon"}`), &input))
+}
+
+func TestDateScan(t *testing.T) {
+	var date Date
+	assert.NoError(t, date.Scan(time.Date(2020, time.February, 29, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, "2020-02-29", date.String())
+
+	assert.NoError(t, date.Scan("2021-03-04"))
+	value, err := date.Value()
+	assert.NoError(t, err)
+	assert.Equal(t, "2021-03-04", value)
+
+	assert.Error(t, date.Scan(42))
+}
+
+func TestCoverKeys(t *testing.T) {
+	assert.Nil(t, Cover{}.Keys())
+	assert.Equal(t, []string{"covers/1/a/original.png", "covers/1/a/small.jpg", "covers/1/a/medium.jpg"}, Cover{Key: "covers/1/a/original.png"}.Keys())
+}
+
+func TestBookWithoutCoverOmitsIt(t *testing.T) {
+	data, err := json.Marshal(Book{Title: "Dune"})
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), `"cover"`)
+
+	data, err = json.Marshal(Book{Title: "Dune", Cover: Cover{Key: "covers/1/a/original.png", URL: "/api/v1/blobs/covers/1/a/original.png"}})
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"cover":{"url":"/api/v1/blobs/covers/1/a/original.png"`)
+	assert.NotContains(t, string(data), "key")
+}
+
+func TestDetectBookFormat(t *testing.T) {
+	epub := append([]byte("PK\x03\x04"), make([]byte, 26)...)
+	epub = append(epub, "mimetypeapplication/epub+zip"...)
+
+	assert.Equal(t, BookFormatPDF, DetectBookFormat([]byte("%PDF-1.7\n")))
+	assert.Equal(t, BookFormatEPUB, DetectBookFormat(epub))
+	assert.Equal(t, "", DetectBookFormat(epub[:40]))
+	assert.Equal(t, "", DetectBookFormat([]byte("PK\x03\x04 a plain zip archive, not an ebook at all")))
+	assert.Equal(t, "", DetectBookFormat([]byte("<html>")))
+}
+
+func TestBookFilename(t *testing.T) {
+	assert.Equal(t, "The-Go-Programming-Language.pdf", BookFilename("The Go Programming Language", BookFormatPDF))
+	assert.Equal(t, "Cien-años-de-soledad.epub", BookFilename("Cien años de soledad!", BookFormatEPUB))
+	assert.Equal(t, "Vol-2-Dune.pdf", BookFilename("Vol. 2: Dune", BookFormatPDF))
+	assert.Equal(t, "book.pdf", BookFilename("???", BookFormatPDF))
+}
@@ -0,0 +1,29 @@
+package models
+
+import "path"
+
+// Cover thumbnails are stored next to the original under these names
+const (
+	CoverSmallName  = "small.jpg"
+	CoverMediumName = "medium.jpg"
+)
+
+// Cover is the cover image of a book. Key is the blob key of the original
+// image; its thumbnails sit in the same directory.
+type Cover struct {
+	Key       string `json:"-" gorm:"size:255"`
+	URL       string `json:"url" gorm:"size:1024"`
+	SmallURL  string `json:"small_url" gorm:"size:1024"`
+	MediumURL string `json:"medium_url" gorm:"size:1024"`
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+}
+
+// Keys returns the blob keys of the original image and its thumbnails
+func (cover Cover) Keys() []string {
+	if cover.Key == "" {
+		return nil
+	}
+	dir := path.Dir(cover.Key)
+	return []string{cover.Key, path.Join(dir, CoverSmallName), path.Join(dir, CoverMediumName)}
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+var ErrInvalidISBN = errors.New("isbn must be a valid ISBN-10 or ISBN-13")
+
+// ISBN is stored in its ISBN-13 form without separators, so the same book
+// entered as ISBN-10 or with hyphens is still recognised as a duplicate
+type ISBN string
+
+// ParseISBN validates the checksum of an ISBN-10 or ISBN-13 and normalizes it.
+// Hyphens and spaces are ignored. An empty string is a valid, unset ISBN.
+func ParseISBN(value string) (ISBN, error) {
+	digits := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(value)))
+
+	switch len(digits) {
+	case 0:
+		return "", nil
+	case 10:
+		if !validISBN10(digits) {
+			return "", ErrInvalidISBN
+		}
+		isbn13 := "978" + digits[:9]
+		return ISBN(isbn13 + isbn13CheckDigit(isbn13)), nil
+	case 13:
+		if !isDigits(digits) || isbn13CheckDigit(digits[:12]) != digits[12:] {
+			return "", ErrInvalidISBN
+		}
+		return ISBN(digits), nil
+	default:
+		return "", ErrInvalidISBN
+	}
+}
+
+func (isbn *ISBN) UnmarshalJSON(data []byte) error {
+	var value *string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	if value == nil {
+		*isbn = ""
+		return nil
+	}
+
+	parsed, err := ParseISBN(*value)
+	if err != nil {
+		return err
+	}
+	*isbn = parsed
+	return nil
+}
+
+func validISBN10(digits string) bool {
+	sum := 0
+	for i := 0; i < 10; i++ {
+		var digit int
+		switch {
+		case digits[i] >= '0' && digits[i] <= '9':
+			digit = int(digits[i] - '0')
+		case digits[i] == 'X' && i == 9:
+			digit = 10
+		default:
+			return false
+		}
+		sum += (10 - i) * digit
+	}
+	return sum%11 == 0
+}
+
+// isbn13CheckDigit computes the check digit of the first 12 digits
+func isbn13CheckDigit(digits string) string {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += weight * int(digits[i]-'0')
+	}
+	return string(rune('0' + (10-sum%10)%10))
+}
+
+func isDigits(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// How a job run was started
+const (
+	JobTriggerSchedule = "schedule"
+	JobTriggerManual   = "manual"
+)
+
+// Outcomes of a job run. A run stays running if its instance dies mid-run.
+const (
+	JobRunRunning   = "running"
+	JobRunSucceeded = "succeeded"
+	JobRunFailed    = "failed"
+)
+
+// JobRun is one run of a background job
+type JobRun struct {
+	ID          uuid.UUID  `json:"uuid" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	Job         string     `json:"job" gorm:"size:64;not null;index:idx_job_runs_job_started"`
+	Trigger     string     `json:"trigger" gorm:"size:16;not null"`
+	TriggeredBy string     `json:"triggered_by,omitempty"`
+	Status      string     `json:"status" gorm:"size:16;not null"`
+	Instance    string     `json:"instance"`
+	Output      string     `json:"output,omitempty" gorm:"type:text"`
+	Error       string     `json:"error,omitempty" gorm:"type:text"`
+	StartedAt   time.Time  `json:"started_at" gorm:"not null;index:idx_job_runs_job_started"`
+	FinishedAt  *time.Time `json:"finished_at"`
+	DurationMs  int64      `json:"duration_ms"`
+}
+
+// Job describes a registered background job. Schedule is empty for jobs that
+// only run when triggered.
+type Job struct {
+	Name           string     `json:"name"`
+	Description    string     `json:"description"`
+	Schedule       string     `json:"schedule"`
+	TimeoutSeconds int        `json:"timeout_seconds"`
+	NextRunAt      *time.Time `json:"next_run_at,omitempty"`
+	LastRun        *JobRun    `json:"last_run,omitempty"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// LanguageCode is a lowercase ISO 639-1 language code such as "en"
+type LanguageCode string
+
+// ParseLanguageCode validates an ISO 639-1 code, ignoring case. An empty
+// string is a valid, unset language.
+func ParseLanguageCode(value string) (LanguageCode, error) {
+	code := strings.ToLower(strings.TrimSpace(value))
+	if code == "" {
+		return "", nil
+	}
+	if _, ok := iso639Codes[code]; !ok {
+		return "", fmt.Errorf("language %q is not an ISO 639-1 code", value)
+	}
+	return LanguageCode(code), nil
+}
+
+func (code *LanguageCode) UnmarshalJSON(data []byte) error {
+	var value *string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	if value == nil {
+		*code = ""
+		return nil
+	}
+
+	parsed, err := ParseLanguageCode(*value)
+	if err != nil {
+		return err
+	}
+	*code = parsed
+	return nil
+}
+
+var iso639Codes = map[string]struct{}{}
+
+func init() {
+	for _, code := range strings.Fields(`
+		aa ab ae af ak am an ar as av ay az ba be bg bi bm bn bo br bs ca ce ch co cr cs cu cv cy
+		da de dv dz ee el en eo es et eu fa ff fi fj fo fr fy ga gd gl gn gu gv ha he hi ho hr ht
+		hu hy hz ia id ie ig ii ik io is it iu ja jv ka kg ki kj kk kl km kn ko kr ks ku kv kw ky
+		la lb lg li ln lo lt lu lv mg mh mi mk ml mn mr ms mt my na nb nd ne ng nl nn no nr nv ny
+		oc oj om or os pa pi pl ps pt qu rm rn ro ru rw sa sc sd se sg si sk sl sm sn so sq sr ss
+		st su sv sw ta te tg th ti tk tl tn to tr ts tt tw ty ug uk ur uz ve vi vo wa wo xh yi yo
+		za zh zu`) {
+		iso639Codes[code] = struct{}{}
+	}
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Loan filters. A loan is active until the book is returned and overdue once
+// it is active past its due date.
+const (
+	LoanStatusActive   = "active"
+	LoanStatusOverdue  = "overdue"
+	LoanStatusReturned = "returned"
+)
+
+// Reservation states. A reservation waits in the queue of a book until a copy
+// is held for it; it is then ready until the hold expires or the user checks
+// the book out.
+const (
+	ReservationStatusWaiting   = "waiting"
+	ReservationStatusReady     = "ready"
+	ReservationStatusFulfilled = "fulfilled"
+	ReservationStatusCancelled = "cancelled"
+	ReservationStatusExpired   = "expired"
+)
+
+// Loan is a copy of a book checked out by a user. A user borrows at most one
+// copy of a book at a time; the username is kept so the history survives the
+// user being purged. The book can't be purged while a copy is out; once it
+// is, BookID is nil and the history stays.
+type Loan struct {
+	ID           uuid.UUID  `json:"uuid" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	BookID       *uuid.UUID `json:"book_id" gorm:"type:uuid;uniqueIndex:idx_loans_active_book_user,where:returned_at IS NULL"`
+	UserID       uuid.UUID  `json:"-" gorm:"type:uuid;not null;index;uniqueIndex:idx_loans_active_book_user,where:returned_at IS NULL"`
+	Username     string     `json:"username" gorm:"not null"`
+	CheckedOutAt time.Time  `json:"checked_out_at" gorm:"not null"`
+	DueAt        time.Time  `json:"due_at" gorm:"not null"`
+	ReturnedAt   *time.Time `json:"returned_at"`
+	Renewals     int        `json:"renewals" gorm:"not null;default:0"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+	Book         *Book      `json:"book,omitempty" gorm:"constraint:OnDelete:SET NULL"`
+}
+
+// Active reports whether the book has not been returned yet
+func (loan Loan) Active() bool {
+	return loan.ReturnedAt == nil
+}
+
+// Overdue reports whether the loan is still active past its due date
+func (loan Loan) Overdue(now time.Time) bool {
+	return loan.Active() && now.After(loan.DueAt)
+}
+
+// Reservation is a user's place in the queue for a book with no copy
+// available. A user has at most one open reservation per book. Purging the
+// book cancels its open reservations and leaves BookID nil.
+type Reservation struct {
+	ID        uuid.UUID  `json:"uuid" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	BookID    *uuid.UUID `json:"book_id" gorm:"type:uuid;index;uniqueIndex:idx_reservations_open_book_user,where:closed_at IS NULL"`
+	UserID    uuid.UUID  `json:"-" gorm:"type:uuid;not null;uniqueIndex:idx_reservations_open_book_user,where:closed_at IS NULL"`
+	Username  string     `json:"username" gorm:"not null"`
+	Status    string     `json:"status" gorm:"size:16;not null;default:waiting;index"`
+	Position  int        `json:"position,omitempty" gorm:"-"`
+	ReadyAt   *time.Time `json:"ready_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	ClosedAt  *time.Time `json:"closed_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+	Book      *Book      `json:"book,omitempty" gorm:"constraint:OnDelete:SET NULL"`
+}
+
+// Open reports whether the reservation is still waiting or holding a copy
+func (reservation Reservation) Open() bool {
+	return reservation.ClosedAt == nil
+}
+
+// UpdateInventory is the payload of PUT /books/:id/inventory
+type UpdateInventory struct {
+	Copies *int `json:"copies" binding:"required,min=0,max=10000"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoanOverdue(t *testing.T) {
+	now := time.Now()
+	returned := now.Add(-time.Hour)
+
+	assert.False(t, Loan{DueAt: now.Add(time.Hour)}.Overdue(now))
+	assert.True(t, Loan{DueAt: now.Add(-time.Hour)}.Overdue(now))
+	assert.False(t, Loan{DueAt: now.Add(-2 * time.Hour), ReturnedAt: &returned}.Overdue(now))
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Review moderation states. Only approved reviews are listed publicly and
+// count toward a book's rating.
+const (
+	ReviewStatusPending  = "pending"
+	ReviewStatusApproved = "approved"
+	ReviewStatusRejected = "rejected"
+	ReviewStatusFlagged  = "flagged"
+)
+
+// Review is a user's rating of a book. A user reviews a book at most once;
+// the username is kept on the review so it survives the user being purged.
+type Review struct {
+	ID               uuid.UUID    `json:"uuid" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	BookID           uuid.UUID    `json:"book_id" gorm:"type:uuid;not null;uniqueIndex:idx_reviews_book_user"`
+	UserID           uuid.UUID    `json:"-" gorm:"type:uuid;not null;uniqueIndex:idx_reviews_book_user"`
+	Username         string       `json:"username" gorm:"not null"`
+	Rating           int          `json:"rating" gorm:"not null;check:chk_reviews_rating,rating BETWEEN 1 AND 5"`
+	Text             string       `json:"text" gorm:"type:text"`
+	Status           string       `json:"status" gorm:"size:16;not null;default:approved;index"`
+	FlagCount        int          `json:"flag_count" gorm:"not null;default:0"`
+	ModerationReason string       `json:"moderation_reason,omitempty"`
+	ModeratedBy      string       `json:"moderated_by,omitempty"`
+	ModeratedAt      *time.Time   `json:"moderated_at,omitempty"`
+	CreatedAt        time.Time    `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt        time.Time    `json:"updated_at" gorm:"autoUpdateTime"`
+	Book             *Book        `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	Flags            []ReviewFlag `json:"flags,omitempty" gorm:"constraint:OnDelete:CASCADE"`
+}
+
+// Counted reports whether the review counts toward the book's rating
+func (review Review) Counted() bool {
+	return review.Status == ReviewStatusApproved
+}
+
+// CreateReview is the payload of POST and PUT /books/:id/reviews
+type CreateReview struct {
+	Rating int    `json:"rating" binding:"required,min=1,max=5"`
+	Text   string `json:"text" binding:"max=10000"`
+}
+
+func (input CreateReview) ApplyTo(review *Review) {
+	review.Rating = input.Rating
+	review.Text = input.Text
+}
+
+// ReviewFlag is a user's report of a review. A user flags a review at most
+// once; flags stay on record after a moderator has decided on the review.
+type ReviewFlag struct {
+	ID        uuid.UUID `json:"uuid" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	ReviewID  uuid.UUID `json:"review_id" gorm:"type:uuid;not null;uniqueIndex:idx_review_flags_review_user"`
+	UserID    uuid.UUID `json:"-" gorm:"type:uuid;not null;uniqueIndex:idx_review_flags_review_user"`
+	Username  string    `json:"username" gorm:"not null"`
+	Reason    string    `json:"reason" gorm:"type:text;not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// FlagReview is the payload of POST /books/:id/reviews/:review_id/flag
+type FlagReview struct {
+	Reason string `json:"reason" binding:"required,max=1000"`
+}
+
+// ModerateReview is the payload of the approve and reject actions
+type ModerateReview struct {
+	Reason string `json:"reason" binding:"max=1000"`
+}
@@ -4,6 +4,13 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
 )
 
 type LoginUser struct {
@@ -11,10 +18,17 @@ type LoginUser struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// SetUserRole is the payload of PUT /users/:id/role
+type SetUserRole struct {
+	Role string `json:"role" binding:"required,oneof=user moderator admin"`
+}
+
 type User struct {
-	ID        uuid.UUID `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
-	Username  string    `json:"username" gorm:"unique"`
-	Password  string    `json:"password"`
-	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+	ID        uuid.UUID      `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	Username  string         `json:"username" gorm:"unique"`
+	Password  string         `json:"password"`
+	Role      string         `json:"role" gorm:"not null;default:user"`
+	CreatedAt time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 }
@@ -0,0 +1,76 @@
+package moderation
+
+import (
+	"strings"
+	"unicode"
+)
+
+// WordList matches text against a list of blocked words. Matching ignores
+// case and punctuation and only hits whole words, so "class" doesn't match
+// a blocked "ass". A blocked entry of several words matches that phrase.
+type WordList struct {
+	phrases [][]string
+}
+
+// NewWordList builds a word list, skipping blank entries
+func NewWordList(words []string) *WordList {
+	list := &WordList{}
+	for _, word := range words {
+		if phrase := tokenize(word); len(phrase) > 0 {
+			list.phrases = append(list.phrases, phrase)
+		}
+	}
+	return list
+}
+
+// ParseWordList builds a word list from a comma separated value such as an
+// environment variable
+func ParseWordList(value string) *WordList {
+	return NewWordList(strings.Split(value, ","))
+}
+
+// Len returns the number of blocked entries
+func (list *WordList) Len() int {
+	if list == nil {
+		return 0
+	}
+	return len(list.phrases)
+}
+
+// Match returns the blocked entries found in text, in list order
+func (list *WordList) Match(text string) []string {
+	if list.Len() == 0 {
+		return nil
+	}
+
+	tokens := tokenize(text)
+	var matches []string
+	for _, phrase := range list.phrases {
+		if containsPhrase(tokens, phrase) {
+			matches = append(matches, strings.Join(phrase, " "))
+		}
+	}
+	return matches
+}
+
+func containsPhrase(tokens []string, phrase []string) bool {
+	for i := 0; i+len(phrase) <= len(tokens); i++ {
+		matched := true
+		for j, word := range phrase {
+			if tokens[i+j] != word {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
@@ -0,0 +1,24 @@
+package moderation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWordListMatch(t *testing.T) {
+	list := ParseWordList(" spam, Buy Now ,, casino")
+
+	assert.Equal(t, 3, list.Len())
+	assert.Equal(t, []string{"spam", "buy now"}, list.Match("SPAM!!! buy   now, cheap"))
+	assert.Equal(t, []string{"casino"}, list.Match("Visit the casino."))
+	assert.Empty(t, list.Match("A spammy read, buy it now"))
+	assert.Empty(t, list.Match(""))
+}
+
+func TestEmptyWordList(t *testing.T) {
+	var list *WordList
+	assert.Equal(t, 0, list.Len())
+	assert.Empty(t, list.Match("anything"))
+	assert.Empty(t, ParseWordList("").Match("anything"))
+}
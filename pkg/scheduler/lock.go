@@ -0,0 +1,45 @@
+package scheduler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"time"
+
+	"github.com/kev1nandreas/go-rest-api-template/pkg/cache"
+)
+
+// Locker takes locks in the cache store shared by every replica
+type Locker struct {
+	store cache.Cache
+}
+
+func NewLocker(store cache.Cache) *Locker {
+	return &Locker{store: store}
+}
+
+// Acquire takes the lock named key for at most ttl. It returns false when
+// another holder has it. The returned release function gives the lock back
+// early.
+func (l *Locker) Acquire(ctx context.Context, key string, ttl time.Duration) (func(), bool, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, false, err
+	}
+	value := []byte(hex.EncodeToString(token))
+
+	ok, err := l.store.SetNX(ctx, key, value, ttl)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	release := func() {
+		// The caller's context may be done by now; the lock must still go.
+		// A lock that expired and was taken by another holder is left alone.
+		if _, err := l.store.DeleteIfValue(context.Background(), key, value); err != nil {
+			log.Printf("Failed to release lock %s: %v", key, err)
+		}
+	}
+	return release, true, nil
+}
@@ -0,0 +1,38 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"gorm.io/gorm"
+)
+
+// RunStore keeps the history of job runs
+type RunStore interface {
+	CreateRun(ctx context.Context, run *models.JobRun) error
+	SaveRun(ctx context.Context, run *models.JobRun) error
+}
+
+// GormRunStore keeps job runs in the job_runs table
+type GormRunStore struct {
+	DB *gorm.DB
+}
+
+func NewGormRunStore(db *gorm.DB) *GormRunStore {
+	return &GormRunStore{DB: db}
+}
+
+func (s *GormRunStore) CreateRun(ctx context.Context, run *models.JobRun) error {
+	return s.DB.WithContext(ctx).Create(run).Error
+}
+
+func (s *GormRunStore) SaveRun(ctx context.Context, run *models.JobRun) error {
+	return s.DB.WithContext(ctx).Model(run).Select("status", "output", "error", "finished_at", "duration_ms").Updates(run).Error
+}
+
+// PruneRuns deletes the runs started before cutoff
+func (s *GormRunStore) PruneRuns(ctx context.Context, cutoff time.Time) (int64, error) {
+	result := s.DB.WithContext(ctx).Where("started_at < ?", cutoff).Delete(&models.JobRun{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,178 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule tells when a job runs next
+type Schedule interface {
+	// Next returns the first run time strictly after t, or the zero time
+	// when the schedule never fires again
+	Next(t time.Time) time.Time
+}
+
+// shorthands are the predefined cron schedules
+var shorthands = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+}
+
+// ParseSchedule parses a five field cron expression (minute, hour, day of
+// month, month, day of week), one of the @hourly style shorthands, or
+// "@every <duration>" such as "@every 15m".
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if expr, ok := shorthands[spec]; ok {
+		spec = expr
+	}
+
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+		if interval < time.Second {
+			return nil, fmt.Errorf("invalid schedule %q: the interval must be at least 1s", spec)
+		}
+		return every(interval), nil
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule %q: expected 5 fields, @every or a shorthand like @daily", spec)
+	}
+
+	var schedule cronSchedule
+	var err error
+	bounds := []struct {
+		set      *uint64
+		min, max int
+	}{
+		{&schedule.minute, 0, 59},
+		{&schedule.hour, 0, 23},
+		{&schedule.dom, 1, 31},
+		{&schedule.month, 1, 12},
+		{&schedule.dow, 0, 7},
+	}
+	for i, field := range fields {
+		if *bounds[i].set, err = parseField(field, bounds[i].min, bounds[i].max); err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+	}
+
+	// 7 is Sunday too
+	if schedule.dow&(1<<7) != 0 {
+		schedule.dow |= 1
+	}
+	schedule.anyDom = fields[2] == "*"
+	schedule.anyDow = fields[4] == "*"
+	return schedule, nil
+}
+
+// parseField parses a comma separated list of values, ranges and steps into
+// a bit set
+func parseField(field string, min int, max int) (uint64, error) {
+	var set uint64
+
+	for _, part := range strings.Split(field, ",") {
+		expr, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepText); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q", part)
+			}
+		}
+
+		low, high := min, max
+		switch {
+		case expr == "*":
+		case strings.Contains(expr, "-"):
+			lowText, highText, _ := strings.Cut(expr, "-")
+			var errLow, errHigh error
+			low, errLow = strconv.Atoi(lowText)
+			high, errHigh = strconv.Atoi(highText)
+			if errLow != nil || errHigh != nil {
+				return 0, fmt.Errorf("invalid range %q", part)
+			}
+		default:
+			value, err := strconv.Atoi(expr)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			// "5/10" starts at 5 and runs to the end of the range
+			low = value
+			if !hasStep {
+				high = value
+			}
+		}
+
+		if low < min || high > max || low > high {
+			return 0, fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+		for value := low; value <= high; value += step {
+			set |= 1 << value
+		}
+	}
+
+	if set == 0 {
+		return 0, errors.New("empty field")
+	}
+	return set, nil
+}
+
+// every fires at multiples of an interval. The slots are aligned to the zero
+// time rather than to when the process started, so every replica agrees on
+// them.
+type every time.Duration
+
+func (interval every) Next(t time.Time) time.Time {
+	return t.Truncate(time.Duration(interval)).Add(time.Duration(interval))
+}
+
+// cronSchedule holds the bit sets of a parsed cron expression
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	anyDom, anyDow                bool
+}
+
+// dayMatches follows cron: when both day fields are restricted, a day
+// matching either of them fires
+func (schedule cronSchedule) dayMatches(t time.Time) bool {
+	dom := schedule.dom&(1<<t.Day()) != 0
+	dow := schedule.dow&(1<<int(t.Weekday())) != 0
+	if schedule.anyDom || schedule.anyDow {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+func (schedule cronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case schedule.month&(1<<int(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !schedule.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case schedule.hour&(1<<t.Hour()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case schedule.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
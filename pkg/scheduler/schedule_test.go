@@ -0,0 +1,57 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSchedule(t *testing.T) {
+	from := time.Date(2024, time.January, 31, 10, 17, 30, 0, time.UTC) // a Wednesday
+
+	tests := []struct {
+		spec string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2024, time.January, 31, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, time.January, 31, 10, 30, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2024, time.January, 31, 11, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, time.January, 31, 11, 0, 0, 0, time.UTC)},
+		{"30 2 * * *", time.Date(2024, time.February, 1, 2, 30, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2024, time.February, 1, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, time.February, 4, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 12 1,15 * *", time.Date(2024, time.February, 1, 12, 0, 0, 0, time.UTC)},
+		{"5/20 10 * * *", time.Date(2024, time.January, 31, 10, 25, 0, 0, time.UTC)},
+		// Both day fields restricted: either one matches
+		{"0 0 13 * 5", time.Date(2024, time.February, 2, 0, 0, 0, 0, time.UTC)},
+		{"@every 15m", time.Date(2024, time.January, 31, 10, 30, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		schedule, err := ParseSchedule(tt.spec)
+		assert.NoError(t, err, tt.spec)
+		assert.Equal(t, tt.next, schedule.Next(from), tt.spec)
+	}
+}
+
+func TestParseScheduleRejectsInvalidSpecs(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "a * * * *", "@every", "@every 10", "@every 500ms", "@often"} {
+		_, err := ParseSchedule(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestEveryIsAlignedAcrossReplicas(t *testing.T) {
+	schedule, err := ParseSchedule("@every 10m")
+	assert.NoError(t, err)
+
+	// Replicas asking at different times within a slot agree on the next one
+	first := schedule.Next(time.Date(2024, time.May, 1, 8, 1, 0, 0, time.UTC))
+	second := schedule.Next(time.Date(2024, time.May, 1, 8, 9, 59, 0, time.UTC))
+	assert.Equal(t, first, second)
+	assert.Equal(t, time.Date(2024, time.May, 1, 8, 10, 0, 0, time.UTC), first)
+}
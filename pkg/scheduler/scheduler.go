@@ -0,0 +1,278 @@
+// Package scheduler runs background jobs on cron-style schedules. Every
+// replica of the server can run the scheduler: a run is claimed in the shared
+// cache store first, so each scheduled slot runs once across the cluster and a
+// job never overlaps with itself.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kev1nandreas/go-rest-api-template/env"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/cache"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+)
+
+var (
+	ErrUnknownJob = errors.New("unknown job")
+	ErrJobRunning = errors.New("job is already running")
+)
+
+// slotClaimTTL is how long the claim on a scheduled slot is kept. It only
+// has to outlast the clock skew between replicas.
+const slotClaimTTL = 5 * time.Minute
+
+// defaultTimeout bounds jobs registered without a timeout
+const defaultTimeout = 10 * time.Minute
+
+// JobFunc does the work of a job and returns a short summary of what it did
+type JobFunc func(ctx context.Context) (string, error)
+
+// Job is a unit of background work. Spec is a schedule ParseSchedule
+// understands, or empty for a job that only runs when triggered.
+type Job struct {
+	Name        string
+	Description string
+	Spec        string
+	Timeout     time.Duration
+	Run         JobFunc
+
+	schedule Schedule
+}
+
+// Scheduler runs registered jobs on their schedules and on demand
+type Scheduler struct {
+	locker   *Locker
+	runs     RunStore
+	instance string
+
+	mu   sync.Mutex
+	jobs []*Job
+}
+
+func New(store cache.Cache, runs RunStore) *Scheduler {
+	hostname, _ := os.Hostname()
+	return &Scheduler{
+		locker:   NewLocker(store),
+		runs:     runs,
+		instance: hostname + "-" + strconv.Itoa(os.Getpid()),
+	}
+}
+
+// ConfiguredSpec returns the schedule of the job named name from
+// SCHEDULE_<NAME>, with dashes as underscores, or fallback when it is unset.
+// "off" disables the schedule.
+func ConfiguredSpec(name string, fallback string) string {
+	key := "SCHEDULE_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+	spec := strings.TrimSpace(env.GetEnvString(key, fallback))
+	if spec == "off" {
+		return ""
+	}
+	return spec
+}
+
+// Register adds a job. Names must be unique.
+func (s *Scheduler) Register(job Job) error {
+	if job.Name == "" || job.Run == nil {
+		return errors.New("a job needs a name and a function")
+	}
+	if job.Spec != "" {
+		schedule, err := ParseSchedule(job.Spec)
+		if err != nil {
+			return fmt.Errorf("job %s: %w", job.Name, err)
+		}
+		job.schedule = schedule
+	}
+	if job.Timeout <= 0 {
+		job.Timeout = defaultTimeout
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, registered := range s.jobs {
+		if registered.Name == job.Name {
+			return fmt.Errorf("job %s is already registered", job.Name)
+		}
+	}
+	s.jobs = append(s.jobs, &job)
+	return nil
+}
+
+// Jobs describes the registered jobs in registration order
+func (s *Scheduler) Jobs() []models.Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	jobs := make([]models.Job, len(s.jobs))
+	for i, job := range s.jobs {
+		jobs[i] = models.Job{
+			Name:           job.Name,
+			Description:    job.Description,
+			Schedule:       job.Spec,
+			TimeoutSeconds: int(job.Timeout / time.Second),
+		}
+		if job.schedule != nil {
+			if next := job.schedule.Next(now); !next.IsZero() {
+				jobs[i].NextRunAt = &next
+			}
+		}
+	}
+	return jobs
+}
+
+func (s *Scheduler) job(name string) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, job := range s.jobs {
+		if job.Name == name {
+			return job, nil
+		}
+	}
+	return nil, ErrUnknownJob
+}
+
+// Start runs every scheduled job on its schedule until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, job := range s.jobs {
+		if job.schedule != nil {
+			go s.loop(ctx, job)
+		}
+	}
+}
+
+func (s *Scheduler) loop(ctx context.Context, job *Job) {
+	for {
+		slot := job.schedule.Next(time.Now())
+		if slot.IsZero() {
+			return
+		}
+
+		timer := time.NewTimer(time.Until(slot))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		s.runSlot(ctx, job, slot)
+	}
+}
+
+// runSlot runs job for a scheduled slot unless another replica claimed it
+func (s *Scheduler) runSlot(ctx context.Context, job *Job, slot time.Time) {
+	key := "scheduler:slot:" + job.Name + ":" + strconv.FormatInt(slot.Unix(), 10)
+	_, claimed, err := s.locker.Acquire(ctx, key, slotClaimTTL)
+	if err != nil {
+		log.Printf("Failed to claim a run of job %s: %v", job.Name, err)
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	run, release, err := s.begin(ctx, job, models.JobTriggerSchedule, "")
+	if errors.Is(err, ErrJobRunning) {
+		log.Printf("Skipped a run of job %s: the previous run has not finished", job.Name)
+		return
+	} else if err != nil {
+		log.Printf("Failed to start job %s: %v", job.Name, err)
+		return
+	}
+	s.execute(job, run, release)
+}
+
+// Trigger starts a run of the job named name in the background, unless it is
+// already running, and returns the run as recorded when it started
+func (s *Scheduler) Trigger(ctx context.Context, name string, triggeredBy string) (models.JobRun, error) {
+	job, err := s.job(name)
+	if err != nil {
+		return models.JobRun{}, err
+	}
+
+	run, release, err := s.begin(ctx, job, models.JobTriggerManual, triggeredBy)
+	if err != nil {
+		return models.JobRun{}, err
+	}
+	started := *run
+
+	go s.execute(job, run, release)
+	return started, nil
+}
+
+// begin takes the lock of job and records the start of a run
+func (s *Scheduler) begin(ctx context.Context, job *Job, trigger string, triggeredBy string) (*models.JobRun, func(), error) {
+	// The lock outlives the run's timeout slightly, so it is released rather
+	// than expiring while the job is still finishing up
+	release, ok, err := s.locker.Acquire(ctx, "scheduler:lock:"+job.Name, job.Timeout+time.Minute)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		return nil, nil, ErrJobRunning
+	}
+
+	run := &models.JobRun{
+		Job:         job.Name,
+		Trigger:     trigger,
+		TriggeredBy: triggeredBy,
+		Status:      models.JobRunRunning,
+		Instance:    s.instance,
+		StartedAt:   time.Now(),
+	}
+	if err := s.runs.CreateRun(ctx, run); err != nil {
+		release()
+		return nil, nil, err
+	}
+	return run, release, nil
+}
+
+// execute runs job, records the outcome of run and releases the job lock.
+// Runs are detached from the request or loop that started them and only
+// bounded by the job timeout.
+func (s *Scheduler) execute(job *Job, run *models.JobRun, release func()) {
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), job.Timeout)
+	defer cancel()
+
+	output, err := call(ctx, job.Run)
+
+	finished := time.Now()
+	run.FinishedAt = &finished
+	run.DurationMs = finished.Sub(run.StartedAt).Milliseconds()
+	run.Output = output
+	run.Status = models.JobRunSucceeded
+	if err != nil {
+		run.Status = models.JobRunFailed
+		run.Error = err.Error()
+		log.Printf("Job %s failed: %v", job.Name, err)
+	}
+
+	if err := s.runs.SaveRun(context.Background(), run); err != nil {
+		log.Printf("Failed to record the run of job %s: %v", job.Name, err)
+	}
+}
+
+// call runs fn, turning a panic into an error so one bad job can't take the
+// scheduler down
+func call(ctx context.Context, fn JobFunc) (output string, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+	return fn(ctx)
+}
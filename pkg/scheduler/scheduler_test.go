@@ -0,0 +1,159 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kev1nandreas/go-rest-api-template/pkg/cache"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryRunStore records runs in memory
+type memoryRunStore struct {
+	mu   sync.Mutex
+	runs []models.JobRun
+	done chan models.JobRun
+}
+
+func newMemoryRunStore() *memoryRunStore {
+	return &memoryRunStore{done: make(chan models.JobRun, 10)}
+}
+
+func (m *memoryRunStore) CreateRun(ctx context.Context, run *models.JobRun) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.runs = append(m.runs, *run)
+	return nil
+}
+
+func (m *memoryRunStore) SaveRun(ctx context.Context, run *models.JobRun) error {
+	m.done <- *run
+	return nil
+}
+
+func (m *memoryRunStore) wait(t *testing.T) models.JobRun {
+	select {
+	case run := <-m.done:
+		return run
+	case <-time.After(5 * time.Second):
+		t.Fatal("the run did not finish")
+		return models.JobRun{}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	s := New(cache.NewMemoryCache(100), newMemoryRunStore())
+	noop := func(ctx context.Context) (string, error) { return "", nil }
+
+	assert.NoError(t, s.Register(Job{Name: "purge", Spec: "@hourly", Run: noop}))
+	assert.NoError(t, s.Register(Job{Name: "manual", Run: noop}))
+	assert.Error(t, s.Register(Job{Name: "purge", Run: noop}), "duplicate name")
+	assert.Error(t, s.Register(Job{Name: "broken", Spec: "every hour", Run: noop}), "invalid spec")
+	assert.Error(t, s.Register(Job{Name: "nothing"}), "no function")
+
+	jobs := s.Jobs()
+	assert.Len(t, jobs, 2)
+	assert.Equal(t, "purge", jobs[0].Name)
+	assert.NotNil(t, jobs[0].NextRunAt)
+	assert.Equal(t, int(defaultTimeout/time.Second), jobs[0].TimeoutSeconds)
+	assert.Nil(t, jobs[1].NextRunAt)
+}
+
+func TestConfiguredSpec(t *testing.T) {
+	t.Setenv("SCHEDULE_PURGE_TRASH", "0 3 * * *")
+	t.Setenv("SCHEDULE_WARM_CACHE", "off")
+
+	assert.Equal(t, "0 3 * * *", ConfiguredSpec("purge-trash", "@hourly"))
+	assert.Equal(t, "", ConfiguredSpec("warm-cache", "@every 1m"))
+	assert.Equal(t, "@daily", ConfiguredSpec("unset", "@daily"))
+}
+
+func TestTrigger(t *testing.T) {
+	runs := newMemoryRunStore()
+	s := New(cache.NewMemoryCache(100), runs)
+
+	proceed := make(chan struct{})
+	assert.NoError(t, s.Register(Job{Name: "report", Run: func(ctx context.Context) (string, error) {
+		<-proceed
+		return "sent 3 reminders", nil
+	}}))
+
+	started, err := s.Trigger(context.Background(), "report", "admin")
+	assert.NoError(t, err)
+	assert.Equal(t, models.JobRunRunning, started.Status)
+	assert.Equal(t, models.JobTriggerManual, started.Trigger)
+	assert.Equal(t, "admin", started.TriggeredBy)
+
+	// The job holds its lock until it finishes
+	_, err = s.Trigger(context.Background(), "report", "admin")
+	assert.ErrorIs(t, err, ErrJobRunning)
+
+	close(proceed)
+	run := runs.wait(t)
+	assert.Equal(t, models.JobRunSucceeded, run.Status)
+	assert.Equal(t, "sent 3 reminders", run.Output)
+	assert.NotNil(t, run.FinishedAt)
+
+	_, err = s.Trigger(context.Background(), "report", "admin")
+	assert.NoError(t, err)
+	runs.wait(t)
+
+	_, err = s.Trigger(context.Background(), "missing", "admin")
+	assert.ErrorIs(t, err, ErrUnknownJob)
+}
+
+func TestFailedRunsAreRecorded(t *testing.T) {
+	runs := newMemoryRunStore()
+	s := New(cache.NewMemoryCache(100), runs)
+	assert.NoError(t, s.Register(Job{Name: "failing", Run: func(ctx context.Context) (string, error) {
+		return "", errors.New("database is down")
+	}}))
+	assert.NoError(t, s.Register(Job{Name: "panicking", Run: func(ctx context.Context) (string, error) {
+		panic("nil map")
+	}}))
+	assert.NoError(t, s.Register(Job{Name: "slow", Timeout: 10 * time.Millisecond, Run: func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}}))
+
+	for job, message := range map[string]string{"failing": "database is down", "panicking": "panic: nil map", "slow": "context deadline exceeded"} {
+		_, err := s.Trigger(context.Background(), job, "")
+		assert.NoError(t, err, job)
+
+		run := runs.wait(t)
+		assert.Equal(t, models.JobRunFailed, run.Status, job)
+		assert.Equal(t, message, run.Error, job)
+	}
+}
+
+func TestScheduledSlotRunsOnce(t *testing.T) {
+	store := cache.NewMemoryCache(100)
+	runs := newMemoryRunStore()
+	count := 0
+	job := Job{Name: "purge", Spec: "@every 1m", Run: func(ctx context.Context) (string, error) {
+		count++
+		return "", nil
+	}}
+
+	// Two replicas wake up for the same slot
+	first, second := New(store, runs), New(store, runs)
+	assert.NoError(t, first.Register(job))
+	assert.NoError(t, second.Register(job))
+	slot := time.Date(2024, time.May, 1, 8, 0, 0, 0, time.UTC)
+
+	jobA, _ := first.job("purge")
+	jobB, _ := second.job("purge")
+	first.runSlot(context.Background(), jobA, slot)
+	second.runSlot(context.Background(), jobB, slot)
+	assert.Equal(t, 1, count)
+	assert.Len(t, runs.runs, 1)
+	assert.Equal(t, models.JobTriggerSchedule, runs.runs[0].Trigger)
+
+	// The next slot is free again
+	second.runSlot(context.Background(), jobB, slot.Add(time.Minute))
+	assert.Equal(t, 2, count)
+}
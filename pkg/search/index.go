@@ -0,0 +1,304 @@
+package search
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+)
+
+type document struct {
+	ID     uuid.UUID
+	Title  string
+	Author string
+	Year   int
+}
+
+// ErrIndexLocked is returned by OpenIndex while another process has the
+// index open
+var ErrIndexLocked = errors.New("search index is open in another process")
+
+// Index is an in-memory inverted index persisted to a single file on disk.
+// It is meant for single node deployments that don't want to rely on
+// Postgres full text search; every replica keeps its own copy.
+//
+// Each write rewrites the file from memory, so a single process may have the
+// index open at a time: it holds a lock on the file at path + ".lock" until
+// Close, and other processes fail to open the index meanwhile.
+type Index struct {
+	mu       sync.RWMutex
+	path     string
+	lock     *os.File
+	docs     map[uuid.UUID]document
+	postings map[string]map[uuid.UUID]int
+}
+
+// OpenIndex loads the index stored at path, starting empty if it doesn't exist
+// yet. It returns ErrIndexLocked if another process has it open.
+func OpenIndex(path string) (*Index, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	lock, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(lock); err != nil {
+		lock.Close()
+		return nil, err
+	}
+
+	idx := &Index{
+		path:     path,
+		lock:     lock,
+		docs:     map[uuid.UUID]document{},
+		postings: map[string]map[uuid.UUID]int{},
+	}
+	if err := idx.load(); err != nil {
+		lock.Close()
+		return nil, err
+	}
+	return idx, nil
+}
+
+// load reads the documents stored at the path of the index
+func (idx *Index) load() error {
+	file, err := os.Open(idx.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var docs []document
+	if err := gob.NewDecoder(file).Decode(&docs); err != nil {
+		return err
+	}
+	for _, doc := range docs {
+		idx.add(doc)
+	}
+	return nil
+}
+
+// Close releases the lock on the index, so another process can open it
+func (idx *Index) Close() error {
+	return idx.lock.Close()
+}
+
+func (idx *Index) Index(ctx context.Context, books ...models.Book) error {
+	if len(books) == 0 {
+		return nil
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for _, book := range books {
+		idx.remove(book.ID)
+		idx.add(document{ID: book.ID, Title: book.Title, Author: book.Author, Year: BookYear(book)})
+	}
+
+	return idx.persist()
+}
+
+func (idx *Index) Remove(ctx context.Context, ids ...uuid.UUID) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	removed := false
+	for _, id := range ids {
+		if _, ok := idx.docs[id]; ok {
+			idx.remove(id)
+			removed = true
+		}
+	}
+	if !removed {
+		return nil
+	}
+
+	return idx.persist()
+}
+
+func (idx *Index) Reset(ctx context.Context) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.docs = map[uuid.UUID]document{}
+	idx.postings = map[string]map[uuid.UUID]int{}
+
+	return idx.persist()
+}
+
+func (idx *Index) Search(ctx context.Context, query Query) (Result, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	scores := idx.match(tokenize(query.Text))
+
+	type hit struct {
+		doc   document
+		score float64
+	}
+	var hits []hit
+	authors := map[string]int{}
+	years := map[string]int{}
+
+	for id, score := range scores {
+		doc := idx.docs[id]
+		if query.Author != "" && doc.Author != query.Author {
+			continue
+		}
+		if query.Year != 0 && doc.Year != query.Year {
+			continue
+		}
+		hits = append(hits, hit{doc: doc, score: score})
+		authors[doc.Author]++
+		years[strconv.Itoa(doc.Year)]++
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].score != hits[j].score {
+			return hits[i].score > hits[j].score
+		}
+		if hits[i].doc.Title != hits[j].doc.Title {
+			return hits[i].doc.Title < hits[j].doc.Title
+		}
+		return hits[i].doc.ID.String() < hits[j].doc.ID.String()
+	})
+
+	result := Result{
+		Total: int64(len(hits)),
+		IDs:   []uuid.UUID{},
+		Facets: map[string][]FacetValue{
+			FacetAuthor: facetValues(authors, false),
+			FacetYear:   facetValues(years, true),
+		},
+	}
+
+	for i := query.Offset; i < len(hits) && (query.Limit <= 0 || i < query.Offset+query.Limit); i++ {
+		result.IDs = append(result.IDs, hits[i].doc.ID)
+	}
+
+	return result, nil
+}
+
+// match returns the TF-IDF score of every document containing all terms.
+// An empty query matches every document with a zero score.
+func (idx *Index) match(terms []string) map[uuid.UUID]float64 {
+	scores := map[uuid.UUID]float64{}
+	if len(terms) == 0 {
+		for id := range idx.docs {
+			scores[id] = 0
+		}
+		return scores
+	}
+
+	for i, term := range terms {
+		postings := idx.postings[term]
+		idf := math.Log(1 + float64(len(idx.docs))/float64(len(postings)+1))
+
+		if i == 0 {
+			for id, tf := range postings {
+				scores[id] = float64(tf) * idf
+			}
+			continue
+		}
+
+		for id := range scores {
+			tf, ok := postings[id]
+			if !ok {
+				delete(scores, id)
+				continue
+			}
+			scores[id] += float64(tf) * idf
+		}
+	}
+
+	return scores
+}
+
+func (idx *Index) add(doc document) {
+	idx.docs[doc.ID] = doc
+	for _, term := range tokenize(doc.Title + " " + doc.Author) {
+		if idx.postings[term] == nil {
+			idx.postings[term] = map[uuid.UUID]int{}
+		}
+		idx.postings[term][doc.ID]++
+	}
+}
+
+func (idx *Index) remove(id uuid.UUID) {
+	doc, ok := idx.docs[id]
+	if !ok {
+		return
+	}
+	for _, term := range tokenize(doc.Title + " " + doc.Author) {
+		delete(idx.postings[term], id)
+		if len(idx.postings[term]) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	delete(idx.docs, id)
+}
+
+// persist writes the documents to a temporary file and renames it over the
+// previous index so a crash never leaves a half written file behind. It
+// rewrites the whole index, so writes are batched per Index or Remove call.
+func (idx *Index) persist() error {
+	if err := os.MkdirAll(filepath.Dir(idx.path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(idx.path), filepath.Base(idx.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	docs := make([]document, 0, len(idx.docs))
+	for _, doc := range idx.docs {
+		docs = append(docs, doc)
+	}
+
+	if err := gob.NewEncoder(tmp).Encode(docs); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), idx.path)
+}
+
+func facetValues(counts map[string]int, byValueDesc bool) []FacetValue {
+	values := make([]FacetValue, 0, len(counts))
+	for value, count := range counts {
+		values = append(values, FacetValue{Value: value, Count: count})
+	}
+
+	sort.Slice(values, func(i, j int) bool {
+		if byValueDesc {
+			return values[i].Value > values[j].Value
+		}
+		if values[i].Count != values[j].Count {
+			return values[i].Count > values[j].Count
+		}
+		return values[i].Value < values[j].Value
+	})
+
+	if len(values) > maxFacetValues {
+		values = values[:maxFacetValues]
+	}
+	return values
+}
@@ -0,0 +1,160 @@
+package search
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestBook(title, author string, year int) models.Book {
+	return models.Book{
+		ID:        uuid.New(),
+		Title:     title,
+		Author:    author,
+		CreatedAt: time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestIndexSearch(t *testing.T) {
+	ctx := context.Background()
+	idx, err := OpenIndex(filepath.Join(t.TempDir(), "books.idx"))
+	assert.NoError(t, err)
+
+	goBook := newTestBook("The Go Programming Language", "Alan Donovan", 2015)
+	effectiveGo := newTestBook("Effective Go", "Robert Griesemer", 2020)
+	rust := newTestBook("The Rust Programming Language", "Steve Klabnik", 2020)
+
+	for _, book := range []models.Book{goBook, effectiveGo, rust} {
+		assert.NoError(t, idx.Index(ctx, book))
+	}
+
+	result, err := idx.Search(ctx, Query{Text: "programming language"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), result.Total)
+	assert.ElementsMatch(t, []uuid.UUID{goBook.ID, rust.ID}, result.IDs)
+
+	result, err = idx.Search(ctx, Query{Text: "GO"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), result.Total)
+	assert.ElementsMatch(t, []FacetValue{{Value: "Alan Donovan", Count: 1}, {Value: "Robert Griesemer", Count: 1}}, result.Facets[FacetAuthor])
+	assert.Equal(t, []FacetValue{{Value: "2020", Count: 1}, {Value: "2015", Count: 1}}, result.Facets[FacetYear])
+
+	result, err = idx.Search(ctx, Query{Year: 2020, Limit: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), result.Total)
+	assert.Len(t, result.IDs, 1)
+
+	result, err = idx.Search(ctx, Query{Author: "Steve Klabnik"})
+	assert.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{rust.ID}, result.IDs)
+}
+
+func TestIndexUpdateAndRemove(t *testing.T) {
+	ctx := context.Background()
+	idx, err := OpenIndex(filepath.Join(t.TempDir(), "books.idx"))
+	assert.NoError(t, err)
+
+	book := newTestBook("Old Title", "Someone", 2021)
+	assert.NoError(t, idx.Index(ctx, book))
+
+	book.Title = "New Title"
+	assert.NoError(t, idx.Index(ctx, book))
+
+	result, _ := idx.Search(ctx, Query{Text: "old"})
+	assert.Equal(t, int64(0), result.Total)
+	result, _ = idx.Search(ctx, Query{Text: "new"})
+	assert.Equal(t, []uuid.UUID{book.ID}, result.IDs)
+
+	assert.NoError(t, idx.Remove(ctx, book.ID))
+	result, _ = idx.Search(ctx, Query{Text: "new"})
+	assert.Equal(t, int64(0), result.Total)
+}
+
+// reopenIndex closes idx and loads it again from path
+func reopenIndex(t *testing.T, idx *Index, path string) *Index {
+	assert.NoError(t, idx.Close())
+	reopened, err := OpenIndex(path)
+	assert.NoError(t, err)
+	return reopened
+}
+
+func TestIndexPersistence(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "nested", "books.idx")
+
+	idx, err := OpenIndex(path)
+	assert.NoError(t, err)
+	book := newTestBook("Persistent Book", "Writer", 2019)
+	assert.NoError(t, idx.Index(ctx, book))
+
+	reopened := reopenIndex(t, idx, path)
+	result, err := reopened.Search(ctx, Query{Text: "persistent"})
+	assert.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{book.ID}, result.IDs)
+
+	assert.NoError(t, reopened.Reset(ctx))
+	reopened = reopenIndex(t, reopened, path)
+	result, _ = reopened.Search(ctx, Query{})
+	assert.Equal(t, int64(0), result.Total)
+}
+
+func TestIndexOpenInOneProcess(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "books.idx")
+
+	idx, err := OpenIndex(path)
+	assert.NoError(t, err)
+
+	// A second open would write its own copy over the first one's
+	_, err = OpenIndex(path)
+	assert.ErrorIs(t, err, ErrIndexLocked)
+
+	assert.NoError(t, idx.Close())
+	idx, err = OpenIndex(path)
+	assert.NoError(t, err)
+	assert.NoError(t, idx.Close())
+}
+
+func TestIndexYearUsesPublicationDate(t *testing.T) {
+	ctx := context.Background()
+	idx, err := OpenIndex(filepath.Join(t.TempDir(), "books.idx"))
+	assert.NoError(t, err)
+
+	book := newTestBook("Structure and Interpretation of Computer Programs", "Harold Abelson", 2024)
+	published := models.NewDate(1985, time.January, 1)
+	book.PublicationDate = &published
+	assert.NoError(t, idx.Index(ctx, book))
+
+	result, err := idx.Search(ctx, Query{Year: 1985})
+	assert.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{book.ID}, result.IDs)
+}
+
+func TestIndexBatch(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "books.idx")
+
+	idx, err := OpenIndex(path)
+	assert.NoError(t, err)
+	first := newTestBook("Batched Book One", "Writer", 2019)
+	second := newTestBook("Batched Book Two", "Writer", 2020)
+	third := newTestBook("Batched Book Three", "Writer", 2021)
+	assert.NoError(t, idx.Index(ctx, first, second, third))
+
+	// The whole batch was written to disk
+	idx = reopenIndex(t, idx, path)
+	result, err := idx.Search(ctx, Query{Text: "batched"})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []uuid.UUID{first.ID, second.ID, third.ID}, result.IDs)
+
+	// Unknown IDs in a batch are skipped
+	assert.NoError(t, idx.Remove(ctx, first.ID, uuid.New(), third.ID))
+	idx = reopenIndex(t, idx, path)
+	result, err = idx.Search(ctx, Query{Text: "batched"})
+	assert.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{second.ID}, result.IDs)
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package search
+
+import "os"
+
+// lockFile is a no-op where flock isn't available: the index isn't guarded
+// against a second process there
+func lockFile(file *os.File) error {
+	return nil
+}
@@ -0,0 +1,20 @@
+//go:build unix
+
+package search
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// lockFile takes an exclusive lock on file, or returns ErrIndexLocked while
+// another open file holds it. The lock goes away with the file, even when the
+// process dies.
+func lockFile(file *os.File) error {
+	err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return ErrIndexLocked
+	}
+	return err
+}
@@ -0,0 +1,91 @@
+package search
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"gorm.io/gorm"
+)
+
+const documentExpr = "to_tsvector('simple', coalesce(title, '') || ' ' || coalesce(author, ''))"
+
+// bookYearColumn matches BookYear
+const bookYearColumn = "coalesce(publication_date, created_at)"
+
+// PostgresSearcher queries the books table directly with full text search,
+// so it has no index of its own to maintain.
+type PostgresSearcher struct {
+	DB *gorm.DB
+}
+
+func NewPostgresSearcher(db *gorm.DB) *PostgresSearcher {
+	return &PostgresSearcher{DB: db}
+}
+
+func (s *PostgresSearcher) Index(ctx context.Context, books ...models.Book) error {
+	return nil
+}
+
+func (s *PostgresSearcher) Remove(ctx context.Context, ids ...uuid.UUID) error {
+	return nil
+}
+
+func (s *PostgresSearcher) Reset(ctx context.Context) error {
+	return nil
+}
+
+func (s *PostgresSearcher) Search(ctx context.Context, query Query) (Result, error) {
+	result := Result{Facets: map[string][]FacetValue{}}
+
+	base := func() *gorm.DB {
+		db := s.DB.WithContext(ctx).Model(&models.Book{})
+		if query.Text != "" {
+			db = db.Where(documentExpr+" @@ plainto_tsquery('simple', ?)", query.Text)
+		}
+		if query.Author != "" {
+			db = db.Where("author = ?", query.Author)
+		}
+		if query.Year != 0 {
+			db = db.Where("EXTRACT(YEAR FROM "+bookYearColumn+") = ?", query.Year)
+		}
+		return db
+	}
+
+	if err := base().Count(&result.Total).Error; err != nil {
+		return result, err
+	}
+
+	hits := base()
+	if query.Text != "" {
+		hits = hits.Order(gorm.Expr("ts_rank("+documentExpr+", plainto_tsquery('simple', ?)) DESC", query.Text))
+	}
+	if err := hits.Order("title").Offset(query.Offset).Limit(query.Limit).Pluck("id", &result.IDs).Error; err != nil {
+		return result, err
+	}
+
+	var authors []FacetValue
+	if err := base().Select("author AS value, count(*) AS count").
+		Group("author").Order("count DESC, author").Limit(maxFacetValues).
+		Scan(&authors).Error; err != nil {
+		return result, err
+	}
+	result.Facets[FacetAuthor] = authors
+
+	var years []struct {
+		Year  int
+		Count int
+	}
+	if err := base().Select("EXTRACT(YEAR FROM " + bookYearColumn + ")::int AS year, count(*) AS count").
+		Group("year").Order("year DESC").Limit(maxFacetValues).
+		Scan(&years).Error; err != nil {
+		return result, err
+	}
+	result.Facets[FacetYear] = make([]FacetValue, 0, len(years))
+	for _, y := range years {
+		result.Facets[FacetYear] = append(result.Facets[FacetYear], FacetValue{Value: strconv.Itoa(y.Year), Count: y.Count})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,102 @@
+package search
+
+import (
+	"context"
+	"log"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/kev1nandreas/go-rest-api-template/env"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"gorm.io/gorm"
+)
+
+const (
+	BackendPostgres = "postgres"
+	BackendEmbedded = "embedded"
+
+	// FacetAuthor and FacetYear are the keys used in Result.Facets
+	FacetAuthor = "author"
+	FacetYear   = "year"
+
+	maxFacetValues = 20
+)
+
+type Query struct {
+	Text   string
+	Author string
+	Year   int
+	Offset int
+	Limit  int
+}
+
+type FacetValue struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+type Result struct {
+	Total  int64                   `json:"total"`
+	IDs    []uuid.UUID             `json:"ids"`
+	Facets map[string][]FacetValue `json:"facets"`
+}
+
+// Searcher is implemented by every search backend. Index and Remove are
+// called by the book handlers after each write so that backends keeping
+// their own index stay in sync with the database. They take a batch, so a
+// backend that persists its index writes it once per call, not once per book.
+type Searcher interface {
+	Index(ctx context.Context, books ...models.Book) error
+	Remove(ctx context.Context, ids ...uuid.UUID) error
+	Search(ctx context.Context, query Query) (Result, error)
+	Reset(ctx context.Context) error
+}
+
+// NewSearcher builds the backend selected by SEARCH_BACKEND
+func NewSearcher(db *gorm.DB) (Searcher, error) {
+	backend := env.GetEnvString("SEARCH_BACKEND", BackendPostgres)
+	indexPath := env.GetEnvString("SEARCH_INDEX_PATH", "data/search/books.idx")
+
+	switch backend {
+	case BackendEmbedded:
+		log.Printf("Using embedded search index at %s", indexPath)
+		return OpenIndex(indexPath)
+	default:
+		return NewPostgresSearcher(db), nil
+	}
+}
+
+// Reindex drops everything from the searcher and rebuilds it from the books table
+func Reindex(ctx context.Context, searcher Searcher, db *gorm.DB, batchSize int) (int, error) {
+	if err := searcher.Reset(ctx); err != nil {
+		return 0, err
+	}
+
+	var books []models.Book
+	total := 0
+	result := db.WithContext(ctx).FindInBatches(&books, batchSize, func(tx *gorm.DB, batch int) error {
+		if err := searcher.Index(ctx, books...); err != nil {
+			return err
+		}
+		total += len(books)
+		return nil
+	})
+
+	return total, result.Error
+}
+
+// BookYear is the value used for the year facet: the publication year, or
+// the year the book was added when it isn't known
+func BookYear(book models.Book) int {
+	if year := book.PublicationYear(); year != 0 {
+		return year
+	}
+	return book.CreatedAt.Year()
+}
+
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
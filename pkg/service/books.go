@@ -0,0 +1,417 @@
+// Package service holds the use cases of the API on top of the stores: the
+// caching, search indexing and invalidation that go with reading and writing
+// the models. Handlers translate HTTP to and from its calls.
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/cache"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/search"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/store"
+)
+
+// ErrInvalidSort is returned for a book list sort order the store doesn't know
+var ErrInvalidSort = errors.New("sort must be rating")
+
+// BooksTag tags every cached page of books
+const BooksTag = "books"
+
+// WarmPageSize is the page size clients get by default
+const WarmPageSize = 10
+
+// bookSchema versions the cache keys of books, so a release that changes
+// models.Book doesn't read the entries of the previous one
+var bookSchema = cache.SchemaVersion(models.Book{})
+
+// PageKey is the key a page of books is cached under, before it is tagged
+// with BooksTag
+func PageKey(query store.BookQuery) string {
+	key := "books_" + bookSchema + "_offset_" + strconv.Itoa(query.Offset) + "_limit_" + strconv.Itoa(query.Limit)
+	if query.Sort != "" {
+		key += "_sort_" + query.Sort
+	}
+	return key
+}
+
+// BookKey is the key a book is cached under, before it is tagged with its
+// BookTag
+func BookKey(id uuid.UUID) string {
+	return "book_" + bookSchema + "_" + id.String()
+}
+
+// BookTag tags the cached entry of one book. Writes retire it rather than
+// deleting the entry, so a read that raced the write can't put the old book
+// back under the current key.
+func BookTag(id uuid.UUID) string {
+	return "book_" + id.String()
+}
+
+// BookPage is a page of books cached together with its validators, so
+// conditional requests can be answered from the cache alone. LastModified is
+// when any book last changed, not just the books of the page, so deletions
+// and books shifting into the page move it too.
+type BookPage struct {
+	ETag         string        `json:"etag"`
+	LastModified time.Time     `json:"last_modified"`
+	Books        []models.Book `json:"books"`
+}
+
+func NewBookPage(books []models.Book, lastModified time.Time) (BookPage, error) {
+	page := BookPage{Books: books, LastModified: lastModified}
+
+	serialized, err := json.Marshal(books)
+	if err != nil {
+		return page, err
+	}
+	sum := sha256.Sum256(serialized)
+	// The response envelope differs between cache hits and misses, so the
+	// validator is weak: it identifies the data, not the exact bytes
+	page.ETag = `W/"` + hex.EncodeToString(sum[:16]) + `"`
+
+	return page, nil
+}
+
+// BookService reads books through the cache and keeps the cache and the
+// search index in sync with the writes
+type BookService struct {
+	stores   store.UnitOfWork
+	cache    cache.Cache
+	pages    *cache.Loader
+	books    *cache.Loader
+	searcher search.Searcher
+}
+
+// NewBookService reads and writes books in stores. The cache policies are
+// the books namespace for pages and the book namespace for single books.
+// searcher may be nil when search is served from the database.
+func NewBookService(stores store.UnitOfWork, c cache.Cache, searcher search.Searcher) *BookService {
+	return &BookService{
+		stores:   stores,
+		cache:    c,
+		pages:    cache.NewLoader(c, cache.ConfiguredPolicy("books", cache.Policy{TTL: time.Minute})),
+		books:    cache.NewLoader(c, cache.ConfiguredPolicy("book", cache.Policy{TTL: 5 * time.Minute, NotFoundTTL: 30 * time.Second})),
+		searcher: searcher,
+	}
+}
+
+// bookStore is the book store for reads, outside of any transaction
+func (s *BookService) bookStore() store.BookStore {
+	return s.stores.Stores().Books
+}
+
+// loadPage loads a page of books and serializes it with its validators
+func (s *BookService) loadPage(query store.BookQuery) cache.LoadFunc {
+	return func(ctx context.Context) ([]byte, error) {
+		// Read the time first, so a write landing between the two reads
+		// shows in the books and makes the page look older, never newer
+		lastModified, err := s.bookStore().LastModified(ctx)
+		if err != nil {
+			return nil, err
+		}
+		books, err := s.bookStore().ListBooks(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		page, err := NewBookPage(books, lastModified)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(page)
+	}
+}
+
+// ListBooks returns a page of books and whether it came from the cache.
+// Concurrent misses of a page share one query, and stale pages may be served
+// while one refreshes. Without a cache key, e.g. while the cache is down, the
+// page is read straight from the store.
+func (s *BookService) ListBooks(ctx context.Context, query store.BookQuery) (BookPage, bool, error) {
+	var page BookPage
+	if !store.ValidBookSort(query.Sort) {
+		return page, false, ErrInvalidSort
+	}
+
+	var data []byte
+	cached := false
+	load := s.loadPage(query)
+	key, err := s.cache.TagKey(ctx, PageKey(query), BooksTag)
+	if err != nil {
+		data, err = load(ctx)
+	} else {
+		data, cached, err = s.pages.Fetch(ctx, key, load)
+	}
+	if err != nil {
+		return page, false, err
+	}
+	err = json.Unmarshal(data, &page)
+	return page, cached, err
+}
+
+// WarmPages caches the first page of books in every sort order, so the
+// busiest page is served from the cache even right after it expires
+func (s *BookService) WarmPages(ctx context.Context) (int, error) {
+	for _, sort := range store.BookSorts() {
+		query := store.BookQuery{Limit: WarmPageSize, Sort: sort}
+		key, err := s.cache.TagKey(ctx, PageKey(query), BooksTag)
+		if err != nil {
+			return 0, err
+		}
+		if err := s.pages.Refresh(ctx, key, s.loadPage(query)); err != nil {
+			return 0, err
+		}
+	}
+	return len(store.BookSorts()), nil
+}
+
+// GetBook returns a book and whether it came from the cache. Missing books
+// are cached too. IDs that aren't UUIDs can't name a book and, like every
+// book while the cache can't tag keys, go straight to the store.
+func (s *BookService) GetBook(ctx context.Context, id string) (models.Book, bool, error) {
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		book, err := s.bookStore().GetBook(ctx, id)
+		return book, false, err
+	}
+
+	var data []byte
+	cached := false
+	load := func(ctx context.Context) ([]byte, error) {
+		book, err := s.bookStore().GetBook(ctx, id)
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, cache.ErrNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(book)
+	}
+	key, err := s.cache.TagKey(ctx, BookKey(parsed), BookTag(parsed))
+	if err != nil {
+		data, err = load(ctx)
+	} else {
+		data, cached, err = s.books.Fetch(ctx, key, load)
+	}
+	if errors.Is(err, cache.ErrNotFound) {
+		return models.Book{}, cached, store.ErrNotFound
+	}
+	if err != nil {
+		return models.Book{}, false, err
+	}
+
+	var book models.Book
+	err = json.Unmarshal(data, &book)
+	return book, cached, err
+}
+
+// GetBookByISBN returns the book with isbn from the store
+func (s *BookService) GetBookByISBN(ctx context.Context, isbn models.ISBN) (models.Book, error) {
+	return s.bookStore().GetBookByISBN(ctx, isbn)
+}
+
+// LoadBook returns a book from the store, bypassing the cache, for a write
+// that needs its current version or a read of the rows that hang off it
+func (s *BookService) LoadBook(ctx context.Context, id string) (models.Book, error) {
+	return s.bookStore().GetBook(ctx, id)
+}
+
+// CreateBook adds a book. Like the other writes, it can run inside a
+// transaction of the caller, and the cache and the search index only learn
+// about it once that transaction commits.
+func (s *BookService) CreateBook(ctx context.Context, input models.CreateBook) (models.Book, error) {
+	book := input.ToBook()
+	err := s.AddBook(ctx, &book)
+	return book, err
+}
+
+// AddBook adds book as built by the caller, e.g. from an imported record
+func (s *BookService) AddBook(ctx context.Context, book *models.Book) error {
+	return s.stores.WithTx(ctx, func(ctx context.Context, tx store.Stores) error {
+		if err := tx.Books.CreateBook(ctx, book); err != nil {
+			return err
+		}
+		s.afterWrite(ctx, *book)
+		return nil
+	})
+}
+
+// UpdateBook replaces the writable fields of book, as read by LoadBook, with
+// input. It fails with store.ErrStale if the book changed since.
+func (s *BookService) UpdateBook(ctx context.Context, book models.Book, input models.UpdateBook) (models.Book, error) {
+	input.ApplyTo(&book)
+	err := s.SaveBook(ctx, &book)
+	return book, err
+}
+
+// SaveBook writes the writable fields of book as changed by the caller, e.g.
+// by a patch, and bumps its version. It fails with store.ErrStale if the book
+// changed since it was read.
+func (s *BookService) SaveBook(ctx context.Context, book *models.Book) error {
+	return s.stores.WithTx(ctx, func(ctx context.Context, tx store.Stores) error {
+		if err := tx.Books.UpdateBook(ctx, book); err != nil {
+			return err
+		}
+		s.afterWrite(ctx, *book)
+		return nil
+	})
+}
+
+// SaveCover replaces the cover of book. It fails with store.ErrStale if the
+// book changed since it was read.
+func (s *BookService) SaveCover(ctx context.Context, book *models.Book, cover models.Cover) error {
+	return s.stores.WithTx(ctx, func(ctx context.Context, tx store.Stores) error {
+		if err := tx.Books.SaveCover(ctx, book, cover); err != nil {
+			return err
+		}
+		s.afterWrite(ctx, *book)
+		return nil
+	})
+}
+
+// SaveAvailability writes the inventory of book, which the caller locked
+// with store.BookStore.LockBook or LockAnyBook
+func (s *BookService) SaveAvailability(ctx context.Context, book *models.Book) error {
+	return s.stores.WithTx(ctx, func(ctx context.Context, tx store.Stores) error {
+		if err := tx.Books.SaveAvailability(ctx, book); err != nil {
+			return err
+		}
+		s.afterChange(ctx, book.ID)
+		return nil
+	})
+}
+
+// RefreshRating recomputes the rating of book, which the caller locked with
+// store.BookStore.LockBook, after a write to its reviews
+func (s *BookService) RefreshRating(ctx context.Context, book *models.Book) error {
+	return s.stores.WithTx(ctx, func(ctx context.Context, tx store.Stores) error {
+		if err := tx.Books.RefreshRating(ctx, book); err != nil {
+			return err
+		}
+		s.afterChange(ctx, book.ID)
+		return nil
+	})
+}
+
+// DeleteBook moves book, as read by LoadBook, to the trash. It fails with
+// store.ErrStale if the book changed since.
+func (s *BookService) DeleteBook(ctx context.Context, book models.Book) error {
+	return s.stores.WithTx(ctx, func(ctx context.Context, tx store.Stores) error {
+		if err := tx.Books.DeleteBook(ctx, book); err != nil {
+			return err
+		}
+		s.afterDelete(ctx, book)
+		return nil
+	})
+}
+
+// RestoreBook moves a book out of the trash. It fails with store.ErrNotFound
+// if the book isn't in the trash and store.ErrConflict if another book took
+// its ISBN meanwhile.
+func (s *BookService) RestoreBook(ctx context.Context, id string) (models.Book, error) {
+	var book models.Book
+	err := s.stores.WithTx(ctx, func(ctx context.Context, tx store.Stores) error {
+		var err error
+		if book, err = tx.Books.RestoreBook(ctx, id); err != nil {
+			return err
+		}
+		s.afterWrite(ctx, book)
+		return nil
+	})
+	return book, err
+}
+
+// PurgeBook deletes a book in the trash for good and returns the keys of the
+// blobs it leaves behind. Trashed books are neither cached nor indexed, so
+// there is nothing to evict.
+func (s *BookService) PurgeBook(ctx context.Context, id string) ([]string, error) {
+	var keys []string
+	err := s.stores.WithTx(ctx, func(ctx context.Context, tx store.Stores) error {
+		var err error
+		keys, err = tx.Books.PurgeBook(ctx, id)
+		return err
+	})
+	return keys, err
+}
+
+// Keys the after commit work of the writes is batched under, so a
+// transaction that writes many books indexes and evicts them in one go
+const (
+	indexBatch      = "books:index"
+	unindexBatch    = "books:unindex"
+	invalidateBatch = "books:invalidate"
+)
+
+// afterWrite indexes book and evicts it from the cache once the transaction
+// of ctx commits
+func (s *BookService) afterWrite(ctx context.Context, book models.Book) {
+	store.AfterCommitBatch(ctx, indexBatch, book, func(ctx context.Context, books []models.Book) {
+		s.Index(ctx, books...)
+	})
+	s.afterChange(ctx, book.ID)
+}
+
+// afterDelete removes book from the search index and the cache once the
+// transaction of ctx commits
+func (s *BookService) afterDelete(ctx context.Context, book models.Book) {
+	store.AfterCommitBatch(ctx, unindexBatch, book, func(ctx context.Context, books []models.Book) {
+		s.Unindex(ctx, books...)
+	})
+	s.afterChange(ctx, book.ID)
+}
+
+// afterChange evicts a book from the cache once the transaction of ctx
+// commits, for writes to columns search doesn't look at, like availability
+func (s *BookService) afterChange(ctx context.Context, id uuid.UUID) {
+	store.AfterCommitBatch(ctx, invalidateBatch, id, func(ctx context.Context, ids []uuid.UUID) {
+		s.Invalidate(ctx, ids...)
+	})
+}
+
+// Invalidate retires every cached page of books and the cached books of
+// ids. Every write that changes a book calls it once the write has
+// committed, with the IDs of the books it changed. It runs even if ctx was
+// cancelled meanwhile, since the write went through. Failures are logged:
+// the cache entries expire on their own.
+func (s *BookService) Invalidate(ctx context.Context, ids ...uuid.UUID) {
+	tags := []string{BooksTag}
+	for _, id := range ids {
+		tags = append(tags, BookTag(id))
+	}
+	if err := s.cache.InvalidateTags(context.WithoutCancel(ctx), tags...); err != nil {
+		log.Printf("Failed to invalidate the cached books: %v", err)
+	}
+}
+
+// Index keeps the search backend in sync after a write. Search is a
+// secondary concern, so failures are logged rather than failing the write.
+// Like Invalidate, it outlives the cancellation of ctx. Writes of many books
+// index them in one call.
+func (s *BookService) Index(ctx context.Context, books ...models.Book) {
+	if s.searcher == nil || len(books) == 0 {
+		return
+	}
+	if err := s.searcher.Index(context.WithoutCancel(ctx), books...); err != nil {
+		log.Printf("Failed to index %d books: %v", len(books), err)
+	}
+}
+
+func (s *BookService) Unindex(ctx context.Context, books ...models.Book) {
+	if s.searcher == nil || len(books) == 0 {
+		return
+	}
+	ids := make([]uuid.UUID, len(books))
+	for i, book := range books {
+		ids[i] = book.ID
+	}
+	if err := s.searcher.Remove(context.WithoutCancel(ctx), ids...); err != nil {
+		log.Printf("Failed to remove %d books from search index: %v", len(books), err)
+	}
+}
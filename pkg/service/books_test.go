@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/cache"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/store"
+	"github.com/stretchr/testify/assert"
+)
+
+// newMockUnitOfWork returns a unit of work over stores whose transactions
+// run fn on stores as is, so after commit hooks run right away
+func newMockUnitOfWork(ctrl *gomock.Controller, stores store.Stores) *store.MockUnitOfWork {
+	uow := store.NewMockUnitOfWork(ctrl)
+	uow.EXPECT().Stores().Return(stores).AnyTimes()
+	uow.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, fn func(context.Context, store.Stores) error) error {
+		return fn(ctx, stores)
+	}).AnyTimes()
+	return uow
+}
+
+func TestBookServiceGetBook(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	books := store.NewMockBookStore(ctrl)
+	s := NewBookService(newMockUnitOfWork(ctrl, store.Stores{Books: books}), cache.NewMemoryCache(100), nil)
+
+	book := models.Book{ID: uuid.New(), Title: "Dune", Author: "Frank Herbert"}
+	missing := uuid.NewString()
+	books.EXPECT().GetBook(gomock.Any(), book.ID.String()).Return(book, nil).Times(1)
+	books.EXPECT().GetBook(gomock.Any(), missing).Return(models.Book{}, store.ErrNotFound).Times(1)
+
+	for _, wantCached := range []bool{false, true} {
+		found, cached, err := s.GetBook(ctx, book.ID.String())
+		assert.NoError(t, err)
+		assert.Equal(t, wantCached, cached)
+		assert.Equal(t, book.Title, found.Title)
+	}
+
+	// Missing books are cached and reported with the store's error
+	for i := 0; i < 2; i++ {
+		_, _, err := s.GetBook(ctx, missing)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	}
+}
+
+func TestBookServiceReadRacingWrite(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	books := store.NewMockBookStore(ctrl)
+	s := NewBookService(newMockUnitOfWork(ctrl, store.Stores{Books: books}), cache.NewMemoryCache(100), nil)
+
+	// A write commits while a miss is loading the book it replaced
+	old := models.Book{ID: uuid.New(), Title: "Dune", Author: "Frank Herbert", Version: 1}
+	updated := old
+	updated.Title, updated.Version = "Dune Messiah", 2
+	books.EXPECT().GetBook(gomock.Any(), old.ID.String()).DoAndReturn(func(ctx context.Context, id string) (models.Book, error) {
+		s.Invalidate(ctx, old.ID)
+		return old, nil
+	})
+	found, _, err := s.GetBook(ctx, old.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, "Dune", found.Title)
+
+	// The old book was cached under a retired key, the next read loads again
+	books.EXPECT().GetBook(gomock.Any(), old.ID.String()).Return(updated, nil)
+	found, cached, err := s.GetBook(ctx, old.ID.String())
+	assert.NoError(t, err)
+	assert.False(t, cached)
+	assert.Equal(t, "Dune Messiah", found.Title)
+}
+
+func TestBookServiceWritesInvalidate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	appCache := cache.NewMemoryCache(100)
+	books := store.NewMockBookStore(ctrl)
+	s := NewBookService(newMockUnitOfWork(ctrl, store.Stores{Books: books}), appCache, nil)
+
+	book := models.Book{ID: uuid.New(), Title: "Dune", Author: "Frank Herbert", Version: 1}
+	written := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	books.EXPECT().GetBook(gomock.Any(), book.ID.String()).Return(book, nil).Times(1)
+	books.EXPECT().LastModified(gomock.Any()).Return(written, nil).Times(1)
+	books.EXPECT().ListBooks(gomock.Any(), store.BookQuery{Limit: WarmPageSize}).Return([]models.Book{book}, nil).Times(1)
+	_, _, err := s.GetBook(ctx, book.ID.String())
+	assert.NoError(t, err)
+	_, _, err = s.ListBooks(ctx, store.BookQuery{Limit: WarmPageSize})
+	assert.NoError(t, err)
+
+	// The request is gone by the time the write commits, the cache is
+	// invalidated anyway
+	cancelled, cancel := context.WithCancel(ctx)
+	books.EXPECT().UpdateBook(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, book *models.Book) error {
+		cancel()
+		book.Version++
+		return nil
+	})
+	updated, err := s.UpdateBook(cancelled, book, models.UpdateBook{Title: "Dune Messiah", Author: "Frank Herbert"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Dune Messiah", updated.Title)
+
+	books.EXPECT().GetBook(gomock.Any(), book.ID.String()).Return(updated, nil).Times(1)
+	books.EXPECT().LastModified(gomock.Any()).Return(written.Add(time.Minute), nil).Times(1)
+	books.EXPECT().ListBooks(gomock.Any(), store.BookQuery{Limit: WarmPageSize}).Return([]models.Book{updated}, nil).Times(1)
+	found, cached, err := s.GetBook(ctx, book.ID.String())
+	assert.NoError(t, err)
+	assert.False(t, cached)
+	assert.Equal(t, "Dune Messiah", found.Title)
+	page, cached, err := s.ListBooks(ctx, store.BookQuery{Limit: WarmPageSize})
+	assert.NoError(t, err)
+	assert.False(t, cached)
+	assert.Equal(t, "Dune Messiah", page.Books[0].Title)
+	assert.Equal(t, written.Add(time.Minute), page.LastModified)
+}
+
+func TestBookServiceListBooks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	books := store.NewMockBookStore(ctrl)
+	s := NewBookService(newMockUnitOfWork(ctrl, store.Stores{Books: books}), cache.NewMemoryCache(100), nil)
+
+	_, _, err := s.ListBooks(ctx, store.BookQuery{Limit: 10, Sort: "title"})
+	assert.ErrorIs(t, err, ErrInvalidSort)
+
+	books.EXPECT().LastModified(gomock.Any()).Return(time.Time{}, errors.New("database is down"))
+	_, _, err = s.ListBooks(ctx, store.BookQuery{Limit: 10})
+	assert.EqualError(t, err, "database is down")
+
+	books.EXPECT().LastModified(gomock.Any()).Return(time.Time{}, nil)
+	books.EXPECT().ListBooks(gomock.Any(), gomock.Any()).Return(nil, errors.New("database is down"))
+	_, _, err = s.ListBooks(ctx, store.BookQuery{Limit: 10})
+	assert.EqualError(t, err, "database is down")
+
+	// Every sort order is warmed
+	books.EXPECT().LastModified(gomock.Any()).Return(time.Time{}, nil).Times(2)
+	books.EXPECT().ListBooks(gomock.Any(), store.BookQuery{Limit: WarmPageSize}).Return(nil, nil)
+	books.EXPECT().ListBooks(gomock.Any(), store.BookQuery{Limit: WarmPageSize, Sort: store.BookSortRating}).Return(nil, nil)
+	warmed, err := s.WarmPages(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, len(store.BookSorts()), warmed)
+	_, cached, err := s.ListBooks(ctx, store.BookQuery{Limit: WarmPageSize, Sort: store.BookSortRating})
+	assert.NoError(t, err)
+	assert.True(t, cached)
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/kev1nandreas/go-rest-api-template/pkg/auth"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/store"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrInvalidCredentials is returned by Login for an unknown username or a
+// wrong password alike, so callers can't tell which usernames exist
+var ErrInvalidCredentials = errors.New("invalid username or password")
+
+// UserService registers users and signs them in
+type UserService struct {
+	stores store.UnitOfWork
+}
+
+func NewUserService(stores store.UnitOfWork) *UserService {
+	return &UserService{stores: stores}
+}
+
+// Register creates a user with the user role. A taken username is
+// store.ErrConflict.
+func (s *UserService) Register(ctx context.Context, input models.LoginUser) (models.User, error) {
+	hashedPassword, err := auth.HashPassword(input.Password)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	user := models.User{Username: input.Username, Password: hashedPassword, Role: models.RoleUser}
+	err = s.stores.WithTx(ctx, func(ctx context.Context, tx store.Stores) error {
+		return tx.Users.CreateUser(ctx, &user)
+	})
+	return user, err
+}
+
+// SetRole changes the role of a user. Tokens already issued keep the old
+// role until they expire. An unknown user is store.ErrNotFound.
+func (s *UserService) SetRole(ctx context.Context, id string, role string) (models.User, error) {
+	var user models.User
+	err := s.stores.WithTx(ctx, func(ctx context.Context, tx store.Stores) error {
+		var err error
+		user, err = tx.Users.SetUserRole(ctx, id, role)
+		return err
+	})
+	return user, err
+}
+
+// Login checks the credentials and returns a JWT for the user
+func (s *UserService) Login(ctx context.Context, input models.LoginUser) (string, error) {
+	user, err := s.stores.Stores().Users.GetUserByUsername(ctx, input.Username)
+	if errors.Is(err, store.ErrNotFound) {
+		return "", ErrInvalidCredentials
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)); err != nil {
+		return "", ErrInvalidCredentials
+	}
+
+	return auth.GenerateToken(user.Username, user.Role)
+}
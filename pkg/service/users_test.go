@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/auth"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserServiceLogin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	users := store.NewMockUserStore(ctrl)
+	s := NewUserService(newMockUnitOfWork(ctrl, store.Stores{Users: users}))
+
+	hashed, err := auth.HashPassword("secret")
+	assert.NoError(t, err)
+	users.EXPECT().GetUserByUsername(gomock.Any(), "reader").Return(models.User{Username: "reader", Password: hashed, Role: models.RoleUser}, nil).Times(2)
+	users.EXPECT().GetUserByUsername(gomock.Any(), "nobody").Return(models.User{}, store.ErrNotFound)
+
+	token, err := s.Login(ctx, models.LoginUser{Username: "reader", Password: "secret"})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+
+	// A wrong password and an unknown user can't be told apart
+	_, err = s.Login(ctx, models.LoginUser{Username: "reader", Password: "wrong"})
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = s.Login(ctx, models.LoginUser{Username: "nobody", Password: "secret"})
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestUserServiceRegister(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	users := store.NewMockUserStore(ctrl)
+	s := NewUserService(newMockUnitOfWork(ctrl, store.Stores{Users: users}))
+
+	users.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Return(nil)
+	user, err := s.Register(ctx, models.LoginUser{Username: "reader", Password: "secret"})
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleUser, user.Role)
+	assert.NotEqual(t, "secret", user.Password)
+
+	users.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Return(store.ErrConflict)
+	_, err = s.Register(ctx, models.LoginUser{Username: "reader", Password: "secret"})
+	assert.ErrorIs(t, err, store.ErrConflict)
+}
+
+func TestUserServiceSetRole(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	users := store.NewMockUserStore(ctrl)
+	s := NewUserService(newMockUnitOfWork(ctrl, store.Stores{Users: users}))
+
+	users.EXPECT().SetUserRole(gomock.Any(), "1", models.RoleModerator).Return(models.User{Username: "reader", Role: models.RoleModerator}, nil)
+	user, err := s.SetRole(ctx, "1", models.RoleModerator)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleModerator, user.Role)
+
+	users.EXPECT().SetUserRole(gomock.Any(), "2", models.RoleModerator).Return(models.User{}, store.ErrNotFound)
+	_, err = s.SetRole(ctx, "2", models.RoleModerator)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+}
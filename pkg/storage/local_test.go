@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStore(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	store := NewLocalStore(root, "/api/v1/blobs/")
+
+	assert.NoError(t, store.Put(ctx, "covers/1/a/original.png", strings.NewReader("png"), 3, "image/png"))
+
+	body, info, err := store.Get(ctx, "covers/1/a/original.png")
+	assert.NoError(t, err)
+	data, _ := io.ReadAll(body)
+	body.Close()
+	assert.Equal(t, "png", string(data))
+	assert.Equal(t, int64(3), info.Size)
+	assert.Equal(t, "image/png", info.ContentType)
+	assert.Equal(t, "/api/v1/blobs/covers/1/a/original.png", store.URL("covers/1/a/original.png"))
+
+	// Deleting removes the directories left empty, and is idempotent
+	assert.NoError(t, store.Delete(ctx, "covers/1/a/original.png"))
+	assert.NoError(t, store.Delete(ctx, "covers/1/a/original.png"))
+	_, _, err = store.Get(ctx, "covers/1/a/original.png")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = os.Stat(filepath.Join(root, "covers"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(root)
+	assert.NoError(t, err)
+}
+
+func TestLocalStoreRejectsEscapingKeys(t *testing.T) {
+	ctx := context.Background()
+	store := NewLocalStore(t.TempDir(), "")
+
+	for _, key := range []string{"", "/etc/passwd", "../secret", "covers/../../secret", "covers//a", "covers/"} {
+		assert.ErrorIs(t, store.Put(ctx, key, strings.NewReader("x"), 1, ""), ErrInvalidKey, key)
+		_, _, err := store.Get(ctx, key)
+		assert.ErrorIs(t, err, ErrInvalidKey, key)
+	}
+}
@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadSeeker(t *testing.T) {
+	ctx := context.Background()
+	store := NewLocalStore(t.TempDir(), "")
+	assert.NoError(t, store.Put(ctx, "ebooks/1/book.pdf", strings.NewReader("0123456789"), 10, ""))
+
+	reader := NewReadSeeker(ctx, store, "ebooks/1/book.pdf", 10)
+	defer reader.Close()
+
+	size, err := reader.Seek(0, io.SeekEnd)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), size)
+
+	_, err = reader.Seek(6, io.SeekStart)
+	assert.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, "6789", string(data))
+
+	_, err = reader.Seek(-8, io.SeekCurrent)
+	assert.NoError(t, err)
+	buf := make([]byte, 3)
+	_, err = io.ReadFull(reader, buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "234", string(buf))
+
+	_, err = reader.Seek(-1, io.SeekStart)
+	assert.Error(t, err)
+}
+
+func TestReadSeekerServesRanges(t *testing.T) {
+	ctx := context.Background()
+	store := NewLocalStore(t.TempDir(), "")
+	assert.NoError(t, store.Put(ctx, "ebooks/1/book.pdf", strings.NewReader("0123456789"), 10, ""))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Range", "bytes=2-4")
+	w := httptest.NewRecorder()
+	http.ServeContent(w, req, "book.pdf", time.Now(), NewReadSeeker(ctx, store, "ebooks/1/book.pdf", 10))
+
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, "234", w.Body.String())
+	assert.Equal(t, "bytes 2-4/10", w.Header().Get("Content-Range"))
+}
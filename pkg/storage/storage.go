@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/kev1nandreas/go-rest-api-template/env"
+)
+
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+var (
+	ErrNotFound   = errors.New("blob not found")
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// BlobInfo describes a stored blob
+type BlobInfo struct {
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+// BlobStore keeps binary objects such as cover images under slash separated
+// keys. GetRange reads length bytes from offset, or up to the end when length
+// is negative. Deleting a missing blob is not an error.
+type BlobStore interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, BlobInfo, error)
+	GetRange(ctx context.Context, key string, offset int64, length int64) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
+
+// NewBlobStore builds the backend selected by STORAGE_BACKEND
+func NewBlobStore() (BlobStore, error) {
+	backend := env.GetEnvString("STORAGE_BACKEND", BackendLocal)
+	publicURL := env.GetEnvString("STORAGE_PUBLIC_URL", "/api/v1/blobs")
+
+	switch backend {
+	case BackendS3:
+		config := S3Config{
+			Endpoint:  env.GetEnvString("S3_ENDPOINT", "https://s3.amazonaws.com"),
+			Region:    env.GetEnvString("S3_REGION", "us-east-1"),
+			Bucket:    env.GetEnvString("S3_BUCKET", ""),
+			AccessKey: env.GetEnvString("S3_ACCESS_KEY", ""),
+			SecretKey: env.GetEnvString("S3_SECRET_KEY", ""),
+			PathStyle: env.GetEnvBool("S3_PATH_STYLE", false),
+			PublicURL: publicURL,
+		}
+		log.Printf("Using S3 blob storage at %s, bucket %s", config.Endpoint, config.Bucket)
+		return NewS3Store(config, &http.Client{Timeout: 60 * time.Second})
+	default:
+		root := env.GetEnvString("STORAGE_LOCAL_PATH", "data/blobs")
+		log.Printf("Using local blob storage at %s", root)
+		return NewLocalStore(root, publicURL), nil
+	}
+}
+
+// DeleteAll deletes every key, carrying on past failures. It returns the
+// first error.
+func DeleteAll(ctx context.Context, store BlobStore, keys []string) error {
+	var first error
+	for _, key := range keys {
+		if err := store.Delete(ctx, key); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// validateKey rejects keys that are empty, absolute or not in clean form,
+// so a key can never climb out of the store
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key || strings.HasPrefix(key, "../") || key == ".." {
+		return ErrInvalidKey
+	}
+	return nil
+}
+
+// joinURL appends a key to a public base URL
+func joinURL(base string, key string) string {
+	return strings.TrimSuffix(base, "/") + "/" + key
+}
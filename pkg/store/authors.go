@@ -0,0 +1,109 @@
+package store
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"gorm.io/gorm"
+)
+
+// AuthorStore keeps the authors and the credits that link them to books
+type AuthorStore interface {
+	GetAuthor(ctx context.Context, id string) (models.Author, error)
+	// CreateAuthor adds author, or returns ErrConflict if another author has
+	// the same name key
+	CreateAuthor(ctx context.Context, author *models.Author) error
+	// UpdateAuthor writes the name and bio of author, or returns ErrConflict
+	// if another author has the same name key
+	UpdateAuthor(ctx context.Context, author models.Author) error
+	// DeleteAuthor deletes author, or returns ErrConflict while a book
+	// credits it
+	DeleteAuthor(ctx context.Context, author models.Author) error
+	// ListCredits returns the credits of a book with their authors, grouped
+	// by role in billing order
+	ListCredits(ctx context.Context, bookID uuid.UUID) ([]models.BookAuthor, error)
+	// ReplaceCredits replaces every credit of a book, or returns ErrNotFound
+	// if one of their authors doesn't exist
+	ReplaceCredits(ctx context.Context, bookID uuid.UUID, credits []models.BookAuthor) error
+}
+
+// GormAuthorStore keeps authors in the authors table and credits in the
+// book_authors table
+type GormAuthorStore struct {
+	DB *gorm.DB
+}
+
+func NewGormAuthorStore(db *gorm.DB) *GormAuthorStore {
+	return &GormAuthorStore{DB: db}
+}
+
+func (s *GormAuthorStore) GetAuthor(ctx context.Context, id string) (models.Author, error) {
+	var author models.Author
+	err := s.DB.WithContext(ctx).Where("id = ?", id).First(&author).Error
+	return author, translate(err)
+}
+
+func (s *GormAuthorStore) CreateAuthor(ctx context.Context, author *models.Author) error {
+	return translate(s.DB.WithContext(ctx).Create(author).Error)
+}
+
+func (s *GormAuthorStore) UpdateAuthor(ctx context.Context, author models.Author) error {
+	return translate(s.DB.WithContext(ctx).Model(&author).Updates(map[string]interface{}{
+		"name":     author.Name,
+		"name_key": author.NameKey,
+		"bio":      author.Bio,
+	}).Error)
+}
+
+func (s *GormAuthorStore) DeleteAuthor(ctx context.Context, author models.Author) error {
+	tx := s.DB.WithContext(ctx)
+
+	var credits int64
+	if err := tx.Model(&models.BookAuthor{}).Where("author_id = ?", author.ID).Count(&credits).Error; err != nil {
+		return translate(err)
+	}
+	if credits > 0 {
+		return ErrConflict
+	}
+	return translate(tx.Delete(&author).Error)
+}
+
+func (s *GormAuthorStore) ListCredits(ctx context.Context, bookID uuid.UUID) ([]models.BookAuthor, error) {
+	credits := []models.BookAuthor{}
+	err := s.DB.WithContext(ctx).Preload("Author").Where("book_id = ?", bookID).Order("role, position").Find(&credits).Error
+	return credits, translate(err)
+}
+
+func (s *GormAuthorStore) ReplaceCredits(ctx context.Context, bookID uuid.UUID, credits []models.BookAuthor) error {
+	tx := s.DB.WithContext(ctx)
+
+	seen := map[uuid.UUID]bool{}
+	ids := []uuid.UUID{}
+	for _, credit := range credits {
+		if !seen[credit.AuthorID] {
+			seen[credit.AuthorID] = true
+			ids = append(ids, credit.AuthorID)
+		}
+	}
+	if len(ids) > 0 {
+		var found int64
+		if err := tx.Model(&models.Author{}).Where("id IN ?", ids).Count(&found).Error; err != nil {
+			return translate(err)
+		}
+		if found != int64(len(ids)) {
+			return ErrNotFound
+		}
+	}
+
+	if err := tx.Where("book_id = ?", bookID).Delete(&models.BookAuthor{}).Error; err != nil {
+		return translate(err)
+	}
+	if len(credits) == 0 {
+		return nil
+	}
+	for i := range credits {
+		credits[i].BookID = bookID
+	}
+	return translate(tx.Create(&credits).Error)
+}
@@ -0,0 +1,123 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: pkg/store/authors.go
+
+// Package store is a generated GoMock package.
+package store
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+	models "github.com/kev1nandreas/go-rest-api-template/pkg/models"
+)
+
+// MockAuthorStore is a mock of AuthorStore interface.
+type MockAuthorStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuthorStoreMockRecorder
+}
+
+// MockAuthorStoreMockRecorder is the mock recorder for MockAuthorStore.
+type MockAuthorStoreMockRecorder struct {
+	mock *MockAuthorStore
+}
+
+// NewMockAuthorStore creates a new mock instance.
+func NewMockAuthorStore(ctrl *gomock.Controller) *MockAuthorStore {
+	mock := &MockAuthorStore{ctrl: ctrl}
+	mock.recorder = &MockAuthorStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuthorStore) EXPECT() *MockAuthorStoreMockRecorder {
+	return m.recorder
+}
+
+// CreateAuthor mocks base method.
+func (m *MockAuthorStore) CreateAuthor(ctx context.Context, author *models.Author) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAuthor", ctx, author)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateAuthor indicates an expected call of CreateAuthor.
+func (mr *MockAuthorStoreMockRecorder) CreateAuthor(ctx, author interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAuthor", reflect.TypeOf((*MockAuthorStore)(nil).CreateAuthor), ctx, author)
+}
+
+// DeleteAuthor mocks base method.
+func (m *MockAuthorStore) DeleteAuthor(ctx context.Context, author models.Author) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAuthor", ctx, author)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAuthor indicates an expected call of DeleteAuthor.
+func (mr *MockAuthorStoreMockRecorder) DeleteAuthor(ctx, author interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAuthor", reflect.TypeOf((*MockAuthorStore)(nil).DeleteAuthor), ctx, author)
+}
+
+// GetAuthor mocks base method.
+func (m *MockAuthorStore) GetAuthor(ctx context.Context, id string) (models.Author, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAuthor", ctx, id)
+	ret0, _ := ret[0].(models.Author)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAuthor indicates an expected call of GetAuthor.
+func (mr *MockAuthorStoreMockRecorder) GetAuthor(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAuthor", reflect.TypeOf((*MockAuthorStore)(nil).GetAuthor), ctx, id)
+}
+
+// ListCredits mocks base method.
+func (m *MockAuthorStore) ListCredits(ctx context.Context, bookID uuid.UUID) ([]models.BookAuthor, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListCredits", ctx, bookID)
+	ret0, _ := ret[0].([]models.BookAuthor)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListCredits indicates an expected call of ListCredits.
+func (mr *MockAuthorStoreMockRecorder) ListCredits(ctx, bookID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListCredits", reflect.TypeOf((*MockAuthorStore)(nil).ListCredits), ctx, bookID)
+}
+
+// ReplaceCredits mocks base method.
+func (m *MockAuthorStore) ReplaceCredits(ctx context.Context, bookID uuid.UUID, credits []models.BookAuthor) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplaceCredits", ctx, bookID, credits)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReplaceCredits indicates an expected call of ReplaceCredits.
+func (mr *MockAuthorStoreMockRecorder) ReplaceCredits(ctx, bookID, credits interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplaceCredits", reflect.TypeOf((*MockAuthorStore)(nil).ReplaceCredits), ctx, bookID, credits)
+}
+
+// UpdateAuthor mocks base method.
+func (m *MockAuthorStore) UpdateAuthor(ctx context.Context, author models.Author) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAuthor", ctx, author)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateAuthor indicates an expected call of UpdateAuthor.
+func (mr *MockAuthorStoreMockRecorder) UpdateAuthor(ctx, author interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAuthor", reflect.TypeOf((*MockAuthorStore)(nil).UpdateAuthor), ctx, author)
+}
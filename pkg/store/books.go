@@ -0,0 +1,305 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/kev1nandreas/go-rest-api-template/pkg/database"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Orders of book lists
+const (
+	BookSortDefault = ""
+	BookSortRating  = "rating"
+)
+
+// bookSortOrders maps the book sort orders to their ORDER BY
+var bookSortOrders = map[string]string{
+	BookSortDefault: "",
+	BookSortRating:  "rating_average DESC, review_count DESC, id",
+}
+
+// BookSorts returns the sort orders ListBooks accepts
+func BookSorts() []string {
+	return []string{BookSortDefault, BookSortRating}
+}
+
+// ValidBookSort reports whether ListBooks accepts sort
+func ValidBookSort(sort string) bool {
+	_, ok := bookSortOrders[sort]
+	return ok
+}
+
+// BookQuery selects a page of books
+type BookQuery struct {
+	Offset int
+	Limit  int
+	Sort   string
+}
+
+// BookStore keeps the books that aren't in the trash
+type BookStore interface {
+	ListBooks(ctx context.Context, query BookQuery) ([]models.Book, error)
+	// LastModified returns when the list of books last changed: the newest
+	// update or deletion of any book, in the trash or not. It is zero while
+	// there are no books.
+	LastModified(ctx context.Context) (time.Time, error)
+	GetBook(ctx context.Context, id string) (models.Book, error)
+	GetBookByISBN(ctx context.Context, isbn models.ISBN) (models.Book, error)
+	CreateBook(ctx context.Context, book *models.Book) error
+	// UpdateBook writes the updatable columns of book and bumps its version,
+	// unless the book changed since it was read, which is ErrStale
+	UpdateBook(ctx context.Context, book *models.Book) error
+	// DeleteBook moves book to the trash, unless it changed since it was
+	// read, which is ErrStale
+	DeleteBook(ctx context.Context, book models.Book) error
+
+	// LockBook reads a book and locks it for the rest of the transaction.
+	// Writes that derive book columns from other tables, like ratings and
+	// availability, take this lock so they serialize per book.
+	LockBook(ctx context.Context, id string) (models.Book, error)
+	// LockAnyBook is LockBook for books in the trash too, whose loans and
+	// reservations still run their course
+	LockAnyBook(ctx context.Context, id string) (models.Book, error)
+	LockBookByISBN(ctx context.Context, isbn models.ISBN) (models.Book, error)
+	// SaveCover replaces the cover of book and bumps its version, unless the
+	// book changed since it was read, which is ErrStale
+	SaveCover(ctx context.Context, book *models.Book, cover models.Cover) error
+	// SaveAvailability writes the inventory of a locked book, trashed or not,
+	// and bumps its version
+	SaveAvailability(ctx context.Context, book *models.Book) error
+	// RefreshRating recomputes the rating aggregates of a locked book from
+	// its approved reviews and bumps its version
+	RefreshRating(ctx context.Context, book *models.Book) error
+	// RestoreBook moves a book out of the trash. It returns ErrNotFound if
+	// the book isn't in the trash, and ErrConflict if another book took its
+	// ISBN meanwhile.
+	RestoreBook(ctx context.Context, id string) (models.Book, error)
+	// PurgeBook deletes a book in the trash for good, with its files, and
+	// returns the blob keys they leave behind. It returns ErrNotFound if the
+	// book isn't in the trash, and ErrConflict while a copy is on loan.
+	PurgeBook(ctx context.Context, id string) ([]string, error)
+}
+
+// GormBookStore keeps books in the books table
+type GormBookStore struct {
+	DB *gorm.DB
+}
+
+func NewGormBookStore(db *gorm.DB) *GormBookStore {
+	return &GormBookStore{DB: db}
+}
+
+func (s *GormBookStore) ListBooks(ctx context.Context, query BookQuery) ([]models.Book, error) {
+	var books []models.Book
+	tx := s.DB.WithContext(ctx)
+	if order := bookSortOrders[query.Sort]; order != "" {
+		tx = tx.Order(order)
+	}
+	err := tx.Offset(query.Offset).Limit(query.Limit).Find(&books).Error
+	return books, translate(err)
+}
+
+func (s *GormBookStore) LastModified(ctx context.Context) (time.Time, error) {
+	var latest struct {
+		Updated *time.Time
+		Deleted *time.Time
+	}
+	err := s.DB.WithContext(ctx).Unscoped().Model(&models.Book{}).
+		Select("MAX(updated_at) AS updated, MAX(deleted_at) AS deleted").
+		Find(&latest).Error
+	if err != nil {
+		return time.Time{}, translate(err)
+	}
+
+	var modified time.Time
+	for _, at := range []*time.Time{latest.Updated, latest.Deleted} {
+		if at != nil && at.After(modified) {
+			modified = *at
+		}
+	}
+	return modified, nil
+}
+
+func (s *GormBookStore) GetBook(ctx context.Context, id string) (models.Book, error) {
+	var book models.Book
+	err := s.DB.WithContext(ctx).Where("id = ?", id).First(&book).Error
+	return book, translate(err)
+}
+
+func (s *GormBookStore) GetBookByISBN(ctx context.Context, isbn models.ISBN) (models.Book, error) {
+	var book models.Book
+	err := s.DB.WithContext(ctx).Where("isbn = ?", isbn).First(&book).Error
+	return book, translate(err)
+}
+
+func (s *GormBookStore) CreateBook(ctx context.Context, book *models.Book) error {
+	return translate(s.DB.WithContext(ctx).Create(book).Error)
+}
+
+func (s *GormBookStore) UpdateBook(ctx context.Context, book *models.Book) error {
+	return saveBookVersion(s.DB.WithContext(ctx).Model(book), book)
+}
+
+func (s *GormBookStore) DeleteBook(ctx context.Context, book models.Book) error {
+	result := s.DB.WithContext(ctx).Delete(&book, "version = ?", book.Version)
+	if result.Error != nil {
+		return translate(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrStale
+	}
+	return nil
+}
+
+func (s *GormBookStore) LockBook(ctx context.Context, id string) (models.Book, error) {
+	return lockBook(s.DB.WithContext(ctx), "id = ?", id)
+}
+
+func (s *GormBookStore) LockAnyBook(ctx context.Context, id string) (models.Book, error) {
+	return lockBook(s.DB.WithContext(ctx).Unscoped(), "id = ?", id)
+}
+
+func (s *GormBookStore) LockBookByISBN(ctx context.Context, isbn models.ISBN) (models.Book, error) {
+	return lockBook(s.DB.WithContext(ctx), "isbn = ?", isbn)
+}
+
+func lockBook(db *gorm.DB, query string, args ...interface{}) (models.Book, error) {
+	var book models.Book
+	err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where(query, args...).First(&book).Error
+	return book, translate(err)
+}
+
+func (s *GormBookStore) SaveCover(ctx context.Context, book *models.Book, cover models.Cover) error {
+	now := time.Now()
+	result := s.DB.WithContext(ctx).Model(book).Where("version = ?", book.Version).Updates(map[string]interface{}{
+		"cover_key":        cover.Key,
+		"cover_url":        cover.URL,
+		"cover_small_url":  cover.SmallURL,
+		"cover_medium_url": cover.MediumURL,
+		"cover_width":      cover.Width,
+		"cover_height":     cover.Height,
+		"version":          gorm.Expr("version + 1"),
+		"updated_at":       now,
+	})
+	if result.Error != nil {
+		return translate(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrStale
+	}
+
+	book.Cover = cover
+	book.Version++
+	book.UpdatedAt = now
+	return nil
+}
+
+func (s *GormBookStore) SaveAvailability(ctx context.Context, book *models.Book) error {
+	// Availability is part of the book representation, so the version and
+	// update time move with it
+	version, now := book.Version+1, time.Now()
+	err := s.DB.WithContext(ctx).Unscoped().Model(book).Updates(map[string]interface{}{
+		"copies":           book.Copies,
+		"available_copies": book.AvailableCopies,
+		"version":          version,
+		"updated_at":       now,
+	}).Error
+	if err != nil {
+		return translate(err)
+	}
+
+	book.Version = version
+	book.UpdatedAt = now
+	return nil
+}
+
+func (s *GormBookStore) RefreshRating(ctx context.Context, book *models.Book) error {
+	tx := s.DB.WithContext(ctx)
+	err := tx.Model(book).Updates(map[string]interface{}{
+		"review_count":   gorm.Expr("(SELECT count(*) FROM reviews WHERE book_id = ? AND status = ?)", book.ID, models.ReviewStatusApproved),
+		"rating_average": gorm.Expr("(SELECT coalesce(avg(rating), 0) FROM reviews WHERE book_id = ? AND status = ?)", book.ID, models.ReviewStatusApproved),
+		"version":        gorm.Expr("version + 1"),
+		"updated_at":     time.Now(),
+	}).Error
+	if err != nil {
+		return translate(err)
+	}
+
+	return translate(tx.Select("review_count", "rating_average", "version", "updated_at").First(book).Error)
+}
+
+func (s *GormBookStore) RestoreBook(ctx context.Context, id string) (models.Book, error) {
+	var book models.Book
+	tx := s.DB.WithContext(ctx)
+
+	result := tx.Unscoped().Model(&models.Book{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return book, translate(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return book, ErrNotFound
+	}
+
+	err := tx.Where("id = ?", id).First(&book).Error
+	return book, translate(err)
+}
+
+func (s *GormBookStore) PurgeBook(ctx context.Context, id string) ([]string, error) {
+	tx := s.DB.WithContext(ctx)
+	purged, keys, err := database.PurgeBooks(tx, "id = ? AND deleted_at IS NOT NULL", id)
+	if err != nil {
+		return nil, translate(err)
+	}
+	if purged > 0 {
+		return keys, nil
+	}
+
+	var onLoan int64
+	if err := tx.Model(&models.Loan{}).Where("book_id = ? AND returned_at IS NULL", id).Count(&onLoan).Error; err != nil {
+		return nil, translate(err)
+	}
+	if onLoan > 0 {
+		return nil, ErrConflict
+	}
+	return nil, ErrNotFound
+}
+
+// saveBookVersion writes the book's updatable columns and bumps its version in
+// a single UPDATE that only matches the version the caller read. query must
+// be scoped to the book, e.g. db.Model(&book), and may be a transaction. When
+// the author string changes, the byline credits follow it.
+func saveBookVersion(query *gorm.DB, book *models.Book) error {
+	tx := query.Session(&gorm.Session{NewDB: true})
+	var previous []string
+	if err := tx.Model(&models.Book{}).Where("id = ?", book.ID).Pluck("author", &previous).Error; err != nil {
+		return translate(err)
+	}
+
+	values := book.UpdatableValues()
+	now := time.Now()
+	values["version"] = gorm.Expr("version + 1")
+	values["updated_at"] = now
+
+	result := query.Where("version = ?", book.Version).Updates(values)
+	if result.Error != nil {
+		return translate(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrStale
+	}
+	if len(previous) > 0 {
+		if err := models.ResyncBylineAuthors(tx, previous[0], *book); err != nil {
+			return translate(err)
+		}
+	}
+
+	book.Version++
+	book.UpdatedAt = now
+	return nil
+}
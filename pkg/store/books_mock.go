@@ -0,0 +1,256 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: pkg/store/books.go
+
+// Package store is a generated GoMock package.
+package store
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+	models "github.com/kev1nandreas/go-rest-api-template/pkg/models"
+)
+
+// MockBookStore is a mock of BookStore interface.
+type MockBookStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockBookStoreMockRecorder
+}
+
+// MockBookStoreMockRecorder is the mock recorder for MockBookStore.
+type MockBookStoreMockRecorder struct {
+	mock *MockBookStore
+}
+
+// NewMockBookStore creates a new mock instance.
+func NewMockBookStore(ctrl *gomock.Controller) *MockBookStore {
+	mock := &MockBookStore{ctrl: ctrl}
+	mock.recorder = &MockBookStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBookStore) EXPECT() *MockBookStoreMockRecorder {
+	return m.recorder
+}
+
+// CreateBook mocks base method.
+func (m *MockBookStore) CreateBook(ctx context.Context, book *models.Book) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateBook", ctx, book)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateBook indicates an expected call of CreateBook.
+func (mr *MockBookStoreMockRecorder) CreateBook(ctx, book interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBook", reflect.TypeOf((*MockBookStore)(nil).CreateBook), ctx, book)
+}
+
+// DeleteBook mocks base method.
+func (m *MockBookStore) DeleteBook(ctx context.Context, book models.Book) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteBook", ctx, book)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteBook indicates an expected call of DeleteBook.
+func (mr *MockBookStoreMockRecorder) DeleteBook(ctx, book interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBook", reflect.TypeOf((*MockBookStore)(nil).DeleteBook), ctx, book)
+}
+
+// GetBook mocks base method.
+func (m *MockBookStore) GetBook(ctx context.Context, id string) (models.Book, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBook", ctx, id)
+	ret0, _ := ret[0].(models.Book)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBook indicates an expected call of GetBook.
+func (mr *MockBookStoreMockRecorder) GetBook(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBook", reflect.TypeOf((*MockBookStore)(nil).GetBook), ctx, id)
+}
+
+// GetBookByISBN mocks base method.
+func (m *MockBookStore) GetBookByISBN(ctx context.Context, isbn models.ISBN) (models.Book, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBookByISBN", ctx, isbn)
+	ret0, _ := ret[0].(models.Book)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBookByISBN indicates an expected call of GetBookByISBN.
+func (mr *MockBookStoreMockRecorder) GetBookByISBN(ctx, isbn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBookByISBN", reflect.TypeOf((*MockBookStore)(nil).GetBookByISBN), ctx, isbn)
+}
+
+// LastModified mocks base method.
+func (m *MockBookStore) LastModified(ctx context.Context) (time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LastModified", ctx)
+	ret0, _ := ret[0].(time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LastModified indicates an expected call of LastModified.
+func (mr *MockBookStoreMockRecorder) LastModified(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LastModified", reflect.TypeOf((*MockBookStore)(nil).LastModified), ctx)
+}
+
+// ListBooks mocks base method.
+func (m *MockBookStore) ListBooks(ctx context.Context, query BookQuery) ([]models.Book, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListBooks", ctx, query)
+	ret0, _ := ret[0].([]models.Book)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListBooks indicates an expected call of ListBooks.
+func (mr *MockBookStoreMockRecorder) ListBooks(ctx, query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListBooks", reflect.TypeOf((*MockBookStore)(nil).ListBooks), ctx, query)
+}
+
+// LockAnyBook mocks base method.
+func (m *MockBookStore) LockAnyBook(ctx context.Context, id string) (models.Book, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockAnyBook", ctx, id)
+	ret0, _ := ret[0].(models.Book)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LockAnyBook indicates an expected call of LockAnyBook.
+func (mr *MockBookStoreMockRecorder) LockAnyBook(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockAnyBook", reflect.TypeOf((*MockBookStore)(nil).LockAnyBook), ctx, id)
+}
+
+// LockBook mocks base method.
+func (m *MockBookStore) LockBook(ctx context.Context, id string) (models.Book, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockBook", ctx, id)
+	ret0, _ := ret[0].(models.Book)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LockBook indicates an expected call of LockBook.
+func (mr *MockBookStoreMockRecorder) LockBook(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockBook", reflect.TypeOf((*MockBookStore)(nil).LockBook), ctx, id)
+}
+
+// LockBookByISBN mocks base method.
+func (m *MockBookStore) LockBookByISBN(ctx context.Context, isbn models.ISBN) (models.Book, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockBookByISBN", ctx, isbn)
+	ret0, _ := ret[0].(models.Book)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LockBookByISBN indicates an expected call of LockBookByISBN.
+func (mr *MockBookStoreMockRecorder) LockBookByISBN(ctx, isbn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockBookByISBN", reflect.TypeOf((*MockBookStore)(nil).LockBookByISBN), ctx, isbn)
+}
+
+// PurgeBook mocks base method.
+func (m *MockBookStore) PurgeBook(ctx context.Context, id string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeBook", ctx, id)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeBook indicates an expected call of PurgeBook.
+func (mr *MockBookStoreMockRecorder) PurgeBook(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeBook", reflect.TypeOf((*MockBookStore)(nil).PurgeBook), ctx, id)
+}
+
+// RefreshRating mocks base method.
+func (m *MockBookStore) RefreshRating(ctx context.Context, book *models.Book) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefreshRating", ctx, book)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RefreshRating indicates an expected call of RefreshRating.
+func (mr *MockBookStoreMockRecorder) RefreshRating(ctx, book interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshRating", reflect.TypeOf((*MockBookStore)(nil).RefreshRating), ctx, book)
+}
+
+// RestoreBook mocks base method.
+func (m *MockBookStore) RestoreBook(ctx context.Context, id string) (models.Book, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreBook", ctx, id)
+	ret0, _ := ret[0].(models.Book)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestoreBook indicates an expected call of RestoreBook.
+func (mr *MockBookStoreMockRecorder) RestoreBook(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreBook", reflect.TypeOf((*MockBookStore)(nil).RestoreBook), ctx, id)
+}
+
+// SaveAvailability mocks base method.
+func (m *MockBookStore) SaveAvailability(ctx context.Context, book *models.Book) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveAvailability", ctx, book)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveAvailability indicates an expected call of SaveAvailability.
+func (mr *MockBookStoreMockRecorder) SaveAvailability(ctx, book interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveAvailability", reflect.TypeOf((*MockBookStore)(nil).SaveAvailability), ctx, book)
+}
+
+// SaveCover mocks base method.
+func (m *MockBookStore) SaveCover(ctx context.Context, book *models.Book, cover models.Cover) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveCover", ctx, book, cover)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveCover indicates an expected call of SaveCover.
+func (mr *MockBookStoreMockRecorder) SaveCover(ctx, book, cover interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveCover", reflect.TypeOf((*MockBookStore)(nil).SaveCover), ctx, book, cover)
+}
+
+// UpdateBook mocks base method.
+func (m *MockBookStore) UpdateBook(ctx context.Context, book *models.Book) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateBook", ctx, book)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateBook indicates an expected call of UpdateBook.
+func (mr *MockBookStoreMockRecorder) UpdateBook(ctx, book interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateBook", reflect.TypeOf((*MockBookStore)(nil).UpdateBook), ctx, book)
+}
@@ -0,0 +1,126 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils/tests"
+)
+
+// newDryRunDB returns a gorm handle that builds statements without running
+// them, so every write reports zero affected rows
+func newDryRunDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true, Logger: logger.Discard})
+	assert.NoError(t, err)
+	return db
+}
+
+func TestGormBookStoreTranslatesErrors(t *testing.T) {
+	ctx := context.Background()
+	db := newDryRunDB(t)
+	db.Callback().Query().After("gorm:query").Register("test:missing", func(tx *gorm.DB) {
+		tx.AddError(gorm.ErrRecordNotFound)
+	})
+	db.Callback().Create().After("gorm:create").Register("test:duplicate", func(tx *gorm.DB) {
+		if _, ok := tx.Statement.Dest.(*models.Book); ok {
+			tx.AddError(gorm.ErrDuplicatedKey)
+		}
+	})
+	books := NewGormBookStore(db)
+
+	_, err := books.GetBook(ctx, uuid.NewString())
+	assert.ErrorIs(t, err, ErrNotFound)
+	// The driver error stays available to callers that need it
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	_, err = books.GetBookByISBN(ctx, "9780134190440")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	err = books.CreateBook(ctx, &models.Book{Title: "Dune", ISBN: "9780441013593"})
+	assert.ErrorIs(t, err, ErrConflict)
+}
+
+func TestGormBookStoreVersionedWrites(t *testing.T) {
+	ctx := context.Background()
+	book := models.Book{ID: uuid.New(), Title: "Dune", Author: "Frank Herbert", Version: 3}
+
+	// Nothing matches the version that was read
+	stale := NewGormBookStore(newDryRunDB(t))
+	updated := book
+	assert.ErrorIs(t, stale.UpdateBook(ctx, &updated), ErrStale)
+	assert.Equal(t, int64(3), updated.Version)
+	assert.ErrorIs(t, stale.DeleteBook(ctx, book), ErrStale)
+
+	db := newDryRunDB(t)
+	var statement string
+	var vars []interface{}
+	db.Callback().Update().After("gorm:update").Register("test:save", func(tx *gorm.DB) {
+		statement, vars = tx.Statement.SQL.String(), tx.Statement.Vars
+		tx.RowsAffected = 1
+	})
+	db.Callback().Delete().After("gorm:delete").Register("test:delete", func(tx *gorm.DB) {
+		tx.RowsAffected = 1
+	})
+	current := NewGormBookStore(db)
+	assert.NoError(t, current.UpdateBook(ctx, &updated))
+	assert.Contains(t, statement, "version = ?")
+	assert.Contains(t, vars, int64(3))
+	assert.Equal(t, int64(4), updated.Version)
+	assert.NoError(t, current.DeleteBook(ctx, updated))
+}
+
+func TestGormBookStoreListBooks(t *testing.T) {
+	db := newDryRunDB(t)
+	var statements []string
+	db.Callback().Query().After("gorm:query").Register("test:record", func(tx *gorm.DB) {
+		statements = append(statements, tx.Statement.SQL.String())
+	})
+	books := NewGormBookStore(db)
+
+	_, err := books.ListBooks(context.Background(), BookQuery{Offset: 10, Limit: 10})
+	assert.NoError(t, err)
+	_, err = books.ListBooks(context.Background(), BookQuery{Limit: 10, Sort: BookSortRating})
+	assert.NoError(t, err)
+
+	assert.NotContains(t, statements[0], "ORDER BY")
+	assert.Contains(t, statements[1], "ORDER BY rating_average DESC")
+	assert.True(t, ValidBookSort(BookSortRating))
+	assert.False(t, ValidBookSort("title"))
+}
+
+func TestGormBookStoreLastModified(t *testing.T) {
+	db := newDryRunDB(t)
+	var statement string
+	db.Callback().Query().After("gorm:query").Register("test:record", func(tx *gorm.DB) {
+		statement = tx.Statement.SQL.String()
+	})
+
+	// No books yet
+	modified, err := NewGormBookStore(db).LastModified(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, modified.IsZero())
+
+	// Books in the trash count, their deletion changed the list
+	assert.Contains(t, statement, "MAX(updated_at)")
+	assert.Contains(t, statement, "MAX(deleted_at)")
+	assert.NotContains(t, statement, "deleted_at IS NULL")
+}
+
+func TestGormBookStoreStopsWithContext(t *testing.T) {
+	db := newDryRunDB(t)
+	db.Callback().Query().Before("gorm:query").Register("test:context", func(tx *gorm.DB) {
+		if err := tx.Statement.Context.Err(); err != nil {
+			tx.AddError(err)
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := NewGormBookStore(db).GetBook(ctx, uuid.NewString())
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,63 @@
+package store
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"gorm.io/gorm"
+)
+
+// BookFileStore keeps the rows of the digital editions of books. The files
+// themselves are in the blob store.
+type BookFileStore interface {
+	GetBookFile(ctx context.Context, id uuid.UUID) (models.BookFile, error)
+	// GetBookFileByFormat returns the file of a book in format
+	GetBookFileByFormat(ctx context.Context, bookID uuid.UUID, format string) (models.BookFile, error)
+	ListBookFiles(ctx context.Context, bookID uuid.UUID) ([]models.BookFile, error)
+	// CreateBookFile adds file, or returns ErrConflict if its book already
+	// has a file in its format
+	CreateBookFile(ctx context.Context, file *models.BookFile) error
+	// UpdateBookFile points file to new content
+	UpdateBookFile(ctx context.Context, file *models.BookFile) error
+	DeleteBookFile(ctx context.Context, file models.BookFile) error
+}
+
+// GormBookFileStore keeps book files in the book_files table
+type GormBookFileStore struct {
+	DB *gorm.DB
+}
+
+func NewGormBookFileStore(db *gorm.DB) *GormBookFileStore {
+	return &GormBookFileStore{DB: db}
+}
+
+func (s *GormBookFileStore) GetBookFile(ctx context.Context, id uuid.UUID) (models.BookFile, error) {
+	var file models.BookFile
+	err := s.DB.WithContext(ctx).Where("id = ?", id).First(&file).Error
+	return file, translate(err)
+}
+
+func (s *GormBookFileStore) GetBookFileByFormat(ctx context.Context, bookID uuid.UUID, format string) (models.BookFile, error) {
+	var file models.BookFile
+	err := s.DB.WithContext(ctx).Where("book_id = ? AND format = ?", bookID, format).First(&file).Error
+	return file, translate(err)
+}
+
+func (s *GormBookFileStore) ListBookFiles(ctx context.Context, bookID uuid.UUID) ([]models.BookFile, error) {
+	files := []models.BookFile{}
+	err := s.DB.WithContext(ctx).Where("book_id = ?", bookID).Find(&files).Error
+	return files, translate(err)
+}
+
+func (s *GormBookFileStore) CreateBookFile(ctx context.Context, file *models.BookFile) error {
+	return translate(s.DB.WithContext(ctx).Create(file).Error)
+}
+
+func (s *GormBookFileStore) UpdateBookFile(ctx context.Context, file *models.BookFile) error {
+	return translate(s.DB.WithContext(ctx).Model(file).Select("key", "filename", "content_type", "size", "sha256", "updated_at").Updates(file).Error)
+}
+
+func (s *GormBookFileStore) DeleteBookFile(ctx context.Context, file models.BookFile) error {
+	return translate(s.DB.WithContext(ctx).Delete(&file).Error)
+}
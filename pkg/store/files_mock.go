@@ -0,0 +1,124 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: pkg/store/files.go
+
+// Package store is a generated GoMock package.
+package store
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+	models "github.com/kev1nandreas/go-rest-api-template/pkg/models"
+)
+
+// MockBookFileStore is a mock of BookFileStore interface.
+type MockBookFileStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockBookFileStoreMockRecorder
+}
+
+// MockBookFileStoreMockRecorder is the mock recorder for MockBookFileStore.
+type MockBookFileStoreMockRecorder struct {
+	mock *MockBookFileStore
+}
+
+// NewMockBookFileStore creates a new mock instance.
+func NewMockBookFileStore(ctrl *gomock.Controller) *MockBookFileStore {
+	mock := &MockBookFileStore{ctrl: ctrl}
+	mock.recorder = &MockBookFileStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBookFileStore) EXPECT() *MockBookFileStoreMockRecorder {
+	return m.recorder
+}
+
+// CreateBookFile mocks base method.
+func (m *MockBookFileStore) CreateBookFile(ctx context.Context, file *models.BookFile) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateBookFile", ctx, file)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateBookFile indicates an expected call of CreateBookFile.
+func (mr *MockBookFileStoreMockRecorder) CreateBookFile(ctx, file interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBookFile", reflect.TypeOf((*MockBookFileStore)(nil).CreateBookFile), ctx, file)
+}
+
+// DeleteBookFile mocks base method.
+func (m *MockBookFileStore) DeleteBookFile(ctx context.Context, file models.BookFile) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteBookFile", ctx, file)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteBookFile indicates an expected call of DeleteBookFile.
+func (mr *MockBookFileStoreMockRecorder) DeleteBookFile(ctx, file interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBookFile", reflect.TypeOf((*MockBookFileStore)(nil).DeleteBookFile), ctx, file)
+}
+
+// GetBookFile mocks base method.
+func (m *MockBookFileStore) GetBookFile(ctx context.Context, id uuid.UUID) (models.BookFile, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBookFile", ctx, id)
+	ret0, _ := ret[0].(models.BookFile)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBookFile indicates an expected call of GetBookFile.
+func (mr *MockBookFileStoreMockRecorder) GetBookFile(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBookFile", reflect.TypeOf((*MockBookFileStore)(nil).GetBookFile), ctx, id)
+}
+
+// GetBookFileByFormat mocks base method.
+func (m *MockBookFileStore) GetBookFileByFormat(ctx context.Context, bookID uuid.UUID, format string) (models.BookFile, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBookFileByFormat", ctx, bookID, format)
+	ret0, _ := ret[0].(models.BookFile)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBookFileByFormat indicates an expected call of GetBookFileByFormat.
+func (mr *MockBookFileStoreMockRecorder) GetBookFileByFormat(ctx, bookID, format interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBookFileByFormat", reflect.TypeOf((*MockBookFileStore)(nil).GetBookFileByFormat), ctx, bookID, format)
+}
+
+// ListBookFiles mocks base method.
+func (m *MockBookFileStore) ListBookFiles(ctx context.Context, bookID uuid.UUID) ([]models.BookFile, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListBookFiles", ctx, bookID)
+	ret0, _ := ret[0].([]models.BookFile)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListBookFiles indicates an expected call of ListBookFiles.
+func (mr *MockBookFileStoreMockRecorder) ListBookFiles(ctx, bookID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListBookFiles", reflect.TypeOf((*MockBookFileStore)(nil).ListBookFiles), ctx, bookID)
+}
+
+// UpdateBookFile mocks base method.
+func (m *MockBookFileStore) UpdateBookFile(ctx context.Context, file *models.BookFile) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateBookFile", ctx, file)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateBookFile indicates an expected call of UpdateBookFile.
+func (mr *MockBookFileStoreMockRecorder) UpdateBookFile(ctx, file interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateBookFile", reflect.TypeOf((*MockBookFileStore)(nil).UpdateBookFile), ctx, file)
+}
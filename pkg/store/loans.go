@@ -0,0 +1,174 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"gorm.io/gorm"
+)
+
+// LoanStore keeps the loans and reservations of books. Writes that move
+// copies between loans, holds and the shelf take the lock of the book first,
+// see BookStore.LockBook, so the loans and reservations of a locked book don't
+// change under them.
+type LoanStore interface {
+	GetLoan(ctx context.Context, id uuid.UUID) (models.Loan, error)
+	// CreateLoan adds loan, or returns ErrConflict if the user already has a
+	// copy of the book
+	CreateLoan(ctx context.Context, loan *models.Loan) error
+	// SaveLoan writes the due date, renewals and return of loan
+	SaveLoan(ctx context.Context, loan models.Loan) error
+	// HasActiveLoan reports whether a user has a copy of a book
+	HasActiveLoan(ctx context.Context, bookID uuid.UUID, userID uuid.UUID) (bool, error)
+
+	GetReservation(ctx context.Context, id uuid.UUID) (models.Reservation, error)
+	// CreateReservation adds reservation, or returns ErrConflict if the user
+	// already has an open reservation of the book
+	CreateReservation(ctx context.Context, reservation *models.Reservation) error
+	// CloseReservation closes reservation with status
+	CloseReservation(ctx context.Context, reservation *models.Reservation, status string, at time.Time) error
+	// FulfillReservation closes the reservation of a user holding a copy of
+	// a book, if any, and reports whether there was one
+	FulfillReservation(ctx context.Context, bookID uuid.UUID, userID uuid.UUID, at time.Time) (bool, error)
+	// ExpireHolds closes the reservations of a book whose hold expired before
+	// now and returns how many copies they held
+	ExpireHolds(ctx context.Context, bookID uuid.UUID, now time.Time) (int, error)
+	// HoldCopies holds up to copies copies of a book until expires for the
+	// oldest waiting reservations and returns how many were held
+	HoldCopies(ctx context.Context, bookID uuid.UUID, copies int, now time.Time, expires time.Time) (int, error)
+	// CountWaiting returns how many reservations wait in the queue of a book
+	CountWaiting(ctx context.Context, bookID uuid.UUID) (int64, error)
+	// SetPosition sets the place of a waiting reservation in the queue of
+	// its book, counting from 1
+	SetPosition(ctx context.Context, reservation *models.Reservation) error
+	// BooksWithExpiredHolds returns the books holding a copy for a
+	// reservation whose hold expired before now
+	BooksWithExpiredHolds(ctx context.Context, now time.Time) ([]uuid.UUID, error)
+}
+
+// GormLoanStore keeps loans in the loans table and reservations in the
+// reservations table
+type GormLoanStore struct {
+	DB *gorm.DB
+}
+
+func NewGormLoanStore(db *gorm.DB) *GormLoanStore {
+	return &GormLoanStore{DB: db}
+}
+
+func (s *GormLoanStore) GetLoan(ctx context.Context, id uuid.UUID) (models.Loan, error) {
+	var loan models.Loan
+	err := s.DB.WithContext(ctx).Where("id = ?", id).First(&loan).Error
+	return loan, translate(err)
+}
+
+func (s *GormLoanStore) CreateLoan(ctx context.Context, loan *models.Loan) error {
+	return translate(s.DB.WithContext(ctx).Create(loan).Error)
+}
+
+func (s *GormLoanStore) SaveLoan(ctx context.Context, loan models.Loan) error {
+	return translate(s.DB.WithContext(ctx).Model(&loan).Updates(map[string]interface{}{
+		"due_at":      loan.DueAt,
+		"renewals":    loan.Renewals,
+		"returned_at": loan.ReturnedAt,
+	}).Error)
+}
+
+func (s *GormLoanStore) HasActiveLoan(ctx context.Context, bookID uuid.UUID, userID uuid.UUID) (bool, error) {
+	var loans []models.Loan
+	err := s.DB.WithContext(ctx).
+		Where("book_id = ? AND user_id = ? AND returned_at IS NULL", bookID, userID).
+		Limit(1).Find(&loans).Error
+	return len(loans) > 0, translate(err)
+}
+
+func (s *GormLoanStore) GetReservation(ctx context.Context, id uuid.UUID) (models.Reservation, error) {
+	var reservation models.Reservation
+	err := s.DB.WithContext(ctx).Where("id = ?", id).First(&reservation).Error
+	return reservation, translate(err)
+}
+
+func (s *GormLoanStore) CreateReservation(ctx context.Context, reservation *models.Reservation) error {
+	return translate(s.DB.WithContext(ctx).Create(reservation).Error)
+}
+
+func (s *GormLoanStore) CloseReservation(ctx context.Context, reservation *models.Reservation, status string, at time.Time) error {
+	err := s.DB.WithContext(ctx).Model(reservation).Updates(map[string]interface{}{"status": status, "closed_at": at}).Error
+	if err != nil {
+		return translate(err)
+	}
+	reservation.Status = status
+	reservation.ClosedAt = &at
+	return nil
+}
+
+func (s *GormLoanStore) FulfillReservation(ctx context.Context, bookID uuid.UUID, userID uuid.UUID, at time.Time) (bool, error) {
+	result := s.DB.WithContext(ctx).Model(&models.Reservation{}).
+		Where("book_id = ? AND user_id = ? AND status = ?", bookID, userID, models.ReservationStatusReady).
+		Updates(map[string]interface{}{"status": models.ReservationStatusFulfilled, "closed_at": at})
+	return result.RowsAffected > 0, translate(result.Error)
+}
+
+func (s *GormLoanStore) ExpireHolds(ctx context.Context, bookID uuid.UUID, now time.Time) (int, error) {
+	result := s.DB.WithContext(ctx).Model(&models.Reservation{}).
+		Where("book_id = ? AND status = ? AND expires_at < ?", bookID, models.ReservationStatusReady, now).
+		Updates(map[string]interface{}{"status": models.ReservationStatusExpired, "closed_at": now})
+	return int(result.RowsAffected), translate(result.Error)
+}
+
+func (s *GormLoanStore) HoldCopies(ctx context.Context, bookID uuid.UUID, copies int, now time.Time, expires time.Time) (int, error) {
+	tx := s.DB.WithContext(ctx)
+
+	var waiting []models.Reservation
+	err := tx.Where("book_id = ? AND status = ?", bookID, models.ReservationStatusWaiting).
+		Order("created_at").Limit(copies).Find(&waiting).Error
+	if err != nil || len(waiting) == 0 {
+		return 0, translate(err)
+	}
+
+	ids := make([]uuid.UUID, len(waiting))
+	for i, reservation := range waiting {
+		ids[i] = reservation.ID
+	}
+	err = tx.Model(&models.Reservation{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+		"status":     models.ReservationStatusReady,
+		"ready_at":   now,
+		"expires_at": expires,
+	}).Error
+	if err != nil {
+		return 0, translate(err)
+	}
+	return len(waiting), nil
+}
+
+func (s *GormLoanStore) CountWaiting(ctx context.Context, bookID uuid.UUID) (int64, error) {
+	var waiting int64
+	err := s.DB.WithContext(ctx).Model(&models.Reservation{}).
+		Where("book_id = ? AND status = ?", bookID, models.ReservationStatusWaiting).
+		Count(&waiting).Error
+	return waiting, translate(err)
+}
+
+func (s *GormLoanStore) SetPosition(ctx context.Context, reservation *models.Reservation) error {
+	if reservation.Status != models.ReservationStatusWaiting {
+		reservation.Position = 0
+		return nil
+	}
+
+	var ahead int64
+	err := s.DB.WithContext(ctx).Model(&models.Reservation{}).
+		Where("book_id = ? AND status = ? AND created_at < ?", reservation.BookID, models.ReservationStatusWaiting, reservation.CreatedAt).
+		Count(&ahead).Error
+	reservation.Position = int(ahead) + 1
+	return translate(err)
+}
+
+func (s *GormLoanStore) BooksWithExpiredHolds(ctx context.Context, now time.Time) ([]uuid.UUID, error) {
+	var bookIDs []uuid.UUID
+	err := s.DB.WithContext(ctx).Model(&models.Reservation{}).
+		Where("status = ? AND expires_at < ?", models.ReservationStatusReady, now).
+		Distinct().Pluck("book_id", &bookIDs).Error
+	return bookIDs, translate(err)
+}
@@ -0,0 +1,228 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: pkg/store/loans.go
+
+// Package store is a generated GoMock package.
+package store
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+	models "github.com/kev1nandreas/go-rest-api-template/pkg/models"
+)
+
+// MockLoanStore is a mock of LoanStore interface.
+type MockLoanStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockLoanStoreMockRecorder
+}
+
+// MockLoanStoreMockRecorder is the mock recorder for MockLoanStore.
+type MockLoanStoreMockRecorder struct {
+	mock *MockLoanStore
+}
+
+// NewMockLoanStore creates a new mock instance.
+func NewMockLoanStore(ctrl *gomock.Controller) *MockLoanStore {
+	mock := &MockLoanStore{ctrl: ctrl}
+	mock.recorder = &MockLoanStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLoanStore) EXPECT() *MockLoanStoreMockRecorder {
+	return m.recorder
+}
+
+// BooksWithExpiredHolds mocks base method.
+func (m *MockLoanStore) BooksWithExpiredHolds(ctx context.Context, now time.Time) ([]uuid.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BooksWithExpiredHolds", ctx, now)
+	ret0, _ := ret[0].([]uuid.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BooksWithExpiredHolds indicates an expected call of BooksWithExpiredHolds.
+func (mr *MockLoanStoreMockRecorder) BooksWithExpiredHolds(ctx, now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BooksWithExpiredHolds", reflect.TypeOf((*MockLoanStore)(nil).BooksWithExpiredHolds), ctx, now)
+}
+
+// CloseReservation mocks base method.
+func (m *MockLoanStore) CloseReservation(ctx context.Context, reservation *models.Reservation, status string, at time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloseReservation", ctx, reservation, status, at)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CloseReservation indicates an expected call of CloseReservation.
+func (mr *MockLoanStoreMockRecorder) CloseReservation(ctx, reservation, status, at interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseReservation", reflect.TypeOf((*MockLoanStore)(nil).CloseReservation), ctx, reservation, status, at)
+}
+
+// CountWaiting mocks base method.
+func (m *MockLoanStore) CountWaiting(ctx context.Context, bookID uuid.UUID) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountWaiting", ctx, bookID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountWaiting indicates an expected call of CountWaiting.
+func (mr *MockLoanStoreMockRecorder) CountWaiting(ctx, bookID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountWaiting", reflect.TypeOf((*MockLoanStore)(nil).CountWaiting), ctx, bookID)
+}
+
+// CreateLoan mocks base method.
+func (m *MockLoanStore) CreateLoan(ctx context.Context, loan *models.Loan) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateLoan", ctx, loan)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateLoan indicates an expected call of CreateLoan.
+func (mr *MockLoanStoreMockRecorder) CreateLoan(ctx, loan interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateLoan", reflect.TypeOf((*MockLoanStore)(nil).CreateLoan), ctx, loan)
+}
+
+// CreateReservation mocks base method.
+func (m *MockLoanStore) CreateReservation(ctx context.Context, reservation *models.Reservation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateReservation", ctx, reservation)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateReservation indicates an expected call of CreateReservation.
+func (mr *MockLoanStoreMockRecorder) CreateReservation(ctx, reservation interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateReservation", reflect.TypeOf((*MockLoanStore)(nil).CreateReservation), ctx, reservation)
+}
+
+// ExpireHolds mocks base method.
+func (m *MockLoanStore) ExpireHolds(ctx context.Context, bookID uuid.UUID, now time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExpireHolds", ctx, bookID, now)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExpireHolds indicates an expected call of ExpireHolds.
+func (mr *MockLoanStoreMockRecorder) ExpireHolds(ctx, bookID, now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpireHolds", reflect.TypeOf((*MockLoanStore)(nil).ExpireHolds), ctx, bookID, now)
+}
+
+// FulfillReservation mocks base method.
+func (m *MockLoanStore) FulfillReservation(ctx context.Context, bookID, userID uuid.UUID, at time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FulfillReservation", ctx, bookID, userID, at)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FulfillReservation indicates an expected call of FulfillReservation.
+func (mr *MockLoanStoreMockRecorder) FulfillReservation(ctx, bookID, userID, at interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FulfillReservation", reflect.TypeOf((*MockLoanStore)(nil).FulfillReservation), ctx, bookID, userID, at)
+}
+
+// GetLoan mocks base method.
+func (m *MockLoanStore) GetLoan(ctx context.Context, id uuid.UUID) (models.Loan, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLoan", ctx, id)
+	ret0, _ := ret[0].(models.Loan)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLoan indicates an expected call of GetLoan.
+func (mr *MockLoanStoreMockRecorder) GetLoan(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLoan", reflect.TypeOf((*MockLoanStore)(nil).GetLoan), ctx, id)
+}
+
+// GetReservation mocks base method.
+func (m *MockLoanStore) GetReservation(ctx context.Context, id uuid.UUID) (models.Reservation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReservation", ctx, id)
+	ret0, _ := ret[0].(models.Reservation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReservation indicates an expected call of GetReservation.
+func (mr *MockLoanStoreMockRecorder) GetReservation(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReservation", reflect.TypeOf((*MockLoanStore)(nil).GetReservation), ctx, id)
+}
+
+// HasActiveLoan mocks base method.
+func (m *MockLoanStore) HasActiveLoan(ctx context.Context, bookID, userID uuid.UUID) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HasActiveLoan", ctx, bookID, userID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HasActiveLoan indicates an expected call of HasActiveLoan.
+func (mr *MockLoanStoreMockRecorder) HasActiveLoan(ctx, bookID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasActiveLoan", reflect.TypeOf((*MockLoanStore)(nil).HasActiveLoan), ctx, bookID, userID)
+}
+
+// HoldCopies mocks base method.
+func (m *MockLoanStore) HoldCopies(ctx context.Context, bookID uuid.UUID, copies int, now, expires time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HoldCopies", ctx, bookID, copies, now, expires)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HoldCopies indicates an expected call of HoldCopies.
+func (mr *MockLoanStoreMockRecorder) HoldCopies(ctx, bookID, copies, now, expires interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HoldCopies", reflect.TypeOf((*MockLoanStore)(nil).HoldCopies), ctx, bookID, copies, now, expires)
+}
+
+// SaveLoan mocks base method.
+func (m *MockLoanStore) SaveLoan(ctx context.Context, loan models.Loan) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveLoan", ctx, loan)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveLoan indicates an expected call of SaveLoan.
+func (mr *MockLoanStoreMockRecorder) SaveLoan(ctx, loan interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveLoan", reflect.TypeOf((*MockLoanStore)(nil).SaveLoan), ctx, loan)
+}
+
+// SetPosition mocks base method.
+func (m *MockLoanStore) SetPosition(ctx context.Context, reservation *models.Reservation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPosition", ctx, reservation)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPosition indicates an expected call of SetPosition.
+func (mr *MockLoanStoreMockRecorder) SetPosition(ctx, reservation interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPosition", reflect.TypeOf((*MockLoanStore)(nil).SetPosition), ctx, reservation)
+}
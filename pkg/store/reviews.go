@@ -0,0 +1,74 @@
+package store
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"gorm.io/gorm"
+)
+
+// ReviewStore keeps the reviews of books and the flags users put on them.
+// Review writes take the lock of the book first, see BookStore.LockBook, so
+// its rating stays consistent with its reviews.
+type ReviewStore interface {
+	GetReview(ctx context.Context, id uuid.UUID) (models.Review, error)
+	// GetUserReview returns the review of a book by a user
+	GetUserReview(ctx context.Context, bookID uuid.UUID, userID uuid.UUID) (models.Review, error)
+	// CreateReview adds review, or returns ErrConflict if the user already
+	// reviewed the book
+	CreateReview(ctx context.Context, review *models.Review) error
+	// UpdateReview writes the given columns of review
+	UpdateReview(ctx context.Context, review *models.Review, columns ...string) error
+	// DeleteUserReview deletes the review of a book by a user, or returns
+	// ErrNotFound
+	DeleteUserReview(ctx context.Context, bookID uuid.UUID, userID uuid.UUID) error
+	// CreateFlag adds flag, or returns ErrConflict if the user already
+	// flagged the review
+	CreateFlag(ctx context.Context, flag *models.ReviewFlag) error
+}
+
+// GormReviewStore keeps reviews in the reviews table and flags in the
+// review_flags table
+type GormReviewStore struct {
+	DB *gorm.DB
+}
+
+func NewGormReviewStore(db *gorm.DB) *GormReviewStore {
+	return &GormReviewStore{DB: db}
+}
+
+func (s *GormReviewStore) GetReview(ctx context.Context, id uuid.UUID) (models.Review, error) {
+	var review models.Review
+	err := s.DB.WithContext(ctx).Where("id = ?", id).First(&review).Error
+	return review, translate(err)
+}
+
+func (s *GormReviewStore) GetUserReview(ctx context.Context, bookID uuid.UUID, userID uuid.UUID) (models.Review, error) {
+	var review models.Review
+	err := s.DB.WithContext(ctx).Where("book_id = ? AND user_id = ?", bookID, userID).First(&review).Error
+	return review, translate(err)
+}
+
+func (s *GormReviewStore) CreateReview(ctx context.Context, review *models.Review) error {
+	return translate(s.DB.WithContext(ctx).Create(review).Error)
+}
+
+func (s *GormReviewStore) UpdateReview(ctx context.Context, review *models.Review, columns ...string) error {
+	return translate(s.DB.WithContext(ctx).Model(review).Select(columns).Updates(review).Error)
+}
+
+func (s *GormReviewStore) DeleteUserReview(ctx context.Context, bookID uuid.UUID, userID uuid.UUID) error {
+	result := s.DB.WithContext(ctx).Where("book_id = ? AND user_id = ?", bookID, userID).Delete(&models.Review{})
+	if result.Error != nil {
+		return translate(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *GormReviewStore) CreateFlag(ctx context.Context, flag *models.ReviewFlag) error {
+	return translate(s.DB.WithContext(ctx).Create(flag).Error)
+}
@@ -0,0 +1,128 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: pkg/store/reviews.go
+
+// Package store is a generated GoMock package.
+package store
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+	models "github.com/kev1nandreas/go-rest-api-template/pkg/models"
+)
+
+// MockReviewStore is a mock of ReviewStore interface.
+type MockReviewStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockReviewStoreMockRecorder
+}
+
+// MockReviewStoreMockRecorder is the mock recorder for MockReviewStore.
+type MockReviewStoreMockRecorder struct {
+	mock *MockReviewStore
+}
+
+// NewMockReviewStore creates a new mock instance.
+func NewMockReviewStore(ctrl *gomock.Controller) *MockReviewStore {
+	mock := &MockReviewStore{ctrl: ctrl}
+	mock.recorder = &MockReviewStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReviewStore) EXPECT() *MockReviewStoreMockRecorder {
+	return m.recorder
+}
+
+// CreateFlag mocks base method.
+func (m *MockReviewStore) CreateFlag(ctx context.Context, flag *models.ReviewFlag) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateFlag", ctx, flag)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateFlag indicates an expected call of CreateFlag.
+func (mr *MockReviewStoreMockRecorder) CreateFlag(ctx, flag interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateFlag", reflect.TypeOf((*MockReviewStore)(nil).CreateFlag), ctx, flag)
+}
+
+// CreateReview mocks base method.
+func (m *MockReviewStore) CreateReview(ctx context.Context, review *models.Review) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateReview", ctx, review)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateReview indicates an expected call of CreateReview.
+func (mr *MockReviewStoreMockRecorder) CreateReview(ctx, review interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateReview", reflect.TypeOf((*MockReviewStore)(nil).CreateReview), ctx, review)
+}
+
+// DeleteUserReview mocks base method.
+func (m *MockReviewStore) DeleteUserReview(ctx context.Context, bookID, userID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUserReview", ctx, bookID, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUserReview indicates an expected call of DeleteUserReview.
+func (mr *MockReviewStoreMockRecorder) DeleteUserReview(ctx, bookID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUserReview", reflect.TypeOf((*MockReviewStore)(nil).DeleteUserReview), ctx, bookID, userID)
+}
+
+// GetReview mocks base method.
+func (m *MockReviewStore) GetReview(ctx context.Context, id uuid.UUID) (models.Review, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReview", ctx, id)
+	ret0, _ := ret[0].(models.Review)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReview indicates an expected call of GetReview.
+func (mr *MockReviewStoreMockRecorder) GetReview(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReview", reflect.TypeOf((*MockReviewStore)(nil).GetReview), ctx, id)
+}
+
+// GetUserReview mocks base method.
+func (m *MockReviewStore) GetUserReview(ctx context.Context, bookID, userID uuid.UUID) (models.Review, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserReview", ctx, bookID, userID)
+	ret0, _ := ret[0].(models.Review)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserReview indicates an expected call of GetUserReview.
+func (mr *MockReviewStoreMockRecorder) GetUserReview(ctx, bookID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserReview", reflect.TypeOf((*MockReviewStore)(nil).GetUserReview), ctx, bookID, userID)
+}
+
+// UpdateReview mocks base method.
+func (m *MockReviewStore) UpdateReview(ctx context.Context, review *models.Review, columns ...string) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, review}
+	for _, a := range columns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "UpdateReview", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateReview indicates an expected call of UpdateReview.
+func (mr *MockReviewStoreMockRecorder) UpdateReview(ctx, review interface{}, columns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, review}, columns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateReview", reflect.TypeOf((*MockReviewStore)(nil).UpdateReview), varargs...)
+}
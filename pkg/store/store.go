@@ -0,0 +1,32 @@
+// Package store reads and writes the domain models. Every method takes the
+// context of the request it serves, so cancelled requests stop querying, and
+// reports failures with the errors below rather than those of the driver.
+package store
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNotFound is returned when no row matches
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when a write collides with a unique index
+	ErrConflict = errors.New("already exists")
+	// ErrStale is returned when a versioned write finds that the row changed
+	// since it was read
+	ErrStale = errors.New("modified by another request")
+)
+
+// translate wraps the errors of GORM in the errors of the store
+func translate(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return fmt.Errorf("%w: %w", ErrConflict, err)
+	}
+	return err
+}
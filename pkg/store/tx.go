@@ -0,0 +1,172 @@
+package store
+
+import (
+	"context"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// Stores are the stores a unit of work reads and writes
+type Stores struct {
+	Books   BookStore
+	Users   UserStore
+	Authors AuthorStore
+	Loans   LoanStore
+	Reviews ReviewStore
+	Files   BookFileStore
+}
+
+// UnitOfWork runs writes that span several stores in one transaction
+type UnitOfWork interface {
+	// Stores returns the stores outside of any transaction
+	Stores() Stores
+	// WithTx runs fn with the stores of a transaction, which is committed if
+	// fn returns nil and rolled back otherwise. Called with a ctx that fn was
+	// given, it runs in a savepoint of that transaction instead: rolling the
+	// savepoint back leaves the enclosing transaction going.
+	WithTx(ctx context.Context, fn func(ctx context.Context, tx Stores) error) error
+}
+
+type txKey struct{}
+
+// txHooks are the after commit hooks of a transaction or savepoint
+type txHooks struct {
+	mu    sync.Mutex
+	hooks []func(ctx context.Context)
+	// batches run after the hooks, in the order their key was first queued
+	batches []*txBatch
+}
+
+// txBatch holds the items queued under one key by AfterCommitBatch
+type txBatch struct {
+	key   string
+	items []any
+	flush func(ctx context.Context, items []any)
+}
+
+func (h *txHooks) add(hooks ...func(ctx context.Context)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.hooks = append(h.hooks, hooks...)
+}
+
+func (h *txHooks) queue(key string, flush func(ctx context.Context, items []any), items ...any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, batch := range h.batches {
+		if batch.key == key {
+			batch.items = append(batch.items, items...)
+			return
+		}
+	}
+	h.batches = append(h.batches, &txBatch{key: key, items: items, flush: flush})
+}
+
+// release hands the hooks and batches over to the enclosing transaction of a
+// savepoint
+func (h *txHooks) release(parent *txHooks) {
+	parent.add(h.hooks...)
+	for _, batch := range h.batches {
+		parent.queue(batch.key, batch.flush, batch.items...)
+	}
+}
+
+// run calls the hooks, then flushes the batches
+func (h *txHooks) run(ctx context.Context) {
+	ctx = context.WithoutCancel(ctx)
+	for _, hook := range h.hooks {
+		hook(ctx)
+	}
+	for _, batch := range h.batches {
+		batch.flush(ctx, batch.items)
+	}
+}
+
+// AfterCommit defers hook until the transaction of ctx commits, so that caches
+// and search indexes never see a write that is rolled back. The hooks of a
+// savepoint run with its transaction, unless the savepoint is rolled back.
+// Outside of a transaction hook runs right away. Either way it runs with a
+// ctx that isn't cancelled with the request, since the write went through.
+func AfterCommit(ctx context.Context, hook func(ctx context.Context)) {
+	if tx, ok := ctx.Value(txKey{}).(*gormTx); ok {
+		tx.add(hook)
+		return
+	}
+	hook(context.WithoutCancel(ctx))
+}
+
+// AfterCommitBatch is AfterCommit for work that is cheaper in bulk, like
+// indexing books: flush runs once after the commit, with every item queued
+// under key during the transaction, in order. Items queued in a savepoint
+// that is rolled back are dropped. Outside of a transaction flush runs right
+// away with item alone.
+func AfterCommitBatch[T any](ctx context.Context, key string, item T, flush func(ctx context.Context, items []T)) {
+	tx, ok := ctx.Value(txKey{}).(*gormTx)
+	if !ok {
+		flush(context.WithoutCancel(ctx), []T{item})
+		return
+	}
+	tx.queue(key, func(ctx context.Context, items []any) {
+		typed := make([]T, len(items))
+		for i, item := range items {
+			typed[i] = item.(T)
+		}
+		flush(ctx, typed)
+	}, item)
+}
+
+// gormTx is a transaction or savepoint of a GormUnitOfWork
+type gormTx struct {
+	txHooks
+	db *gorm.DB
+}
+
+// GormUnitOfWork runs units of work in database transactions
+type GormUnitOfWork struct {
+	DB *gorm.DB
+}
+
+func NewGormUnitOfWork(db *gorm.DB) *GormUnitOfWork {
+	return &GormUnitOfWork{DB: db}
+}
+
+func gormStores(db *gorm.DB) Stores {
+	return Stores{
+		Books:   NewGormBookStore(db),
+		Users:   NewGormUserStore(db),
+		Authors: NewGormAuthorStore(db),
+		Loans:   NewGormLoanStore(db),
+		Reviews: NewGormReviewStore(db),
+		Files:   NewGormBookFileStore(db),
+	}
+}
+
+func (u *GormUnitOfWork) Stores() Stores {
+	return gormStores(u.DB)
+}
+
+func (u *GormUnitOfWork) WithTx(ctx context.Context, fn func(ctx context.Context, tx Stores) error) error {
+	// GORM turns a transaction started inside another into a savepoint
+	db := u.DB
+	parent, nested := ctx.Value(txKey{}).(*gormTx)
+	if nested {
+		db = parent.db
+	}
+
+	tx := &gormTx{}
+	err := db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		tx.db = db
+		return fn(context.WithValue(ctx, txKey{}, tx), gormStores(db))
+	})
+	if err != nil {
+		return err
+	}
+
+	if nested {
+		tx.release(&parent.txHooks)
+		return nil
+	}
+	tx.run(ctx)
+	return nil
+}
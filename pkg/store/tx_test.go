@@ -0,0 +1,238 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils/tests"
+)
+
+// recorder is a database/sql driver that records what it runs: statements by
+// their verb, and transactions. Every statement succeeds, affects one row and
+// returns none.
+type recorder struct {
+	mu   sync.Mutex
+	runs []string
+}
+
+func (r *recorder) record(run string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs = append(r.runs, run)
+}
+
+func (r *recorder) recorded() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.runs...)
+}
+
+func (r *recorder) Connect(context.Context) (driver.Conn, error) { return recordingConn{r}, nil }
+func (r *recorder) Driver() driver.Driver                        { return nil }
+
+type recordingConn struct{ r *recorder }
+
+func (c recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return recordingStmt{c.r, query}, nil
+}
+func (c recordingConn) Close() error { return nil }
+func (c recordingConn) Begin() (driver.Tx, error) {
+	c.r.record("BEGIN")
+	return recordingTx{c.r}, nil
+}
+
+type recordingTx struct{ r *recorder }
+
+func (t recordingTx) Commit() error   { t.r.record("COMMIT"); return nil }
+func (t recordingTx) Rollback() error { t.r.record("ROLLBACK"); return nil }
+
+type recordingStmt struct {
+	r     *recorder
+	query string
+}
+
+func (s recordingStmt) Close() error  { return nil }
+func (s recordingStmt) NumInput() int { return -1 }
+func (s recordingStmt) Exec([]driver.Value) (driver.Result, error) {
+	s.run()
+	return driver.RowsAffected(1), nil
+}
+func (s recordingStmt) Query([]driver.Value) (driver.Rows, error) {
+	s.run()
+	return emptyRows{}, nil
+}
+
+func (s recordingStmt) run() {
+	if strings.HasPrefix(s.query, "ROLLBACK TO") {
+		s.r.record("ROLLBACK TO")
+		return
+	}
+	s.r.record(strings.Fields(s.query)[0])
+}
+
+type emptyRows struct{}
+
+func (emptyRows) Columns() []string         { return nil }
+func (emptyRows) Close() error              { return nil }
+func (emptyRows) Next([]driver.Value) error { return io.EOF }
+
+// savepointDialector is the dummy dialector with savepoints
+type savepointDialector struct {
+	tests.DummyDialector
+}
+
+func (savepointDialector) SavePoint(tx *gorm.DB, name string) error {
+	return tx.Exec("SAVEPOINT " + name).Error
+}
+
+func (savepointDialector) RollbackTo(tx *gorm.DB, name string) error {
+	return tx.Exec("ROLLBACK TO SAVEPOINT " + name).Error
+}
+
+// newRecordingUnitOfWork returns a unit of work over a recorder
+func newRecordingUnitOfWork(t *testing.T) (*recorder, *GormUnitOfWork) {
+	r := &recorder{}
+	pool := sql.OpenDB(r)
+	t.Cleanup(func() { pool.Close() })
+	db, err := gorm.Open(savepointDialector{}, &gorm.Config{ConnPool: pool, Logger: logger.Discard})
+	assert.NoError(t, err)
+	return r, NewGormUnitOfWork(db)
+}
+
+func TestGormUnitOfWorkCommits(t *testing.T) {
+	ctx := context.Background()
+	r, uow := newRecordingUnitOfWork(t)
+
+	book := models.Book{ID: uuid.New(), Title: "Dune", Author: "Frank Herbert", Version: 1}
+	err := uow.WithTx(ctx, func(ctx context.Context, tx Stores) error {
+		if err := tx.Books.UpdateBook(ctx, &book); err != nil {
+			return err
+		}
+		AfterCommit(ctx, func(ctx context.Context) { r.record("hook") })
+		return tx.Users.CreateUser(ctx, &models.User{Username: "reader"})
+	})
+	assert.NoError(t, err)
+
+	// Both stores write in the transaction, the hook waits for the commit
+	assert.Equal(t, []string{"BEGIN", "SELECT", "UPDATE", "INSERT", "COMMIT", "hook"}, r.recorded())
+}
+
+func TestGormUnitOfWorkRollsBack(t *testing.T) {
+	ctx := context.Background()
+	r, uow := newRecordingUnitOfWork(t)
+
+	failed := errors.New("failed")
+	book := models.Book{ID: uuid.New(), Title: "Dune", Author: "Frank Herbert", Version: 1}
+	err := uow.WithTx(ctx, func(ctx context.Context, tx Stores) error {
+		if err := tx.Books.UpdateBook(ctx, &book); err != nil {
+			return err
+		}
+		AfterCommit(ctx, func(ctx context.Context) { r.record("hook") })
+		return failed
+	})
+	assert.ErrorIs(t, err, failed)
+	assert.Equal(t, []string{"BEGIN", "SELECT", "UPDATE", "ROLLBACK"}, r.recorded())
+}
+
+func TestGormUnitOfWorkSavepoints(t *testing.T) {
+	ctx := context.Background()
+	r, uow := newRecordingUnitOfWork(t)
+
+	failed := errors.New("failed")
+	book := models.Book{ID: uuid.New(), Title: "Dune", Author: "Frank Herbert", Version: 1}
+	err := uow.WithTx(ctx, func(ctx context.Context, tx Stores) error {
+		if err := tx.Books.UpdateBook(ctx, &book); err != nil {
+			return err
+		}
+		err := uow.WithTx(ctx, func(ctx context.Context, tx Stores) error {
+			AfterCommit(ctx, func(ctx context.Context) { r.record("released hook") })
+			return tx.Books.UpdateBook(ctx, &book)
+		})
+		assert.NoError(t, err)
+
+		// A failed savepoint is rolled back on its own
+		err = uow.WithTx(ctx, func(ctx context.Context, tx Stores) error {
+			AfterCommit(ctx, func(ctx context.Context) { r.record("rolled back hook") })
+			if err := tx.Books.UpdateBook(ctx, &book); err != nil {
+				return err
+			}
+			return failed
+		})
+		assert.ErrorIs(t, err, failed)
+
+		AfterCommit(ctx, func(ctx context.Context) { r.record("hook") })
+		return nil
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"BEGIN", "SELECT", "UPDATE",
+		"SAVEPOINT", "SELECT", "UPDATE",
+		"SAVEPOINT", "SELECT", "UPDATE", "ROLLBACK TO",
+		"COMMIT", "released hook", "hook",
+	}, r.recorded())
+}
+
+func TestAfterCommitBatch(t *testing.T) {
+	ctx := context.Background()
+	r, uow := newRecordingUnitOfWork(t)
+
+	var flushed [][]string
+	flush := func(ctx context.Context, items []string) {
+		flushed = append(flushed, items)
+	}
+
+	failed := errors.New("failed")
+	err := uow.WithTx(ctx, func(ctx context.Context, tx Stores) error {
+		AfterCommitBatch(ctx, "titles", "Dune", flush)
+		err := uow.WithTx(ctx, func(ctx context.Context, tx Stores) error {
+			AfterCommitBatch(ctx, "titles", "Emma", flush)
+			return nil
+		})
+		assert.NoError(t, err)
+
+		err = uow.WithTx(ctx, func(ctx context.Context, tx Stores) error {
+			AfterCommitBatch(ctx, "titles", "Ulysses", flush)
+			return failed
+		})
+		assert.ErrorIs(t, err, failed)
+
+		AfterCommitBatch(ctx, "authors", "Austen", flush)
+		AfterCommitBatch(ctx, "titles", "Persuasion", flush)
+		assert.Empty(t, flushed)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"BEGIN", "SAVEPOINT", "SAVEPOINT", "ROLLBACK TO", "COMMIT"}, r.recorded())
+
+	// One flush per key, without the items of the rolled back savepoint
+	assert.Equal(t, [][]string{{"Dune", "Emma", "Persuasion"}, {"Austen"}}, flushed)
+
+	// Outside of a transaction every item is flushed right away
+	flushed = nil
+	AfterCommitBatch(ctx, "titles", "Dune", flush)
+	assert.Equal(t, [][]string{{"Dune"}}, flushed)
+}
+
+func TestAfterCommitOutsideTransaction(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ran := false
+	AfterCommit(ctx, func(ctx context.Context) {
+		ran = true
+		assert.NoError(t, ctx.Err())
+	})
+	assert.True(t, ran)
+}
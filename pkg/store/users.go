@@ -0,0 +1,51 @@
+package store
+
+import (
+	"context"
+
+	"github.com/kev1nandreas/go-rest-api-template/pkg/models"
+	"gorm.io/gorm"
+)
+
+// UserStore keeps the user accounts
+type UserStore interface {
+	GetUserByUsername(ctx context.Context, username string) (models.User, error)
+	// CreateUser adds user, or returns ErrConflict if the username is taken
+	CreateUser(ctx context.Context, user *models.User) error
+	// SetUserRole changes the role of the user with the given ID and returns
+	// the user, or ErrNotFound
+	SetUserRole(ctx context.Context, id string, role string) (models.User, error)
+}
+
+// GormUserStore keeps users in the users table
+type GormUserStore struct {
+	DB *gorm.DB
+}
+
+func NewGormUserStore(db *gorm.DB) *GormUserStore {
+	return &GormUserStore{DB: db}
+}
+
+func (s *GormUserStore) GetUserByUsername(ctx context.Context, username string) (models.User, error) {
+	var user models.User
+	err := s.DB.WithContext(ctx).Where("username = ?", username).First(&user).Error
+	return user, translate(err)
+}
+
+func (s *GormUserStore) CreateUser(ctx context.Context, user *models.User) error {
+	return translate(s.DB.WithContext(ctx).Create(user).Error)
+}
+
+func (s *GormUserStore) SetUserRole(ctx context.Context, id string, role string) (models.User, error) {
+	var user models.User
+	result := s.DB.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Update("role", role)
+	if result.Error != nil {
+		return user, translate(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return user, ErrNotFound
+	}
+
+	err := s.DB.WithContext(ctx).Where("id = ?", id).First(&user).Error
+	return user, translate(err)
+}